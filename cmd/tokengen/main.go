@@ -17,6 +17,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/integration/nwo/artifactgen/gen"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/certfier"
 	pp2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/ttxdb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/version"
 )
 
@@ -44,6 +45,7 @@ func main() {
 	mainCmd.AddCommand(pp2.Cmd())
 	mainCmd.AddCommand(certfier.KeyPairGenCmd())
 	mainCmd.AddCommand(gen.Cmd())
+	mainCmd.AddCommand(ttxdb.ExportCmd())
 	mainCmd.AddCommand(version.Cmd())
 
 	// On failure Cobra prints the usage message and error string, so we only
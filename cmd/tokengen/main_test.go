@@ -18,6 +18,7 @@ import (
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh/driver"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
@@ -79,6 +80,82 @@ func TestGenFullSuccess(t *testing.T) {
 	gt.Expect(idemixPK).To(BeEquivalentTo(pp.IdemixIssuerPK))
 }
 
+func TestGenBulletproofs(t *testing.T) {
+	gt := NewGomegaWithT(t)
+	tokengen, err := gexec.Build("github.com/hyperledger-labs/fabric-token-sdk/cmd/tokengen")
+	gt.Expect(err).NotTo(HaveOccurred())
+	defer gexec.CleanupBuildArtifacts()
+
+	tempOutput, err := ioutil.TempDir("", "tokengen-test")
+	gt.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempOutput)
+
+	testGenRun(
+		gt,
+		tokengen,
+		[]string{
+			"gen",
+			"dlog",
+			"--idemix",
+			"./testdata/idemix",
+			"--range-proof",
+			"bulletproofs",
+			"--bit-length",
+			"32",
+			"--output",
+			tempOutput,
+		},
+	)
+
+	ppRaw, err := ioutil.ReadFile(filepath.Join(tempOutput, "zkatdlog_pp.json"))
+	gt.Expect(err).NotTo(HaveOccurred())
+
+	pp, err := crypto.NewPublicParamsFromBytes(ppRaw, crypto.DLogPublicParameters)
+	gt.Expect(err).NotTo(HaveOccurred())
+	gt.Expect(pp.Validate()).To(Succeed())
+	gt.Expect(pp.RangeProofType).To(Equal(crypto.Bulletproofs))
+	gt.Expect(pp.BulletproofParams.BitLength).To(Equal(32))
+	gt.Expect(pp.MaxTokenValue()).To(Equal(uint64(1<<32 - 1)))
+}
+
+func TestGenGraphHiding(t *testing.T) {
+	gt := NewGomegaWithT(t)
+	tokengen, err := gexec.Build("github.com/hyperledger-labs/fabric-token-sdk/cmd/tokengen")
+	gt.Expect(err).NotTo(HaveOccurred())
+	defer gexec.CleanupBuildArtifacts()
+
+	tempOutput, err := ioutil.TempDir("", "tokengen-test")
+	gt.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempOutput)
+
+	testGenRun(
+		gt,
+		tokengen,
+		[]string{
+			"gen",
+			"dlog",
+			"--idemix",
+			"./testdata/idemix",
+			"--graph-hiding",
+			"--anonymity-set-bit-length",
+			"3",
+			"--output",
+			tempOutput,
+		},
+	)
+
+	ppRaw, err := ioutil.ReadFile(filepath.Join(tempOutput, "zkatdlog_pp.json"))
+	gt.Expect(err).NotTo(HaveOccurred())
+
+	_, err = crypto.NewPublicParamsFromBytes(ppRaw, crypto.DLogPublicParameters)
+	gt.Expect(err).To(HaveOccurred())
+	pp, err := crypto.NewPublicParamsFromBytes(ppRaw, crypto.DLogGraphHidingPublicParameters)
+	gt.Expect(err).NotTo(HaveOccurred())
+	gt.Expect(pp.Validate()).To(Succeed())
+	gt.Expect(pp.GraphHiding()).To(BeTrue())
+	gt.Expect(pp.GraphHidingParams.AnonymitySetSize()).To(Equal(8))
+}
+
 func TestGenFailure(t *testing.T) {
 	gt := NewGomegaWithT(t)
 	tokengen, err := gexec.Build("github.com/hyperledger-labs/fabric-token-sdk/cmd/tokengen")
@@ -47,6 +47,8 @@ const (
 	ZKATDLogInteropHTLCTwoFabricNetworksOrion
 	ZKATDLogInteropFastExchangeTwoFabricNetworksOrion
 	ZKATDLogInteropHTLCSwapNoCrossWithOrionAndFabricNetworks
+	FabTokenInteropSwapTwoFabricNetworks
+	ZKATDLogInteropSwapTwoFabricNetworks
 )
 
 // StartPortForNode On linux, the default ephemeral port range is 32768-60999 and can be
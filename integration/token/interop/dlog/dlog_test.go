@@ -104,6 +104,25 @@ var _ = Describe("DLog end to end", func() {
 		})
 	})
 
+	Describe("Swap Two Fabric Networks", func() {
+		BeforeEach(func() {
+			var err error
+			ii, err = integration.New(
+				integration2.ZKATDLogInteropSwapTwoFabricNetworks.StartPortForNode(),
+				"",
+				interop.HTLCTwoFabricNetworksTopology("dlog")...,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			ii.RegisterPlatformFactory(token.NewPlatformFactory())
+			ii.Generate()
+			ii.Start()
+		})
+
+		It("Performed an atomic swap with the swap coordinator", func() {
+			interop.TestSwap(ii)
+		})
+	})
+
 	Describe("HTLC No Cross Claim Two Fabric Networks", func() {
 		BeforeEach(func() {
 			var err error
@@ -20,6 +20,7 @@ import (
 	views2 "github.com/hyperledger-labs/fabric-token-sdk/integration/token/interop/views"
 	"github.com/hyperledger-labs/fabric-token-sdk/integration/token/interop/views/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/query"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	. "github.com/onsi/gomega"
@@ -327,6 +328,37 @@ func fastExchange(network *integration.Infrastructure, id string, recipient stri
 	time.Sleep(5 * time.Second)
 }
 
+func swap(network *integration.Infrastructure, id string, recipient string, tmsID1 token.TMSID, typ1 string, amount1 uint64, tmsID2 token.TMSID, typ2 string, amount2 uint64, deadline time.Duration) {
+	res, err := network.Client(id).CallView("htlc.swap", common.JSONMarshall(&htlc.Swap{
+		Recipient:           network.Identity(recipient),
+		TMSID1:              tmsID1,
+		Type1:               typ1,
+		Amount1:             amount1,
+		TMSID2:              tmsID2,
+		Type2:               typ2,
+		Amount2:             amount2,
+		ReclamationDeadline: deadline,
+	}))
+	Expect(err).NotTo(HaveOccurred())
+	result := &htlc2.Swap{}
+	common.JSONUnmarshal(res.([]byte), result)
+	Expect(result.Status).To(Equal(htlc2.SwapCompleted))
+	Expect(result.PreImage).NotTo(BeEmpty())
+	Expect(result.ClaimTxID).NotTo(BeEmpty())
+
+	// the responder completes its side once it finds the pre-image
+	Eventually(func() []string { return pendingSwaps(network, recipient) }, 2*time.Minute, 5*time.Second).Should(BeEmpty())
+	Expect(pendingSwaps(network, id)).To(BeEmpty())
+}
+
+func pendingSwaps(network *integration.Infrastructure, id string) []string {
+	res, err := network.Client(id).CallView("htlc.pendingSwaps", nil)
+	Expect(err).NotTo(HaveOccurred())
+	var ids []string
+	common.JSONUnmarshal(res.([]byte), &ids)
+	return ids
+}
+
 func scan(network *integration.Infrastructure, id string, hash []byte, hashFunc crypto.Hash, startingTransactionID string, opts ...token.ServiceOption) {
 	options, err := token.CompileServiceOptions(opts...)
 	Expect(err).NotTo(HaveOccurred())
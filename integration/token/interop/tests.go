@@ -252,3 +252,27 @@ func TestFastExchange(network *integration.Infrastructure) {
 	CheckBalance(network, "alice", "", "USD", 10, token.WithTMSID(beta))
 	CheckBalance(network, "bob", "", "USD", 20, token.WithTMSID(beta))
 }
+
+func TestSwap(network *integration.Infrastructure) {
+	alpha := token.TMSID{Network: "alpha"}
+	beta := token.TMSID{Network: "beta"}
+
+	RegisterAuditor(network, token.WithTMSID(alpha))
+	RegisterAuditor(network, token.WithTMSID(beta))
+
+	IssueCashWithTMS(network, alpha, "issuer", "", "EUR", 30, "alice")
+	CheckBalance(network, "alice", "", "EUR", 30, token.WithTMSID(alpha))
+
+	IssueCashWithTMS(network, beta, "issuer", "", "USD", 30, "bob")
+	CheckBalance(network, "bob", "", "USD", 30, token.WithTMSID(beta))
+
+	swap(network, "alice", "bob", alpha, "EUR", 10, beta, "USD", 10, 1*time.Hour)
+
+	CheckBalanceWithLockedAndHolding(network, "alice", "", "EUR", 20, 0, 0, -1, token.WithTMSID(alpha))
+	CheckBalanceWithLockedAndHolding(network, "bob", "", "EUR", 10, 0, 0, -1, token.WithTMSID(alpha))
+	CheckBalanceWithLockedAndHolding(network, "alice", "", "USD", 10, 0, 0, -1, token.WithTMSID(beta))
+	CheckBalanceWithLockedAndHolding(network, "bob", "", "USD", 20, 0, 0, -1, token.WithTMSID(beta))
+
+	CheckAuditorDB(network, alpha, "auditor", "", nil)
+	CheckAuditorDB(network, beta, "auditor", "", nil)
+}
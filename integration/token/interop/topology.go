@@ -221,6 +221,8 @@ func HTLCTwoFabricNetworksTopology(tokenSDKDriver string) []api.Topology {
 	alice.RegisterViewFactory("htlc.claim", &htlc.ClaimViewFactory{})
 	alice.RegisterResponder(&htlc.LockAcceptView{}, &htlc.LockView{})
 	alice.RegisterViewFactory("htlc.fastExchange", &htlc.FastExchangeInitiatorViewFactory{})
+	alice.RegisterViewFactory("htlc.swap", &htlc.SwapInitiatorViewFactory{})
+	alice.RegisterViewFactory("htlc.pendingSwaps", &htlc.PendingSwapsViewFactory{})
 	alice.RegisterViewFactory("balance", &views2.BalanceViewFactory{})
 	alice.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	alice.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
@@ -239,6 +241,8 @@ func HTLCTwoFabricNetworksTopology(tokenSDKDriver string) []api.Topology {
 	bob.RegisterViewFactory("htlc.claim", &htlc.ClaimViewFactory{})
 	bob.RegisterResponder(&htlc.LockAcceptView{}, &htlc.LockView{})
 	bob.RegisterResponder(&htlc.FastExchangeResponderView{}, &htlc.FastExchangeInitiatorView{})
+	bob.RegisterResponder(&htlc.SwapResponderView{}, &htlc.SwapInitiatorView{})
+	bob.RegisterViewFactory("htlc.pendingSwaps", &htlc.PendingSwapsViewFactory{})
 	bob.RegisterViewFactory("balance", &views2.BalanceViewFactory{})
 	bob.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	bob.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"encoding/json"
+	"time"
+
+	view3 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/assert"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+)
+
+// Swap contains the input information to run an atomic swap with the swap coordinator
+type Swap struct {
+	// Recipient is the identity of the counterparty's FSC node
+	Recipient view.Identity
+	// TMSID1 identifies the TMS of the initiator's leg
+	TMSID1 token.TMSID
+	// Type1 is the type of the initiator's leg
+	Type1 string
+	// Amount1 is the amount of the initiator's leg
+	Amount1 uint64
+
+	TMSID2  token.TMSID
+	Type2   string
+	Amount2 uint64
+
+	// ReclamationDeadline is the deadline of the initiator's lock
+	ReclamationDeadline time.Duration
+}
+
+// SwapInitiatorView runs the initiator's side of an atomic swap, with the transactions audited by the auditor
+type SwapInitiatorView struct {
+	*Swap
+}
+
+func (v *SwapInitiatorView) Call(context view.Context) (interface{}, error) {
+	return context.RunView(htlc.NewSwapInitiatorView(
+		v.Recipient,
+		&htlc.Terms{
+			ReclamationDeadline: v.ReclamationDeadline,
+			TMSID1:              v.TMSID1,
+			Type1:               v.Type1,
+			Amount1:             v.Amount1,
+			TMSID2:              v.TMSID2,
+			Type2:               v.Type2,
+			Amount2:             v.Amount2,
+		},
+		htlc.WithSwapAuditor(view3.GetIdentityProvider(context).Identity("auditor")),
+	))
+}
+
+type SwapInitiatorViewFactory struct{}
+
+func (f *SwapInitiatorViewFactory) NewView(in []byte) (view.View, error) {
+	v := &SwapInitiatorView{Swap: &Swap{}}
+	err := json.Unmarshal(in, v.Swap)
+	assert.NoError(err, "failed unmarshalling input")
+
+	return v, nil
+}
+
+// SwapResponderView runs the responder's side of an atomic swap, with the transactions audited by the auditor
+type SwapResponderView struct{}
+
+func (v *SwapResponderView) Call(context view.Context) (interface{}, error) {
+	return context.RunView(htlc.NewSwapResponderView(
+		htlc.WithSwapAuditor(view3.GetIdentityProvider(context).Identity("auditor")),
+	))
+}
+
+// PendingSwapsView returns the IDs of the swaps of this node that can still progress
+type PendingSwapsView struct{}
+
+func (v *PendingSwapsView) Call(context view.Context) (interface{}, error) {
+	swaps, err := htlc.GetSwapStore(context).Pending()
+	assert.NoError(err, "failed listing pending swaps")
+	ids := make([]string, len(swaps))
+	for i, swap := range swaps {
+		ids[i] = swap.ID
+	}
+	return ids, nil
+}
+
+type PendingSwapsViewFactory struct{}
+
+func (f *PendingSwapsViewFactory) NewView(in []byte) (view.View, error) {
+	return &PendingSwapsView{}, nil
+}
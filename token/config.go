@@ -19,3 +19,23 @@ type ConfigManager struct {
 func (m *ConfigManager) Certifiers() []string {
 	return m.cm.TMS().Certification.Interactive.IDs
 }
+
+// TTXDB returns the configuration of the transaction db, if any.
+func (m *ConfigManager) TTXDB() *config.TTXDB {
+	return m.cm.TMS().TTXDB
+}
+
+// Selector returns the configuration of the token selector, if any.
+func (m *ConfigManager) Selector() *config.Selector {
+	return m.cm.TMS().Selector
+}
+
+// HTLC returns the configuration of the htlc service, if any.
+func (m *ConfigManager) HTLC() *config.HTLC {
+	return m.cm.TMS().HTLC
+}
+
+// NFT returns the configuration of the nft service, if any.
+func (m *ConfigManager) NFT() *config.NFT {
+	return m.cm.TMS().NFT
+}
@@ -20,6 +20,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	// Membership selects the range proof based on the membership proofs of signed values
+	Membership = "membership"
+	// Bulletproofs selects the Bulletproofs range proof
+	Bulletproofs = crypto.Bulletproofs
+)
+
 type GeneratorArgs struct {
 	// IdemixMSPDir is the directory containing the Idemix MSP config (Issuer Key Pair)
 	IdemixMSPDir string
@@ -35,6 +42,14 @@ type GeneratorArgs struct {
 	Base int64
 	// Exponent is a dlog driver related parameter
 	Exponent int
+	// RangeProof is the range proof scheme, membership or bulletproofs
+	RangeProof string
+	// BitLength is the bit length of the token values when the range proof scheme is bulletproofs
+	BitLength int
+	// GraphHiding indicates whether to generate the public parameters of the graph hiding driver
+	GraphHiding bool
+	// AnonymitySetBitLength is the bit length of the size of the anonymity sets, when the graph is hidden
+	AnonymitySetBitLength int
 }
 
 var (
@@ -54,6 +69,17 @@ var (
 	// Exponent is a dlog driver related parameter
 	// It is used to define the maximum quantity a token can contain as Base^Exponent
 	Exponent int
+	// RangeProof is the range proof scheme, membership or bulletproofs.
+	// The membership range proof uses Base and Exponent, the bulletproofs range proof uses BitLength
+	RangeProof string
+	// BitLength is a dlog driver related parameter.
+	// It is used to define the maximum quantity a token can contain as 2^BitLength-1, when the range proof is bulletproofs
+	BitLength int
+	// GraphHiding indicates whether to generate the public parameters of the graph hiding driver
+	GraphHiding bool
+	// AnonymitySetBitLength is a graph hiding driver related parameter.
+	// Each spent token is hidden among 2^AnonymitySetBitLength tokens
+	AnonymitySetBitLength int
 )
 
 // Cmd returns the Cobra Command for Version
@@ -67,6 +93,10 @@ func Cmd() *cobra.Command {
 	flags.StringVarP(&IdemixMSPDir, "idemix", "i", "", "idemix msp dir")
 	flags.Int64VarP(&Base, "base", "b", 100, "base is used to define the maximum quantity a token can contain as Base^Exponent")
 	flags.IntVarP(&Exponent, "exponent", "e", 2, "exponent is used to define the maximum quantity a token can contain as Base^Exponent")
+	flags.StringVarP(&RangeProof, "range-proof", "r", Membership, "range proof scheme (membership, bulletproofs)")
+	flags.IntVarP(&BitLength, "bit-length", "l", 64, "bit length is used to define the maximum quantity a token can contain as 2^BitLength-1, when the range proof is bulletproofs (8, 16, 32, 64)")
+	flags.BoolVarP(&GraphHiding, "graph-hiding", "", false, "generate the public parameters of the graph hiding driver")
+	flags.IntVarP(&AnonymitySetBitLength, "anonymity-set-bit-length", "", 4, "each spent token is hidden among 2^AnonymitySetBitLength tokens, when the graph is hidden")
 
 	return cobraCommand
 }
@@ -82,13 +112,17 @@ var cobraCommand = &cobra.Command{
 		// Parsing of the command line is done so silence cmd usage
 		cmd.SilenceUsage = true
 		raw, err := Gen(&GeneratorArgs{
-			IdemixMSPDir:      IdemixMSPDir,
-			OutputDir:         OutputDir,
-			GenerateCCPackage: GenerateCCPackage,
-			Issuers:           Issuers,
-			Auditors:          Auditors,
-			Base:              Base,
-			Exponent:          Exponent,
+			IdemixMSPDir:          IdemixMSPDir,
+			OutputDir:             OutputDir,
+			GenerateCCPackage:     GenerateCCPackage,
+			Issuers:               Issuers,
+			Auditors:              Auditors,
+			Base:                  Base,
+			Exponent:              Exponent,
+			RangeProof:            RangeProof,
+			BitLength:             BitLength,
+			GraphHiding:           GraphHiding,
+			AnonymitySetBitLength: AnonymitySetBitLength,
 		})
 		if err != nil {
 			return errors.Wrap(err, "failed to generate public parameters")
@@ -114,10 +148,24 @@ func Gen(args *GeneratorArgs) ([]byte, error) {
 
 	// Setup
 	// TODO: update the curve here
-	pp, err := crypto.Setup(args.Base, args.Exponent, ipkBytes, math3.BN254)
+	var pp *crypto.PublicParams
+	switch args.RangeProof {
+	case "", Membership:
+		pp, err = crypto.Setup(args.Base, args.Exponent, ipkBytes, math3.BN254)
+	case Bulletproofs:
+		pp, err = crypto.SetupBulletproofs(args.BitLength, ipkBytes, math3.BN254)
+	default:
+		return nil, errors.Errorf("unknown range proof [%s], expected [%s] or [%s]", args.RangeProof, Membership, Bulletproofs)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed setting up public parameters")
 	}
+	if args.GraphHiding {
+		pp.Label = crypto.DLogGraphHidingPublicParameters
+		if err := pp.GenerateGraphHidingParameters(args.AnonymitySetBitLength); err != nil {
+			return nil, errors.Wrap(err, "failed setting up graph hiding parameters")
+		}
+	}
 	if err := common.SetupIssuersAndAuditors(pp, args.Auditors, args.Issuers); err != nil {
 		return nil, err
 	}
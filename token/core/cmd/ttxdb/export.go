@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/badger"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// Transactions selects the export of the transaction records
+	Transactions = "transactions"
+	// Movements selects the export of the movement records
+	Movements = "movements"
+)
+
+var dbPath string
+var records string
+var format string
+var output string
+var from string
+var to string
+var sender string
+var recipient string
+var statuses []string
+
+// ExportCmd returns the Cobra Command for exporting the records of a ttxdb
+func ExportCmd() *cobra.Command {
+	// Set the flags on the node start command.
+	flags := cobraCommand.Flags()
+	flags.StringVarP(&dbPath, "path", "p", "", "path to the badger ttxdb folder")
+	flags.StringVarP(&records, "records", "r", Transactions, "records to export (transactions, movements)")
+	flags.StringVarP(&format, "format", "f", string(ttxdb.CSV), "export format (csv, jsonl)")
+	flags.StringVarP(&output, "output", "o", "", "output file, standard output if empty")
+	flags.StringVar(&from, "from", "", "export records stored from this time on, in RFC3339 format")
+	flags.StringVar(&to, "to", "", "export records stored up to this time, in RFC3339 format")
+	flags.StringVar(&sender, "sender", "", "sender enrollment ID, records match if either the sender or the recipient match")
+	flags.StringVar(&recipient, "recipient", "", "recipient enrollment ID, records match if either the sender or the recipient match")
+	flags.StringSliceVar(&statuses, "status", nil, "statuses to export (Pending, Confirmed, Deleted), any if empty")
+
+	return cobraCommand
+}
+
+var cobraCommand = &cobra.Command{
+	Use:   "ttxdb-export",
+	Short: "Export the records of a token transactions db.",
+	Long:  `Export the transaction or movement records of a badger token transactions db, opened offline, to CSV or JSON Lines.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return export()
+	},
+}
+
+// export opens the db at dbPath and exports the selected records
+func export() error {
+	if len(dbPath) == 0 {
+		return errors.New("path to the ttxdb folder not specified")
+	}
+	params := ttxdb.QueryTransactionsParams{
+		SenderWallet:    sender,
+		RecipientWallet: recipient,
+	}
+	var err error
+	if params.From, err = parseTime(from); err != nil {
+		return errors.WithMessage(err, "invalid from")
+	}
+	if params.To, err = parseTime(to); err != nil {
+		return errors.WithMessage(err, "invalid to")
+	}
+	for _, status := range statuses {
+		params.Statuses = append(params.Statuses, ttxdb.TxStatus(status))
+	}
+
+	if _, err := os.Stat(dbPath); err != nil {
+		return errors.Wrapf(err, "failed accessing ttxdb at [%s]", dbPath)
+	}
+	persistence, err := badger.OpenDB(dbPath)
+	if err != nil {
+		return errors.WithMessagef(err, "failed opening ttxdb at [%s]", dbPath)
+	}
+	defer persistence.Close()
+
+	var w io.Writer = os.Stdout
+	if len(output) != 0 {
+		f, err := os.Create(output)
+		if err != nil {
+			return errors.Wrapf(err, "failed creating output file [%s]", output)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	qe := ttxdb.NewDB(persistence).NewQueryExecutor()
+	defer qe.Done()
+	switch records {
+	case Transactions:
+		err = qe.ExportTransactions(w, ttxdb.ExportFormat(format), params)
+	case Movements:
+		err = qe.ExportMovements(w, ttxdb.ExportFormat(format), params)
+	default:
+		return errors.Errorf("records [%s] not supported, expected [%s] or [%s]", records, Transactions, Movements)
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "failed exporting %s", records)
+	}
+	return nil
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed parsing time [%s]", s)
+	}
+	return &t, nil
+}
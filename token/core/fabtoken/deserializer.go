@@ -7,12 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package fabtoken
 
 import (
-	"encoding/json"
-
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 )
 
@@ -32,10 +29,11 @@ type deserializer struct {
 
 // NewDeserializer returns a deserializer
 func NewDeserializer() *deserializer {
+	ownerDeserializer := identity.NewRawOwnerIdentityDeserializer(&x509.MSPIdentityDeserializer{})
 	return &deserializer{
 		auditorDeserializer: &x509.MSPIdentityDeserializer{},
 		issuerDeserializer:  &x509.MSPIdentityDeserializer{},
-		ownerDeserializer:   htlc.NewDeserializer(identity.NewRawOwnerIdentityDeserializer(&x509.MSPIdentityDeserializer{})),
+		ownerDeserializer:   identity.NewScriptDeserializer(ownerDeserializer),
 	}
 }
 
@@ -73,14 +71,9 @@ func (e *enrollmentService) GetEnrollmentID(auditInfo []byte) (string, error) {
 		return "", nil
 	}
 
-	// Try to unmarshal it as ScriptInfo
-	si := &htlc.ScriptInfo{}
-	err := json.Unmarshal(auditInfo, si)
-	if err == nil && (len(si.Sender) != 0 || len(si.Recipient) != 0) {
-		if len(si.Recipient) != 0 {
-			return string(si.Recipient), nil
-		}
-		return "", nil
+	// Try to unmarshal it as the audit info of a script
+	if eID, ok, err := identity.ScriptEnrollmentID(auditInfo, e.GetEnrollmentID); ok || err != nil {
+		return eID, err
 	}
 	return string(auditInfo), nil
 }
@@ -17,6 +17,21 @@ import (
 // Issue also returns a serialization OutputMetadata associated with issued tokens
 // and the identity of the issuer
 func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+	types := make([]string, len(values))
+	for i := range types {
+		types[i] = typ
+	}
+	return s.BatchIssue(issuerIdentity, types, values, owners, opts)
+}
+
+// BatchIssue returns an IssueAction whose i-th output has the i-th type, value, and owner.
+// Each fabtoken output carries its own type, therefore the types can differ.
+// BatchIssue also returns a serialization OutputMetadata associated with issued tokens
+// and the identity of the issuer
+func (s *Service) BatchIssue(issuerIdentity view.Identity, types []string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+	if len(types) != len(values) || len(owners) != len(values) {
+		return nil, nil, nil, errors.Errorf("expected [%d] types and owners, got [%d] and [%d]", len(values), len(types), len(owners))
+	}
 	for _, owner := range owners {
 		// a recipient cannot be empty
 		if len(owner) == 0 {
@@ -37,7 +52,7 @@ func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 				Owner: &token2.Owner{
 					Raw: owners[i],
 				},
-				Type:     typ,
+				Type:     types[i],
 				Quantity: q.Hex(),
 			},
 		})
@@ -10,7 +10,6 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -65,24 +64,16 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token2
 			receivers = append(receivers, output.Output.Owner.Raw)
 			continue
 		}
-		owner, err := identity.UnmarshallRawOwner(output.Output.Owner.Raw)
+		recipient, err := identity.Recipient(output.Output.Owner.Raw)
 		if err != nil {
-			return nil, nil, errors.Wrap(err, "failed to unmarshal owner of input token")
-		}
-		if owner.Type == identity.SerializedIdentityType {
-			receivers = append(receivers, output.Output.Owner.Raw)
-			continue
-		}
-		_, recipient, err := htlc.GetScriptSenderAndRecipient(owner)
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "failed getting script sender and recipient")
+			return nil, nil, errors.Wrap(err, "failed getting the recipient of the output owner")
 		}
 		receivers = append(receivers, recipient)
 	}
 
 	var senderAuditInfos [][]byte
 	for _, t := range inputTokens {
-		auditInfo, err := htlc.GetOwnerAuditInfo(t.Owner.Raw, s)
+		auditInfo, err := identity.GetOwnerAuditInfo(t.Owner.Raw, s)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for sender identity [%s]", view.Identity(t.Owner.Raw).String())
 		}
@@ -91,7 +82,7 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token2
 
 	var receiverAuditInfos [][]byte
 	for _, output := range outs {
-		auditInfo, err := htlc.GetOwnerAuditInfo(output.Output.Owner.Raw, s)
+		auditInfo, err := identity.GetOwnerAuditInfo(output.Output.Owner.Raw, s)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(output.Output.Owner.Raw).String())
 		}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fractional"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -41,6 +42,10 @@ func NewValidator(pp *PublicParams, deserializer driver.Deserializer, extraValid
 		TransferSignatureValidate,
 		TransferBalanceValidate,
 		TransferHTLCValidate,
+		TransferEscrowValidate,
+		TransferMultisigValidate,
+		TransferTimeLockValidate,
+		TransferFractionalValidate,
 	}
 	transferValidators = append(transferValidators, extraValidators...)
 	v := &Validator{
@@ -86,6 +91,11 @@ func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider d
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
 	}
+	// verify that the shares of the NFTs in custody match their custody
+	err = v.VerifyFractions(ledger, ia, ta)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to verify the shares of the NFTs in custody [%s]", binding)
+	}
 
 	var actions []interface{}
 	for _, action := range ia {
@@ -268,6 +278,48 @@ func (v *Validator) VerifyTransfer(ledger driver.Ledger, inputTokens []*token2.T
 	return nil
 }
 
+// VerifyFractions checks that the shares of an NFT are issued only in the token request that locks the NFT in custody,
+// and redeemed, all of them, only in the token request that releases it
+func (v *Validator) VerifyFractions(ledger driver.Ledger, issues []*IssueAction, transfers []*TransferAction) error {
+	supply := fractional.NewSupply()
+	for _, issue := range issues {
+		for _, output := range issue.Outputs {
+			q, err := token2.ToQuantity(output.Output.Quantity, v.pp.QuantityPrecision)
+			if err != nil {
+				return errors.Wrapf(err, "failed parsing quantity [%s]", output.Output.Quantity)
+			}
+			if err := supply.Issue(output.Output.Type, q, output.Output.Owner.Raw); err != nil {
+				return err
+			}
+		}
+	}
+	for i, t := range transfers {
+		inputTokens, err := RetrieveInputsFromTransferAction(t, ledger)
+		if err != nil {
+			return errors.Wrapf(err, "failed to retrieve input from transfer action at index %d", i)
+		}
+		for _, in := range inputTokens {
+			if err := supply.Release(in.Owner.Raw); err != nil {
+				return err
+			}
+		}
+		for _, output := range t.Outputs {
+			q, err := token2.ToQuantity(output.Output.Quantity, v.pp.QuantityPrecision)
+			if err != nil {
+				return errors.Wrapf(err, "failed parsing quantity [%s]", output.Output.Quantity)
+			}
+			if output.IsRedeem() {
+				supply.Redeem(output.Output.Type, q)
+				continue
+			}
+			if err := supply.Lock(output.Output.Type, q, output.Output.Owner.Raw); err != nil {
+				return err
+			}
+		}
+	}
+	return supply.Verify()
+}
+
 // RetrieveInputsFromTransferAction retrieves from the passed ledger the inputs identified in TransferAction
 func RetrieveInputsFromTransferAction(t *TransferAction, ledger driver.Ledger) ([]*token2.Token, error) {
 	var inputTokens []*token2.Token
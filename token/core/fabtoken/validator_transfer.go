@@ -11,9 +11,15 @@ import (
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fractional"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	escrow2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/escrow"
 	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/multisig"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/timelock"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	fractional2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -183,3 +189,143 @@ func TransferHTLCValidate(ctx *Context) error {
 	}
 	return nil
 }
+
+// TransferEscrowValidate checks the validity of the escrow scripts, if any.
+// The signatures of the parties releasing an escrow-token are checked by TransferSignatureValidate.
+func TransferEscrowValidate(ctx *Context) error {
+	for _, in := range ctx.InputTokens {
+		owner, err := identity.UnmarshallRawOwner(in.Owner.Raw)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal owner of input token")
+		}
+		// is it owned by an escrow script?
+		if owner.Type == escrow.ScriptType {
+			// Then, the action must release this input only, to a single output.
+			if len(ctx.InputTokens) != 1 || len(ctx.Action.GetOutputs()) != 1 {
+				return errors.New("invalid transfer action: an escrow script only transfers the ownership of a token")
+			}
+
+			// check type and quantity
+			output := ctx.Action.GetOutputs()[0].(*Output)
+			tok := output.Output
+			if in.Type != tok.Type {
+				return errors.New("invalid transfer action: type of input does not match type of output")
+			}
+			if in.Quantity != tok.Quantity {
+				return errors.New("invalid transfer action: quantity of input does not match quantity of output")
+			}
+			if output.IsRedeem() {
+				return errors.New("invalid transfer action: the output corresponding to an escrow release should not be a redeem")
+			}
+
+			// check owner field
+			if _, err := escrow2.VerifyOwner(in.Owner.Raw, tok.Owner.Raw); err != nil {
+				return errors.Wrap(err, "failed to verify transfer from escrow script")
+			}
+		}
+	}
+
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*Output)
+		if !ok {
+			return errors.New("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		owner, err := identity.UnmarshallRawOwner(out.Output.Owner.Raw)
+		if err != nil {
+			return err
+		}
+		if owner.Type == escrow.ScriptType {
+			if _, err := escrow2.VerifyScript(out.Output.Owner.Raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TransferMultisigValidate checks the validity of the multisig identities owning the outputs, if any.
+// The signatures of the co-owners spending a multisig token are checked by TransferSignatureValidate.
+func TransferMultisigValidate(ctx *Context) error {
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*Output)
+		if !ok {
+			return errors.New("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if err := multisig.VerifyOwner(out.Output.Owner.Raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferTimeLockValidate checks that the time-locked inputs, if any, are unlocked,
+// and that the time-lock scripts owning the outputs, if any, are valid.
+// The signature of the owner of a time-locked input is checked by TransferSignatureValidate.
+func TransferTimeLockValidate(ctx *Context) error {
+	now := time.Now()
+	for _, in := range ctx.InputTokens {
+		if err := timelock.VerifyOwner(in.Owner.Raw, now); err != nil {
+			return errors.Wrap(err, "failed to verify transfer from time-lock script")
+		}
+	}
+
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*Output)
+		if !ok {
+			return errors.New("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if err := timelock.VerifyScript(out.Output.Owner.Raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferFractionalValidate checks that an NFT in custody, if any, is released by a transfer of its ownership only,
+// and that the custody scripts owning the outputs, if any, are valid.
+// The signature of the custodian is checked by TransferSignatureValidate, the redeem of the shares by Validator.VerifyFractions.
+func TransferFractionalValidate(ctx *Context) error {
+	for _, in := range ctx.InputTokens {
+		if !fractional2.IsInCustody(in.Owner.Raw) {
+			continue
+		}
+		// Then, the action must release this input only, to a single output.
+		if len(ctx.InputTokens) != 1 || len(ctx.Action.GetOutputs()) != 1 {
+			return errors.New("invalid transfer action: an NFT in custody is released by a transfer of its ownership only")
+		}
+		output := ctx.Action.GetOutputs()[0].(*Output)
+		tok := output.Output
+		if in.Type != tok.Type {
+			return errors.New("invalid transfer action: type of input does not match type of output")
+		}
+		if in.Quantity != tok.Quantity {
+			return errors.New("invalid transfer action: quantity of input does not match quantity of output")
+		}
+		if output.IsRedeem() {
+			return errors.New("invalid transfer action: the output corresponding to a release from custody should not be a redeem")
+		}
+	}
+
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*Output)
+		if !ok {
+			return errors.New("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if err := fractional.VerifyScript(out.Output.Owner.Raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabtoken
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// party signs by prefixing the message with its name
+type party string
+
+func (p party) Sign(message []byte) ([]byte, error) {
+	return append([]byte(p), message...), nil
+}
+
+func (p party) Verify(message, sigma []byte) error {
+	if !bytes.Equal(append([]byte(p), message...), sigma) {
+		return errors.Errorf("invalid signature of [%s]", p)
+	}
+	return nil
+}
+
+// partyDeserializer returns the party named by a serialized identity
+type partyDeserializer struct{}
+
+func (d *partyDeserializer) DeserializeVerifier(id view.Identity) (driver.Verifier, error) {
+	ro, err := identity.UnmarshallRawOwner(id)
+	if err != nil {
+		return nil, err
+	}
+	return party(ro.Identity), nil
+}
+
+type testDeserializer struct {
+	owner *identity.ScriptDeserializer
+}
+
+func (d *testDeserializer) GetOwnerVerifier(id view.Identity) (driver.Verifier, error) {
+	return d.owner.DeserializeVerifier(id)
+}
+
+func (d *testDeserializer) GetIssuerVerifier(id view.Identity) (driver.Verifier, error) {
+	panic("not needed")
+}
+
+func (d *testDeserializer) GetAuditorVerifier(id view.Identity) (driver.Verifier, error) {
+	panic("not needed")
+}
+
+func (d *testDeserializer) GetOwnerMatcher(raw []byte) (driver.Matcher, error) {
+	panic("not needed")
+}
+
+// signatureProvider verifies the signature of each owner on message
+type signatureProvider struct {
+	message    []byte
+	signatures map[string][]byte
+}
+
+func (s *signatureProvider) HasBeenSignedBy(id view.Identity, verifier driver.Verifier) ([]byte, error) {
+	sigma := s.signatures[id.UniqueID()]
+	return sigma, verifier.Verify(s.message, sigma)
+}
+
+func (s *signatureProvider) Signatures() [][]byte {
+	return nil
+}
+
+func partyIdentity(t *testing.T, name string) view.Identity {
+	id, err := identity.MarshallRawOwner(&identity.RawOwner{Type: identity.SerializedIdentityType, Identity: []byte(name)})
+	assert.NoError(t, err)
+	return id
+}
+
+func TestTransferEscrowValidate(t *testing.T) {
+	buyer, seller, arbitrator := partyIdentity(t, "buyer"), partyIdentity(t, "seller"), partyIdentity(t, "arbitrator")
+	rawScript, err := json.Marshal(&escrow.Script{Buyer: buyer, Seller: seller, Arbitrator: arbitrator})
+	assert.NoError(t, err)
+	owner, err := identity.MarshallRawOwner(&identity.RawOwner{Type: escrow.ScriptType, Identity: rawScript})
+	assert.NoError(t, err)
+	message := []byte("request")
+
+	// release returns the context of the release of an escrow-token to the passed recipient,
+	// signed by the passed signers on behalf of the passed parties, indexed by the role they sign for
+	release := func(recipient view.Identity, signers map[string]party) *Context {
+		sig := &escrow.Signature{}
+		for role, signer := range signers {
+			sigma, err := signer.Sign(message)
+			assert.NoError(t, err)
+			switch role {
+			case "buyer":
+				sig.Buyer = sigma
+			case "seller":
+				sig.Seller = sigma
+			case "arbitrator":
+				sig.Arbitrator = sigma
+			}
+		}
+		sigma, err := json.Marshal(sig)
+		assert.NoError(t, err)
+		return &Context{
+			PP:                &PublicParams{QuantityPrecision: 64},
+			Deserializer:      &testDeserializer{owner: identity.NewScriptDeserializer(&partyDeserializer{})},
+			SignatureProvider: &signatureProvider{message: message, signatures: map[string][]byte{view.Identity(owner).UniqueID(): sigma}},
+			InputTokens:       []*token.Token{{Owner: &token.Owner{Raw: owner}, Type: "USD", Quantity: "0x0a"}},
+			Action: &TransferAction{
+				Inputs:  []string{"escrow"},
+				Outputs: []*Output{{Output: &token.Token{Owner: &token.Owner{Raw: recipient}, Type: "USD", Quantity: "0x0a"}}},
+			},
+		}
+	}
+	validate := func(ctx *Context) error {
+		if err := TransferSignatureValidate(ctx); err != nil {
+			return err
+		}
+		return TransferEscrowValidate(ctx)
+	}
+
+	// the seller and the arbitrator release to the seller
+	assert.NoError(t, validate(release(seller, map[string]party{"seller": "seller", "arbitrator": "arbitrator"})))
+
+	// the buyer and the arbitrator refund the buyer
+	assert.NoError(t, validate(release(buyer, map[string]party{"buyer": "buyer", "arbitrator": "arbitrator"})))
+
+	// a single party cannot release
+	err = validate(release(seller, map[string]party{"seller": "seller"}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "escrow-token released by [1] parties, at least [2] expected")
+
+	// the seller cannot sign for the arbitrator
+	err = validate(release(seller, map[string]party{"seller": "seller", "arbitrator": "seller"}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed verifying the signature of the arbitrator")
+
+	// the arbitrator is not a beneficiary
+	err = validate(release(arbitrator, map[string]party{"seller": "seller", "arbitrator": "arbitrator"}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "owner of output token does not correspond to the buyer or the seller in escrow script")
+
+	// nor any other identity
+	err = validate(release(partyIdentity(t, "mallory"), map[string]party{"buyer": "buyer", "seller": "seller"}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "owner of output token does not correspond to the buyer or the seller in escrow script")
+}
@@ -47,8 +47,18 @@ func (s *Service) RegisterAuditInfo(id view.Identity, auditInfo []byte) error {
 	return nil
 }
 
+// GetAuditInfo returns the audit info of the passed identity.
+// The audit info of an owner script, if not registered, is assembled from the audit info of its parties,
+// so that tokens can be issued to the script.
 func (s *Service) GetAuditInfo(id view.Identity) ([]byte, error) {
-	return view2.GetSigService(s.SP).GetAuditInfo(id)
+	auditInfo, err := view2.GetSigService(s.SP).GetAuditInfo(id)
+	if err != nil || len(auditInfo) != 0 {
+		return auditInfo, err
+	}
+	if owner, err := identity.UnmarshallRawOwner(id); err == nil && owner.Type != identity.SerializedIdentityType {
+		return identity.GetOwnerAuditInfo(id, view2.GetSigService(s.SP))
+	}
+	return nil, nil
 }
 
 func (s *Service) GetEnrollmentID(auditInfo []byte) (string, error) {
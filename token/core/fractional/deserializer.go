@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+	"github.com/pkg/errors"
+)
+
+// GetVerifier returns the verifier of the passed custody script.
+// The verifier of a custody script is the verifier of its custodian, the redeem of the shares is checked by the validator.
+func GetVerifier(id view.Identity, des identity.VerifierDeserializer) (driver.Verifier, error) {
+	script, err := unwrap(id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := des.DeserializeVerifier(script.Custodian)
+	if err != nil {
+		return nil, errors.Errorf("failed to unmarshal the identity of the custodian in the custody script")
+	}
+	return v, nil
+}
+
+func unwrap(raw []byte) (*fractional.Script, error) {
+	script, ok, err := fractional.Unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("[%s] is not a custody script", view.Identity(raw).String())
+	}
+	return script, nil
+}
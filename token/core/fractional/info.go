@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/pkg/errors"
+)
+
+// GetOwnerAuditInfo returns the audit info of the passed custody script
+func GetOwnerAuditInfo(raw view.Identity, s identity.AuditInfoProvider) ([]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	auditInfo := &ScriptInfo{}
+	auditInfo.Custodian, err = s.GetAuditInfo(script.Custodian)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting audit info for the custodian of custody script [%s]", raw.String())
+	}
+	return auditInfo.Marshal()
+}
+
+// ScriptInfo includes info about the custodian
+type ScriptInfo struct {
+	Custodian []byte
+}
+
+func (si *ScriptInfo) Marshal() ([]byte, error) {
+	return json.Marshal(si)
+}
+
+func (si *ScriptInfo) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, si)
+}
+
+// CustodianAuditInfo returns the audit info of the custodian, if the passed audit info is the one of a custody script
+func CustodianAuditInfo(auditInfo []byte) ([]byte, bool) {
+	si := &ScriptInfo{}
+	if err := si.Unmarshal(auditInfo); err != nil || len(si.Custodian) == 0 {
+		return nil, false
+	}
+	return si.Custodian, true
+}
+
+// GetEnrollmentID returns the enrollment ID of the custodian of the custody script with the passed audit info
+func GetEnrollmentID(auditInfo []byte, eID func([]byte) (string, error)) (string, bool, error) {
+	custodian, ok := CustodianAuditInfo(auditInfo)
+	if !ok {
+		return "", false, nil
+	}
+	id, err := eID(custodian)
+	return id, true, err
+}
+
+// GetParties returns the custodian of the passed custody script, and its audit info
+func GetParties(raw view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptInf := &ScriptInfo{}
+	if err := scriptInf.Unmarshal(auditInfo); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to unmarshal custody script info")
+	}
+	return []view.Identity{script.Custodian}, [][]byte{scriptInf.Custodian}, nil
+}
+
+// GetCustodian returns the custodian of the passed custody script, it receives the NFT and signs for it
+func GetCustodian(raw view.Identity) (view.Identity, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	return script.Custodian, nil
+}
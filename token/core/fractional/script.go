@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+)
+
+func init() {
+	identity.RegisterScriptType(fractional.ScriptType, &identity.ScriptType{
+		Verifier:     GetVerifier,
+		AuditInfo:    GetOwnerAuditInfo,
+		EnrollmentID: GetEnrollmentID,
+		Parties:      GetParties,
+		Recipient:    GetCustodian,
+		Signer:       GetCustodian,
+		// an NFT in custody is released only together with the redeem of its shares, it is never selected
+		IsLocked: func(owner view.Identity, _ time.Time) bool {
+			return fractional.IsInCustody(owner)
+		},
+	})
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"math/big"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// VerifyScript checks that the passed owner of an output, if a custody script, is valid.
+// The custodian of the script must be a serialized identity.
+func VerifyScript(outRawOwner []byte) error {
+	script, ok, err := fractional.Unwrap(outRawOwner)
+	if err != nil || !ok {
+		return err
+	}
+	if err := script.Validate(); err != nil {
+		return errors.WithMessagef(err, "custody script invalid")
+	}
+	custodian, err := identity.UnmarshallRawOwner(script.Custodian)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal the custodian of the custody script")
+	}
+	if custodian.Type != identity.SerializedIdentityType {
+		return errors.Errorf("the custodian of the custody script is not a serialized identity, got [%s]", custodian.Type)
+	}
+	return nil
+}
+
+// Supply collects the NFTs locked in custody and released by a token request, and the shares it issues and redeems.
+// It ties the supply of the shares of an NFT to its custody: the shares are issued only in the request that locks the NFT,
+// and redeemed only in the request that releases it, all of them at once.
+type Supply struct {
+	// locked are the shares backed by the NFTs locked in custody, by share type
+	locked map[string]*big.Int
+	// released are the shares backed by the NFTs released from custody, by share type
+	released map[string]*big.Int
+	// issued are the issued shares, by share type
+	issued map[string]*big.Int
+	// redeemed are the redeemed shares, by share type
+	redeemed map[string]*big.Int
+}
+
+func NewSupply() *Supply {
+	return &Supply{
+		locked:   map[string]*big.Int{},
+		released: map[string]*big.Int{},
+		issued:   map[string]*big.Int{},
+		redeemed: map[string]*big.Int{},
+	}
+}
+
+// Issue records an issued token of the passed type, quantity, and owner
+func (s *Supply) Issue(typ string, q token.Quantity, rawOwner []byte) error {
+	if fractional.IsInCustody(rawOwner) {
+		return errors.New("a token cannot be issued in custody")
+	}
+	if fractional.IsShareType(typ) {
+		add(s.issued, typ, q.ToBigInt())
+	}
+	return nil
+}
+
+// Lock records a transferred token of the passed type, quantity, and owner
+func (s *Supply) Lock(typ string, q token.Quantity, rawOwner []byte) error {
+	script, ok, err := fractional.Unwrap(rawOwner)
+	if err != nil || !ok {
+		return err
+	}
+	if err := VerifyScript(rawOwner); err != nil {
+		return err
+	}
+	if q.ToBigInt().Cmp(big.NewInt(1)) != 0 {
+		return errors.Errorf("only NFTs can be locked in custody, quantity is [%s]", q.Decimal())
+	}
+	if script.ShareType != fractional.ShareType(typ) {
+		return errors.Errorf("share type [%s] does not match the locked NFT", script.ShareType)
+	}
+	if _, ok := s.locked[script.ShareType]; ok {
+		return errors.Errorf("NFT of share type [%s] locked more than once", script.ShareType)
+	}
+	s.locked[script.ShareType] = new(big.Int).SetUint64(script.Shares)
+	return nil
+}
+
+// Release records a spent token owned by the passed owner
+func (s *Supply) Release(rawOwner []byte) error {
+	script, ok, err := fractional.Unwrap(rawOwner)
+	if err != nil || !ok {
+		return err
+	}
+	if _, ok := s.released[script.ShareType]; ok {
+		return errors.Errorf("NFT of share type [%s] released more than once", script.ShareType)
+	}
+	s.released[script.ShareType] = new(big.Int).SetUint64(script.Shares)
+	return nil
+}
+
+// Redeem records a redeemed token of the passed type and quantity
+func (s *Supply) Redeem(typ string, q token.Quantity) {
+	if fractional.IsShareType(typ) {
+		add(s.redeemed, typ, q.ToBigInt())
+	}
+}
+
+// Verify checks that the shares issued match the NFTs locked in custody,
+// and that the shares redeemed match the NFTs released
+func (s *Supply) Verify() error {
+	if err := match(s.locked, s.issued, "issued"); err != nil {
+		return err
+	}
+	return match(s.released, s.redeemed, "redeemed")
+}
+
+func match(expected, moved map[string]*big.Int, op string) error {
+	for typ, shares := range expected {
+		q, ok := moved[typ]
+		if !ok || q.Cmp(shares) != 0 {
+			return errors.Errorf("[%s] shares of type [%s] must be %s, got [%v]", shares, typ, op, q)
+		}
+	}
+	for typ := range moved {
+		if _, ok := expected[typ]; !ok {
+			return errors.Errorf("shares of type [%s] %s without their NFT", typ, op)
+		}
+	}
+	return nil
+}
+
+func add(m map[string]*big.Int, typ string, q *big.Int) {
+	if sum, ok := m[typ]; ok {
+		sum.Add(sum, q)
+		return
+	}
+	m[typ] = q
+}
@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupply(t *testing.T) {
+	alice, err := identity.MarshallRawOwner(&identity.RawOwner{Type: identity.SerializedIdentityType, Identity: []byte("alice")})
+	assert.NoError(t, err)
+	custody, err := fractional.Wrap(alice, "house", 100)
+	assert.NoError(t, err)
+	shareType := fractional.ShareType("house")
+	one := token.NewQuantityFromUInt64(1)
+
+	// lock the NFT and issue all the shares
+	s := NewSupply()
+	assert.NoError(t, s.Lock("house", one, custody))
+	assert.NoError(t, s.Issue(shareType, token.NewQuantityFromUInt64(60), alice))
+	assert.NoError(t, s.Issue(shareType, token.NewQuantityFromUInt64(40), alice))
+	assert.NoError(t, s.Verify())
+
+	// lock the NFT and issue too few shares
+	s = NewSupply()
+	assert.NoError(t, s.Lock("house", one, custody))
+	assert.NoError(t, s.Issue(shareType, token.NewQuantityFromUInt64(99), alice))
+	assert.EqualError(t, s.Verify(), "[100] shares of type ["+shareType+"] must be issued, got [99]")
+
+	// issue shares without locking the NFT
+	s = NewSupply()
+	assert.NoError(t, s.Issue(shareType, token.NewQuantityFromUInt64(100), alice))
+	assert.EqualError(t, s.Verify(), "shares of type ["+shareType+"] issued without their NFT")
+
+	// lock a token that does not match the script
+	s = NewSupply()
+	assert.Error(t, s.Lock("villa", one, custody))
+	assert.Error(t, s.Lock("house", token.NewQuantityFromUInt64(2), custody))
+	assert.Error(t, s.Issue("house", one, custody))
+
+	// release the NFT and redeem all the shares
+	s = NewSupply()
+	assert.NoError(t, s.Release(custody))
+	assert.NoError(t, s.Lock("house", one, alice))
+	s.Redeem(shareType, token.NewQuantityFromUInt64(100))
+	assert.NoError(t, s.Verify())
+
+	// release the NFT and redeem part of the shares
+	s = NewSupply()
+	assert.NoError(t, s.Release(custody))
+	s.Redeem(shareType, token.NewQuantityFromUInt64(50))
+	assert.EqualError(t, s.Verify(), "[100] shares of type ["+shareType+"] must be redeemed, got [50]")
+
+	// redeem shares without releasing the NFT
+	s = NewSupply()
+	s.Redeem(shareType, token.NewQuantityFromUInt64(100))
+	assert.EqualError(t, s.Verify(), "shares of type ["+shareType+"] redeemed without their NFT")
+
+	// the custodian of the script must be a serialized identity
+	invalid, err := fractional.Wrap(view.Identity("alice"), "house", 100)
+	assert.NoError(t, err)
+	assert.Error(t, VerifyScript(invalid))
+	assert.NoError(t, VerifyScript(custody))
+}
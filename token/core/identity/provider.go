@@ -83,6 +83,12 @@ func (p *Provider) GetAuditInfo(identity view.Identity) ([]byte, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", identity.String())
 	}
+	if len(auditInfo) == 0 {
+		// the audit info of a script, if not registered, is assembled from the audit info of its parties
+		if _, ok, err := lookupScript(identity); err == nil && ok {
+			return GetOwnerAuditInfo(identity, view2.GetSigService(p.sp))
+		}
+	}
 	return auditInfo, nil
 }
 
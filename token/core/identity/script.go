@@ -0,0 +1,256 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package identity
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+var (
+	scriptTypesMu sync.RWMutex
+	scriptTypes   = make(map[string]*ScriptType)
+	// scriptTypeNames keeps the registration order, the audit info of the script types is tried in this order
+	scriptTypeNames []string
+)
+
+// VerifierDeserializer deserializes the verifier of an identity
+type VerifierDeserializer interface {
+	DeserializeVerifier(id view.Identity) (driver.Verifier, error)
+}
+
+// AuditInfoProvider returns the audit info of an identity
+type AuditInfoProvider interface {
+	GetAuditInfo(identity view.Identity) ([]byte, error)
+}
+
+// ScriptType tells the token drivers and services how to handle the owners of a type of script,
+// the RawOwners whose type is not SerializedIdentityType.
+// Each hook gets the whole RawOwner. The optional hooks can be left nil.
+type ScriptType struct {
+	// Verifier returns the verifier of the passed script,
+	// given the deserializer of the serialized identities named in the script
+	Verifier func(owner view.Identity, des VerifierDeserializer) (driver.Verifier, error)
+	// AuditInfo returns the audit info of the passed script,
+	// given the provider of the audit info of the serialized identities named in the script
+	AuditInfo func(owner view.Identity, p AuditInfoProvider) ([]byte, error)
+	// EnrollmentID returns the enrollment ID of the owner of a script, given its audit info, and true,
+	// if the audit info is the one of this type of script. It returns false otherwise.
+	// The enrollment IDs of the identities named in the script are returned by eID.
+	EnrollmentID func(auditInfo []byte, eID func(auditInfo []byte) (string, error)) (string, bool, error)
+	// Parties returns the serialized identities named in the passed script, and the audit info of each of them,
+	// extracted from the passed audit info of the script. The auditor matches each identity to its audit info.
+	Parties func(owner view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error)
+	// Recipient returns the identity that receives the tokens owned by the passed script.
+	// Optional, the script itself is the recipient if not set.
+	Recipient func(owner view.Identity) (view.Identity, error)
+	// Signer returns the identity that signs for the passed script.
+	// Optional, the script has no single signer if not set.
+	Signer func(owner view.Identity) (view.Identity, error)
+	// CoOwners returns the identities that sign together for the passed script.
+	// Each of them receives the transactions moving the tokens owned by the script.
+	// Optional, the script has no co-owners if not set.
+	CoOwners func(owner view.Identity) ([]view.Identity, error)
+	// JoinSignatures returns the signature of the passed script, given the signatures of some of its co-owners,
+	// indexed by the unique ID of the co-owner. It must be set if CoOwners is set.
+	JoinSignatures func(owner view.Identity, sigmas map[string][]byte) ([]byte, error)
+	// IsLocked returns true if a token owned by the passed script cannot be selected at the passed time.
+	// Optional, the tokens are never locked if not set.
+	IsLocked func(owner view.Identity, timeReference time.Time) bool
+}
+
+// RegisterScriptType makes a type of script available by the provided RawOwner type.
+// If RegisterScriptType is called twice with the same name, or if the script type is nil, it panics.
+func RegisterScriptType(name string, scriptType *ScriptType) {
+	scriptTypesMu.Lock()
+	defer scriptTypesMu.Unlock()
+	if scriptType == nil {
+		panic("RegisterScriptType script type is nil")
+	}
+	if name == SerializedIdentityType {
+		panic("RegisterScriptType called for the serialized identity type")
+	}
+	if _, dup := scriptTypes[name]; dup {
+		panic("RegisterScriptType called twice for script type " + name)
+	}
+	scriptTypes[name] = scriptType
+	scriptTypeNames = append(scriptTypeNames, name)
+}
+
+// ScriptTypes returns the names of the registered script types, in registration order
+func ScriptTypes() []string {
+	scriptTypesMu.RLock()
+	defer scriptTypesMu.RUnlock()
+	return append([]string{}, scriptTypeNames...)
+}
+
+func getScriptType(name string) (*ScriptType, bool) {
+	scriptTypesMu.RLock()
+	defer scriptTypesMu.RUnlock()
+	scriptType, ok := scriptTypes[name]
+	return scriptType, ok
+}
+
+// lookupScript returns the script type of the passed owner.
+// It returns false if the owner is empty or a serialized identity, and fails if the type of the owner is not registered.
+func lookupScript(owner view.Identity) (*ScriptType, bool, error) {
+	if len(owner) == 0 {
+		return nil, false, nil
+	}
+	ro, err := UnmarshallRawOwner(owner)
+	if err != nil {
+		return nil, false, err
+	}
+	if ro.Type == SerializedIdentityType {
+		return nil, false, nil
+	}
+	scriptType, ok := getScriptType(ro.Type)
+	if !ok {
+		return nil, false, errors.Errorf("unknown owner type [%s]", ro.Type)
+	}
+	return scriptType, true, nil
+}
+
+// ScriptDeserializer returns the verifiers of the owners, either serialized identities or registered scripts
+type ScriptDeserializer struct {
+	// OwnerDeserializer deserializes the verifiers of the serialized identities
+	OwnerDeserializer VerifierDeserializer
+}
+
+func NewScriptDeserializer(ownerDeserializer VerifierDeserializer) *ScriptDeserializer {
+	return &ScriptDeserializer{OwnerDeserializer: ownerDeserializer}
+}
+
+func (d *ScriptDeserializer) DeserializeVerifier(id view.Identity) (driver.Verifier, error) {
+	scriptType, ok, err := lookupScript(id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to deserialize RawOwner")
+	}
+	if !ok {
+		return d.OwnerDeserializer.DeserializeVerifier(id)
+	}
+	return scriptType.Verifier(id, d.OwnerDeserializer)
+}
+
+// GetOwnerAuditInfo returns the audit info of the passed owner, either a serialized identity or a registered script.
+// The audit info of a redeemed token's owner is empty.
+func GetOwnerAuditInfo(owner view.Identity, p AuditInfoProvider) ([]byte, error) {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to unmarshal owner [%s]", owner.String())
+	}
+	if len(owner) == 0 {
+		// this is a redeem
+		return nil, nil
+	}
+	if !ok {
+		auditInfo, err := p.GetAuditInfo(owner)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", owner.String())
+		}
+		return auditInfo, nil
+	}
+	return scriptType.AuditInfo(owner, p)
+}
+
+// ScriptEnrollmentID returns the enrollment ID of the script owner with the passed audit info, and true,
+// if the audit info is the one of a registered script. It returns false otherwise.
+// The enrollment IDs of the identities named in the script are returned by eID.
+func ScriptEnrollmentID(auditInfo []byte, eID func(auditInfo []byte) (string, error)) (string, bool, error) {
+	for _, name := range ScriptTypes() {
+		scriptType, _ := getScriptType(name)
+		if scriptType.EnrollmentID == nil {
+			continue
+		}
+		if id, ok, err := scriptType.EnrollmentID(auditInfo, eID); ok || err != nil {
+			return id, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// ScriptParties returns the serialized identities named in the passed script owner, and the audit info of each of them,
+// extracted from the passed audit info of the script. The returned flag is false if the owner is not a script.
+func ScriptParties(owner view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, bool, error) {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil || !ok {
+		return nil, nil, ok, err
+	}
+	parties, auditInfos, err := scriptType.Parties(owner, auditInfo)
+	if err != nil {
+		return nil, nil, true, err
+	}
+	if len(parties) != len(auditInfos) {
+		return nil, nil, true, errors.Errorf("[%d] parties, but audit info for [%d] is provided", len(parties), len(auditInfos))
+	}
+	return parties, auditInfos, true, nil
+}
+
+// Recipient returns the identity that receives the tokens owned by the passed owner, the owner itself if not a script
+func Recipient(owner view.Identity) (view.Identity, error) {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || scriptType.Recipient == nil {
+		return owner, nil
+	}
+	return scriptType.Recipient(owner)
+}
+
+// Signer returns the identity that signs for the passed owner, the owner itself if it is not a script with a single signer
+func Signer(owner view.Identity) (view.Identity, error) {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || scriptType.Signer == nil {
+		return owner, nil
+	}
+	return scriptType.Signer(owner)
+}
+
+// CoOwners returns the identities that sign together for the passed owner.
+// The returned flag is false if the owner is not a script with co-owners.
+func CoOwners(owner view.Identity) ([]view.Identity, bool, error) {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil || !ok || scriptType.CoOwners == nil {
+		return nil, false, err
+	}
+	coOwners, err := scriptType.CoOwners(owner)
+	if err != nil {
+		return nil, true, err
+	}
+	return coOwners, true, nil
+}
+
+// JoinSignatures returns the signature of the passed owner, a script with co-owners,
+// given the signatures of some of its co-owners indexed by their unique IDs
+func JoinSignatures(owner view.Identity, sigmas map[string][]byte) ([]byte, error) {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || scriptType.JoinSignatures == nil {
+		return nil, errors.Errorf("owner [%s] has no co-owners", owner.String())
+	}
+	return scriptType.JoinSignatures(owner, sigmas)
+}
+
+// IsLockedByScript returns true if a token owned by the passed owner cannot be selected at the passed time.
+// The owners that are not registered scripts do not lock their tokens.
+func IsLockedByScript(owner view.Identity, timeReference time.Time) bool {
+	scriptType, ok, err := lookupScript(owner)
+	if err != nil || !ok || scriptType.IsLocked == nil {
+		return false
+	}
+	return scriptType.IsLocked(owner, timeReference)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package identity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+const testScriptType = "test-script"
+
+func init() {
+	RegisterScriptType(testScriptType, &ScriptType{
+		Parties: func(owner view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error) {
+			return []view.Identity{[]byte("alice"), []byte("bob")}, [][]byte{auditInfo}, nil
+		},
+		Signer: func(owner view.Identity) (view.Identity, error) {
+			return []byte("alice"), nil
+		},
+		IsLocked: func(owner view.Identity, timeReference time.Time) bool {
+			return timeReference.Before(time.Unix(100, 0))
+		},
+	})
+}
+
+func TestScriptTypes(t *testing.T) {
+	script, err := MarshallRawOwner(&RawOwner{Type: testScriptType, Identity: []byte("script")})
+	assert.NoError(t, err)
+	si, err := MarshallRawOwner(&RawOwner{Type: SerializedIdentityType, Identity: []byte("alice")})
+	assert.NoError(t, err)
+	unknown, err := MarshallRawOwner(&RawOwner{Type: "unknown", Identity: []byte("script")})
+	assert.NoError(t, err)
+
+	assert.Contains(t, ScriptTypes(), testScriptType)
+	assert.Panics(t, func() { RegisterScriptType(testScriptType, &ScriptType{}) })
+	assert.Panics(t, func() { RegisterScriptType(SerializedIdentityType, &ScriptType{}) })
+
+	// the hooks are called for the scripts, the serialized identities stand for themselves
+	signer, err := Signer(script)
+	assert.NoError(t, err)
+	assert.Equal(t, view.Identity("alice"), signer)
+	signer, err = Signer(si)
+	assert.NoError(t, err)
+	assert.Equal(t, si, signer)
+	recipient, err := Recipient(script)
+	assert.NoError(t, err)
+	assert.Equal(t, script, recipient)
+	_, ok, err := CoOwners(script)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.True(t, IsLockedByScript(script, time.Unix(10, 0)))
+	assert.False(t, IsLockedByScript(script, time.Unix(1000, 0)))
+	assert.False(t, IsLockedByScript(si, time.Unix(10, 0)))
+	assert.False(t, IsLockedByScript(unknown, time.Unix(10, 0)))
+
+	_, _, _, err = ScriptParties(script, []byte("audit info"))
+	assert.EqualError(t, err, "[2] parties, but audit info for [1] is provided")
+	_, _, ok, err = ScriptParties(si, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = Signer(unknown)
+	assert.EqualError(t, err, "unknown owner type [unknown]")
+}
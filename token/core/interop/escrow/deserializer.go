@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
+	"github.com/pkg/errors"
+)
+
+// GetVerifier returns the verifier of the passed escrow script
+func GetVerifier(id view.Identity, des identity.VerifierDeserializer) (driver.Verifier, error) {
+	script, err := unwrap(id)
+	if err != nil {
+		return nil, err
+	}
+	v := &escrow.Verifier{}
+	v.Buyer, err = des.DeserializeVerifier(script.Buyer)
+	if err != nil {
+		return nil, errors.Errorf("failed to unmarshal the identity of the buyer in the escrow script")
+	}
+	v.Seller, err = des.DeserializeVerifier(script.Seller)
+	if err != nil {
+		return nil, errors.Errorf("failed to unmarshal the identity of the seller in the escrow script")
+	}
+	v.Arbitrator, err = des.DeserializeVerifier(script.Arbitrator)
+	if err != nil {
+		return nil, errors.Errorf("failed to unmarshal the identity of the arbitrator in the escrow script")
+	}
+	return v, nil
+}
+
+func unwrap(raw []byte) (*escrow.Script, error) {
+	owner, err := identity.UnmarshallRawOwner(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal RawOwner")
+	}
+	if owner.Type != escrow.ScriptType {
+		return nil, errors.Errorf("[%s] is not an escrow script", view.Identity(raw).String())
+	}
+	script := &escrow.Script{}
+	if err := json.Unmarshal(owner.Identity, script); err != nil {
+		return nil, errors.Errorf("failed to unmarshal RawOwner as an escrow script")
+	}
+	return script, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
+	"github.com/pkg/errors"
+)
+
+// GetOwnerAuditInfo returns the audit info of the passed escrow script
+func GetOwnerAuditInfo(raw view.Identity, s identity.AuditInfoProvider) ([]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	auditInfo := &ScriptInfo{}
+	auditInfo.Buyer, err = s.GetAuditInfo(script.Buyer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting audit info for the buyer of escrow script [%s]", raw.String())
+	}
+	auditInfo.Seller, err = s.GetAuditInfo(script.Seller)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting audit info for the seller of escrow script [%s]", raw.String())
+	}
+	auditInfo.Arbitrator, err = s.GetAuditInfo(script.Arbitrator)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting audit info for the arbitrator of escrow script [%s]", raw.String())
+	}
+	raw, err = json.Marshal(auditInfo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshaling audit info for escrow script")
+	}
+	return raw, nil
+}
+
+// ScriptInfo includes info about the buyer, the seller, and the arbitrator
+type ScriptInfo struct {
+	Buyer      []byte
+	Seller     []byte
+	Arbitrator []byte
+}
+
+func (si *ScriptInfo) Marshal() ([]byte, error) {
+	return json.Marshal(si)
+}
+
+func (si *ScriptInfo) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, si)
+}
+
+// GetEnrollmentID returns the enrollment ID of the escrow script with the passed audit info,
+// made of the enrollment IDs of its buyer, seller, and arbitrator
+func GetEnrollmentID(auditInfo []byte, eID func([]byte) (string, error)) (string, bool, error) {
+	si := &ScriptInfo{}
+	if err := si.Unmarshal(auditInfo); err != nil || len(si.Buyer) == 0 || len(si.Seller) == 0 || len(si.Arbitrator) == 0 {
+		return "", false, nil
+	}
+	var eIDs []string
+	for _, party := range [][]byte{si.Buyer, si.Seller, si.Arbitrator} {
+		id, err := eID(party)
+		if err != nil {
+			return "", true, err
+		}
+		eIDs = append(eIDs, id)
+	}
+	return escrow.EnrollmentID(eIDs[0], eIDs[1], eIDs[2]), true, nil
+}
+
+// GetParties returns the buyer, the seller, and the arbitrator of the passed escrow script, and their audit info
+func GetParties(raw view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptInf := &ScriptInfo{}
+	if err := scriptInf.Unmarshal(auditInfo); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to unmarshal escrow script info")
+	}
+	return []view.Identity{script.Buyer, script.Seller, script.Arbitrator},
+		[][]byte{scriptInf.Buyer, scriptInf.Seller, scriptInf.Arbitrator}, nil
+}
+
+// GetSeller returns the seller of the passed escrow script, the party that an escrow-token is meant for
+func GetSeller(raw view.Identity) (view.Identity, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	return script.Seller, nil
+}
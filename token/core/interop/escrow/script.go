@@ -0,0 +1,22 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
+)
+
+func init() {
+	identity.RegisterScriptType(escrow.ScriptType, &identity.ScriptType{
+		Verifier:     GetVerifier,
+		AuditInfo:    GetOwnerAuditInfo,
+		EnrollmentID: GetEnrollmentID,
+		Parties:      GetParties,
+		Recipient:    GetSeller,
+	})
+}
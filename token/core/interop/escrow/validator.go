@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
+	"github.com/pkg/errors"
+)
+
+// VerifyOwner checks that the owner of the output releasing an escrow-token is either the buyer or the seller
+func VerifyOwner(inRawOwner []byte, outRawOwner []byte) (*escrow.Script, error) {
+	script, err := escrow.ScriptFromOwner(inRawOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !script.IsBeneficiary(outRawOwner) {
+		return nil, errors.New("owner of output token does not correspond to the buyer or the seller in escrow script")
+	}
+	return script, nil
+}
+
+// VerifyScript checks that the passed owner of an output is a valid escrow script
+func VerifyScript(outRawOwner []byte) (*escrow.Script, error) {
+	script, err := escrow.ScriptFromOwner(outRawOwner)
+	if err != nil {
+		return nil, err
+	}
+	if err := script.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "escrow script invalid")
+	}
+	return script, nil
+}
@@ -16,44 +16,18 @@ import (
 	"github.com/pkg/errors"
 )
 
-type VerifierDES interface {
-	DeserializeVerifier(id view.Identity) (driver.Verifier, error)
-}
-
-type Deserializer struct {
-	OwnerDeserializer VerifierDES
-}
-
-func NewDeserializer(ownerDeserializer VerifierDES) *Deserializer {
-	return &Deserializer{OwnerDeserializer: ownerDeserializer}
-}
-
-func (d *Deserializer) DeserializeVerifier(id view.Identity) (driver.Verifier, error) {
-	si, err := identity.UnmarshallRawOwner(id)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal RawOwner")
-	}
-	if si.Type == identity.SerializedIdentityType {
-		return d.OwnerDeserializer.DeserializeVerifier(id)
-	}
-	if si.Type == htlc.ScriptType {
-		return d.getHTLCVerifier(si.Identity)
-	}
-	return nil, errors.Errorf("failed to deserialize RawOwner: Unknown owner type %s", si.Type)
-}
-
-func (d *Deserializer) getHTLCVerifier(raw []byte) (driver.Verifier, error) {
-	script := &htlc.Script{}
-	err := json.Unmarshal(raw, script)
+// GetVerifier returns the verifier of the passed htlc script
+func GetVerifier(id view.Identity, des identity.VerifierDeserializer) (driver.Verifier, error) {
+	script, err := unwrap(id)
 	if err != nil {
-		return nil, errors.Errorf("failed to unmarshal RawOwner as an htlc script")
+		return nil, err
 	}
 	v := &htlc.Verifier{}
-	v.Sender, err = d.OwnerDeserializer.DeserializeVerifier(script.Sender)
+	v.Sender, err = des.DeserializeVerifier(script.Sender)
 	if err != nil {
 		return nil, errors.Errorf("failed to unmarshal the identity of the sender in the htlc script")
 	}
-	v.Recipient, err = d.OwnerDeserializer.DeserializeVerifier(script.Recipient)
+	v.Recipient, err = des.DeserializeVerifier(script.Recipient)
 	if err != nil {
 		return nil, errors.Errorf("failed to unmarshal the identity of the recipient in the htlc script")
 	}
@@ -63,3 +37,18 @@ func (d *Deserializer) getHTLCVerifier(raw []byte) (driver.Verifier, error) {
 	v.HashInfo.HashEncoding = script.HashInfo.HashEncoding
 	return v, nil
 }
+
+func unwrap(raw []byte) (*htlc.Script, error) {
+	owner, err := identity.UnmarshallRawOwner(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal RawOwner")
+	}
+	if owner.Type != htlc.ScriptType {
+		return nil, errors.Errorf("[%s] is not an htlc script", view.Identity(raw).String())
+	}
+	script := &htlc.Script{}
+	if err := json.Unmarshal(owner.Identity, script); err != nil {
+		return nil, errors.Errorf("failed to unmarshal RawOwner as an htlc script")
+	}
+	return script, nil
+}
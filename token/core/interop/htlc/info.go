@@ -11,47 +11,23 @@ import (
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/pkg/errors"
 )
 
-type AuditInfoProvider interface {
-	GetAuditInfo(identity view.Identity) ([]byte, error)
-}
-
-// GetOwnerAuditInfo returns the audit info of the owner
-func GetOwnerAuditInfo(raw []byte, s AuditInfoProvider) ([]byte, error) {
-	if len(raw) == 0 {
-		// this is a redeem
-		return nil, nil
-	}
-
-	owner, err := identity.UnmarshallRawOwner(raw)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal owner of input token")
-	}
-	if owner.Type == identity.SerializedIdentityType {
-		auditInfo, err := s.GetAuditInfo(raw)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(raw).String())
-		}
-		return auditInfo, nil
-	}
-
-	sender, recipient, err := GetScriptSenderAndRecipient(owner)
+// GetOwnerAuditInfo returns the audit info of the passed htlc script
+func GetOwnerAuditInfo(raw view.Identity, s identity.AuditInfoProvider) ([]byte, error) {
+	script, err := unwrap(raw)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed getting script sender and recipient")
+		return nil, err
 	}
-
 	auditInfo := &ScriptInfo{}
-	auditInfo.Sender, err = s.GetAuditInfo(sender)
+	auditInfo.Sender, err = s.GetAuditInfo(script.Sender)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed getting audit info for htlc script [%s]", view.Identity(raw).String())
+		return nil, errors.Wrapf(err, "failed getting audit info for htlc script [%s]", raw.String())
 	}
-
-	auditInfo.Recipient, err = s.GetAuditInfo(recipient)
+	auditInfo.Recipient, err = s.GetAuditInfo(script.Recipient)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed getting audit info for script [%s]", view.Identity(raw).String())
+		return nil, errors.Wrapf(err, "failed getting audit info for script [%s]", raw.String())
 	}
 	raw, err = json.Marshal(auditInfo)
 	if err != nil {
@@ -74,15 +50,37 @@ func (si *ScriptInfo) Unarshal(raw []byte) error {
 	return json.Unmarshal(raw, si)
 }
 
-// GetScriptSenderAndRecipient returns the script's sender and recipient according to the type of the given owner
-func GetScriptSenderAndRecipient(ro *identity.RawOwner) (sender, recipient view.Identity, err error) {
-	if ro.Type == htlc.ScriptType {
-		script := &htlc.Script{}
-		err = json.Unmarshal(ro.Identity, script)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "failed to unmarshal htlc script")
-		}
-		return script.Sender, script.Recipient, nil
+// GetEnrollmentID returns the enrollment ID of the recipient of the htlc script with the passed audit info
+func GetEnrollmentID(auditInfo []byte, eID func([]byte) (string, error)) (string, bool, error) {
+	si := &ScriptInfo{}
+	if err := si.Unarshal(auditInfo); err != nil || (len(si.Sender) == 0 && len(si.Recipient) == 0) {
+		return "", false, nil
+	}
+	if len(si.Recipient) == 0 {
+		return "", true, nil
+	}
+	id, err := eID(si.Recipient)
+	return id, true, err
+}
+
+// GetParties returns the sender and the recipient of the passed htlc script, and their audit info
+func GetParties(raw view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptInf := &ScriptInfo{}
+	if err := scriptInf.Unarshal(auditInfo); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to unmarshal htlc script info")
+	}
+	return []view.Identity{script.Sender, script.Recipient}, [][]byte{scriptInf.Sender, scriptInf.Recipient}, nil
+}
+
+// GetRecipient returns the recipient of the passed htlc script
+func GetRecipient(raw view.Identity) (view.Identity, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil, errors.New("unknown identity type")
+	return script.Recipient, nil
 }
@@ -0,0 +1,22 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+)
+
+func init() {
+	identity.RegisterScriptType(htlc.ScriptType, &identity.ScriptType{
+		Verifier:     GetVerifier,
+		AuditInfo:    GetOwnerAuditInfo,
+		EnrollmentID: GetEnrollmentID,
+		Parties:      GetParties,
+		Recipient:    GetRecipient,
+	})
+}
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
+	"github.com/pkg/errors"
+)
+
+// GetVerifier returns the verifier of the passed multisig identity.
+// It checks that at least the threshold of the co-owners signed.
+func GetVerifier(id view.Identity, des identity.VerifierDeserializer) (driver.Verifier, error) {
+	m, err := unwrap(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid multisig identity")
+	}
+	v := &multisig.Verifier{Threshold: m.Threshold}
+	for i, coOwner := range m.Identities {
+		verifier, err := des.DeserializeVerifier(coOwner)
+		if err != nil {
+			return nil, errors.Errorf("failed to unmarshal the identity of the co-owner at index [%d] in the multisig identity", i)
+		}
+		v.Verifiers = append(v.Verifiers, verifier)
+	}
+	return v, nil
+}
+
+func unwrap(raw []byte) (*multisig.MultiIdentity, error) {
+	m, ok, err := multisig.Unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("[%s] is not a multisig identity", view.Identity(raw).String())
+	}
+	return m, nil
+}
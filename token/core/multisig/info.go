@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
+	"github.com/pkg/errors"
+)
+
+// GetOwnerAuditInfo returns the audit info of the passed multisig identity
+func GetOwnerAuditInfo(raw view.Identity, s identity.AuditInfoProvider) ([]byte, error) {
+	m, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	auditInfo := &AuditInfo{}
+	for i, coOwner := range m.Identities {
+		coOwnerAuditInfo, err := s.GetAuditInfo(coOwner)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed getting audit info for the co-owner at index [%d] of multisig identity [%s]", i, raw.String())
+		}
+		auditInfo.AuditInfos = append(auditInfo.AuditInfos, coOwnerAuditInfo)
+	}
+	return auditInfo.Marshal()
+}
+
+// AuditInfo includes the audit info of the co-owners of a multisig identity, in the order of MultiIdentity.Identities
+type AuditInfo struct {
+	AuditInfos [][]byte
+}
+
+func (a *AuditInfo) Marshal() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+func (a *AuditInfo) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, a)
+}
+
+// CoOwnersAuditInfo returns the audit info of the co-owners, if the passed audit info is the one of a multisig identity
+func CoOwnersAuditInfo(auditInfo []byte) ([][]byte, bool) {
+	ai := &AuditInfo{}
+	if err := ai.Unmarshal(auditInfo); err != nil || len(ai.AuditInfos) == 0 {
+		return nil, false
+	}
+	return ai.AuditInfos, true
+}
+
+// GetEnrollmentID returns the enrollment ID of the multisig identity with the passed audit info,
+// made of the enrollment IDs of its co-owners
+func GetEnrollmentID(auditInfo []byte, eID func([]byte) (string, error)) (string, bool, error) {
+	coOwners, ok := CoOwnersAuditInfo(auditInfo)
+	if !ok {
+		return "", false, nil
+	}
+	var eIDs []string
+	for _, coOwner := range coOwners {
+		id, err := eID(coOwner)
+		if err != nil {
+			return "", true, err
+		}
+		eIDs = append(eIDs, id)
+	}
+	return multisig.EnrollmentID(eIDs...), true, nil
+}
+
+// GetParties returns the co-owners of the passed multisig identity, and their audit info
+func GetParties(raw view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error) {
+	m, err := unwrap(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	ai := &AuditInfo{}
+	if err := ai.Unmarshal(auditInfo); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to unmarshal multisig audit info")
+	}
+	if len(ai.AuditInfos) != len(m.Identities) {
+		return nil, nil, errors.Errorf("[%d] co-owners, but audit info for [%d] is provided", len(m.Identities), len(ai.AuditInfos))
+	}
+	return m.Identities, ai.AuditInfos, nil
+}
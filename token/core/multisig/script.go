@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
+)
+
+func init() {
+	identity.RegisterScriptType(multisig.Multisig, &identity.ScriptType{
+		Verifier:     GetVerifier,
+		AuditInfo:    GetOwnerAuditInfo,
+		EnrollmentID: GetEnrollmentID,
+		Parties:      GetParties,
+		// a multisig identity is the recipient of the tokens it owns, its co-owners sign together
+		CoOwners: func(owner view.Identity) ([]view.Identity, error) {
+			m, err := unwrap(owner)
+			if err != nil {
+				return nil, err
+			}
+			return m.Identities, nil
+		},
+		JoinSignatures: func(owner view.Identity, sigmas map[string][]byte) ([]byte, error) {
+			m, err := unwrap(owner)
+			if err != nil {
+				return nil, err
+			}
+			return multisig.JoinSignatures(m, sigmas)
+		},
+	})
+}
@@ -0,0 +1,35 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
+	"github.com/pkg/errors"
+)
+
+// VerifyOwner checks that the passed owner of an output, if a multisig identity, is valid.
+// The co-owners must be serialized identities.
+func VerifyOwner(outRawOwner []byte) error {
+	m, ok, err := multisig.Unwrap(outRawOwner)
+	if err != nil || !ok {
+		return err
+	}
+	if err := m.Validate(); err != nil {
+		return errors.WithMessagef(err, "multisig identity invalid")
+	}
+	for i, coOwner := range m.Identities {
+		owner, err := identity.UnmarshallRawOwner(coOwner)
+		if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal the co-owner at index [%d] of the multisig identity", i)
+		}
+		if owner.Type != identity.SerializedIdentityType {
+			return errors.Errorf("the co-owner at index [%d] of the multisig identity is not a serialized identity, got [%s]", i, owner.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timelock"
+	"github.com/pkg/errors"
+)
+
+// GetVerifier returns the verifier of the passed time-lock script.
+// The verifier of a time-lock script is the verifier of its owner, the unlock time is checked by the validator.
+func GetVerifier(id view.Identity, des identity.VerifierDeserializer) (driver.Verifier, error) {
+	script, err := unwrap(id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := des.DeserializeVerifier(script.Owner)
+	if err != nil {
+		return nil, errors.Errorf("failed to unmarshal the identity of the owner in the time-lock script")
+	}
+	return v, nil
+}
+
+func unwrap(raw []byte) (*timelock.Script, error) {
+	script, ok, err := timelock.Unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("[%s] is not a time-lock script", view.Identity(raw).String())
+	}
+	return script, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/pkg/errors"
+)
+
+// GetOwnerAuditInfo returns the audit info of the passed time-lock script
+func GetOwnerAuditInfo(raw view.Identity, s identity.AuditInfoProvider) ([]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	auditInfo := &ScriptInfo{}
+	auditInfo.Owner, err = s.GetAuditInfo(script.Owner)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting audit info for the owner of time-lock script [%s]", raw.String())
+	}
+	return auditInfo.Marshal()
+}
+
+// ScriptInfo includes info about the owner
+type ScriptInfo struct {
+	Owner []byte
+}
+
+func (si *ScriptInfo) Marshal() ([]byte, error) {
+	return json.Marshal(si)
+}
+
+func (si *ScriptInfo) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, si)
+}
+
+// OwnerAuditInfo returns the audit info of the owner, if the passed audit info is the one of a time-lock script
+func OwnerAuditInfo(auditInfo []byte) ([]byte, bool) {
+	si := &ScriptInfo{}
+	if err := si.Unmarshal(auditInfo); err != nil || len(si.Owner) == 0 {
+		return nil, false
+	}
+	return si.Owner, true
+}
+
+// GetEnrollmentID returns the enrollment ID of the owner of the time-lock script with the passed audit info
+func GetEnrollmentID(auditInfo []byte, eID func([]byte) (string, error)) (string, bool, error) {
+	owner, ok := OwnerAuditInfo(auditInfo)
+	if !ok {
+		return "", false, nil
+	}
+	id, err := eID(owner)
+	return id, true, err
+}
+
+// GetParties returns the owner of the passed time-lock script, and its audit info
+func GetParties(raw view.Identity, auditInfo []byte) ([]view.Identity, [][]byte, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptInf := &ScriptInfo{}
+	if err := scriptInf.Unmarshal(auditInfo); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to unmarshal time-lock script info")
+	}
+	return []view.Identity{script.Owner}, [][]byte{scriptInf.Owner}, nil
+}
+
+// GetOwner returns the owner of the passed time-lock script, it receives the tokens and signs for them
+func GetOwner(raw view.Identity) (view.Identity, error) {
+	script, err := unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	return script.Owner, nil
+}
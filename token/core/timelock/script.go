@@ -0,0 +1,29 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timelock"
+)
+
+func init() {
+	identity.RegisterScriptType(timelock.ScriptType, &identity.ScriptType{
+		Verifier:     GetVerifier,
+		AuditInfo:    GetOwnerAuditInfo,
+		EnrollmentID: GetEnrollmentID,
+		Parties:      GetParties,
+		Recipient:    GetOwner,
+		Signer:       GetOwner,
+		IsLocked: func(owner view.Identity, timeReference time.Time) bool {
+			return timelock.IsLocked(owner, timeReference)
+		},
+	})
+}
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timelock"
+	"github.com/pkg/errors"
+)
+
+// VerifyOwner checks that the passed owner of an input, if a time-lock script, is unlocked at the passed time
+func VerifyOwner(inRawOwner []byte, now time.Time) error {
+	script, ok, err := timelock.Unwrap(inRawOwner)
+	if err != nil || !ok {
+		return err
+	}
+	if script.IsLocked(now) {
+		return errors.Errorf("time-locked token cannot be spent before [%s]", script.UnlockTime)
+	}
+	return nil
+}
+
+// VerifyScript checks that the passed owner of an output, if a time-lock script, is valid.
+// The owner of the script must be a serialized identity.
+func VerifyScript(outRawOwner []byte) error {
+	script, ok, err := timelock.Unwrap(outRawOwner)
+	if err != nil || !ok {
+		return err
+	}
+	if err := script.Validate(); err != nil {
+		return errors.WithMessagef(err, "time-lock script invalid")
+	}
+	owner, err := identity.UnmarshallRawOwner(script.Owner)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal the owner of the time-lock script")
+	}
+	if owner.Type != identity.SerializedIdentityType {
+		return errors.Errorf("the owner of the time-lock script is not a serialized identity, got [%s]", owner.Type)
+	}
+	return nil
+}
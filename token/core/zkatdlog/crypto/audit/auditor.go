@@ -14,7 +14,6 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
@@ -270,47 +269,26 @@ func InspectTokenOwner(des Deserializer, token *AuditableToken, index int) error
 		}
 		return nil
 	}
-	return inspectTokenOwnerOfScript(des, token, index)
-}
-
-func inspectTokenOwnerOfScript(des Deserializer, token *AuditableToken, index int) error {
-	owner, err := identity.UnmarshallRawOwner(token.Token.Owner)
-	if err != nil {
-		return errors.Errorf("input owner at index [%d] cannot be unmarshalled", index)
-	}
-	scriptInf := &htlc.ScriptInfo{}
-	if err := json.Unmarshal(token.Owner.OwnerInfo, scriptInf); err != nil {
-		return errors.Wrapf(err, "failed to unmarshal script info")
-	}
-	scriptSender, scriptRecipient, err := htlc.GetScriptSenderAndRecipient(owner)
-	if err != nil {
-		return errors.Wrap(err, "failed getting script sender and recipient")
-	}
-
-	sender, err := des.GetOwnerMatcher(scriptInf.Sender)
-	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal audit info from script sender [%s]", string(scriptInf.Sender))
-	}
-	ro, err := identity.UnmarshallRawOwner(scriptSender)
-	if err != nil {
-		return errors.Wrapf(err, "failed to retrieve raw owner from sender in script")
-	}
-	if err := sender.Match(ro.Identity); err != nil {
-		return errors.Wrapf(err, "token at index [%d] does not match the provided opening [%s]", index, string(scriptInf.Sender))
-	}
-
-	recipient, err := des.GetOwnerMatcher(scriptInf.Recipient)
+	parties, auditInfos, ok, err := identity.ScriptParties(token.Token.Owner, token.Owner.OwnerInfo)
 	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal audit info from script recipient [%s]", string(scriptInf.Recipient))
+		return errors.WithMessagef(err, "failed getting the parties of the owner at index [%d]", index)
 	}
-	ro, err = identity.UnmarshallRawOwner(scriptRecipient)
-	if err != nil {
-		return errors.Wrapf(err, "failed to retrieve raw owner from recipien in script")
+	if !ok {
+		return errors.Errorf("owner at index [%d] is not a script", index)
 	}
-	if err := recipient.Match(ro.Identity); err != nil {
-		return errors.Wrapf(err, "token at index [%d] does not match the provided opening [%s]", index, string(scriptInf.Recipient))
+	for i, party := range parties {
+		matcher, err := des.GetOwnerMatcher(auditInfos[i])
+		if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal audit info from script party [%s]", string(auditInfos[i]))
+		}
+		ro, err := identity.UnmarshallRawOwner(party)
+		if err != nil {
+			return errors.Wrapf(err, "failed to retrieve raw owner from party in script")
+		}
+		if err := matcher.Match(ro.Identity); err != nil {
+			return errors.Wrapf(err, "token at index [%d] does not match the provided opening [%s]", index, string(auditInfos[i]))
+		}
 	}
-
 	return nil
 }
 
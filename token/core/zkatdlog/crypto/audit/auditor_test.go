@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	multisig2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/multisig"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit/mock"
@@ -30,6 +31,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	transfer2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
 )
 
 type idemix interface {
@@ -122,6 +124,56 @@ var _ = Describe("Auditor", func() {
 			})
 		})
 	})
+
+	Describe("Audit a transfer of tokens owned by a multisig identity", func() {
+		var (
+			transfer   *transfer2.TransferAction
+			metadata   driver.TransferMetadata
+			tokens     [][]*token.Token
+			auditInfos [][]byte
+		)
+		BeforeEach(func() {
+			transfer, metadata, tokens, auditInfos = createMultisigTransfer(pp)
+		})
+		check := func() error {
+			raw, err := transfer.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			return auditor.Check(&driver.TokenRequest{Transfers: [][]byte{raw}}, &driver.TokenRequestMetadata{Transfers: []driver.TransferMetadata{metadata}}, tokens, "1")
+		}
+		When("the audit info of all the co-owners is provided", func() {
+			It("succeeds", func() {
+				Expect(check()).To(Succeed())
+			})
+		})
+		When("the audit info of the co-owners of an input is swapped", func() {
+			It("fails", func() {
+				raw, err := (&multisig2.AuditInfo{AuditInfos: [][]byte{auditInfos[1], auditInfos[0]}}).Marshal()
+				Expect(err).NotTo(HaveOccurred())
+				metadata.SenderAuditInfos[0] = raw
+				err = check()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("token at index [0] does not match the provided opening"))
+			})
+		})
+		When("the audit info of a co-owner of an output is missing", func() {
+			It("fails", func() {
+				raw, err := (&multisig2.AuditInfo{AuditInfos: auditInfos[:1]}).Marshal()
+				Expect(err).NotTo(HaveOccurred())
+				metadata.ReceiverAuditInfos[1] = raw
+				err = check()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the owner at index [1]: [2] co-owners, but audit info for [1] is provided"))
+			})
+		})
+		When("the audit info of a single owner is provided", func() {
+			It("fails", func() {
+				metadata.SenderAuditInfos[1] = auditInfos[0]
+				err := check()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the owner at index [1]: [2] co-owners, but audit info for [0] is provided"))
+			})
+		})
+	})
 })
 
 func createTransfer(pp *crypto.PublicParams) (*transfer2.TransferAction, driver.TransferMetadata, [][]*token.Token) {
@@ -157,6 +209,40 @@ func createTransfer(pp *crypto.PublicParams) (*transfer2.TransferAction, driver.
 	return transfer, metadata, tokns
 }
 
+// createMultisigTransfer returns a transfer between tokens owned by a 2-out-of-2 multisig identity,
+// and the audit info of the co-owners
+func createMultisigTransfer(pp *crypto.PublicParams) (*transfer2.TransferAction, driver.TransferMetadata, [][]*token.Token, [][]byte) {
+	var coOwners []view.Identity
+	var auditInfos [][]byte
+	for i := 0; i < 2; i++ {
+		coOwner, auditInfo := getIdemixInfo("./testdata/idemix")
+		raw, err := auditInfo.Bytes()
+		Expect(err).NotTo(HaveOccurred())
+		coOwners = append(coOwners, coOwner)
+		auditInfos = append(auditInfos, raw)
+	}
+	id, err := multisig.Wrap(2, coOwners...)
+	Expect(err).NotTo(HaveOccurred())
+	auditInfo, err := (&multisig2.AuditInfo{AuditInfos: auditInfos}).Marshal()
+	Expect(err).NotTo(HaveOccurred())
+
+	transfer, inf, inputs := prepareTransfer(pp, id)
+	metadata := driver.TransferMetadata{}
+	for range transfer.Inputs {
+		metadata.SenderAuditInfos = append(metadata.SenderAuditInfos, auditInfo)
+	}
+	for i := 0; i < len(transfer.OutputTokens); i++ {
+		info, err := json.Marshal(inf[i])
+		Expect(err).NotTo(HaveOccurred())
+		metadata.OutputsMetadata = append(metadata.OutputsMetadata, info)
+		output, err := json.Marshal(transfer.OutputTokens[i].Data)
+		Expect(err).NotTo(HaveOccurred())
+		metadata.Outputs = append(metadata.Outputs, output)
+		metadata.ReceiverAuditInfos = append(metadata.ReceiverAuditInfos, auditInfo)
+	}
+	return transfer, metadata, [][]*token.Token{inputs}, auditInfos
+}
+
 func createTransferWithBogusOutput(pp *crypto.PublicParams) (*transfer2.TransferAction, driver.TransferMetadata, [][]*token.Token) {
 	id, auditInfo := getIdemixInfo("./testdata/idemix")
 	transfer, inf, inputs := prepareTransfer(pp, id)
@@ -0,0 +1,359 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package batch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	math "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// Equation is a multi-exponentiation equation \prod_i Bases[i]^Exponents[i] = 1.
+// A proof whose verification reduces to Equations can be verified in a batch.
+type Equation struct {
+	Bases     []*math.G1
+	Exponents []*math.Zr
+}
+
+// AddTerm adds the term base^exponent to the equation
+func (e *Equation) AddTerm(base *math.G1, exponent *math.Zr) {
+	e.Bases = append(e.Bases, base)
+	e.Exponents = append(e.Exponents, exponent)
+}
+
+// Holds returns true if the equation holds
+func (e *Equation) Holds(c *math.Curve) bool {
+	return e.eval(c).IsInfinity()
+}
+
+// eval returns \prod_i Bases[i]^Exponents[i]
+func (e *Equation) eval(c *math.Curve) *math.G1 {
+	res := c.NewG1()
+	for i := 0; i < len(e.Bases); i++ {
+		res.Add(e.Bases[i].Mul(e.Exponents[i]))
+	}
+	return res
+}
+
+// PairingEquation is a pairing product equation \prod_i e(G2[i], G1[i]) * \prod_j Gt[j] = 1,
+// where the G1 argument of each pairing is the multi-exponentiation encoded by an Equation,
+// and the Gt elements are final exponentiations, as sent by a prover.
+// A proof whose verification reduces to PairingEquations can be verified in a batch.
+type PairingEquation struct {
+	G2 []*math.G2
+	G1 []*Equation
+	Gt []*math.Gt
+}
+
+// AddPairing adds the term e(g2, g1) to the equation
+func (e *PairingEquation) AddPairing(g2 *math.G2, g1 *Equation) {
+	e.G2 = append(e.G2, g2)
+	e.G1 = append(e.G1, g1)
+}
+
+// Holds returns true if the equation holds
+func (e *PairingEquation) Holds(c *math.Curve) bool {
+	if len(e.G2) != len(e.G1) {
+		return false
+	}
+	g1 := make([]*math.G1, len(e.G1))
+	for i, eq := range e.G1 {
+		g1[i] = eq.eval(c)
+	}
+	res := pairingProduct(c, e.G2, g1)
+	for _, gt := range e.Gt {
+		if res != nil {
+			res.Mul(gt)
+			continue
+		}
+		// copy the first element, not to modify the equation
+		var err error
+		if res, err = c.NewGtFromBytes(gt.Bytes()); err != nil {
+			return false
+		}
+	}
+	return res == nil || res.IsUnity()
+}
+
+// Error is returned by Verifier when some proofs in the batch are invalid
+type Error struct {
+	// Labels are the labels of the invalid proofs
+	Labels []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid proofs [%s]", strings.Join(e.Labels, ", "))
+}
+
+type entry struct {
+	label     string
+	equations []*Equation
+	pairings  []*PairingEquation
+}
+
+// Verifier collects the equations of several proofs and checks them at once, as a random linear combination.
+// The terms of the combination that share a base are merged, so that the generators common to the proofs
+// are exponentiated only once, and the pairings that share a G2 element are computed only once.
+// The pairing equations are combined with 63-bit random weights, because their Gt elements are
+// exponentiated by square-and-multiply.
+type Verifier struct {
+	Curve   *math.Curve
+	entries []*entry
+}
+
+// NewVerifier returns an empty batch Verifier
+func NewVerifier(c *math.Curve) *Verifier {
+	return &Verifier{Curve: c}
+}
+
+// Add adds the equations of the proof with the passed label to the batch
+func (v *Verifier) Add(label string, equations ...*Equation) {
+	e := v.entry(label)
+	e.equations = append(e.equations, equations...)
+}
+
+// AddPairings adds the pairing equations of the proof with the passed label to the batch
+func (v *Verifier) AddPairings(label string, equations ...*PairingEquation) {
+	e := v.entry(label)
+	e.pairings = append(e.pairings, equations...)
+}
+
+// entry returns the last entry if it has the passed label, a new entry otherwise
+func (v *Verifier) entry(label string) *entry {
+	if len(v.entries) != 0 && v.entries[len(v.entries)-1].label == label {
+		return v.entries[len(v.entries)-1]
+	}
+	e := &entry{label: label}
+	v.entries = append(v.entries, e)
+	return e
+}
+
+// Append adds the proofs in the passed batch to this batch, prefixing their labels
+func (v *Verifier) Append(prefix string, other *Verifier) {
+	for _, e := range other.entries {
+		v.entries = append(v.entries, &entry{label: prefix + e.label, equations: e.equations, pairings: e.pairings})
+	}
+}
+
+// Len returns the number of proofs in the batch
+func (v *Verifier) Len() int {
+	return len(v.entries)
+}
+
+// Verify checks all the proofs in the batch.
+// If the combined check fails, Verify checks the proofs one by one, and returns an *Error with the labels of the invalid ones.
+func (v *Verifier) Verify() error {
+	if len(v.entries) == 0 {
+		return nil
+	}
+	ok, err := v.verifyCombined()
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	batchErr := &Error{}
+	invalid := map[string]bool{}
+	for _, e := range v.entries {
+		if invalid[e.label] || e.holds(v.Curve) {
+			continue
+		}
+		invalid[e.label] = true
+		batchErr.Labels = append(batchErr.Labels, e.label)
+	}
+	if len(batchErr.Labels) == 0 {
+		// a combination of equations that hold holds as well, this is not expected
+		return errors.New("batch verification failed")
+	}
+	return batchErr
+}
+
+func (v *Verifier) verifyCombined() (bool, error) {
+	c := v.Curve
+	rand, err := c.Rand()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get RNG")
+	}
+	res := newCombination(c)
+	var g2s []*math.G2
+	args := map[string]*combination{}
+	var gt *math.Gt
+	for _, e := range v.entries {
+		for _, eq := range e.equations {
+			if len(eq.Bases) != len(eq.Exponents) {
+				return false, errors.Errorf("invalid equation of proof [%s]", e.label)
+			}
+			res.add(eq, c.NewRandomZr(rand))
+		}
+		for _, eq := range e.pairings {
+			if len(eq.G2) != len(eq.G1) {
+				return false, errors.Errorf("invalid pairing equation of proof [%s]", e.label)
+			}
+			weight, err := smallWeight(rand)
+			if err != nil {
+				return false, err
+			}
+			for i, g2 := range eq.G2 {
+				if len(eq.G1[i].Bases) != len(eq.G1[i].Exponents) {
+					return false, errors.Errorf("invalid pairing equation of proof [%s]", e.label)
+				}
+				key := string(g2.Bytes())
+				arg, ok := args[key]
+				if !ok {
+					arg = newCombination(c)
+					args[key] = arg
+					g2s = append(g2s, g2)
+				}
+				arg.add(eq.G1[i], c.NewZrFromInt(int64(weight)))
+			}
+			for _, t := range eq.Gt {
+				tw, err := gtExp(c, t, weight)
+				if err != nil {
+					return false, err
+				}
+				gt = mulGt(gt, tw)
+			}
+		}
+	}
+	if !res.eval().IsInfinity() {
+		return false, nil
+	}
+	if len(g2s) == 0 && gt == nil {
+		return true, nil
+	}
+	g1s := make([]*math.G1, len(g2s))
+	for i, g2 := range g2s {
+		g1s[i] = args[string(g2.Bytes())].eval()
+	}
+	prod := mulGt(pairingProduct(c, g2s, g1s), gt)
+	return prod == nil || prod.IsUnity(), nil
+}
+
+func (e *entry) holds(c *math.Curve) bool {
+	for _, eq := range e.equations {
+		if !eq.Holds(c) {
+			return false
+		}
+	}
+	for _, eq := range e.pairings {
+		if !eq.Holds(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// combination is a random linear combination of equations, whose terms with the same base are merged
+type combination struct {
+	c         *math.Curve
+	bases     []*math.G1
+	exponents map[*math.G1]*math.Zr
+}
+
+func newCombination(c *math.Curve) *combination {
+	return &combination{c: c, exponents: map[*math.G1]*math.Zr{}}
+}
+
+// add adds the passed equation, multiplied by the passed weight, to the combination
+func (r *combination) add(eq *Equation, weight *math.Zr) {
+	c := r.c
+	for i, base := range eq.Bases {
+		exp := c.ModMul(weight, eq.Exponents[i], c.GroupOrder)
+		if sum, ok := r.exponents[base]; ok {
+			r.exponents[base] = c.ModAdd(sum, exp, c.GroupOrder)
+			continue
+		}
+		r.bases = append(r.bases, base)
+		r.exponents[base] = exp
+	}
+}
+
+func (r *combination) eval() *math.G1 {
+	res := r.c.NewG1()
+	for _, base := range r.bases {
+		res.Add(base.Mul(r.exponents[base]))
+	}
+	return res
+}
+
+// pairingProduct returns the final exponentiation of \prod_i e(g2s[i], g1s[i]), nil if there are no pairings
+func pairingProduct(c *math.Curve, g2s []*math.G2, g1s []*math.G1) *math.Gt {
+	var res *math.Gt
+	for i := 0; i < len(g2s); i += 2 {
+		var p *math.Gt
+		if i+1 < len(g2s) {
+			p = c.Pairing2(g2s[i], g1s[i], g2s[i+1], g1s[i+1])
+		} else {
+			p = c.Pairing(g2s[i], g1s[i])
+		}
+		if res == nil {
+			res = p
+			continue
+		}
+		res.Mul(p)
+	}
+	if res == nil {
+		return nil
+	}
+	return c.FExp(res)
+}
+
+// mulGt returns a*b, where nil stands for the unity, a is modified
+func mulGt(a, b *math.Gt) *math.Gt {
+	if a == nil {
+		return b
+	}
+	if b != nil {
+		a.Mul(b)
+	}
+	return a
+}
+
+// smallWeight returns a random weight in [1, 2^63)
+func smallWeight(rand io.Reader) (uint64, error) {
+	var raw [8]byte
+	if _, err := io.ReadFull(rand, raw[:]); err != nil {
+		return 0, errors.Wrap(err, "failed to get random weight")
+	}
+	if w := binary.BigEndian.Uint64(raw[:]) >> 1; w != 0 {
+		return w, nil
+	}
+	return 1, nil
+}
+
+// gtExp returns g^e, computed by square-and-multiply
+func gtExp(c *math.Curve, g *math.Gt, e uint64) (*math.Gt, error) {
+	base, err := c.NewGtFromBytes(g.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Gt element")
+	}
+	var res *math.Gt
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			if res == nil {
+				res, err = c.NewGtFromBytes(base.Bytes())
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid Gt element")
+				}
+			} else {
+				res.Mul(base)
+			}
+		}
+		if e > 1 {
+			square, err := c.NewGtFromBytes(base.Bytes())
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid Gt element")
+			}
+			base.Mul(square)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package batch_test
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("batch verification", func() {
+	var (
+		c         *math.Curve
+		verifiers []*bulletproof.Verifier
+		proofs    [][]byte
+	)
+	BeforeEach(func() {
+		c = math.Curves[1]
+		verifiers, proofs = nil, nil
+		g := c.HashToG1([]byte("value"))
+		hs := []*math.G1{c.HashToG1([]byte("type")), c.HashToG1([]byte("blinding factor"))}
+		for _, values := range [][]int64{{1, 2}, {3}, {4, 5, 6}} {
+			prover := getProver(c, values, g, hs)
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			verifiers = append(verifiers, prover.Verifier)
+			proofs = append(proofs, proof)
+		}
+	})
+	add := func(b *batch.Verifier, labels ...string) {
+		for i, label := range labels {
+			equations, err := verifiers[i].Equations(proofs[i])
+			Expect(err).NotTo(HaveOccurred())
+			b.Add(label, equations...)
+		}
+	}
+	Context("when all the proofs are valid", func() {
+		It("succeeds", func() {
+			b := batch.NewVerifier(c)
+			add(b, "a", "b", "c")
+			Expect(b.Len()).To(Equal(3))
+			Expect(b.Verify()).To(Succeed())
+		})
+	})
+	Context("when the batch is empty", func() {
+		It("succeeds", func() {
+			Expect(batch.NewVerifier(c).Verify()).To(Succeed())
+		})
+	})
+	Context("when a proof is invalid", func() {
+		BeforeEach(func() {
+			proof := &bulletproof.RangeProof{}
+			Expect(json.Unmarshal(proofs[1], proof)).To(Succeed())
+			proof.THat = c.ModAdd(proof.THat, c.NewZrFromInt(1), c.GroupOrder)
+			var err error
+			proofs[1], err = json.Marshal(proof)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("pinpoints the invalid proof", func() {
+			b := batch.NewVerifier(c)
+			add(b, "a", "b", "c")
+			err := b.Verify()
+			Expect(err).To(HaveOccurred())
+			batchErr, ok := errors.Cause(err).(*batch.Error)
+			Expect(ok).To(BeTrue())
+			Expect(batchErr.Labels).To(Equal([]string{"b"}))
+			Expect(err.Error()).To(Equal("invalid proofs [b]"))
+		})
+		It("pinpoints the invalid proof of an appended batch", func() {
+			b1 := batch.NewVerifier(c)
+			add(b1, "a")
+			b2 := batch.NewVerifier(c)
+			add(b2, "a", "b")
+			b := batch.NewVerifier(c)
+			b.Append("first ", b1)
+			b.Append("second ", b2)
+			err := b.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid proofs [second b]"))
+		})
+	})
+	Context("when the batch contains pairing equations", func() {
+		// pairing returns the equation e(Q, P^{-x}) * e(Q, P)^y = 1, that holds if x = y
+		pairing := func(x, y int64) *batch.PairingEquation {
+			eq := &batch.PairingEquation{}
+			eq.AddPairing(c.GenG2, &batch.Equation{Bases: []*math.G1{c.GenG1}, Exponents: []*math.Zr{c.ModNeg(c.NewZrFromInt(x), c.GroupOrder)}})
+			eq.Gt = []*math.Gt{c.FExp(c.Pairing(c.GenG2, c.GenG1.Mul(c.NewZrFromInt(y))))}
+			return eq
+		}
+		It("succeeds when all the equations hold", func() {
+			b := batch.NewVerifier(c)
+			add(b, "a")
+			b.AddPairings("a", pairing(3, 3))
+			b.AddPairings("b", pairing(5, 5), pairing(7, 7))
+			Expect(b.Len()).To(Equal(2))
+			Expect(b.Verify()).To(Succeed())
+		})
+		It("pinpoints the proof whose pairing equation does not hold", func() {
+			b := batch.NewVerifier(c)
+			add(b, "a")
+			b.AddPairings("a", pairing(3, 3))
+			b.AddPairings("b", pairing(5, 5), pairing(7, 8))
+			b.AddPairings("c", pairing(9, 9))
+			err := b.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid proofs [b]"))
+		})
+	})
+})
+
+func getProver(c *math.Curve, values []int64, g *math.G1, hs []*math.G1) *bulletproof.Prover {
+	rand, err := c.Rand()
+	Expect(err).NotTo(HaveOccurred())
+	zrs := make([]*math.Zr, len(values))
+	bfs := make([][]*math.Zr, len(values))
+	coms := make([]*math.G1, len(values))
+	for i, v := range values {
+		zrs[i] = c.NewZrFromInt(v)
+		bfs[i] = []*math.Zr{c.NewRandomZr(rand), c.NewRandomZr(rand)}
+		coms[i] = g.Mul(zrs[i])
+		coms[i].Add(hs[0].Mul(bfs[i][0]))
+		coms[i].Add(hs[1].Mul(bfs[i][1]))
+	}
+	return bulletproof.NewProver(zrs, bfs, coms, g, hs, 16, c)
+}
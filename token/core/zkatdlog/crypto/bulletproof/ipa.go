@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bulletproof
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/pkg/errors"
+)
+
+// InnerProductProof shows knowledge of vectors a and b such that P = G^a H^b U^<a,b>.
+// The size of the proof is logarithmic in the size of the vectors.
+type InnerProductProof struct {
+	// L and R are the cross commitments of each folding round
+	L []*math.G1
+	R []*math.G1
+	// A and B are the folded vectors, of size one
+	A *math.Zr
+	B *math.Zr
+}
+
+// proveInnerProduct generates an InnerProductProof for the passed vectors, whose size is a power of two.
+// The challenges of the proof are chained to the passed one.
+func proveInnerProduct(G, H []*math.G1, U *math.G1, a, b []*math.Zr, chal *math.Zr, c *math.Curve) (*InnerProductProof, error) {
+	proof := &InnerProductProof{}
+	for len(a) > 1 {
+		n := len(a) / 2
+		cL := innerProduct(a[:n], b[n:], c)
+		cR := innerProduct(a[n:], b[:n], c)
+
+		L := multiExp(G[n:], a[:n], c)
+		L.Add(multiExp(H[:n], b[n:], c))
+		L.Add(U.Mul(cL))
+		R := multiExp(G[:n], a[n:], c)
+		R.Add(multiExp(H[n:], b[:n], c))
+		R.Add(U.Mul(cR))
+		proof.L = append(proof.L, L)
+		proof.R = append(proof.R, R)
+
+		var err error
+		chal, err = challenge(chal, c, L, R)
+		if err != nil {
+			return nil, err
+		}
+		chalInv := inverse(chal, c)
+
+		a = add(scale(a[:n], chal, c), scale(a[n:], chalInv, c), c)
+		b = add(scale(b[:n], chalInv, c), scale(b[n:], chal, c), c)
+		G = fold(G, chalInv, chal)
+		H = fold(H, chal, chalInv)
+	}
+	proof.A = a[0]
+	proof.B = b[0]
+	return proof, nil
+}
+
+// challenges returns the challenges of the folding rounds of the proof, for vectors of the passed size
+func (proof *InnerProductProof) challenges(chal *math.Zr, size int, c *math.Curve) ([]*math.Zr, error) {
+	if proof.A == nil || proof.B == nil {
+		return nil, errors.New("invalid inner product proof: nil elements")
+	}
+	if len(proof.L) != len(proof.R) || 1<<len(proof.L) != size {
+		return nil, errors.Errorf("invalid inner product proof: expected [%d] rounds", log2(size))
+	}
+	chals := make([]*math.Zr, len(proof.L))
+	for i := 0; i < len(proof.L); i++ {
+		if proof.L[i] == nil || proof.R[i] == nil {
+			return nil, errors.New("invalid inner product proof: nil elements")
+		}
+		var err error
+		chal, err = challenge(chal, c, proof.L[i], proof.R[i])
+		if err != nil {
+			return nil, err
+		}
+		chals[i] = chal
+	}
+	return chals, nil
+}
+
+// foldingExponents returns the vectors s and sInv such that, once folded with the passed challenges,
+// G becomes \prod_i G_i^{s_i} and H becomes \prod_i H_i^{sInv_i}
+func foldingExponents(chals []*math.Zr, size int, c *math.Curve) ([]*math.Zr, []*math.Zr) {
+	chalsInv := make([]*math.Zr, len(chals))
+	for j := 0; j < len(chals); j++ {
+		chalsInv[j] = inverse(chals[j], c)
+	}
+	s := make([]*math.Zr, size)
+	sInv := make([]*math.Zr, size)
+	for i := 0; i < size; i++ {
+		s[i] = c.NewZrFromInt(1)
+		sInv[i] = c.NewZrFromInt(1)
+		for j := 0; j < len(chals); j++ {
+			// the j-th round folds the upper half of G with the challenge and its lower half with the inverse,
+			// and H the other way around
+			if (i>>(len(chals)-1-j))&1 == 1 {
+				s[i] = c.ModMul(s[i], chals[j], c.GroupOrder)
+				sInv[i] = c.ModMul(sInv[i], chalsInv[j], c.GroupOrder)
+			} else {
+				s[i] = c.ModMul(s[i], chalsInv[j], c.GroupOrder)
+				sInv[i] = c.ModMul(sInv[i], chals[j], c.GroupOrder)
+			}
+		}
+	}
+	return s, sInv
+}
+
+// fold returns the vector whose i-th entry is bases[i]^left * bases[i+n/2]^right
+func fold(bases []*math.G1, left, right *math.Zr) []*math.G1 {
+	n := len(bases) / 2
+	res := make([]*math.G1, n)
+	for i := 0; i < n; i++ {
+		res[i] = bases[i].Mul(left)
+		res[i].Add(bases[n+i].Mul(right))
+	}
+	return res
+}
+
+// challenge returns the challenge that follows the passed one, once the passed elements are sent
+func challenge(prev *math.Zr, c *math.Curve, elements ...*math.G1) (*math.Zr, error) {
+	raw, err := common.GetG1Array(elements).Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute challenge")
+	}
+	return c.HashToZr(append(prev.Bytes(), raw...)), nil
+}
+
+func log2(n int) int {
+	res := 0
+	for n > 1 {
+		n >>= 1
+		res++
+	}
+	return res
+}
@@ -0,0 +1,389 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bulletproof
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/pkg/errors"
+)
+
+// RangeProof is an aggregated Bulletproofs range proof.
+// It shows that the values committed in an array of commitments are all in [0, 2^BitLength).
+// Each commitment has the form V = G^v \prod_k H_k^{r_k}, where G is the value generator and
+// H_k are the blinding generators.
+// The size of the proof is logarithmic in the number of commitments and in the bit length.
+type RangeProof struct {
+	// A is the commitment to the bits of the values
+	A *math.G1
+	// S is the commitment to the blinding vectors of the bits
+	S *math.G1
+	// T1 and T2 are the commitments to the coefficients of the polynomial t(X)
+	T1 *math.G1
+	T2 *math.G1
+	// Tau are the blinding factors of t(x), one for each blinding generator
+	Tau []*math.Zr
+	// Mu is the blinding factor of A and S
+	Mu *math.Zr
+	// THat is the evaluation of t(X) at the challenge
+	THat *math.Zr
+	// IPA is the inner product argument for the vectors l(x) and r(x)
+	IPA *InnerProductProof
+}
+
+// Verifier checks a RangeProof
+type Verifier struct {
+	// Commitments are the commitments to the values in range
+	Commitments []*math.G1
+	// ValueGenerator is the generator of the committed values
+	ValueGenerator *math.G1
+	// BlindingGenerators are the generators of the blinding factors of the commitments.
+	// The last one also blinds the commitments of the proof.
+	BlindingGenerators []*math.G1
+	// BitLength is the bit length of the range
+	BitLength int
+	// Curve is an elliptic curve
+	Curve *math.Curve
+}
+
+// Prover produces a RangeProof
+type Prover struct {
+	*Verifier
+	// values are the committed values
+	values []*math.Zr
+	// blindingFactors are the blinding factors of the commitments, one array for each commitment
+	blindingFactors [][]*math.Zr
+}
+
+// NewVerifier returns a Verifier for the passed commitments
+func NewVerifier(commitments []*math.G1, valueGenerator *math.G1, blindingGenerators []*math.G1, bitLength int, c *math.Curve) *Verifier {
+	return &Verifier{
+		Commitments:        commitments,
+		ValueGenerator:     valueGenerator,
+		BlindingGenerators: blindingGenerators,
+		BitLength:          bitLength,
+		Curve:              c,
+	}
+}
+
+// NewProver returns a Prover for the passed commitments and their openings
+func NewProver(values []*math.Zr, blindingFactors [][]*math.Zr, commitments []*math.G1, valueGenerator *math.G1, blindingGenerators []*math.G1, bitLength int, c *math.Curve) *Prover {
+	return &Prover{
+		Verifier:        NewVerifier(commitments, valueGenerator, blindingGenerators, bitLength, c),
+		values:          values,
+		blindingFactors: blindingFactors,
+	}
+}
+
+// Prove generates a RangeProof
+func (p *Prover) Prove() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if len(p.values) != len(p.Commitments) || len(p.blindingFactors) != len(p.Commitments) {
+		return nil, errors.New("cannot compute range proof: the number of openings does not match the number of commitments")
+	}
+	for j := 0; j < len(p.blindingFactors); j++ {
+		if len(p.blindingFactors[j]) != len(p.BlindingGenerators) {
+			return nil, errors.Errorf("cannot compute range proof: invalid number of blinding factors for commitment [%d]", j)
+		}
+	}
+	c := p.Curve
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot compute range proof")
+	}
+
+	n := p.BitLength
+	m := nextPowerOfTwo(len(p.Commitments))
+	size := n * m
+	gens := getGenerators(c, size)
+	h := p.BlindingGenerators[len(p.BlindingGenerators)-1]
+
+	// bits of the values, the padding values are zero
+	aL := make([]*math.Zr, 0, size)
+	for j := 0; j < m; j++ {
+		v := c.NewZrFromInt(0)
+		if j < len(p.values) {
+			v = p.values[j]
+		}
+		b, err := bits(v, n, c)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot compute range proof for commitment [%d]", j)
+		}
+		aL = append(aL, b...)
+	}
+	one := c.NewZrFromInt(1)
+	aR := make([]*math.Zr, size)
+	sL := make([]*math.Zr, size)
+	sR := make([]*math.Zr, size)
+	for i := 0; i < size; i++ {
+		aR[i] = c.ModSub(aL[i], one, c.GroupOrder)
+		sL[i] = c.NewRandomZr(rand)
+		sR[i] = c.NewRandomZr(rand)
+	}
+
+	proof := &RangeProof{}
+	alpha := c.NewRandomZr(rand)
+	rho := c.NewRandomZr(rand)
+	proof.A = multiExp(gens.G, aL, c)
+	proof.A.Add(multiExp(gens.H, aR, c))
+	proof.A.Add(h.Mul(alpha))
+	proof.S = multiExp(gens.G, sL, c)
+	proof.S.Add(multiExp(gens.H, sR, c))
+	proof.S.Add(h.Mul(rho))
+
+	y, z, err := p.computeYZ(proof)
+	if err != nil {
+		return nil, err
+	}
+	yN := powers(y, size, c)
+	zs := powers(z, m+2, c)[2:]
+	d := p.computeD(zs, m)
+
+	// l(X) = l0 + l1 X and r(X) = r0 + r1 X
+	l0 := add(aL, constant(c.ModNeg(z, c.GroupOrder), size), c)
+	l1 := sL
+	r0 := add(hadamard(yN, add(aR, constant(z, size), c), c), d, c)
+	r1 := hadamard(yN, sR, c)
+
+	// t(X) = <l(X), r(X)> = t0 + t1 X + t2 X^2
+	t1 := c.ModAdd(innerProduct(l0, r1, c), innerProduct(l1, r0, c), c.GroupOrder)
+	t2 := innerProduct(l1, r1, c)
+	tau1 := make([]*math.Zr, len(p.BlindingGenerators))
+	tau2 := make([]*math.Zr, len(p.BlindingGenerators))
+	for k := 0; k < len(p.BlindingGenerators); k++ {
+		tau1[k] = c.NewRandomZr(rand)
+		tau2[k] = c.NewRandomZr(rand)
+	}
+	proof.T1 = p.ValueGenerator.Mul(t1)
+	proof.T1.Add(multiExp(p.BlindingGenerators, tau1, c))
+	proof.T2 = p.ValueGenerator.Mul(t2)
+	proof.T2.Add(multiExp(p.BlindingGenerators, tau2, c))
+
+	x, err := challenge(z, c, proof.T1, proof.T2)
+	if err != nil {
+		return nil, err
+	}
+	xSquare := c.ModMul(x, x, c.GroupOrder)
+
+	l := add(l0, scale(l1, x, c), c)
+	r := add(r0, scale(r1, x, c), c)
+	proof.THat = innerProduct(l, r, c)
+	proof.Mu = c.ModAdd(alpha, c.ModMul(rho, x, c.GroupOrder), c.GroupOrder)
+	proof.Tau = make([]*math.Zr, len(p.BlindingGenerators))
+	for k := 0; k < len(p.BlindingGenerators); k++ {
+		proof.Tau[k] = c.ModAdd(c.ModMul(tau2[k], xSquare, c.GroupOrder), c.ModMul(tau1[k], x, c.GroupOrder), c.GroupOrder)
+		for j := 0; j < len(p.blindingFactors); j++ {
+			proof.Tau[k] = c.ModAdd(proof.Tau[k], c.ModMul(zs[j], p.blindingFactors[j][k], c.GroupOrder), c.GroupOrder)
+		}
+	}
+
+	w := p.computeW(x, proof)
+	u := gens.U.Mul(w)
+	proof.IPA, err = proveInnerProduct(gens.G, p.primeH(gens.H, y), u, l, r, w, c)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot compute range proof")
+	}
+	return json.Marshal(proof)
+}
+
+// Verify checks the passed serialized RangeProof
+func (v *Verifier) Verify(raw []byte) error {
+	equations, err := v.Equations(raw)
+	if err != nil {
+		return err
+	}
+	if !equations[0].Holds(v.Curve) {
+		return errors.New("invalid range proof: polynomial commitment does not match")
+	}
+	if !equations[1].Holds(v.Curve) {
+		return errors.New("invalid range proof: invalid inner product proof")
+	}
+	return nil
+}
+
+// VerifyBatch adds the equations of the passed serialized RangeProof to the passed batch, under the passed label
+func (v *Verifier) VerifyBatch(raw []byte, b *batch.Verifier, label string) error {
+	equations, err := v.Equations(raw)
+	if err != nil {
+		return err
+	}
+	b.Add(label, equations...)
+	return nil
+}
+
+// Equations returns the equations that hold if and only if the passed serialized RangeProof is valid:
+// the first one checks the commitment to t(x), the second one checks the inner product argument.
+// The equations can be checked at once with those of other proofs, in a batch.
+func (v *Verifier) Equations(raw []byte) ([]*batch.Equation, error) {
+	if err := v.validate(); err != nil {
+		return nil, err
+	}
+	proof := &RangeProof{}
+	if err := json.Unmarshal(raw, proof); err != nil {
+		return nil, errors.Wrap(err, "invalid range proof")
+	}
+	if proof.A == nil || proof.S == nil || proof.T1 == nil || proof.T2 == nil || proof.Mu == nil || proof.THat == nil || proof.IPA == nil {
+		return nil, errors.New("invalid range proof: nil elements")
+	}
+	if len(proof.Tau) != len(v.BlindingGenerators) {
+		return nil, errors.Errorf("invalid range proof: expected [%d] blinding factors, got [%d]", len(v.BlindingGenerators), len(proof.Tau))
+	}
+	for _, tau := range proof.Tau {
+		if tau == nil {
+			return nil, errors.New("invalid range proof: nil elements")
+		}
+	}
+	c := v.Curve
+
+	n := v.BitLength
+	m := nextPowerOfTwo(len(v.Commitments))
+	size := n * m
+	gens := getGenerators(c, size)
+	h := v.BlindingGenerators[len(v.BlindingGenerators)-1]
+
+	y, z, err := v.computeYZ(proof)
+	if err != nil {
+		return nil, err
+	}
+	x, err := challenge(z, c, proof.T1, proof.T2)
+	if err != nil {
+		return nil, err
+	}
+	xSquare := c.ModMul(x, x, c.GroupOrder)
+	yN := powers(y, size, c)
+	zs := powers(z, m+3, c)
+	zSquare := zs[2]
+
+	// delta(y,z) = (z - z^2) <1, y^N> - \sum_j z^{3+j} <1, 2^n>
+	sumY := c.NewZrFromInt(0)
+	for i := 0; i < size; i++ {
+		sumY = c.ModAdd(sumY, yN[i], c.GroupOrder)
+	}
+	sum2 := c.NewZrFromInt(0)
+	for _, p := range powers(c.NewZrFromInt(2), n, c) {
+		sum2 = c.ModAdd(sum2, p, c.GroupOrder)
+	}
+	delta := c.ModMul(c.ModSub(z, zSquare, c.GroupOrder), sumY, c.GroupOrder)
+	for j := 0; j < m; j++ {
+		delta = c.ModSub(delta, c.ModMul(zs[3+j], sum2, c.GroupOrder), c.GroupOrder)
+	}
+
+	// G^{THat - delta} \prod_k H_k^{Tau_k} = \prod_j V_j^{z^{2+j}} T1^x T2^{x^2}
+	polynomial := &batch.Equation{}
+	polynomial.AddTerm(v.ValueGenerator, c.ModSub(proof.THat, delta, c.GroupOrder))
+	for k := 0; k < len(v.BlindingGenerators); k++ {
+		polynomial.AddTerm(v.BlindingGenerators[k], proof.Tau[k])
+	}
+	for j := 0; j < len(v.Commitments); j++ {
+		polynomial.AddTerm(v.Commitments[j], c.ModNeg(zs[2+j], c.GroupOrder))
+	}
+	polynomial.AddTerm(proof.T1, c.ModNeg(x, c.GroupOrder))
+	polynomial.AddTerm(proof.T2, c.ModNeg(xSquare, c.GroupOrder))
+
+	// the inner product argument shows that P = A S^x G^{-z} H'^{z y^N + d} h^{-mu} u^{THat},
+	// where H'_i = H_i^{y^{-i}} and u = U^w, opens to l(x) and r(x)
+	w := v.computeW(x, proof)
+	chals, err := proof.IPA.challenges(w, size, c)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid range proof")
+	}
+	s, sInv := foldingExponents(chals, size, c)
+	d := v.computeD(zs[2:2+m], m)
+	yInv := powers(inverse(y, c), size, c)
+	a, b := proof.IPA.A, proof.IPA.B
+
+	ipa := &batch.Equation{}
+	ipa.AddTerm(proof.A, c.NewZrFromInt(1))
+	ipa.AddTerm(proof.S, x)
+	for i := 0; i < size; i++ {
+		ipa.AddTerm(gens.G[i], c.ModNeg(c.ModAdd(z, c.ModMul(a, s[i], c.GroupOrder), c.GroupOrder), c.GroupOrder))
+		ipa.AddTerm(gens.H[i], c.ModAdd(z, c.ModMul(yInv[i], c.ModSub(d[i], c.ModMul(b, sInv[i], c.GroupOrder), c.GroupOrder), c.GroupOrder), c.GroupOrder))
+	}
+	ipa.AddTerm(h, c.ModNeg(proof.Mu, c.GroupOrder))
+	ipa.AddTerm(gens.U, c.ModMul(w, c.ModSub(proof.THat, c.ModMul(a, b, c.GroupOrder), c.GroupOrder), c.GroupOrder))
+	for j := 0; j < len(chals); j++ {
+		chalSquare := c.ModMul(chals[j], chals[j], c.GroupOrder)
+		ipa.AddTerm(proof.IPA.L[j], chalSquare)
+		ipa.AddTerm(proof.IPA.R[j], inverse(chalSquare, c))
+	}
+
+	return []*batch.Equation{polynomial, ipa}, nil
+}
+
+func (v *Verifier) validate() error {
+	if len(v.Commitments) == 0 {
+		return errors.New("invalid range proof parameters: no commitments")
+	}
+	if v.BitLength <= 0 || v.BitLength > 64 || v.BitLength&(v.BitLength-1) != 0 {
+		return errors.Errorf("invalid range proof parameters: bit length must be a power of two not larger than 64, got [%d]", v.BitLength)
+	}
+	if v.ValueGenerator == nil || len(v.BlindingGenerators) == 0 || v.Curve == nil {
+		return errors.New("invalid range proof parameters: missing generators")
+	}
+	for _, com := range v.Commitments {
+		if com == nil {
+			return errors.New("invalid range proof parameters: nil commitment")
+		}
+	}
+	for _, gen := range v.BlindingGenerators {
+		if gen == nil {
+			return errors.New("invalid range proof parameters: nil generator")
+		}
+	}
+	return nil
+}
+
+// computeYZ computes the challenges y and z, bound to the statement and to the commitments A and S
+func (v *Verifier) computeYZ(proof *RangeProof) (*math.Zr, *math.Zr, error) {
+	raw, err := common.GetG1Array([]*math.G1{v.ValueGenerator}, v.BlindingGenerators, v.Commitments).Bytes()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to compute challenge")
+	}
+	statement := v.Curve.HashToZr(append(raw, byte(v.BitLength)))
+	y, err := challenge(statement, v.Curve, proof.A, proof.S)
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := challenge(y, v.Curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	return y, z, nil
+}
+
+// computeW computes the challenge that binds the inner product argument to THat
+func (v *Verifier) computeW(x *math.Zr, proof *RangeProof) *math.Zr {
+	raw := common.GetBytesArray(x.Bytes(), proof.Mu.Bytes(), proof.THat.Bytes())
+	for _, tau := range proof.Tau {
+		raw = append(raw, tau.Bytes()...)
+	}
+	return v.Curve.HashToZr(raw)
+}
+
+// computeD returns the vector d such that d[j*n+i] = z^{2+j} 2^i, where zs[j] = z^{2+j}
+func (v *Verifier) computeD(zs []*math.Zr, m int) []*math.Zr {
+	twoN := powers(v.Curve.NewZrFromInt(2), v.BitLength, v.Curve)
+	d := make([]*math.Zr, 0, v.BitLength*m)
+	for j := 0; j < m; j++ {
+		d = append(d, scale(twoN, zs[j], v.Curve)...)
+	}
+	return d
+}
+
+// primeH returns the generators H'_i = H_i^{y^{-i}}
+func (v *Verifier) primeH(H []*math.G1, y *math.Zr) []*math.G1 {
+	yInv := powers(inverse(y, v.Curve), len(H), v.Curve)
+	res := make([]*math.G1, len(H))
+	for i := 0; i < len(H); i++ {
+		res[i] = H[i].Mul(yInv[i])
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bulletproof_test
+
+import (
+	"encoding/json"
+	"math"
+
+	mathlib "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("bulletproof range proof", func() {
+	var c *mathlib.Curve
+	BeforeEach(func() {
+		c = mathlib.Curves[1]
+	})
+	Context("when a single value is in range", func() {
+		It("succeeds", func() {
+			prover := getProver(c, []uint64{115}, 8)
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prover.Verifier.Verify(proof)).To(Succeed())
+		})
+	})
+	Context("when the proof aggregates several values", func() {
+		It("succeeds", func() {
+			prover := getProver(c, []uint64{0, 42, 255}, 8)
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prover.Verifier.Verify(proof)).To(Succeed())
+		})
+	})
+	Context("when the values use 64 bits", func() {
+		It("succeeds", func() {
+			prover := getProver(c, []uint64{math.MaxUint64, 1 << 63}, 64)
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prover.Verifier.Verify(proof)).To(Succeed())
+		})
+	})
+	Context("when a value is out of range", func() {
+		It("fails", func() {
+			prover := getProver(c, []uint64{3, 256}, 8)
+			_, err := prover.Prove()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot compute range proof for commitment [1]: value is out of range [0, 2^8)"))
+		})
+	})
+	Context("when the proof is checked against other commitments", func() {
+		It("fails", func() {
+			prover := getProver(c, []uint64{7, 8}, 8)
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			other := getProver(c, []uint64{7, 8}, 8)
+			err = other.Verifier.Verify(proof)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid range proof"))
+		})
+	})
+	Context("when the proof is tampered with", func() {
+		It("fails", func() {
+			prover := getProver(c, []uint64{7, 8}, 8)
+			raw, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			proof := &bulletproof.RangeProof{}
+			Expect(json.Unmarshal(raw, proof)).To(Succeed())
+			proof.IPA.A = c.ModAdd(proof.IPA.A, c.NewZrFromInt(1), c.GroupOrder)
+			raw, err = json.Marshal(proof)
+			Expect(err).NotTo(HaveOccurred())
+			err = prover.Verifier.Verify(raw)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid inner product proof"))
+		})
+	})
+})
+
+func getProver(c *mathlib.Curve, values []uint64, bitLength int) *bulletproof.Prover {
+	rand, err := c.Rand()
+	Expect(err).NotTo(HaveOccurred())
+	g := c.HashToG1([]byte("value"))
+	hs := []*mathlib.G1{c.HashToG1([]byte("type")), c.HashToG1([]byte("blinding factor"))}
+
+	zrs := make([]*mathlib.Zr, len(values))
+	bfs := make([][]*mathlib.Zr, len(values))
+	coms := make([]*mathlib.G1, len(values))
+	for i, v := range values {
+		zrs[i] = c.NewZrFromBytes(uint64ToBytes(v))
+		bfs[i] = []*mathlib.Zr{c.NewRandomZr(rand), c.NewRandomZr(rand)}
+		coms[i] = g.Mul(zrs[i])
+		coms[i].Add(hs[0].Mul(bfs[i][0]))
+		coms[i].Add(hs[1].Mul(bfs[i][1]))
+	}
+	return bulletproof.NewProver(zrs, bfs, coms, g, hs, bitLength, c)
+}
+
+func uint64ToBytes(v uint64) []byte {
+	raw := make([]byte, 32)
+	for i := 0; i < 8; i++ {
+		raw[31-i] = byte(v >> (8 * i))
+	}
+	return raw
+}
@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bulletproof
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	math "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// generators are the vector generators of the proofs, G and H, and the generator of the inner products, U.
+// They are hashed to the curve, so that no one knows the discrete logarithms among them.
+type generators struct {
+	G []*math.G1
+	H []*math.G1
+	U *math.G1
+}
+
+type generatorsKey struct {
+	curve *math.Curve
+	size  int
+}
+
+var generatorsCache sync.Map
+
+// getGenerators returns the generators for vectors of the passed size
+func getGenerators(c *math.Curve, size int) *generators {
+	key := generatorsKey{curve: c, size: size}
+	if gens, ok := generatorsCache.Load(key); ok {
+		return gens.(*generators)
+	}
+	gens := &generators{
+		G: make([]*math.G1, size),
+		H: make([]*math.G1, size),
+		U: c.HashToG1([]byte("zkatdlog.bulletproof.U")),
+	}
+	for i := 0; i < size; i++ {
+		gens.G[i] = c.HashToG1([]byte(fmt.Sprintf("zkatdlog.bulletproof.G.%d", i)))
+		gens.H[i] = c.HashToG1([]byte(fmt.Sprintf("zkatdlog.bulletproof.H.%d", i)))
+	}
+	generatorsCache.Store(key, gens)
+	return gens
+}
+
+// bits returns the bitLength bits of the passed value, from the least significant one
+func bits(v *math.Zr, bitLength int, c *math.Curve) ([]*math.Zr, error) {
+	value := new(big.Int).SetBytes(v.Bytes())
+	if value.BitLen() > bitLength {
+		return nil, errors.Errorf("value is out of range [0, 2^%d)", bitLength)
+	}
+	res := make([]*math.Zr, bitLength)
+	for i := 0; i < bitLength; i++ {
+		res[i] = c.NewZrFromInt(int64(value.Bit(i)))
+	}
+	return res, nil
+}
+
+// powers returns [1, x, x^2, ..., x^(n-1)]
+func powers(x *math.Zr, n int, c *math.Curve) []*math.Zr {
+	res := make([]*math.Zr, n)
+	res[0] = c.NewZrFromInt(1)
+	for i := 1; i < n; i++ {
+		res[i] = c.ModMul(res[i-1], x, c.GroupOrder)
+	}
+	return res
+}
+
+// constant returns a vector of size n, whose entries are all x
+func constant(x *math.Zr, n int) []*math.Zr {
+	res := make([]*math.Zr, n)
+	for i := 0; i < n; i++ {
+		res[i] = x.Copy()
+	}
+	return res
+}
+
+func innerProduct(a, b []*math.Zr, c *math.Curve) *math.Zr {
+	res := c.NewZrFromInt(0)
+	for i := 0; i < len(a); i++ {
+		res = c.ModAdd(res, c.ModMul(a[i], b[i], c.GroupOrder), c.GroupOrder)
+	}
+	return res
+}
+
+func add(a, b []*math.Zr, c *math.Curve) []*math.Zr {
+	res := make([]*math.Zr, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = c.ModAdd(a[i], b[i], c.GroupOrder)
+	}
+	return res
+}
+
+func hadamard(a, b []*math.Zr, c *math.Curve) []*math.Zr {
+	res := make([]*math.Zr, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = c.ModMul(a[i], b[i], c.GroupOrder)
+	}
+	return res
+}
+
+func scale(a []*math.Zr, x *math.Zr, c *math.Curve) []*math.Zr {
+	res := make([]*math.Zr, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = c.ModMul(a[i], x, c.GroupOrder)
+	}
+	return res
+}
+
+func inverse(x *math.Zr, c *math.Curve) *math.Zr {
+	res := x.Copy()
+	res.InvModP(c.GroupOrder)
+	return res
+}
+
+// multiExp returns \prod_i bases[i]^exponents[i]
+func multiExp(bases []*math.G1, exponents []*math.Zr, c *math.Curve) *math.G1 {
+	res := c.NewG1()
+	for i := 0; i < len(bases); i++ {
+		res.Add(bases[i].Mul(exponents[i]))
+	}
+	return res
+}
+
+// nextPowerOfTwo returns the smallest power of two that is not smaller than n
+func nextPowerOfTwo(n int) int {
+	res := 1
+	for res < n {
+		res <<= 1
+	}
+	return res
+}
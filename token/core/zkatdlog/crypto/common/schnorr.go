@@ -7,6 +7,7 @@ package common
 
 import (
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/pkg/errors"
 )
 
@@ -103,6 +104,50 @@ func (v *SchnorrVerifier) RecomputeCommitment(zkp *SchnorrProof) (*math.G1, erro
 	return com, nil
 }
 
+// Equation is called by the verifier when the prover sends the randomness commitment along with the SchnorrProof.
+// It returns the equation \prod_{i=1}^n P_i^{proof_i} * Statement^{-challenge} * commitment^{-1} = 1,
+// which holds if and only if the passed commitment is the one recomputed by RecomputeCommitment.
+// The equation can be checked at once with those of other proofs, in a batch.
+func (v *SchnorrVerifier) Equation(zkp *SchnorrProof, commitment *math.G1) (*batch.Equation, error) {
+	// safety checks
+	if zkp.Challenge == nil || zkp.Statement == nil || commitment == nil {
+		return nil, errors.Errorf("invalid zero-knowledge proof: nil challenge, statement or commitment")
+	}
+	if v.Curve == nil {
+		return nil, errors.New("please initialize curve")
+	}
+	if len(zkp.Proof) > len(v.PedParams) {
+		return nil, errors.Errorf("please initialize Pedersen parameters correctly")
+	}
+	eq := &batch.Equation{}
+	for i, p := range zkp.Proof {
+		if p == nil {
+			return nil, errors.New("invalid zero-knowledge proof: nil proof")
+		}
+		eq.AddTerm(v.PedParams[i], p)
+	}
+	eq.AddTerm(zkp.Statement, v.Curve.ModNeg(zkp.Challenge, v.Curve.GroupOrder))
+	eq.AddTerm(commitment, v.Curve.ModNeg(v.Curve.NewZrFromInt(1), v.Curve.GroupOrder))
+	return eq, nil
+}
+
+// Equations returns the equations of the passed SchnorrProofs, for the passed challenge and commitments
+func (v *SchnorrVerifier) Equations(zkps []*SchnorrProof, challenge *math.Zr, commitments []*math.G1) ([]*batch.Equation, error) {
+	if len(zkps) != len(commitments) {
+		return nil, errors.Errorf("invalid zero-knowledge proof: expected [%d] commitments, got [%d]", len(zkps), len(commitments))
+	}
+	equations := make([]*batch.Equation, len(zkps))
+	var err error
+	for i, zkp := range zkps {
+		zkp.Challenge = challenge
+		equations[i], err = v.Equation(zkp, commitments[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compute equation at index [%d]", i)
+		}
+	}
+	return equations, nil
+}
+
 func (v *SchnorrVerifier) RecomputeCommitments(zkps []*SchnorrProof, challenge *math.Zr) ([]*math.G1, error) {
 	commitments := make([]*math.G1, len(zkps))
 	var err error
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"runtime"
+	"sync"
+)
+
+// DefaultWorkerPool is the WorkerPool used when none is set, it has as many workers as CPUs
+var DefaultWorkerPool = NewWorkerPool(0)
+
+// WorkerPool bounds the number of goroutines used to generate the sub-proofs of a proof.
+// A WorkerPool can be shared by several provers, and a task can run in turn tasks on the same pool:
+// when no worker is free, a task runs on the goroutine that submitted it.
+type WorkerPool struct {
+	workers chan struct{}
+}
+
+// NewWorkerPool returns a WorkerPool with the passed number of workers.
+// If size is not positive, the pool has as many workers as CPUs.
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+	return &WorkerPool{workers: make(chan struct{}, size)}
+}
+
+// Size returns the number of workers of the pool
+func (p *WorkerPool) Size() int {
+	return cap(p.workers)
+}
+
+// Run calls task(i), for i in [0, n), and waits for all the calls to return.
+// Each task must write its results at its own index, so that the outcome does not depend on the scheduling.
+// Run returns the error of the task with the smallest index, if any.
+// A nil WorkerPool runs the tasks on DefaultWorkerPool.
+func (p *WorkerPool) Run(n int, task func(i int) error) error {
+	if p == nil {
+		p = DefaultWorkerPool
+	}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case p.workers <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-p.workers
+					wg.Done()
+				}()
+				errs[i] = task(i)
+			}(i)
+		default:
+			// all workers are busy, run the task here, so that nested calls cannot deadlock
+			errs[i] = task(i)
+		}
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
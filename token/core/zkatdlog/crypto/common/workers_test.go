@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPoolRun(t *testing.T) {
+	pool := NewWorkerPool(2)
+	assert.Equal(t, 2, pool.Size())
+
+	// the results are stored by index
+	res := make([]int, 100)
+	assert.NoError(t, pool.Run(len(res), func(i int) error {
+		res[i] = i * i
+		return nil
+	}))
+	for i := range res {
+		assert.Equal(t, i*i, res[i])
+	}
+
+	// the workers, plus the submitting goroutine, bound the number of tasks running at once
+	var running, max int32
+	assert.NoError(t, pool.Run(50, func(i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		return nil
+	}))
+	assert.LessOrEqual(t, max, int32(pool.Size()+1))
+}
+
+func TestWorkerPoolErrors(t *testing.T) {
+	pool := NewWorkerPool(4)
+	err := pool.Run(10, func(i int) error {
+		if i == 3 || i == 7 {
+			return errors.Errorf("task [%d] failed", i)
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "task [3] failed")
+}
+
+func TestWorkerPoolNested(t *testing.T) {
+	// tasks running tasks on a pool with a single worker do not deadlock
+	pool := NewWorkerPool(1)
+	var count int32
+	assert.NoError(t, pool.Run(4, func(i int) error {
+		return pool.Run(4, func(j int) error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+	}))
+	assert.Equal(t, int32(16), count)
+
+	// a nil pool runs on the default one
+	var nilPool *WorkerPool
+	assert.NoError(t, nilPool.Run(4, func(i int) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}))
+	assert.Equal(t, int32(20), count)
+}
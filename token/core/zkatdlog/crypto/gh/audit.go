@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+// NewAuditor returns an audit.Auditor for graph hiding token requests.
+// The auditor checks that the owner commitments of the outputs match their metadata, and then inspects
+// the owners in the clear. The inputs are inspected through their signers, fresh identities of their owners.
+func NewAuditor(des audit.Deserializer, signer audit.SigningIdentity, pp *crypto.PublicParams) *audit.Auditor {
+	a := audit.NewAuditor(des, pp.PedParams, pp.IdemixIssuerPK, signer, math.Curves[pp.Curve])
+	a.GetAuditInfoForIssuesFunc = func(issues [][]byte, metadata []driver.IssueMetadata) ([][]*audit.AuditableToken, error) {
+		return GetAuditInfoForIssues(issues, metadata, pp)
+	}
+	a.GetAuditInfoForTransfersFunc = func(transfers [][]byte, metadata []driver.TransferMetadata, _ [][]*token.Token) ([][]*audit.AuditableToken, [][]*audit.AuditableToken, error) {
+		return GetAuditInfoForTransfers(transfers, metadata, pp)
+	}
+	return a
+}
+
+// GetAuditInfoForIssues returns an array of AuditableToken for each issue action.
+// The owner of each AuditableToken is the owner in the clear.
+func GetAuditInfoForIssues(issues [][]byte, metadata []driver.IssueMetadata, pp *crypto.PublicParams) ([][]*audit.AuditableToken, error) {
+	if len(issues) != len(metadata) {
+		return nil, errors.Errorf("number of issues does not match number of provided metadata")
+	}
+	outputs := make([][]*audit.AuditableToken, len(issues))
+	for k, md := range metadata {
+		ia := &issue2.IssueAction{}
+		if err := json.Unmarshal(issues[k], ia); err != nil {
+			return nil, err
+		}
+		if len(ia.OutputTokens) != len(md.ReceiversAuditInfos) || len(ia.OutputTokens) != len(md.TokenInfo) {
+			return nil, errors.Errorf("number of output does not match number of provided metadata")
+		}
+		for i := 0; i < len(md.ReceiversAuditInfos); i++ {
+			if ia.OutputTokens[i] == nil {
+				return nil, errors.Errorf("output token at index [%d] is nil", i)
+			}
+			if ia.OutputTokens[i].IsRedeem() {
+				return nil, errors.Errorf("issue cannot redeem tokens")
+			}
+			ao, err := auditableOutput(ia.OutputTokens[i], md.TokenInfo[i], md.ReceiversAuditInfos[i], pp)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid output at index [%d]", i)
+			}
+			outputs[k] = append(outputs[k], ao)
+		}
+	}
+	return outputs, nil
+}
+
+// GetAuditInfoForTransfers returns an array of AuditableToken for the inputs and for the outputs of each transfer action.
+// The owner of each AuditableToken is the owner in the clear.
+func GetAuditInfoForTransfers(transfers [][]byte, metadata []driver.TransferMetadata, pp *crypto.PublicParams) ([][]*audit.AuditableToken, [][]*audit.AuditableToken, error) {
+	if len(transfers) != len(metadata) {
+		return nil, nil, errors.Errorf("number of transfers does not match the number of provided metadata")
+	}
+	inputs := make([][]*audit.AuditableToken, len(transfers))
+	outputs := make([][]*audit.AuditableToken, len(transfers))
+	for k, tr := range metadata {
+		ta := &TransferAction{}
+		if err := ta.Deserialize(transfers[k]); err != nil {
+			return nil, nil, err
+		}
+		if len(tr.SenderAuditInfos) != len(ta.Inputs) {
+			return nil, nil, errors.Errorf("number of inputs does not match the number of senders")
+		}
+		for i, in := range ta.Inputs {
+			if in == nil {
+				return nil, nil, errors.Errorf("input[%d][%d] is nil", k, i)
+			}
+			ai, err := audit.NewAuditableToken(&token.Token{Owner: in.Signer, Data: in.Commitment}, tr.SenderAuditInfos[i], "", nil, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			inputs[k] = append(inputs[k], ai)
+		}
+		if len(ta.OutputTokens) != len(tr.ReceiverAuditInfos) || len(ta.OutputTokens) != len(tr.OutputsMetadata) {
+			return nil, nil, errors.Errorf("number of outputs does not match the number of receivers")
+		}
+		for i := 0; i < len(tr.ReceiverAuditInfos); i++ {
+			if ta.OutputTokens[i] == nil {
+				return nil, nil, errors.Errorf("output token at index [%d] is nil", i)
+			}
+			ao, err := auditableOutput(ta.OutputTokens[i], tr.OutputsMetadata[i], tr.ReceiverAuditInfos[i], pp)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid output at index [%d]", i)
+			}
+			outputs[k] = append(outputs[k], ao)
+		}
+	}
+	return inputs, outputs, nil
+}
+
+// auditableOutput returns the AuditableToken of the passed output, whose owner is the owner in the clear
+func auditableOutput(output *token.Token, rawMeta []byte, auditInfo []byte, pp *crypto.PublicParams) (*audit.AuditableToken, error) {
+	meta := &Metadata{}
+	if err := meta.Deserialize(rawMeta); err != nil {
+		return nil, err
+	}
+	tok := &token.Token{Data: output.Data}
+	if !output.IsRedeem() {
+		if meta.SerialNumber == nil || meta.SpendKey == nil || len(meta.Owner) == 0 {
+			return nil, errors.New("missing owner opening")
+		}
+		owner, err := math.Curves[pp.Curve].NewG1FromBytes(output.Owner)
+		if err != nil {
+			return nil, errors.Wrap(err, "owner is not a commitment")
+		}
+		if !OwnerCommitment(meta.Owner, meta.SerialNumber, meta.SpendKey, pp).Equals(owner) {
+			return nil, errors.New("owner does not match the provided opening")
+		}
+		tok.Owner = meta.Owner
+	}
+	return audit.NewAuditableToken(tok, auditInfo, meta.Type, meta.Value, meta.BlindingFactor)
+}
@@ -0,0 +1,349 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package gh_test
+
+import (
+	"encoding/base64"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh/validator"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+type deserializer struct {
+	verifiers map[string]driver.Verifier
+}
+
+func (d *deserializer) GetOwnerVerifier(id view.Identity) (driver.Verifier, error) {
+	v, ok := d.verifiers[string(id)]
+	if !ok {
+		return nil, errors.New("unknown owner")
+	}
+	return v, nil
+}
+
+func (d *deserializer) GetIssuerVerifier(id view.Identity) (driver.Verifier, error) {
+	return nil, errors.New("not supported")
+}
+
+func (d *deserializer) GetAuditorVerifier(id view.Identity) (driver.Verifier, error) {
+	return nil, errors.New("not supported")
+}
+
+func (d *deserializer) GetOwnerMatcher(raw []byte) (driver.Matcher, error) {
+	return nil, errors.New("not supported")
+}
+
+var _ = Describe("Graph hiding transfer", func() {
+	var (
+		pp     *crypto.PublicParams
+		c      *math.Curve
+		signer *ecdsa.ECDSASigner
+		owner  view.Identity
+		sender view.Identity
+		ledger map[string][]byte
+
+		ids   []*token3.ID
+		metas []*gh.Metadata
+		sks   []*math.Zr
+	)
+	BeforeEach(func() {
+		var err error
+		pp, err = crypto.SetupWithCustomLabel(100, 2, nil, crypto.DLogGraphHidingPublicParameters, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp.GenerateGraphHidingParameters(2)).To(Succeed())
+		Expect(pp.GraphHidingParams.Validate()).To(Succeed())
+		c = math.Curves[pp.Curve]
+
+		signer, err = ecdsa.NewECDSASigner()
+		Expect(err).NotTo(HaveOccurred())
+		id, err := signer.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		// the owner of the tokens is not revealed, the sender identity signs in its place
+		owner = []byte("owner")
+		sender, err = identity.MarshallRawOwner(&identity.RawOwner{Type: identity.SerializedIdentityType, Identity: id})
+		Expect(err).NotTo(HaveOccurred())
+
+		// four tokens on the ledger, the anonymity sets have size four
+		ledger = map[string][]byte{}
+		ids, metas, sks = prepareTokens(ledger, owner, []int64{70, 30, 20, 10}, pp)
+	})
+
+	Describe("Spend proof", func() {
+		var (
+			set     []*math.G1
+			input   *gh.Input
+			witness *gh.SpendWitness
+		)
+		BeforeEach(func() {
+			set = make([]*math.G1, len(ids))
+			for i, id := range ids {
+				tok := loadToken(ledger, id)
+				var err error
+				set[i], err = gh.SetElement(tok, c)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			rand, err := c.Rand()
+			Expect(err).NotTo(HaveOccurred())
+			bf := c.NewRandomZr(rand)
+			com, err := common.ComputePedersenCommitment([]*math.Zr{c.HashToZr([]byte("ABC")), metas[2].Value, bf}, pp.PedParams, c)
+			Expect(err).NotTo(HaveOccurred())
+			input = &gh.Input{
+				Set:        ids,
+				Commitment: com,
+				Signer:     sender,
+				Nullifier:  gh.Nullifier(metas[2].SerialNumber, sks[2], pp),
+			}
+			witness = &gh.SpendWitness{
+				Index:                    2,
+				BlindingFactor:           metas[2].BlindingFactor,
+				CommitmentBlindingFactor: bf,
+				Owner:                    owner,
+				SerialNumber:             c.ModAdd(metas[2].SerialNumber, sks[2], c.GroupOrder),
+			}
+		})
+		It("succeeds", func() {
+			proof, err := gh.NewSpendProver(witness, set, input, []byte("message"), pp).Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gh.NewSpendVerifier(set, input, []byte("message"), pp).Verify(proof)).To(Succeed())
+		})
+		Context("the nullifier is not the one of the spent token", func() {
+			It("fails", func() {
+				proof, err := gh.NewSpendProver(witness, set, input, []byte("message"), pp).Prove()
+				Expect(err).NotTo(HaveOccurred())
+				input.Nullifier = gh.Nullifier(metas[1].SerialNumber, sks[1], pp)
+				err = gh.NewSpendVerifier(set, input, []byte("message"), pp).Verify(proof)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("nullifier does not match the owner commitment"))
+			})
+		})
+		Context("the owner is not the one of the spent token", func() {
+			It("fails", func() {
+				witness.Owner = []byte("another owner")
+				proof, err := gh.NewSpendProver(witness, set, input, []byte("message"), pp).Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(gh.NewSpendVerifier(set, input, []byte("message"), pp).Verify(proof)).NotTo(Succeed())
+			})
+		})
+		Context("the spender does not know the secret key of the owner", func() {
+			It("fails", func() {
+				// the creator of the token knows all of its opening but the secret key of the owner
+				rand, err := c.Rand()
+				Expect(err).NotTo(HaveOccurred())
+				guess := c.NewRandomZr(rand)
+				witness.SerialNumber = c.ModAdd(metas[2].SerialNumber, guess, c.GroupOrder)
+				input.Nullifier = gh.Nullifier(metas[2].SerialNumber, guess, pp)
+				proof, err := gh.NewSpendProver(witness, set, input, []byte("message"), pp).Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(gh.NewSpendVerifier(set, input, []byte("message"), pp).Verify(proof)).NotTo(Succeed())
+			})
+		})
+		Context("the spent token is not in the set", func() {
+			It("fails", func() {
+				set[2] = set[3]
+				proof, err := gh.NewSpendProver(witness, set, input, []byte("message"), pp).Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(gh.NewSpendVerifier(set, input, []byte("message"), pp).Verify(proof)).NotTo(Succeed())
+			})
+		})
+		Context("the message changes", func() {
+			It("fails", func() {
+				proof, err := gh.NewSpendProver(witness, set, input, []byte("message"), pp).Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(gh.NewSpendVerifier(set, input, []byte("another message"), pp).Verify(proof)).NotTo(Succeed())
+			})
+		})
+	})
+
+	Describe("Validator", func() {
+		var (
+			v       *validator.Validator
+			action  *gh.TransferAction
+			outputs []*gh.Metadata
+		)
+		BeforeEach(func() {
+			v = validator.New(pp, &deserializer{verifiers: map[string]driver.Verifier{string(sender): signer.ECDSAVerifier}})
+			action, outputs = prepareTransfer(ledger, ids, metas, sks, []int{0, 1}, []uint64{65, 35}, owner, sender, pp)
+		})
+		It("succeeds", func() {
+			raw := prepareRequest(signer, "1", action)
+			actions, err := v.VerifyTokenRequestFromRaw(getState(ledger), "1", raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actions).To(HaveLen(1))
+
+			// the outputs can be opened
+			for i, out := range action.OutputTokens {
+				tok, err := gh.GetTokenInTheClear(out, outputs[i], pp)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tok.Type).To(Equal("ABC"))
+				Expect(tok.Owner.Raw).To(BeEquivalentTo(owner))
+			}
+			Expect(outputs[0].Value.Equals(c.NewZrFromInt(65))).To(BeTrue())
+			Expect(outputs[1].Value.Equals(c.NewZrFromInt(35))).To(BeTrue())
+
+			// the inputs reveal neither the spent tokens nor their owner
+			serialized, err := action.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(serialized)).NotTo(ContainSubstring(base64.StdEncoding.EncodeToString(owner)))
+		})
+		Context("the spend key of an input does not match the secret key", func() {
+			It("fails", func() {
+				inputs := []*gh.SpentToken{{Metadata: metas[0], Set: ids, SetTokens: make([]*token.Token, len(ids)), SecretKey: sks[1], Signer: sender}}
+				_, err := gh.NewSender(inputs, pp)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not match its spend key"))
+			})
+		})
+		Context("the request is bound to another anchor", func() {
+			It("fails", func() {
+				raw := prepareRequest(signer, "2", action)
+				_, err := v.VerifyTokenRequestFromRaw(getState(ledger), "2", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid spend proof"))
+			})
+		})
+		Context("a token is already spent", func() {
+			It("fails", func() {
+				key, err := gh.NullifierKey(gh.Nullifier(metas[1].SerialNumber, sks[1], pp))
+				Expect(err).NotTo(HaveOccurred())
+				ledger[key] = []byte("true")
+				raw := prepareRequest(signer, "1", action)
+				_, err = v.VerifyTokenRequestFromRaw(getState(ledger), "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("input [1] is already spent"))
+			})
+		})
+		Context("a token is spent twice in the same request", func() {
+			It("fails", func() {
+				other, _ := prepareTransfer(ledger, ids, metas, sks, []int{1}, []uint64{30}, owner, sender, pp)
+				raw := prepareRequest(signer, "1", action, other)
+				_, err := v.VerifyTokenRequestFromRaw(getState(ledger), "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("is revealed twice"))
+			})
+		})
+		Context("the anonymity set contains a token that does not exist", func() {
+			It("fails", func() {
+				action.Inputs[0].Set[3] = &token3.ID{TxId: "unknown", Index: 0}
+				raw := prepareRequest(signer, "1", action)
+				_, err := v.VerifyTokenRequestFromRaw(getState(ledger), "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not exist"))
+			})
+		})
+	})
+})
+
+// prepareTokens stores on the ledger tokens of type ABC with the passed values, owned by the passed owner.
+// Each token commits to the spend key of a fresh secret key of the owner.
+func prepareTokens(ledger map[string][]byte, owner []byte, values []int64, pp *crypto.PublicParams) ([]*token3.ID, []*gh.Metadata, []*math.Zr) {
+	c := math.Curves[pp.Curve]
+	rand, err := c.Rand()
+	Expect(err).NotTo(HaveOccurred())
+	ids := make([]*token3.ID, len(values))
+	metas := make([]*gh.Metadata, len(values))
+	sks := make([]*math.Zr, len(values))
+	for i, value := range values {
+		sks[i] = c.NewRandomZr(rand)
+		metas[i] = &gh.Metadata{
+			Metadata: token.Metadata{
+				Type:           "ABC",
+				Value:          c.NewZrFromInt(value),
+				BlindingFactor: c.NewRandomZr(rand),
+				Owner:          owner,
+			},
+			SerialNumber: c.NewRandomZr(rand),
+			SpendKey:     gh.SpendKey(sks[i], pp),
+		}
+		data, err := common.ComputePedersenCommitment([]*math.Zr{c.HashToZr([]byte("ABC")), metas[i].Value, metas[i].BlindingFactor}, pp.PedParams, c)
+		Expect(err).NotTo(HaveOccurred())
+		tok := &token.Token{Owner: gh.OwnerCommitment(owner, metas[i].SerialNumber, metas[i].SpendKey, pp).Bytes(), Data: data}
+		raw, err := tok.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		ids[i] = &token3.ID{TxId: "issue", Index: uint64(i)}
+		key, err := keys.CreateTokenKey(ids[i].TxId, ids[i].Index)
+		Expect(err).NotTo(HaveOccurred())
+		ledger[key] = raw
+	}
+	return ids, metas, sks
+}
+
+// prepareTransfer spends the tokens at the passed indices, each of them hidden among all the tokens,
+// and signed for by the passed sender
+func prepareTransfer(ledger map[string][]byte, ids []*token3.ID, metas []*gh.Metadata, sks []*math.Zr, spent []int, values []uint64, owner []byte, sender []byte, pp *crypto.PublicParams) (*gh.TransferAction, []*gh.Metadata) {
+	setTokens := make([]*token.Token, len(ids))
+	for i, id := range ids {
+		setTokens[i] = loadToken(ledger, id)
+	}
+	inputs := make([]*gh.SpentToken, len(spent))
+	for i, index := range spent {
+		set := make([]*token3.ID, len(ids))
+		copy(set, ids)
+		inputs[i] = &gh.SpentToken{Metadata: metas[index], Set: set, SetTokens: setTokens, Index: index, SecretKey: sks[index], Signer: sender}
+	}
+	s, err := gh.NewSender(inputs, pp)
+	Expect(err).NotTo(HaveOccurred())
+	c := math.Curves[pp.Curve]
+	rand, err := c.Rand()
+	Expect(err).NotTo(HaveOccurred())
+	owners := make([][]byte, len(values))
+	spendKeys := make([]*math.G1, len(values))
+	for i := range owners {
+		owners[i] = owner
+		spendKeys[i] = gh.SpendKey(c.NewRandomZr(rand), pp)
+	}
+	action, outputs, err := s.GenerateZKTransfer("1", values, owners, spendKeys)
+	Expect(err).NotTo(HaveOccurred())
+	return action, outputs
+}
+
+// prepareRequest returns a token request with the passed actions, signed by the signer of their inputs
+func prepareRequest(signer driver.Signer, anchor string, actions ...*gh.TransferAction) []byte {
+	tr := &driver.TokenRequest{}
+	for _, action := range actions {
+		raw, err := action.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		tr.Transfers = append(tr.Transfers, raw)
+	}
+	raw, err := tr.Bytes()
+	Expect(err).NotTo(HaveOccurred())
+	for _, action := range actions {
+		for range action.Inputs {
+			sigma, err := signer.Sign(append(raw, []byte(anchor)...))
+			Expect(err).NotTo(HaveOccurred())
+			tr.Signatures = append(tr.Signatures, sigma)
+		}
+	}
+	raw, err = tr.Bytes()
+	Expect(err).NotTo(HaveOccurred())
+	return raw
+}
+
+func loadToken(ledger map[string][]byte, id *token3.ID) *token.Token {
+	key, err := keys.CreateTokenKey(id.TxId, id.Index)
+	Expect(err).NotTo(HaveOccurred())
+	tok := &token.Token{}
+	Expect(tok.Deserialize(ledger[key])).To(Succeed())
+	return tok
+}
+
+func getState(ledger map[string][]byte) driver.GetStateFnc {
+	return func(key string) ([]byte, error) {
+		return ledger[key], nil
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// SpentToken is a token to be spent by a graph hiding transfer, together with its anonymity set
+type SpentToken struct {
+	// Metadata is the opening of the token
+	Metadata *Metadata
+	// Set contains the identifiers of the tokens of the anonymity set
+	Set []*token3.ID
+	// SetTokens are the tokens of the anonymity set
+	SetTokens []*token.Token
+	// Index is the position of the token in the anonymity set
+	Index int
+	// SecretKey is the secret key of the owner of the token, whose spend key the token commits to
+	SecretKey *math.Zr
+	// Signer is a fresh identity of the owner of the token, that signs the transfer in place of the owner
+	Signer []byte
+}
+
+// Sender produces graph hiding TransferActions
+type Sender struct {
+	// Inputs are the tokens to be spent
+	Inputs []*SpentToken
+	// PublicParams refers to the public cryptographic parameters to be used
+	PublicParams *crypto.PublicParams
+	// Workers generate the proofs of the transfer, DefaultWorkerPool if nil
+	Workers *common.WorkerPool
+}
+
+// NewSender returns a Sender
+func NewSender(inputs []*SpentToken, pp *crypto.PublicParams) (*Sender, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("no tokens to be spent")
+	}
+	size := pp.GraphHidingParams.AnonymitySetSize()
+	for i, in := range inputs {
+		if len(in.Set) != size || len(in.SetTokens) != size {
+			return nil, errors.Errorf("anonymity set of input [%d] should have [%d] tokens, instead it has [%d,%d]", i, size, len(in.Set), len(in.SetTokens))
+		}
+		if in.Index < 0 || in.Index >= size {
+			return nil, errors.Errorf("invalid index [%d] of input [%d] in its anonymity set", in.Index, i)
+		}
+		if in.Metadata == nil || in.Metadata.SerialNumber == nil || in.Metadata.SpendKey == nil {
+			return nil, errors.Errorf("invalid metadata of input [%d]", i)
+		}
+		if in.SecretKey == nil || !SpendKey(in.SecretKey, pp).Equals(in.Metadata.SpendKey) {
+			return nil, errors.Errorf("the secret key of input [%d] does not match its spend key", i)
+		}
+		if len(in.Signer) == 0 {
+			return nil, errors.Errorf("no signer for input [%d]", i)
+		}
+	}
+	return &Sender{Inputs: inputs, PublicParams: pp}, nil
+}
+
+// CommitOwners returns the commitments to the passed owners and spend keys, and the serial numbers they commit to.
+// The commitment of an empty owner, a redeem, is empty.
+func CommitOwners(owners [][]byte, spendKeys []*math.G1, pp *crypto.PublicParams) ([][]byte, []*math.Zr, error) {
+	if len(owners) != len(spendKeys) {
+		return nil, nil, errors.Errorf("number of owners [%d] does not match number of spend keys [%d]", len(owners), len(spendKeys))
+	}
+	c := math.Curves[pp.Curve]
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get RNG")
+	}
+	coms := make([][]byte, len(owners))
+	sns := make([]*math.Zr, len(owners))
+	for i, owner := range owners {
+		if len(owner) == 0 {
+			continue
+		}
+		if spendKeys[i] == nil {
+			return nil, nil, errors.Errorf("no spend key for owner [%d]", i)
+		}
+		sns[i] = c.NewRandomZr(rand)
+		coms[i] = OwnerCommitment(owner, sns[i], spendKeys[i], pp).Bytes()
+	}
+	return coms, sns, nil
+}
+
+// GenerateZKTransfer produces a TransferAction bound to the passed anchor, and an array of Metadata
+// that corresponds to the openings of the newly created outputs.
+// Each output commits to the spend key of its owner, nil for a redeem.
+func (s *Sender) GenerateZKTransfer(anchor string, values []uint64, owners [][]byte, spendKeys []*math.G1) (*TransferAction, []*Metadata, error) {
+	if len(values) != len(owners) {
+		return nil, nil, errors.Errorf("cannot generate transfer: number of values [%d] does not match number of recipients [%d]", len(values), len(owners))
+	}
+	pp := s.PublicParams
+	c := math.Curves[pp.Curve]
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot generate transfer")
+	}
+	ttype := s.Inputs[0].Metadata.Type
+
+	// commit again to the type and value of the inputs, with fresh blinding factors
+	inputs := make([]*Input, len(s.Inputs))
+	intw := make([]*token.TokenDataWitness, len(s.Inputs))
+	in := make([]*math.G1, len(s.Inputs))
+	for i, spent := range s.Inputs {
+		if spent.Metadata.Type != ttype {
+			return nil, nil, errors.New("cannot generate transfer: please choose inputs of the same token type")
+		}
+		intw[i] = &token.TokenDataWitness{Type: ttype, Value: spent.Metadata.Value, BlindingFactor: c.NewRandomZr(rand)}
+		in[i], err = common.ComputePedersenCommitment([]*math.Zr{c.HashToZr([]byte(ttype)), intw[i].Value, intw[i].BlindingFactor}, pp.PedParams, c)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "cannot generate transfer")
+		}
+		inputs[i] = &Input{
+			Set:        spent.Set,
+			Commitment: in[i],
+			Signer:     spent.Signer,
+			Nullifier:  Nullifier(spent.Metadata.SerialNumber, spent.SecretKey, pp),
+		}
+	}
+
+	out, outtw, err := token.GetTokensWithWitness(values, ttype, pp.PedParams, c)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot generate transfer")
+	}
+	ownerComs, sns, err := CommitOwners(owners, spendKeys, pp)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot generate transfer")
+	}
+	outputs := make([]*token.Token, len(out))
+	for i := 0; i < len(out); i++ {
+		outputs[i] = &token.Token{Owner: ownerComs[i], Data: out[i]}
+	}
+
+	proof, err := transfer.NewProver(intw, outtw, in, out, pp, s.Workers).Prove()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot generate zero-knowledge proof for transfer")
+	}
+	action := &TransferAction{
+		Inputs:       inputs,
+		OutputTokens: outputs,
+		Proof:        proof,
+		Metadata:     map[string][]byte{},
+	}
+
+	// the spend proofs are bound to the rest of the action
+	message, err := action.SpendMessage(anchor)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot generate transfer")
+	}
+	err = s.Workers.Run(len(s.Inputs), func(i int) error {
+		spent := s.Inputs[i]
+		set := make([]*math.G1, len(spent.SetTokens))
+		for j, tok := range spent.SetTokens {
+			var err error
+			set[j], err = SetElement(tok, c)
+			if err != nil {
+				return errors.Wrapf(err, "invalid token [%d] in the anonymity set of input [%d]", j, i)
+			}
+		}
+		witness := &SpendWitness{
+			Index:                    spent.Index,
+			BlindingFactor:           spent.Metadata.BlindingFactor,
+			CommitmentBlindingFactor: intw[i].BlindingFactor,
+			Owner:                    spent.Metadata.Owner,
+			SerialNumber:             c.ModAdd(spent.Metadata.SerialNumber, spent.SecretKey, c.GroupOrder),
+		}
+		var err error
+		inputs[i].Proof, err = NewSpendProver(witness, set, inputs[i], message, pp).Prove()
+		return errors.Wrapf(err, "cannot generate spend proof for input [%d]", i)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inf := make([]*Metadata, len(owners))
+	for i := 0; i < len(inf); i++ {
+		inf[i] = &Metadata{
+			Metadata: token.Metadata{
+				Type:           ttype,
+				Value:          outtw[i].Value,
+				BlindingFactor: outtw[i].BlindingFactor,
+				Owner:          owners[i],
+			},
+			SerialNumber: sns[i],
+			SpendKey:     spendKeys[i],
+		}
+	}
+	return action, inf, nil
+}
@@ -0,0 +1,265 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/o2omp"
+	"github.com/pkg/errors"
+)
+
+// SpendProof shows that the spender of an input knows the opening of one of the tokens in the anonymity set of the input,
+// such that the token has the type and value committed in the input commitment, and its serial number,
+// that includes the secret key of its owner, is the one of the nullifier of the input.
+// Neither the spent token nor its owner are revealed, and only the owner can produce the proof.
+type SpendProof struct {
+	// OwnerCommitment is P2^rho * OwnerGen^{H(owner)} * SerialNumberGen^sn, it hides the owner and the serial number
+	// of the spent token
+	OwnerCommitment *math.G1
+	// Membership is a one-out-of-many proof that one of the tokens in the anonymity set, divided by the input commitment
+	// and the owner commitment, is a commitment to zero
+	Membership []byte
+	// Challenge is the challenge of the proof that the owner commitment and the nullifier hide the same serial number
+	Challenge *math.Zr
+	// Randomness is the response for rho
+	Randomness *math.Zr
+	// Owner is the response for the hash of the owner
+	Owner *math.Zr
+	// SerialNumber is the response for the serial number
+	SerialNumber *math.Zr
+}
+
+// Serialize marshals SpendProof
+func (p *SpendProof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Deserialize un-marshals SpendProof
+func (p *SpendProof) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, p)
+}
+
+// SpendWitness contains the secret information of a SpendProof
+type SpendWitness struct {
+	// Index is the position of the spent token in the anonymity set
+	Index int
+	// BlindingFactor is the blinding factor of the data of the spent token
+	BlindingFactor *math.Zr
+	// CommitmentBlindingFactor is the blinding factor of the input commitment
+	CommitmentBlindingFactor *math.Zr
+	// Owner is the owner of the spent token
+	Owner []byte
+	// SerialNumber is the serial number of the spent token, the one in its metadata plus the secret key of its owner
+	SerialNumber *math.Zr
+}
+
+// SpendVerifier checks a SpendProof
+type SpendVerifier struct {
+	// Set contains the elements of the anonymity set, see SetElement
+	Set []*math.G1
+	// Commitment is the input commitment, a commitment to the type and value of the spent token
+	Commitment *math.G1
+	// Nullifier is the nullifier of the spent token
+	Nullifier *math.G1
+	// Message binds the proof to the transfer
+	Message []byte
+	PP      *crypto.PublicParams
+}
+
+// SpendProver produces a SpendProof
+type SpendProver struct {
+	*SpendVerifier
+	witness *SpendWitness
+}
+
+// NewSpendVerifier returns a SpendVerifier for the passed input of a transfer
+func NewSpendVerifier(set []*math.G1, input *Input, message []byte, pp *crypto.PublicParams) *SpendVerifier {
+	return &SpendVerifier{
+		Set:        set,
+		Commitment: input.Commitment,
+		Nullifier:  input.Nullifier,
+		Message:    message,
+		PP:         pp,
+	}
+}
+
+// NewSpendProver returns a SpendProver for the passed input of a transfer
+func NewSpendProver(witness *SpendWitness, set []*math.G1, input *Input, message []byte, pp *crypto.PublicParams) *SpendProver {
+	return &SpendProver{
+		SpendVerifier: NewSpendVerifier(set, input, message, pp),
+		witness:       witness,
+	}
+}
+
+// Prove produces a serialized SpendProof
+func (p *SpendProver) Prove() ([]byte, error) {
+	c := math.Curves[p.PP.Curve]
+	ghp := p.PP.GraphHidingParams
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get RNG")
+	}
+	owner := c.HashToZr(p.witness.Owner)
+	rho := c.NewRandomZr(rand)
+	ownerCom, err := common.ComputePedersenCommitment([]*math.Zr{rho, owner, p.witness.SerialNumber}, p.ownerParams(), c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute owner commitment")
+	}
+
+	transcript, err := p.transcript(ownerCom)
+	if err != nil {
+		return nil, err
+	}
+
+	// prove that the owner commitment and the nullifier hide the same serial number
+	randomness := []*math.Zr{c.NewRandomZr(rand), c.NewRandomZr(rand), c.NewRandomZr(rand)}
+	ownerComRand, err := common.ComputePedersenCommitment(randomness, p.ownerParams(), c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate spend proof")
+	}
+	nullifierRand := ghp.NullifierGen.Mul(randomness[2])
+	chal, err := challenge(c, transcript, ownerComRand, nullifierRand)
+	if err != nil {
+		return nil, err
+	}
+	sp := &common.SchnorrProver{
+		SchnorrVerifier: &common.SchnorrVerifier{Curve: c},
+		Witness:         []*math.Zr{rho, owner, p.witness.SerialNumber},
+		Randomness:      randomness,
+		Challenge:       chal,
+	}
+	responses, err := sp.Prove()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate spend proof")
+	}
+
+	// prove that the spent token is in the anonymity set
+	statement, err := p.statement(ownerCom)
+	if err != nil {
+		return nil, err
+	}
+	// the element of the spent token divided by the input commitment and the owner commitment, is P2^{bf - bf' - rho}
+	r := c.ModSub(p.witness.BlindingFactor, p.witness.CommitmentBlindingFactor, c.GroupOrder)
+	r = c.ModSub(r, rho, c.GroupOrder)
+	membership, err := o2omp.NewProver(statement, transcript, p.membershipParams(), ghp.AnonymitySetBitLength, p.witness.Index, r, c).Prove()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate membership proof")
+	}
+
+	proof := &SpendProof{
+		OwnerCommitment: ownerCom,
+		Membership:      membership,
+		Challenge:       chal,
+		Randomness:      responses[0],
+		Owner:           responses[1],
+		SerialNumber:    responses[2],
+	}
+	return proof.Serialize()
+}
+
+// Verify checks the validity of a serialized SpendProof
+func (v *SpendVerifier) Verify(raw []byte) error {
+	c := math.Curves[v.PP.Curve]
+	ghp := v.PP.GraphHidingParams
+	proof := &SpendProof{}
+	if err := proof.Deserialize(raw); err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	if proof.OwnerCommitment == nil || proof.Challenge == nil || proof.Randomness == nil || proof.Owner == nil || proof.SerialNumber == nil {
+		return errors.New("invalid spend proof: nil elements")
+	}
+	if v.Commitment == nil || v.Nullifier == nil {
+		return errors.New("invalid spend proof: nil input commitment or nullifier")
+	}
+	transcript, err := v.transcript(proof.OwnerCommitment)
+	if err != nil {
+		return err
+	}
+
+	// check that the owner commitment and the nullifier hide the same serial number
+	sv := &common.SchnorrVerifier{PedParams: v.ownerParams(), Curve: c}
+	ownerComRand, err := sv.RecomputeCommitment(&common.SchnorrProof{
+		Statement: proof.OwnerCommitment,
+		Proof:     []*math.Zr{proof.Randomness, proof.Owner, proof.SerialNumber},
+		Challenge: proof.Challenge,
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	nv := &common.SchnorrVerifier{PedParams: []*math.G1{ghp.NullifierGen}, Curve: c}
+	nullifierRand, err := nv.RecomputeCommitment(&common.SchnorrProof{
+		Statement: v.Nullifier,
+		Proof:     []*math.Zr{proof.SerialNumber},
+		Challenge: proof.Challenge,
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	chal, err := challenge(c, transcript, ownerComRand, nullifierRand)
+	if err != nil {
+		return err
+	}
+	if !chal.Equals(proof.Challenge) {
+		return errors.New("invalid spend proof: nullifier does not match the owner commitment")
+	}
+
+	// check that the spent token is in the anonymity set
+	statement, err := v.statement(proof.OwnerCommitment)
+	if err != nil {
+		return err
+	}
+	if err := o2omp.NewVerifier(statement, transcript, v.membershipParams(), ghp.AnonymitySetBitLength, c).Verify(proof.Membership); err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	return nil
+}
+
+// statement returns the elements of the anonymity set divided by the input commitment and the owner commitment.
+// The element of the spent token becomes a commitment to zero.
+func (v *SpendVerifier) statement(ownerCom *math.G1) ([]*math.G1, error) {
+	shift := v.Commitment.Copy()
+	shift.Add(ownerCom)
+	statement := make([]*math.G1, len(v.Set))
+	for i, e := range v.Set {
+		if e == nil {
+			return nil, errors.Errorf("invalid anonymity set: nil element at index [%d]", i)
+		}
+		statement[i] = e.Copy()
+		statement[i].Sub(shift)
+	}
+	return statement, nil
+}
+
+// transcript returns the public information the proofs are bound to
+func (v *SpendVerifier) transcript(ownerCom *math.G1) ([]byte, error) {
+	raw, err := common.GetG1Array([]*math.G1{ownerCom, v.Commitment, v.Nullifier}).Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute spend proof transcript")
+	}
+	return append(raw, v.Message...), nil
+}
+
+// ownerParams returns the generators of the owner commitment
+func (v *SpendVerifier) ownerParams() []*math.G1 {
+	return []*math.G1{v.PP.PedParams[2], v.PP.GraphHidingParams.OwnerGen, v.PP.GraphHidingParams.SerialNumberGen}
+}
+
+func (v *SpendVerifier) membershipParams() []*math.G1 {
+	return []*math.G1{v.PP.PedParams[0], v.PP.PedParams[2]}
+}
+
+func challenge(c *math.Curve, transcript []byte, elements ...*math.G1) (*math.Zr, error) {
+	raw, err := common.GetG1Array(elements).Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute challenge")
+	}
+	return c.HashToZr(append(raw, transcript...)), nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Metadata contains the metadata of a graph hiding token.
+// The Owner field of the token on the ledger is a commitment to Owner, SerialNumber, and SpendKey.
+type Metadata struct {
+	token.Metadata
+	// SerialNumber is the part of the serial number of the token chosen by the creator of the token
+	SerialNumber *math.Zr
+	// SpendKey is the spend key of the owner, SerialNumberGen^{sk} for a secret key sk known only to the owner.
+	// The serial number of the token is SerialNumber + sk, therefore only the owner can compute the nullifier.
+	SpendKey *math.G1
+}
+
+// Deserialize un-marshals Metadata
+func (m *Metadata) Deserialize(b []byte) error {
+	return json.Unmarshal(b, m)
+}
+
+// Serialize marshals Metadata
+func (m *Metadata) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// SpendKey returns the spend key of the passed secret key, SerialNumberGen^{sk}
+func SpendKey(sk *math.Zr, pp *crypto.PublicParams) *math.G1 {
+	return pp.GraphHidingParams.SerialNumberGen.Mul(sk)
+}
+
+// OwnerCommitment returns the commitment to the passed owner, serial number, and spend key,
+// OwnerGen^{H(owner)} * SerialNumberGen^{sn} * nk
+func OwnerCommitment(owner []byte, sn *math.Zr, nk *math.G1, pp *crypto.PublicParams) *math.G1 {
+	c := math.Curves[pp.Curve]
+	com := pp.GraphHidingParams.OwnerGen.Mul(c.HashToZr(owner))
+	com.Add(pp.GraphHidingParams.SerialNumberGen.Mul(sn))
+	com.Add(nk)
+	return com
+}
+
+// Nullifier returns the nullifier of the token with the passed serial number, owned by the owner with the passed
+// secret key, NullifierGen^{sn + sk}. The nullifier is revealed when the token is spent.
+func Nullifier(sn *math.Zr, sk *math.Zr, pp *crypto.PublicParams) *math.G1 {
+	c := math.Curves[pp.Curve]
+	return pp.GraphHidingParams.NullifierGen.Mul(c.ModAdd(sn, sk, c.GroupOrder))
+}
+
+// SetElement returns the element of an anonymity set that corresponds to the passed token,
+// the product of its data and of its owner commitment
+func SetElement(tok *token.Token, c *math.Curve) (*math.G1, error) {
+	if tok == nil || tok.Data == nil {
+		return nil, errors.New("invalid token: nil data")
+	}
+	owner, err := c.NewG1FromBytes(tok.Owner)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token: owner is not a commitment")
+	}
+	owner.Add(tok.Data)
+	return owner, nil
+}
+
+// GetTokenInTheClear returns the passed token in the clear, if it matches the passed metadata.
+// The owner of the returned token is the owner in the metadata.
+func GetTokenInTheClear(tok *token.Token, meta *Metadata, pp *crypto.PublicParams) (*token2.Token, error) {
+	if meta.SerialNumber == nil || meta.SpendKey == nil {
+		return nil, errors.New("cannot retrieve token in the clear: nil serial number or spend key")
+	}
+	if tok.IsRedeem() {
+		return nil, errors.New("cannot retrieve token in the clear: redeemed token")
+	}
+	clear, err := tok.GetTokenInTheClear(&meta.Metadata, pp)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := math.Curves[pp.Curve].NewG1FromBytes(tok.Owner)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot retrieve token in the clear: owner is not a commitment")
+	}
+	if !OwnerCommitment(meta.Owner, meta.SerialNumber, meta.SpendKey, pp).Equals(owner) {
+		return nil, errors.New("cannot retrieve token in the clear: owner does not match provided opening")
+	}
+	clear.Owner = &token2.Owner{Raw: meta.Owner}
+	return clear, nil
+}
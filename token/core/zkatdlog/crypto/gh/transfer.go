@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Input is a token spent by a graph hiding transfer.
+// The spent token is not revealed, it is hidden among the tokens of the anonymity set, and so is its owner.
+type Input struct {
+	// Set contains the identifiers of the tokens of the anonymity set
+	Set []*token3.ID
+	// Commitment is a fresh commitment to the type and value of the spent token
+	Commitment *math.G1
+	// Signer is a fresh identity of the owner of the spent token, that signs the transfer in place of the owner.
+	// The spend proof, not the signature, shows that the spender owns the token.
+	Signer []byte
+	// Nullifier is the nullifier of the spent token, it prevents the token from being spent twice
+	Nullifier *math.G1
+	// Proof is a SpendProof
+	Proof []byte
+}
+
+// TransferAction specifies a graph hiding transfer of one or more tokens
+type TransferAction struct {
+	// Inputs are the tokens to be spent
+	Inputs []*Input
+	// OutputTokens are the new tokens resulting from the transfer
+	OutputTokens []*token.Token
+	// Proof shows that the input commitments and the outputs have the same type and total value,
+	// and that the outputs have value in the authorized range
+	Proof []byte
+	// Metadata contains the transfer action's metadata
+	Metadata map[string][]byte
+}
+
+// NullifierKey returns the key under which the passed nullifier is stored on the ledger, once its token is spent
+func NullifierKey(nullifier *math.G1) (string, error) {
+	return keys.CreateSNKey(hex.EncodeToString(nullifier.Bytes()))
+}
+
+// GetInputs returns the keys of the nullifiers of the inputs in the TransferAction
+func (t *TransferAction) GetInputs() ([]string, error) {
+	res := make([]string, len(t.Inputs))
+	for i, in := range t.Inputs {
+		if in == nil || in.Nullifier == nil {
+			return nil, errors.Errorf("invalid input at index [%d]: nil nullifier", i)
+		}
+		var err error
+		res[i], err = NullifierKey(in.Nullifier)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed computing the key of the nullifier at index [%d]", i)
+		}
+	}
+	return res, nil
+}
+
+// NumOutputs returns the number of outputs in the TransferAction
+func (t *TransferAction) NumOutputs() int {
+	return len(t.OutputTokens)
+}
+
+// GetOutputs returns the outputs in the TransferAction
+func (t *TransferAction) GetOutputs() []driver.Output {
+	var res []driver.Output
+	for _, outputToken := range t.OutputTokens {
+		res = append(res, outputToken)
+	}
+	return res
+}
+
+// IsRedeemAt checks if output in the TransferAction at the passed index is redeemed
+func (t *TransferAction) IsRedeemAt(index int) bool {
+	return t.OutputTokens[index].IsRedeem()
+}
+
+// SerializeOutputAt marshals the output in the TransferAction at the passed index
+func (t *TransferAction) SerializeOutputAt(index int) ([]byte, error) {
+	return t.OutputTokens[index].Serialize()
+}
+
+// Serialize marshals the TransferAction
+func (t *TransferAction) Serialize() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// GetProof returns the proof in the TransferAction
+func (t *TransferAction) GetProof() []byte {
+	return t.Proof
+}
+
+// Deserialize unmarshals the TransferAction
+func (t *TransferAction) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, t)
+}
+
+// GetSerializedOutputs returns the outputs in the TransferAction serialized
+func (t *TransferAction) GetSerializedOutputs() ([][]byte, error) {
+	var res [][]byte
+	for _, token := range t.OutputTokens {
+		r, err := token.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// GetInputCommitments returns the input commitments in the TransferAction
+func (t *TransferAction) GetInputCommitments() []*math.G1 {
+	com := make([]*math.G1, len(t.Inputs))
+	for i := 0; i < len(com); i++ {
+		com[i] = t.Inputs[i].Commitment
+	}
+	return com
+}
+
+// GetOutputCommitments returns the Pedersen commitments in the TransferAction
+func (t *TransferAction) GetOutputCommitments() []*math.G1 {
+	com := make([]*math.G1, len(t.OutputTokens))
+	for i := 0; i < len(com); i++ {
+		com[i] = t.OutputTokens[i].Data
+	}
+	return com
+}
+
+// IsGraphHiding returns true, the spent tokens are not revealed
+func (t *TransferAction) IsGraphHiding() bool {
+	return true
+}
+
+// GetMetadata returns metadata of the TransferAction
+func (t *TransferAction) GetMetadata() map[string][]byte {
+	return t.Metadata
+}
+
+// SpendMessage returns the message the spend proofs of the TransferAction are bound to.
+// It binds the passed anchor and the whole action, but for the spend proofs.
+func (t *TransferAction) SpendMessage(anchor string) ([]byte, error) {
+	inputs := make([]*Input, len(t.Inputs))
+	for i, in := range t.Inputs {
+		if in == nil {
+			return nil, errors.Errorf("invalid input at index [%d]", i)
+		}
+		inputs[i] = &Input{Set: in.Set, Commitment: in.Commitment, Signer: in.Signer, Nullifier: in.Nullifier}
+	}
+	raw, err := json.Marshal(&TransferAction{Inputs: inputs, OutputTokens: t.OutputTokens, Proof: t.Proof, Metadata: t.Metadata})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute spend message")
+	}
+	return append(raw, []byte(anchor)...), nil
+}
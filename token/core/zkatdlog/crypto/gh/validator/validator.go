@@ -0,0 +1,319 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package validator
+
+import (
+	"bytes"
+	"fmt"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.zkatdlog.gh")
+
+// Validator validates graph hiding token requests
+type Validator struct {
+	pp           *crypto.PublicParams
+	deserializer driver.Deserializer
+}
+
+func New(pp *crypto.PublicParams, deserializer driver.Deserializer) *Validator {
+	return &Validator{
+		pp:           pp,
+		deserializer: deserializer,
+	}
+}
+
+func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("empty token request")
+	}
+	tr := &driver.TokenRequest{}
+	err := tr.FromBytes(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal token request")
+	}
+
+	// Prepare message expected to be signed
+	req := &driver.TokenRequest{}
+	req.Transfers = tr.Transfers
+	req.Issues = tr.Issues
+	bytes, err := req.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal signed token request")
+	}
+
+	logger.Debugf("cc tx-id [%s][%s]", hash.Hashable(bytes).String(), binding)
+	signed := append(bytes, []byte(binding)...)
+	var signatures [][]byte
+	if len(v.pp.Auditor) != 0 {
+		signatures = append(signatures, tr.AuditorSignatures...)
+		signatures = append(signatures, tr.Signatures...)
+	} else {
+		signatures = tr.Signatures
+	}
+
+	backend := common.NewBackend(getState, signed, signatures)
+	return v.VerifyTokenRequest(backend, backend, binding, tr)
+}
+
+func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) ([]interface{}, error) {
+	if err := v.verifyAuditorSignature(signatureProvider); err != nil {
+		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
+	}
+	ia, err := v.unmarshalIssueActions(tr.Issues)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve issue actions [%s]", binding)
+	}
+	ta, err := v.unmarshalTransferActions(tr.Transfers)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve transfer actions [%s]", binding)
+	}
+	b := batch.NewVerifier(math.Curves[v.pp.Curve])
+	if err := v.verifyIssues(ia, signatureProvider, b); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", binding)
+	}
+	if err := v.verifyTransfers(ledger, ta, signatureProvider, binding, b); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
+	}
+	if err := b.Verify(); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify proofs [%s]", binding)
+	}
+
+	var actions []interface{}
+	for _, action := range ia {
+		actions = append(actions, action)
+	}
+	for _, action := range ta {
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+func (v *Validator) unmarshalTransferActions(raw [][]byte) ([]*gh.TransferAction, error) {
+	res := make([]*gh.TransferAction, len(raw))
+	for i := 0; i < len(raw); i++ {
+		ta := &gh.TransferAction{}
+		if err := ta.Deserialize(raw[i]); err != nil {
+			return nil, err
+		}
+		res[i] = ta
+	}
+	return res, nil
+}
+
+func (v *Validator) unmarshalIssueActions(raw [][]byte) ([]*issue2.IssueAction, error) {
+	res := make([]*issue2.IssueAction, len(raw))
+	for i := 0; i < len(raw); i++ {
+		ia := &issue2.IssueAction{}
+		if err := ia.Deserialize(raw[i]); err != nil {
+			return nil, err
+		}
+		res[i] = ia
+	}
+	return res, nil
+}
+
+func (v *Validator) verifyAuditorSignature(signatureProvider driver.SignatureProvider) error {
+	if v.pp.Auditor != nil {
+		verifier, err := v.deserializer.GetAuditorVerifier(v.pp.Auditor)
+		if err != nil {
+			return errors.Errorf("failed to deserialize auditor's public key")
+		}
+
+		_, err = signatureProvider.HasBeenSignedBy(v.pp.Auditor, verifier)
+		return err
+	}
+	return nil
+}
+
+func (v *Validator) verifyIssues(issues []*issue2.IssueAction, signatureProvider driver.SignatureProvider, b *batch.Verifier) error {
+	for i, a := range issues {
+		if err := v.verifyOutputs(a.OutputTokens); err != nil {
+			return errors.Wrapf(err, "invalid issue action [%d]", i)
+		}
+		coms, err := a.GetCommitments()
+		if err != nil {
+			return errors.New("failed to verify issue")
+		}
+		if err := issue2.NewVerifier(coms, a.IsAnonymous(), v.pp).VerifyBatch(a.GetProof(), b, fmt.Sprintf("issue action [%d]", i)); err != nil {
+			return errors.Wrapf(err, "failed to verify issue action")
+		}
+
+		issuers := v.pp.Issuers
+		if len(issuers) != 0 {
+			// Check that a.Issuer is in issuers
+			found := false
+			for _, issuer := range issuers {
+				if bytes.Equal(a.Issuer, issuer) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return errors.Errorf("issuer [%s] is not in issuers", view.Identity(a.Issuer).String())
+			}
+		}
+
+		verifier, err := v.deserializer.GetIssuerVerifier(a.Issuer)
+		if err != nil {
+			return errors.Wrapf(err, "failed getting verifier for [%s]", view.Identity(a.Issuer).String())
+		}
+		if _, err := signatureProvider.HasBeenSignedBy(a.Issuer, verifier); err != nil {
+			return errors.Wrapf(err, "failed verifying signature")
+		}
+	}
+	return nil
+}
+
+func (v *Validator) verifyTransfers(ledger driver.Ledger, transferActions []*gh.TransferAction, signatureProvider driver.SignatureProvider, binding string, b *batch.Verifier) error {
+	logger.Debugf("check sender start...")
+	defer logger.Debugf("check sender finished.")
+	// a nullifier can be revealed only once
+	nullifiers := map[string]bool{}
+	for i, t := range transferActions {
+		inputs, err := t.GetInputs()
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify transfer action")
+		}
+		for _, key := range inputs {
+			if nullifiers[key] {
+				return errors.Errorf("failed to verify transfer action: nullifier [%s] is revealed twice", key)
+			}
+			nullifiers[key] = true
+		}
+		if err := v.verifyTransfer(t, ledger, signatureProvider, binding, b, i); err != nil {
+			return errors.Wrapf(err, "failed to verify transfer action")
+		}
+	}
+	return nil
+}
+
+func (v *Validator) verifyTransfer(action *gh.TransferAction, ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, b *batch.Verifier, index int) error {
+	if len(action.Inputs) == 0 {
+		return errors.New("invalid transfer action: no inputs")
+	}
+	if len(action.Metadata) != 0 {
+		return errors.New("invalid transfer action: metadata is not supported by this driver")
+	}
+	if err := v.verifyOutputs(action.OutputTokens); err != nil {
+		return errors.Wrap(err, "invalid transfer action")
+	}
+	message, err := action.SpendMessage(binding)
+	if err != nil {
+		return err
+	}
+	nullifiers, err := action.GetInputs()
+	if err != nil {
+		return err
+	}
+	for i, in := range action.Inputs {
+		// the token must not be spent yet
+		raw, err := ledger.GetState(nullifiers[i])
+		if err != nil {
+			return errors.Wrapf(err, "failed to retrieve nullifier [%s]", nullifiers[i])
+		}
+		if len(raw) != 0 {
+			return errors.Errorf("input [%d] is already spent [%s]", i, nullifiers[i])
+		}
+
+		set, err := v.loadAnonymitySet(ledger, in)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load the anonymity set of input [%d]", i)
+		}
+		if err := gh.NewSpendVerifier(set, in, message, v.pp).Verify(in.Proof); err != nil {
+			return errors.Wrapf(err, "failed to verify input [%d]", i)
+		}
+
+		// the signer, a fresh identity of the owner, signs the transfer
+		signer, err := identity.UnmarshallRawOwner(in.Signer)
+		if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal signer of input [%d]", i)
+		}
+		if signer.Type != identity.SerializedIdentityType {
+			return errors.Errorf("invalid signer of input [%d]: scripts are not supported by this driver", i)
+		}
+		verifier, err := v.deserializer.GetOwnerVerifier(in.Signer)
+		if err != nil {
+			return errors.Wrapf(err, "failed deserializing signer [%d][%s]", i, view.Identity(in.Signer).UniqueID())
+		}
+		if _, err := signatureProvider.HasBeenSignedBy(in.Signer, verifier); err != nil {
+			return errors.Wrapf(err, "failed signature verification [%d][%s]", i, view.Identity(in.Signer).UniqueID())
+		}
+	}
+
+	return transfer.NewVerifier(
+		action.GetInputCommitments(),
+		action.GetOutputCommitments(),
+		v.pp).VerifyBatch(action.GetProof(), b, fmt.Sprintf("transfer action [%d]", index))
+}
+
+// loadAnonymitySet returns the elements of the anonymity set of the passed input
+func (v *Validator) loadAnonymitySet(ledger driver.Ledger, in *gh.Input) ([]*math.G1, error) {
+	size := v.pp.GraphHidingParams.AnonymitySetSize()
+	if len(in.Set) != size {
+		return nil, errors.Errorf("the anonymity set should have [%d] tokens, instead it has [%d]", size, len(in.Set))
+	}
+	c := math.Curves[v.pp.Curve]
+	set := make([]*math.G1, size)
+	for i, id := range in.Set {
+		if id == nil {
+			return nil, errors.Errorf("nil token identifier at index [%d]", i)
+		}
+		key, err := keys.CreateTokenKey(id.TxId, id.Index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed computing the key of token [%v]", id)
+		}
+		raw, err := ledger.GetState(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve token [%v]", id)
+		}
+		if len(raw) == 0 {
+			return nil, errors.Errorf("token [%v] does not exist", id)
+		}
+		tok := &token.Token{}
+		if err := tok.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to deserialize token [%v]", id)
+		}
+		set[i], err = gh.SetElement(tok, c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid token [%v]", id)
+		}
+	}
+	return set, nil
+}
+
+// verifyOutputs checks that the owner of each output, but the redeemed ones, is a commitment
+func (v *Validator) verifyOutputs(outputs []*token.Token) error {
+	c := math.Curves[v.pp.Curve]
+	for i, out := range outputs {
+		if out == nil || out.Data == nil {
+			return errors.Errorf("invalid output at index [%d]", i)
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if _, err := c.NewG1FromBytes(out.Owner); err != nil {
+			return errors.Wrapf(err, "invalid owner of output at index [%d]", i)
+		}
+	}
+	return nil
+}
@@ -10,6 +10,8 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	rp "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -145,15 +147,18 @@ type Prover struct {
 	// WellFormedness encodes the WellFormedness Prover
 	WellFormedness *WellFormednessProver
 	// RangeCorrectness encodes the range proof Prover
-	RangeCorrectness *rp.Prover
+	RangeCorrectness rp.RangeProver
+	// Workers generate the proofs, DefaultWorkerPool if nil
+	Workers *common.WorkerPool
 }
 
-func NewProver(tw []*token.TokenDataWitness, tokens []*math.G1, anonymous bool, pp *crypto.PublicParams) *Prover {
+// NewProver returns an IssueAction Prover that generates its proofs on the passed WorkerPool
+func NewProver(tw []*token.TokenDataWitness, tokens []*math.G1, anonymous bool, pp *crypto.PublicParams, workers *common.WorkerPool) *Prover {
 	c := math.Curves[pp.Curve]
-	p := &Prover{}
+	p := &Prover{Workers: workers}
 	p.WellFormedness = NewWellFormednessProver(tw, tokens, anonymous, pp.PedParams, c)
 
-	p.RangeCorrectness = rp.NewProver(tw, tokens, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.PedParams, pp.RangeProofParams.SignPK, pp.PedGen, pp.RangeProofParams.Q, math.Curves[pp.Curve])
+	p.RangeCorrectness = rp.NewRangeProver(tw, tokens, pp, workers)
 
 	return p
 }
@@ -164,16 +169,19 @@ func (p *Prover) Prove() ([]byte, error) {
 	if p.WellFormedness == nil || p.RangeCorrectness == nil {
 		return nil, errors.New("please initialize issue action prover correctly")
 	}
-	// WellFormedness proof
-	wf, err := p.WellFormedness.Prove()
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate issue proof")
-	}
-
-	// RangeCorrectness proof
-	rc, err := p.RangeCorrectness.Prove()
+	// WellFormedness and RangeCorrectness proofs
+	var wf, rc []byte
+	err := p.Workers.Run(2, func(i int) error {
+		var err error
+		if i == 0 {
+			wf, err = p.WellFormedness.Prove()
+			return errors.Wrapf(err, "failed to generate issue proof")
+		}
+		rc, err = p.RangeCorrectness.Prove()
+		return errors.Wrapf(err, "failed to generate range proof for issue")
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate range proof for issue")
+		return nil, err
 	}
 
 	proof := &Proof{
@@ -188,18 +196,28 @@ type Verifier struct {
 	// WellFormedness encodes the WellFormedness Verifier
 	WellFormedness *WellFormednessVerifier
 	// RangeCorrectness encodes the range proof verifier
-	RangeCorrectness *rp.Verifier
+	RangeCorrectness rp.RangeVerifier
 }
 
 func NewVerifier(tokens []*math.G1, anonymous bool, pp *crypto.PublicParams) *Verifier {
 	v := &Verifier{}
 	v.WellFormedness = NewWellFormednessVerifier(tokens, anonymous, pp.PedParams, math.Curves[pp.Curve])
-	v.RangeCorrectness = rp.NewVerifier(tokens, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.PedParams, pp.RangeProofParams.SignPK, pp.PedGen, pp.RangeProofParams.Q, math.Curves[pp.Curve])
+	v.RangeCorrectness = rp.NewRangeVerifier(tokens, pp)
 	return v
 }
 
 // Verify returns an error if Proof of an IssueAction is invalid
 func (v *Verifier) Verify(proof []byte) error {
+	b := batch.NewVerifier(v.WellFormedness.Curve)
+	if err := v.VerifyBatch(proof, b, "issue"); err != nil {
+		return err
+	}
+	return errors.Wrapf(b.Verify(), "invalid issue proof")
+}
+
+// VerifyBatch adds the equations of the well-formedness proof and of the range proof of an IssueAction
+// to the passed batch, under the passed label. The proofs that do not reduce to equations are checked right away.
+func (v *Verifier) VerifyBatch(proof []byte, b *batch.Verifier, label string) error {
 	if v.RangeCorrectness == nil || v.WellFormedness == nil {
 		return errors.New("please initialize issue action verifier correctly")
 	}
@@ -210,11 +228,17 @@ func (v *Verifier) Verify(proof []byte) error {
 		return err
 	}
 	// verify WellFormedness proof
-	err = v.WellFormedness.Verify(ip.WellFormedness)
+	equations, err := v.WellFormedness.Equations(ip.WellFormedness)
 	if err != nil {
 		return errors.Wrapf(err, "invalid issue proof")
 	}
+	if len(equations) != 0 {
+		b.Add(label+": well-formedness proof", equations...)
+	}
 	// verify RangeCorrectness proof
+	if bv, ok := v.RangeCorrectness.(rp.BatchRangeVerifier); ok {
+		return errors.Wrapf(bv.VerifyBatch(ip.RangeCorrectness, b, label+": range proof"), "invalid issue proof")
+	}
 	err = v.RangeCorrectness.Verify(ip.RangeCorrectness)
 	if err != nil {
 		return errors.Wrapf(err, "invalid issue proof")
@@ -32,6 +32,18 @@ var _ = Describe("Issue Correctness", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+		Context("the range proof is a Bulletproofs range proof", func() {
+			BeforeEach(func() {
+				prover, verifier = prepareZKIssueWithBulletproofs()
+			})
+			It("Succeeds", func() {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proof).NotTo(BeNil())
+				err = verifier.Verify(proof)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 })
 
@@ -57,7 +69,19 @@ func prepareZKIssue() (*issue.Prover, *issue.Verifier) {
 
 	tw, tokens := prepareInputsForZKIssue(pp)
 
-	prover := issue.NewProver(tw, tokens, true, pp)
+	prover := issue.NewProver(tw, tokens, true, pp, nil)
+	verifier := issue.NewVerifier(tokens, true, pp)
+
+	return prover, verifier
+}
+
+func prepareZKIssueWithBulletproofs() (*issue.Prover, *issue.Verifier) {
+	pp, err := crypto.SetupBulletproofs(64, nil, math.BN254)
+	Expect(err).NotTo(HaveOccurred())
+
+	tw, tokens := prepareInputsForZKIssue(pp)
+
+	prover := issue.NewProver(tw, tokens, true, pp, nil)
 	verifier := issue.NewVerifier(tokens, true, pp)
 
 	return prover, verifier
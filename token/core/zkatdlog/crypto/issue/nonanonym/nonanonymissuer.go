@@ -26,6 +26,8 @@ type Issuer struct {
 	Signer       SigningIdentity
 	PublicParams *crypto.PublicParams
 	Type         string
+	// Workers generate the proofs of the issue, DefaultWorkerPool if nil
+	Workers *common.WorkerPool
 }
 
 func (i *Issuer) New(ttype string, signer common.SigningIdentity, pp *crypto.PublicParams) {
@@ -46,7 +48,7 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 		return nil, nil, err
 	}
 
-	prover := issue2.NewProver(tw, tokens, false, i.PublicParams)
+	prover := issue2.NewProver(tw, tokens, false, i.PublicParams, i.Workers)
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Errorf("failed to generate zero knwoledge proof for issue")
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
@@ -29,6 +30,9 @@ type WellFormedness struct {
 	TypeInTheClear string
 	// Challenge computed using the Fiat-Shamir Heuristic
 	Challenge *math.Zr
+	// Commitments are the commitments to the randomness used in the proof, one for each issued token.
+	// They let the proof be verified in a batch.
+	Commitments []*math.G1
 }
 
 // Serialize marshals WellFormedness proof
@@ -89,6 +93,7 @@ func (p *WellFormednessProver) Prove() ([]byte, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "The computation of the issue proof failed")
 	}
+	wf.Commitments = p.Commitments
 	// serialize proof
 	return wf.Serialize()
 }
@@ -204,35 +209,61 @@ func NewWellFormednessVerifier(tokens []*math.G1, anonymous bool, pp []*math.G1,
 
 // Verify returns an error if the serialized proof is an invalid WellFormedness proof
 func (v *WellFormednessVerifier) Verify(proof []byte) error {
+	equations, err := v.Equations(proof)
+	if err != nil {
+		return err
+	}
+	for _, eq := range equations {
+		if !eq.Holds(v.Curve) {
+			return errors.Errorf("invalid well-formedness proof")
+		}
+	}
+	return nil
+}
+
+// Equations returns the equations that hold if and only if the passed serialized WellFormedness is valid,
+// one for each issued token. The equations can be checked at once with those of other proofs, in a batch.
+// A WellFormedness that does not carry the commitments to its randomness is verified right away,
+// and no equation is returned.
+func (v *WellFormednessVerifier) Equations(proof []byte) ([]*batch.Equation, error) {
 	wf := &WellFormedness{}
 	err := wf.Deserialize(proof)
 	if err != nil {
-		return errors.Wrap(err, "failed to verify well-formedness proof")
+		return nil, errors.Wrap(err, "failed to verify well-formedness proof")
 	}
-
 	// parse ZK proofs
 	zkps, err := v.parseProof(wf)
 	if err != nil {
-		return errors.Wrapf(err, "invalid zero-knowledge issue")
+		return nil, errors.Wrapf(err, "invalid zero-knowledge issue")
 	}
-
-	// recompute commitments used in ZK proofs
 	// initialize scchnorr verifier
 	ver := &common.SchnorrVerifier{PedParams: v.PedParams, Curve: v.Curve}
-	coms, err := ver.RecomputeCommitments(zkps, wf.Challenge)
-	if err != nil {
-		return errors.Wrapf(err, "failed to verify well-formedness proof")
+	coms := wf.Commitments
+	if len(coms) == 0 {
+		// recompute commitments used in ZK proofs
+		coms, err = ver.RecomputeCommitments(zkps, wf.Challenge)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to verify well-formedness proof")
+		}
 	}
-
 	// recompute challenge and check proof validity
 	raw, err := common.GetG1Array(coms, v.Tokens).Bytes()
 	if err != nil {
-		return errors.Wrapf(err, "failed to verify well-formedness proof")
+		return nil, errors.Wrapf(err, "failed to verify well-formedness proof")
 	}
 	if !v.Curve.HashToZr(raw).Equals(wf.Challenge) {
-		return errors.Errorf("invalid well-formedness proof")
+		return nil, errors.Errorf("invalid well-formedness proof")
 	}
-	return nil
+	if len(wf.Commitments) == 0 {
+		return nil, nil
+	}
+	// the challenge is computed on the commitments sent by the prover,
+	// the equations show that they are the commitments to the randomness
+	equations, err := ver.Equations(zkps, wf.Challenge, wf.Commitments)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to verify well-formedness proof")
+	}
+	return equations, nil
 }
 
 // parseProof takes a WellFormedness proof and returns the ZKPs that compose it
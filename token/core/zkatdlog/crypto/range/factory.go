@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rangeproof
+
+import (
+	mathlib "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+)
+
+// RangeProver produces a proof that the values of an array of tokens are in the authorized range
+type RangeProver interface {
+	Prove() ([]byte, error)
+}
+
+// RangeVerifier checks a proof that the values of an array of tokens are in the authorized range
+type RangeVerifier interface {
+	Verify(raw []byte) error
+}
+
+// BatchRangeVerifier is a RangeVerifier whose checks reduce to equations that can be verified in a batch
+type BatchRangeVerifier interface {
+	RangeVerifier
+	// VerifyBatch adds the equations of the passed serialized range proof to the passed batch, under the passed label
+	VerifyBatch(raw []byte, b *batch.Verifier, label string) error
+}
+
+// NewRangeProver returns the RangeProver of the range proof scheme selected by the passed public parameters.
+// The sub-proofs of the range proof, if any, are generated on the passed WorkerPool.
+func NewRangeProver(tw []*token.TokenDataWitness, tokens []*mathlib.G1, pp *crypto.PublicParams, workers *common.WorkerPool) RangeProver {
+	c := mathlib.Curves[pp.Curve]
+	if pp.RangeProofType == crypto.Bulletproofs {
+		// a token is a commitment to its value, blinded by the hash of its type and by its blinding factor.
+		// As for the membership proof, all tokens have the type of the first witness.
+		values := make([]*mathlib.Zr, len(tw))
+		blindingFactors := make([][]*mathlib.Zr, len(tw))
+		for i := 0; i < len(tw); i++ {
+			values[i] = tw[i].Value
+			blindingFactors[i] = []*mathlib.Zr{c.HashToZr([]byte(tw[0].Type)), tw[i].BlindingFactor}
+		}
+		return bulletproof.NewProver(values, blindingFactors, tokens, pp.PedParams[1], []*mathlib.G1{pp.PedParams[0], pp.PedParams[2]}, pp.BulletproofParams.BitLength, c)
+	}
+	p := NewProver(tw, tokens, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.PedParams, pp.RangeProofParams.SignPK, pp.PedGen, pp.RangeProofParams.Q, c)
+	p.Workers = workers
+	return p
+}
+
+// NewRangeVerifier returns the RangeVerifier of the range proof scheme selected by the passed public parameters
+func NewRangeVerifier(tokens []*mathlib.G1, pp *crypto.PublicParams) RangeVerifier {
+	c := mathlib.Curves[pp.Curve]
+	if pp.RangeProofType == crypto.Bulletproofs {
+		return bulletproof.NewVerifier(tokens, pp.PedParams[1], []*mathlib.G1{pp.PedParams[0], pp.PedParams[2]}, pp.BulletproofParams.BitLength, c)
+	}
+	return NewVerifier(tokens, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.PedParams, pp.RangeProofParams.SignPK, pp.PedGen, pp.RangeProofParams.Q, c)
+}
@@ -7,11 +7,13 @@ package rangeproof
 
 import (
 	"encoding/json"
+	"fmt"
 	"math"
 	"sync"
 	"sync/atomic"
 
 	mathlib "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/sigproof"
@@ -30,6 +32,9 @@ type RangeProof struct {
 	EqualityProofs *EqualityProofs
 	// MembershipProofs show that  0=<v_i =<base-1
 	MembershipProofs []*MembershipProof
+	// Commitment is the commitment to the randomness used in EqualityProofs.
+	// It lets the proof be verified in a batch.
+	Commitment *Commitment
 }
 
 // EqualityProofs show that for each token in an array of tokens,
@@ -63,6 +68,8 @@ type Prover struct {
 	tokenWitness []*token.TokenDataWitness
 	// Signatures are an array of Pointcheval-Sanders signatures
 	Signatures []*pssign.Signature
+	// Workers generate the membership proofs, DefaultWorkerPool if nil
+	Workers *common.WorkerPool
 }
 
 // NewProver returns a Prover
@@ -146,36 +153,26 @@ func (p *Prover) Prove() ([]byte, error) {
 		return nil, err
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(len(p.Tokens) * p.Exponent)
-
-	parallelErr := &atomic.Value{}
-
 	// produce proof that each committed value is signed
 	proof.MembershipProofs = make([]*MembershipProof, len(p.Tokens))
+	provers := make([]*sigproof.MembershipProver, len(p.Tokens)*p.Exponent)
 	for k := 0; k < len(proof.MembershipProofs); k++ {
 		proof.MembershipProofs[k] = &MembershipProof{}
 		proof.MembershipProofs[k].Commitments = make([]*mathlib.G1, p.Exponent)
 		proof.MembershipProofs[k].SignatureProofs = make([]*sigproof.MembershipProof, p.Exponent)
 		for i := 0; i < p.Exponent; i++ {
 			proof.MembershipProofs[k].Commitments[i] = preProcessed.commitmentsToValues[k][i]
-			mp := sigproof.NewMembershipProver(preProcessed.membershipWitnesses[k][i], proof.MembershipProofs[k].Commitments[i], p.P, p.Q, p.PK, p.PedersenParams[:2], p.Curve)
-
-			go func(k, i int) {
-				defer wg.Done()
-				var err error
-				proof.MembershipProofs[k].SignatureProofs[i], err = mp.Prove()
-				if err != nil {
-					parallelErr.Store(err)
-				}
-			}(k, i)
+			provers[k*p.Exponent+i] = sigproof.NewMembershipProver(preProcessed.membershipWitnesses[k][i], proof.MembershipProofs[k].Commitments[i], p.P, p.Q, p.PK, p.PedersenParams[:2], p.Curve)
 		}
 	}
-
-	wg.Wait()
-
-	if parallelErr.Load() != nil {
-		return nil, parallelErr.Load().(error)
+	// each proof is stored at its own position, the challenge below does not depend on the order of completion
+	err = p.Workers.Run(len(provers), func(j int) error {
+		var err error
+		proof.MembershipProofs[j/p.Exponent].SignatureProofs[j%p.Exponent], err = provers[j].Prove()
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// show that value in token = \prod_{i=0}^Exponent com_i^{Base^i}
@@ -204,11 +201,25 @@ func (p *Prover) Prove() ([]byte, error) {
 	}
 	proof.EqualityProofs.Type = p.Curve.ModMul(proof.Challenge, p.Curve.HashToZr([]byte(p.tokenWitness[0].Type)), p.Curve.GroupOrder)
 	proof.EqualityProofs.Type = p.Curve.ModAdd(proof.EqualityProofs.Type, randomness.tokenType, p.Curve.GroupOrder)
+	proof.Commitment = commitment
 
 	return json.Marshal(proof)
 }
 
+// Verify returns an error if the passed serialized RangeProof is invalid
 func (v *Verifier) Verify(raw []byte) error {
+	b := batch.NewVerifier(v.Curve)
+	if err := v.VerifyBatch(raw, b, "range proof"); err != nil {
+		return err
+	}
+	return errors.Wrap(b.Verify(), "failed to verify range proof")
+}
+
+// VerifyBatch adds the equations of the passed serialized RangeProof to the passed batch.
+// The equations of each membership proof are labelled with the position of its token and of its digit,
+// those of the equality proofs with the passed label.
+// The parts of the proof that do not carry the commitments to their randomness are verified right away.
+func (v *Verifier) VerifyBatch(raw []byte, b *batch.Verifier, label string) error {
 	// todo check length of public parameters
 	proof := &RangeProof{}
 	err := json.Unmarshal(raw, proof)
@@ -220,11 +231,11 @@ func (v *Verifier) Verify(raw []byte) error {
 		return errors.Errorf("range proof not well formed")
 	}
 
-	var verifications []func()
-	parallelErr := &atomic.Value{}
-
 	// verify membership
 	// each committed value v_i is signed (i.e., v_i < Base)
+	var labels []string
+	var verifiers []*sigproof.MembershipVerifier
+	var proofs []*sigproof.MembershipProof
 	for k := 0; k < len(v.Tokens); k++ {
 		if proof.MembershipProofs[k] == nil {
 			return errors.Errorf("range proof not well formed")
@@ -233,52 +244,94 @@ func (v *Verifier) Verify(raw []byte) error {
 			return errors.Errorf("range proof not well formed")
 		}
 		for i := 0; i < len(proof.MembershipProofs[k].Commitments); i++ {
-			mv := sigproof.NewMembershipVerifier(proof.MembershipProofs[k].Commitments[i], v.P, v.Q, v.PK, v.PedersenParams[:2], v.Curve)
-			proofToVerify := proof.MembershipProofs[k].SignatureProofs[i]
-			verifications = append(verifications, func() {
-				err := mv.Verify(proofToVerify)
-				if err != nil {
-					parallelErr.Store(errors.Wrapf(err, "failed to verify range proof"))
-				}
-			})
+			labels = append(labels, fmt.Sprintf("%s: membership proof [%d][%d]", label, k, i))
+			verifiers = append(verifiers, sigproof.NewMembershipVerifier(proof.MembershipProofs[k].Commitments[i], v.P, v.Q, v.PK, v.PedersenParams[:2], v.Curve))
+			proofs = append(proofs, proof.MembershipProofs[k].SignatureProofs[i])
 		}
 	}
 
+	// the membership proofs that do not carry their commitments are verified here, in parallel
+	equations := make([]*batch.Equation, len(proofs))
+	pairings := make([]*batch.PairingEquation, len(proofs))
+	parallelErr := &atomic.Value{}
 	var wg sync.WaitGroup
-	wg.Add(len(verifications))
-
-	for _, verification := range verifications {
-		go func(f func()) {
+	wg.Add(len(proofs))
+	for j := range proofs {
+		go func(j int) {
 			defer wg.Done()
-			f()
-		}(verification)
+			var err error
+			equations[j], pairings[j], err = verifiers[j].Equations(proofs[j])
+			if err != nil {
+				parallelErr.Store(errors.Wrapf(err, "failed to verify range proof"))
+			}
+		}(j)
 	}
-
 	wg.Wait()
-
 	if parallelErr.Load() != nil {
 		return parallelErr.Load().(error)
 	}
+	for j := range proofs {
+		if equations[j] != nil {
+			b.Add(labels[j], equations[j])
+			b.AddPairings(labels[j], pairings[j])
+		}
+	}
 
 	// verify equality proof
 	// value in token = \prod_{i=0}^Exponent com_i^{Base^i}
-	com, err := v.recomputeCommitments(proof)
-	if err != nil {
-		return err
-	}
 	coms := make([][]*mathlib.G1, len(proof.MembershipProofs))
 	for i := 0; i < len(proof.MembershipProofs); i++ {
-		for k := 0; k < len(proof.MembershipProofs[i].Commitments); k++ {
-			coms[i] = append(coms[i], proof.MembershipProofs[i].Commitments[k])
+		coms[i] = append(coms[i], proof.MembershipProofs[i].Commitments...)
+	}
+	if proof.Commitment == nil {
+		com, err := v.recomputeCommitments(proof)
+		if err != nil {
+			return err
+		}
+		chal, err := v.computeChallenge(com, coms)
+		if err != nil {
+			return errors.Wrap(err, "failed to verify range proof")
+		}
+		if !chal.Equals(proof.Challenge) {
+			return errors.Errorf("invalid range proof")
 		}
+		return nil
 	}
-	chal, err := v.computeChallenge(com, coms)
+
+	// the challenge is computed on the commitment sent by the prover,
+	// the equations show that it is the commitment to the randomness
+	if len(proof.Commitment.Tokens) != len(v.Tokens) || len(proof.Commitment.CommitmentsToValues) != len(v.Tokens) {
+		return errors.Errorf("range proof not well formed")
+	}
+	chal, err := v.computeChallenge(proof.Commitment, coms)
 	if err != nil {
 		return errors.Wrap(err, "failed to verify range proof")
 	}
-	if !chal.Equals(proof.Challenge) {
+	if proof.Challenge == nil || !chal.Equals(proof.Challenge) {
 		return errors.Errorf("invalid range proof")
 	}
+	zkps, err := v.equalityProofs(proof)
+	if err != nil {
+		return err
+	}
+	var eqs []*batch.Equation
+	for j := 0; j < len(v.Tokens); j++ {
+		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams, Curve: v.Curve}
+		eq, err := ver.Equation(zkps[j], proof.Commitment.Tokens[j])
+		if err != nil {
+			return err
+		}
+		eqs = append(eqs, eq)
+	}
+	for j := 0; j < len(v.Tokens); j++ {
+		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams[:2], Curve: v.Curve}
+		eq, err := ver.Equation(zkps[len(v.Tokens)+j], proof.Commitment.CommitmentsToValues[j])
+		if err != nil {
+			return err
+		}
+		eqs = append(eqs, eq)
+	}
+	b.Add(label, eqs...)
 
 	return nil
 }
@@ -391,6 +444,35 @@ func (v *Verifier) computeChallenge(commitment *Commitment, comToValue [][]*math
 // recomputeCommitments computes the commitment to randomness used in the proof as a function
 // of the proof
 func (v *Verifier) recomputeCommitments(p *RangeProof) (*Commitment, error) {
+	zkps, err := v.equalityProofs(p)
+	if err != nil {
+		return nil, err
+	}
+	c := &Commitment{}
+	// recompute commitments for verification
+	for j := 0; j < len(v.Tokens); j++ {
+		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams, Curve: v.Curve}
+		com, err := ver.RecomputeCommitment(zkps[j])
+		if err != nil {
+			return nil, err
+		}
+		c.Tokens = append(c.Tokens, com)
+	}
+
+	for j := 0; j < len(v.Tokens); j++ {
+		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams[:2], Curve: v.Curve}
+		com, err := ver.RecomputeCommitment(zkps[len(v.Tokens)+j])
+		if err != nil {
+			return nil, err
+		}
+		c.CommitmentsToValues = append(c.CommitmentsToValues, com)
+	}
+	return c, nil
+}
+
+// equalityProofs returns the Schnorr proofs the EqualityProofs are made of:
+// first those of the openings of the tokens, then those of the openings of \prod_{i=0}^Exponent com_i^{Base^i}
+func (v *Verifier) equalityProofs(p *RangeProof) ([]*common.SchnorrProof, error) {
 	if p.EqualityProofs == nil {
 		return nil, errors.Errorf("range proof not well formed")
 	}
@@ -404,16 +486,9 @@ func (v *Verifier) recomputeCommitments(p *RangeProof) (*Commitment, error) {
 		return nil, errors.Errorf("range proof not well formed")
 	}
 
-	c := &Commitment{}
-	// recompute commitments for verification
+	var zkps []*common.SchnorrProof
 	for j := 0; j < len(v.Tokens); j++ {
-		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams, Curve: v.Curve}
-		zkp := &common.SchnorrProof{Statement: v.Tokens[j], Proof: []*mathlib.Zr{p.EqualityProofs.Type, p.EqualityProofs.Value[j], p.EqualityProofs.TokenBlindingFactor[j]}, Challenge: p.Challenge}
-		com, err := ver.RecomputeCommitment(zkp)
-		if err != nil {
-			return nil, err
-		}
-		c.Tokens = append(c.Tokens, com)
+		zkps = append(zkps, &common.SchnorrProof{Statement: v.Tokens[j], Proof: []*mathlib.Zr{p.EqualityProofs.Type, p.EqualityProofs.Value[j], p.EqualityProofs.TokenBlindingFactor[j]}, Challenge: p.Challenge})
 	}
 
 	for j := 0; j < len(v.Tokens); j++ {
@@ -426,19 +501,12 @@ func (v *Verifier) recomputeCommitments(p *RangeProof) (*Commitment, error) {
 		}
 		for i := 0; i < v.Exponent; i++ {
 			pow := v.Curve.NewZrFromInt(int64(math.Pow(float64(v.Base), float64(i))))
-			if p.MembershipProofs[j].Commitments == nil {
+			if p.MembershipProofs[j].Commitments[i] == nil {
 				return nil, errors.Errorf("range proof not well formed")
 			}
 			com.Add(p.MembershipProofs[j].Commitments[i].Mul(pow))
 		}
-
-		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams[:2], Curve: v.Curve}
-		zkp := &common.SchnorrProof{Statement: com, Proof: []*mathlib.Zr{p.EqualityProofs.Value[j], p.EqualityProofs.CommitmentBlindingFactor[j]}, Challenge: p.Challenge}
-		com, err := ver.RecomputeCommitment(zkp)
-		if err != nil {
-			return nil, err
-		}
-		c.CommitmentsToValues = append(c.CommitmentsToValues, com)
+		zkps = append(zkps, &common.SchnorrProof{Statement: com, Proof: []*mathlib.Zr{p.EqualityProofs.Value[j], p.EqualityProofs.CommitmentBlindingFactor[j]}, Challenge: p.Challenge})
 	}
-	return c, nil
+	return zkps, nil
 }
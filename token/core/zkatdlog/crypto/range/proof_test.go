@@ -6,12 +6,17 @@ SPDX-License-Identifier: Apache-2.0
 package rangeproof_test
 
 import (
+	"encoding/json"
+
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	rp "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 )
 
 var _ = Describe("range proof", func() {
@@ -32,6 +37,92 @@ var _ = Describe("range proof", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+	Context("when the membership proofs are generated on a single worker", func() {
+		BeforeEach(func() {
+			prover.Workers = common.NewWorkerPool(1)
+		})
+		It("Succeeds ", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proof).NotTo(BeNil())
+			err = verifier.Verify(proof)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+	Context("when the proofs are verified in a batch", func() {
+		var (
+			c      *math.Curve
+			proofs [][]byte
+		)
+		BeforeEach(func() {
+			c = math.Curves[1]
+			proofs = nil
+			for i := 0; i < 2; i++ {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				proofs = append(proofs, proof)
+			}
+		})
+		It("succeeds", func() {
+			b := batch.NewVerifier(c)
+			Expect(verifier.VerifyBatch(proofs[0], b, "a")).To(Succeed())
+			Expect(verifier.VerifyBatch(proofs[1], b, "b")).To(Succeed())
+			// two membership proofs and the equality proofs, for each range proof
+			Expect(b.Len()).To(Equal(6))
+			Expect(b.Verify()).To(Succeed())
+		})
+		It("pinpoints an invalid membership proof", func() {
+			proof := &rp.RangeProof{}
+			Expect(json.Unmarshal(proofs[1], proof)).To(Succeed())
+			mp := proof.MembershipProofs[0].SignatureProofs[1]
+			mp.Hash = c.ModAdd(mp.Hash, c.NewZrFromInt(1), c.GroupOrder)
+			raw, err := json.Marshal(proof)
+			Expect(err).NotTo(HaveOccurred())
+
+			b := batch.NewVerifier(c)
+			Expect(verifier.VerifyBatch(proofs[0], b, "a")).To(Succeed())
+			Expect(verifier.VerifyBatch(raw, b, "b")).To(Succeed())
+			err = b.Verify()
+			Expect(err).To(HaveOccurred())
+			batchErr, ok := errors.Cause(err).(*batch.Error)
+			Expect(ok).To(BeTrue())
+			Expect(batchErr.Labels).To(Equal([]string{"b: membership proof [0][1]"}))
+			Expect(verifier.Verify(raw)).NotTo(Succeed())
+		})
+		It("pinpoints an invalid equality proof", func() {
+			proof := &rp.RangeProof{}
+			Expect(json.Unmarshal(proofs[0], proof)).To(Succeed())
+			proof.EqualityProofs.Value[0] = c.ModAdd(proof.EqualityProofs.Value[0], c.NewZrFromInt(1), c.GroupOrder)
+			raw, err := json.Marshal(proof)
+			Expect(err).NotTo(HaveOccurred())
+
+			b := batch.NewVerifier(c)
+			Expect(verifier.VerifyBatch(raw, b, "a")).To(Succeed())
+			batchErr, ok := errors.Cause(b.Verify()).(*batch.Error)
+			Expect(ok).To(BeTrue())
+			Expect(batchErr.Labels).To(Equal([]string{"a"}))
+		})
+		It("verifies right away the proofs that do not carry their commitments", func() {
+			proof := &rp.RangeProof{}
+			Expect(json.Unmarshal(proofs[0], proof)).To(Succeed())
+			proof.Commitment = nil
+			for _, mp := range proof.MembershipProofs[0].SignatureProofs {
+				mp.ProofCommitment = nil
+			}
+			raw, err := json.Marshal(proof)
+			Expect(err).NotTo(HaveOccurred())
+
+			b := batch.NewVerifier(c)
+			Expect(verifier.VerifyBatch(raw, b, "a")).To(Succeed())
+			Expect(b.Len()).To(Equal(0))
+			Expect(verifier.Verify(raw)).To(Succeed())
+
+			proof.EqualityProofs.Value[0] = c.ModAdd(proof.EqualityProofs.Value[0], c.NewZrFromInt(1), c.GroupOrder)
+			raw, err = json.Marshal(proof)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verifier.VerifyBatch(raw, batch.NewVerifier(c), "a")).To(MatchError("invalid range proof"))
+		})
+	})
 })
 
 func getRangeProver() *rp.Prover {
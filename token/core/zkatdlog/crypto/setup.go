@@ -19,7 +19,13 @@ import (
 
 const (
 	DLogPublicParameters = "zkatdlog"
-	DefaultPrecision     = uint64(64)
+	// DLogGraphHidingPublicParameters is the label of the public parameters of the graph hiding driver
+	DLogGraphHidingPublicParameters = "zkatdlog-gh"
+	DefaultPrecision                = uint64(64)
+	// Bulletproofs selects the Bulletproofs range proof
+	Bulletproofs = "bulletproofs"
+	// MaxAnonymitySetBitLength bounds the size of the anonymity sets of the graph hiding transfers
+	MaxAnonymitySetBitLength = 10
 )
 
 type PublicParams struct {
@@ -32,8 +38,16 @@ type PublicParams struct {
 	PedGen *math.G1
 	// PedParams contains the public parameters for the Pedersen commitment scheme.
 	PedParams []*math.G1
+	// RangeProofType is the range proof scheme. If empty, the range proof is the
+	// membership proof over the signed values in RangeProofParams.
+	// If Bulletproofs, the range proof is the Bulletproofs range proof over BulletproofParams.
+	RangeProofType string
 	// RangeProofParams contains the public parameters for the range proof scheme.
 	RangeProofParams *RangeProofParams
+	// BulletproofParams contains the public parameters for the Bulletproofs range proof.
+	BulletproofParams *BulletproofParams
+	// GraphHidingParams contains the public parameters of the graph hiding transfers, if the graph is hidden.
+	GraphHidingParams *GraphHidingParams
 	// IdemixCurveID is the pairing-friendly curve used for the idemix scheme.
 	IdemixCurveID math.CurveID
 	// IdemixIssuerPK is the public key of the issuer of the idemix scheme.
@@ -79,6 +93,47 @@ func (rpp *RangeProofParams) Validate() error {
 	return nil
 }
 
+type BulletproofParams struct {
+	// BitLength is the bit length of the token values, the max value of any given token is 2^BitLength - 1
+	BitLength int
+}
+
+func (bpp *BulletproofParams) Validate() error {
+	switch bpp.BitLength {
+	case 8, 16, 32, 64:
+		return nil
+	default:
+		return errors.Errorf("invalid bulletproof parameters: bit length should be 8, 16, 32, or 64, instead it is %d", bpp.BitLength)
+	}
+}
+
+// GraphHidingParams contains the public parameters of the graph hiding transfers.
+// The owner field of a token commits to its owner and to its serial number, as OwnerGen^{H(owner)} * SerialNumberGen^{sn + sk},
+// where sk is a secret key of the owner. Spending the token reveals its nullifier NullifierGen^{sn + sk},
+// but neither the token, hidden among the tokens of an anonymity set, nor its owner.
+type GraphHidingParams struct {
+	OwnerGen        *math.G1
+	SerialNumberGen *math.G1
+	NullifierGen    *math.G1
+	// AnonymitySetBitLength is the bit length of the size of the anonymity sets
+	AnonymitySetBitLength int
+}
+
+func (ghp *GraphHidingParams) Validate() error {
+	if ghp.OwnerGen == nil || ghp.SerialNumberGen == nil || ghp.NullifierGen == nil {
+		return errors.New("invalid graph hiding parameters: nil generators")
+	}
+	if ghp.AnonymitySetBitLength < 1 || ghp.AnonymitySetBitLength > MaxAnonymitySetBitLength {
+		return errors.Errorf("invalid graph hiding parameters: anonymity set bit length should be in [1, %d], instead it is %d", MaxAnonymitySetBitLength, ghp.AnonymitySetBitLength)
+	}
+	return nil
+}
+
+// AnonymitySetSize returns the number of tokens among which a spent token is hidden
+func (ghp *GraphHidingParams) AnonymitySetSize() int {
+	return 1 << ghp.AnonymitySetBitLength
+}
+
 func NewPublicParamsFromBytes(raw []byte, label string) (*PublicParams, error) {
 	pp := &PublicParams{}
 	pp.Label = label
@@ -101,10 +156,13 @@ func (pp *PublicParams) TokenDataHiding() bool {
 }
 
 func (pp *PublicParams) GraphHiding() bool {
-	return false
+	return pp.GraphHidingParams != nil
 }
 
 func (pp *PublicParams) MaxTokenValue() uint64 {
+	if pp.RangeProofType == Bulletproofs {
+		return ^uint64(0) >> (64 - pp.BulletproofParams.BitLength)
+	}
 	return uint64(len(pp.RangeProofParams.SignedValues)) - 1
 }
 
@@ -183,6 +241,23 @@ func (pp *PublicParams) GenerateRangeProofParameters(signer *pssign.Signer, maxV
 	return nil
 }
 
+// GenerateGraphHidingParameters generates the parameters of the graph hiding transfers,
+// whose anonymity sets have 2^anonymitySetBitLength tokens
+func (pp *PublicParams) GenerateGraphHidingParameters(anonymitySetBitLength int) error {
+	curve := math.Curves[pp.Curve]
+	rand, err := curve.Rand()
+	if err != nil {
+		return errors.Errorf("failed to get RNG")
+	}
+	pp.GraphHidingParams = &GraphHidingParams{
+		OwnerGen:              curve.GenG1.Mul(curve.NewRandomZr(rand)),
+		SerialNumberGen:       curve.GenG1.Mul(curve.NewRandomZr(rand)),
+		NullifierGen:          curve.GenG1.Mul(curve.NewRandomZr(rand)),
+		AnonymitySetBitLength: anonymitySetBitLength,
+	}
+	return pp.GraphHidingParams.Validate()
+}
+
 func (pp *PublicParams) AddAuditor(auditor view.Identity) {
 	pp.Auditor = auditor
 }
@@ -235,6 +310,30 @@ func SetupWithCustomLabel(base int64, exponent int, nymPK []byte, label string,
 	return pp, nil
 }
 
+// SetupBulletproofs returns public parameters whose range proof is the Bulletproofs range proof
+// for values of the passed bit length
+func SetupBulletproofs(bitLength int, nymPK []byte, idemixCurveID math.CurveID) (*PublicParams, error) {
+	return SetupBulletproofsWithCustomLabel(bitLength, nymPK, DLogPublicParameters, idemixCurveID)
+}
+
+func SetupBulletproofsWithCustomLabel(bitLength int, nymPK []byte, label string, idemixCurveID math.CurveID) (*PublicParams, error) {
+	pp := &PublicParams{Curve: math.BN254}
+	pp.Label = label
+	err := pp.GeneratePedersenParameters()
+	if err != nil {
+		return nil, err
+	}
+	pp.RangeProofType = Bulletproofs
+	pp.BulletproofParams = &BulletproofParams{BitLength: bitLength}
+	if err := pp.BulletproofParams.Validate(); err != nil {
+		return nil, err
+	}
+	pp.IdemixIssuerPK = nymPK
+	pp.IdemixCurveID = idemixCurveID
+	pp.QuantityPrecision = DefaultPrecision
+	return pp, nil
+}
+
 func (pp *PublicParams) Validate() error {
 	if int(pp.Curve) > len(math.Curves)-1 {
 		return errors.Errorf("invalid public parameters: invalid curveID [%d > %d]", int(pp.Curve), len(math.Curves)-1)
@@ -253,12 +352,36 @@ func (pp *PublicParams) Validate() error {
 			return errors.Errorf("invalid public parameters: nil Pedersen parameter at index %d", i)
 		}
 	}
-	if pp.RangeProofParams == nil {
-		return errors.New("invalid public parameters: nil range proof parameters")
+	switch pp.RangeProofType {
+	case "":
+		if pp.RangeProofParams == nil {
+			return errors.New("invalid public parameters: nil range proof parameters")
+		}
+		err := pp.RangeProofParams.Validate()
+		if err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
+	case Bulletproofs:
+		if pp.BulletproofParams == nil {
+			return errors.New("invalid public parameters: nil bulletproof parameters")
+		}
+		err := pp.BulletproofParams.Validate()
+		if err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
+	default:
+		return errors.Errorf("invalid public parameters: unknown range proof type [%s]", pp.RangeProofType)
+	}
+	if pp.Label == DLogGraphHidingPublicParameters && pp.GraphHidingParams == nil {
+		return errors.New("invalid public parameters: nil graph hiding parameters")
 	}
-	err := pp.RangeProofParams.Validate()
-	if err != nil {
-		return errors.Wrap(err, "invalid public parameters")
+	if pp.GraphHidingParams != nil {
+		if pp.Label != DLogGraphHidingPublicParameters {
+			return errors.Errorf("invalid public parameters: graph hiding parameters are not supported by [%s]", pp.Label)
+		}
+		if err := pp.GraphHidingParams.Validate(); err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
 	}
 	if pp.QuantityPrecision != DefaultPrecision {
 		return errors.Errorf("invalid public parameters: quantity precision should be %d instead it is %d", DefaultPrecision, pp.QuantityPrecision)
@@ -8,6 +8,7 @@ package crypto
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"testing"
 	"time"
 
@@ -50,3 +51,54 @@ func TestSerialization(t *testing.T) {
 	assert.NoError(t, pp.Validate())
 
 }
+
+func TestSetupBulletproofs(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err := SetupBulletproofs(64, raw, math3.BN254)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.Validate())
+	assert.Equal(t, uint64(math.MaxUint64), pp.MaxTokenValue())
+
+	ser, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp2, err := NewPublicParamsFromBytes(ser, DLogPublicParameters)
+	assert.NoError(t, err)
+	assert.Equal(t, pp, pp2)
+
+	pp, err = SetupBulletproofs(16, raw, math3.BN254)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1<<16-1), pp.MaxTokenValue())
+
+	_, err = SetupBulletproofs(12, raw, math3.BN254)
+	assert.EqualError(t, err, "invalid bulletproof parameters: bit length should be 8, 16, 32, or 64, instead it is 12")
+}
+
+func TestSetupGraphHiding(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err := SetupBulletproofsWithCustomLabel(32, raw, DLogGraphHidingPublicParameters, math3.BN254)
+	assert.NoError(t, err)
+	assert.False(t, pp.GraphHiding())
+	assert.EqualError(t, pp.Validate(), "invalid public parameters: nil graph hiding parameters")
+
+	assert.NoError(t, pp.GenerateGraphHidingParameters(4))
+	assert.NoError(t, pp.Validate())
+	assert.True(t, pp.GraphHiding())
+	assert.Equal(t, 16, pp.GraphHidingParams.AnonymitySetSize())
+
+	ser, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp2, err := NewPublicParamsFromBytes(ser, DLogGraphHidingPublicParameters)
+	assert.NoError(t, err)
+	assert.Equal(t, pp, pp2)
+	_, err = NewPublicParamsFromBytes(ser, DLogPublicParameters)
+	assert.EqualError(t, err, "failed parsing public parameters: invalid identifier, expecting [zkatdlog], got [zkatdlog-gh]")
+
+	assert.EqualError(t, pp.GenerateGraphHidingParameters(11), "invalid graph hiding parameters: anonymity set bit length should be in [1, 10], instead it is 11")
+
+	pp, err = SetupBulletproofs(32, raw, math3.BN254)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.GenerateGraphHidingParameters(4))
+	assert.EqualError(t, pp.Validate(), "invalid public parameters: graph hiding parameters are not supported by [zkatdlog]")
+}
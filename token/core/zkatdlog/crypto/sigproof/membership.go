@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	"github.com/pkg/errors"
@@ -31,6 +32,9 @@ type MembershipProof struct {
 	Hash *math.Zr
 	// Pedersen commitment to Value
 	Commitment *math.G1
+	// ProofCommitment is the commitment to the randomness used in the proof.
+	// It lets the proof be verified in a batch.
+	ProofCommitment *MembershipCommitment
 }
 
 // Serialize marshals MembershipProof
@@ -153,6 +157,7 @@ func (p *MembershipProver) Prove() (*MembershipProof, error) {
 	proof.ComBlindingFactor = proofs[1]
 	proof.Hash = proofs[2]
 	proof.SigBlindingFactor = proofs[3]
+	proof.ProofCommitment = commitment
 
 	return proof, nil
 }
@@ -160,23 +165,75 @@ func (p *MembershipProver) Prove() (*MembershipProof, error) {
 // Verify checks the validity of a serialized MembershipProof
 // Verify returns an error if the serialized MembershipProof is invalid
 func (v *MembershipVerifier) Verify(proof *MembershipProof) error {
-	// recompute commitments to randomness used in MembershipProof
-	com, err := v.recomputeCommitments(proof)
+	eq, peq, err := v.Equations(proof)
 	if err != nil {
-		return errors.Wrap(err, "failed to verify membership proof")
+		return err
+	}
+	if eq == nil {
+		return nil
+	}
+	if !eq.Holds(v.Curve) || !peq.Holds(v.Curve) {
+		return errors.New("invalid membership proof")
+	}
+	return nil
+}
+
+// Equations returns the equation and the pairing equation that hold if and only if the passed MembershipProof is valid:
+// the first one checks the proof of knowledge of the opening of the Pedersen commitment,
+// the second one checks the proof of knowledge of the Pointcheval-Sanders signature.
+// The equations can be checked at once with those of other proofs, in a batch.
+// A MembershipProof that does not carry the commitment to its randomness is verified right away,
+// and no equation is returned.
+func (v *MembershipVerifier) Equations(proof *MembershipProof) (*batch.Equation, *batch.PairingEquation, error) {
+	if proof == nil {
+		return nil, nil, errors.New("failed to verify membership proof: nil proof")
+	}
+	com := proof.ProofCommitment
+	sent := com != nil && com.CommitmentToValue != nil && com.Signature != nil
+	if !sent {
+		// recompute commitments to randomness used in MembershipProof
+		var err error
+		com, err = v.recomputeCommitments(proof)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to verify membership proof")
+		}
 	}
 
 	// compute challenge
 	chal, err := v.computeChallenge(proof.Commitment, com, proof.Signature)
 	if err != nil {
-		return errors.Wrap(err, "failed to verify membership proof")
+		return nil, nil, errors.Wrap(err, "failed to verify membership proof")
 	}
 
 	// check if MembershipProof is valid
-	if !chal.Equals(proof.Challenge) {
-		return errors.New("invalid membership proof")
+	if proof.Challenge == nil || !chal.Equals(proof.Challenge) {
+		return nil, nil, errors.New("invalid membership proof")
 	}
-	return nil
+	if !sent {
+		return nil, nil, nil
+	}
+
+	// the challenge is computed on the commitment sent by the prover,
+	// the equations show that it is the commitment to the randomness
+	ver := &common.SchnorrVerifier{PedParams: v.PedersenParams, Curve: v.Curve}
+	zkp := &common.SchnorrProof{Statement: v.CommitmentToValue, Proof: []*math.Zr{proof.Value, proof.ComBlindingFactor}, Challenge: proof.Challenge}
+	eq, err := ver.Equation(zkp, com.CommitmentToValue)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to verify membership proof")
+	}
+	psv := &POKVerifier{P: v.P, Q: v.Q, PK: v.PK, Curve: v.Curve}
+	psp := &POK{
+		Challenge:      proof.Challenge,
+		Signature:      proof.Signature,
+		Messages:       []*math.Zr{proof.Value},
+		Hash:           proof.Hash,
+		BlindingFactor: proof.SigBlindingFactor,
+	}
+	peq, err := psv.pairingEquation(psp, com.Signature)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to verify membership proof")
+	}
+	return eq, peq, nil
 }
 
 // obfuscatedSignature contains an obfuscated Pointcheval-Sanders signature
@@ -7,6 +7,7 @@ package sigproof
 
 import (
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	"github.com/pkg/errors"
@@ -203,6 +204,53 @@ func (v *POKVerifier) recomputeCommitment(p *POK) (*math.Gt, error) {
 	return v.Curve.FExp(com), nil
 }
 
+// pairingEquation returns the pairing equation that holds if and only if the passed commitment
+// is the one recomputed by recomputeCommitment for the passed POK proof:
+// e(Q, P^BlindingFactor * S^{-c}) * e(PK_0, R^c) * \prod_i e(PK_i, R^{m_i}) * e(PK_{n+1}, R^Hash) * com^{-1} = 1
+func (v *POKVerifier) pairingEquation(p *POK, com *math.Gt) (*batch.PairingEquation, error) {
+	if v.Curve == nil {
+		return nil, errors.New("please initialize curve")
+	}
+	if len(v.PK) != len(p.Messages)+2 {
+		return nil, errors.New("length of signature public key does not match size of proof")
+	}
+	for _, pk := range v.PK {
+		if pk == nil {
+			return nil, errors.New("please initialize verifier correctly")
+		}
+	}
+	if v.Q == nil || v.P == nil {
+		return nil, errors.New("please initialize verifier correctly")
+	}
+	if p.Signature == nil || p.Signature.R == nil || p.Signature.S == nil {
+		return nil, errors.New("nil elements")
+	}
+	if p.Challenge == nil || p.BlindingFactor == nil || p.Hash == nil || com == nil {
+		return nil, errors.New("nil elements")
+	}
+	c := v.Curve
+	eq := &batch.PairingEquation{}
+	signature := &batch.Equation{}
+	signature.AddTerm(v.P, p.BlindingFactor)
+	signature.AddTerm(p.Signature.S, c.ModNeg(p.Challenge, c.GroupOrder))
+	eq.AddPairing(v.Q, signature)
+	eq.AddPairing(v.PK[0], &batch.Equation{Bases: []*math.G1{p.Signature.R}, Exponents: []*math.Zr{p.Challenge}})
+	for i := 0; i < len(p.Messages); i++ {
+		if p.Messages[i] == nil {
+			return nil, errors.New("nil elements")
+		}
+		eq.AddPairing(v.PK[i+1], &batch.Equation{Bases: []*math.G1{p.Signature.R}, Exponents: []*math.Zr{p.Messages[i]}})
+	}
+	eq.AddPairing(v.PK[len(p.Messages)+1], &batch.Equation{Bases: []*math.G1{p.Signature.R}, Exponents: []*math.Zr{p.Hash}})
+	inverse, err := c.NewGtFromBytes(com.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid commitment")
+	}
+	inverse.Inverse()
+	eq.Gt = []*math.Gt{inverse}
+	return eq, nil
+}
+
 // HashMessages returns a hash of the passed array of messages
 func HashMessages(m []*math.Zr, c *math.Curve) (*math.Zr, error) {
 	var bytesToHash []byte
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"encoding/binary"
 	"encoding/json"
 
 	math "github.com/IBM/mathlib"
@@ -87,7 +88,7 @@ func GetTokensWithWitness(values []uint64, ttype string, pp []*math.G1, c *math.
 	for i, v := range values {
 		tw[i] = &TokenDataWitness{}
 		tw[i].BlindingFactor = c.NewRandomZr(rand)
-		tw[i].Value = c.NewZrFromInt(int64(v))
+		tw[i].Value = newZrFromUint64(v, c)
 		tw[i].Type = ttype
 	}
 	tokens, err := computeTokens(tw, pp, c)
@@ -97,6 +98,14 @@ func GetTokensWithWitness(values []uint64, ttype string, pp []*math.G1, c *math.
 	return tokens, tw, nil
 }
 
+// newZrFromUint64 returns the passed value as an element of Zr.
+// Values that do not fit an int64 are supported as well.
+func newZrFromUint64(v uint64, c *math.Curve) *math.Zr {
+	raw := make([]byte, len(c.GroupOrder.Bytes()))
+	binary.BigEndian.PutUint64(raw[len(raw)-8:], v)
+	return c.NewZrFromBytes(raw)
+}
+
 // Metadata contains the metadata of a token
 type Metadata struct {
 	// Type is the type of the token
@@ -11,6 +11,7 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
@@ -37,6 +38,8 @@ type Sender struct {
 	// PublicParams refers to the public cryptographic parameters to be used
 	// to produce the TokenRequest
 	PublicParams *crypto.PublicParams
+	// Workers generate the proofs of the transfer, DefaultWorkerPool if nil
+	Workers *common.WorkerPool
 }
 
 // NewSender returns a Sender
@@ -65,7 +68,7 @@ func (s *Sender) GenerateZKTransfer(values []uint64, owners [][]byte) (*Transfer
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "cannot generate transfer")
 	}
-	prover := NewProver(intw, outtw, in, out, s.PublicParams)
+	prover := NewProver(intw, outtw, in, out, s.PublicParams, s.Workers)
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "cannot generate zero-knowledge proof for transfer")
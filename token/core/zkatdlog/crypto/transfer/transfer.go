@@ -11,6 +11,8 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	rangeproof "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
@@ -29,18 +31,21 @@ type Proof struct {
 // Verifier verifies if a TransferAction is valid
 type Verifier struct {
 	WellFormedness   *WellFormednessVerifier
-	RangeCorrectness *rangeproof.Verifier
+	RangeCorrectness rangeproof.RangeVerifier
 }
 
 // Prover produces a proof that a TransferAction is valid
 type Prover struct {
 	WellFormedness   *WellFormednessProver
-	RangeCorrectness *rangeproof.Prover
+	RangeCorrectness rangeproof.RangeProver
+	// Workers generate the proofs, DefaultWorkerPool if nil
+	Workers *common.WorkerPool
 }
 
-// NewProver returns a TransferAction Prover that corresponds to the passed arguments
-func NewProver(inputwitness, outputwitness []*token.TokenDataWitness, inputs, outputs []*math.G1, pp *crypto.PublicParams) *Prover {
-	p := &Prover{}
+// NewProver returns a TransferAction Prover that corresponds to the passed arguments.
+// The proofs are generated on the passed WorkerPool.
+func NewProver(inputwitness, outputwitness []*token.TokenDataWitness, inputs, outputs []*math.G1, pp *crypto.PublicParams, workers *common.WorkerPool) *Prover {
+	p := &Prover{Workers: workers}
 
 	inW := make([]*token.TokenDataWitness, len(inputwitness))
 	outW := make([]*token.TokenDataWitness, len(outputwitness))
@@ -55,7 +60,7 @@ func NewProver(inputwitness, outputwitness []*token.TokenDataWitness, inputs, ou
 	// check if this is an ownership transfer
 	// if so, skip range proof, well-formedness proof is enough
 	if len(inputwitness) != 1 || len(outputwitness) != 1 {
-		p.RangeCorrectness = rangeproof.NewProver(outW, outputs, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.PedParams, pp.RangeProofParams.SignPK, pp.PedGen, pp.RangeProofParams.Q, math.Curves[pp.Curve])
+		p.RangeCorrectness = rangeproof.NewRangeProver(outW, outputs, pp, workers)
 	}
 	wfw := NewWellFormednessWitness(inW, outW)
 	p.WellFormedness = NewWellFormednessProver(wfw, pp.PedParams, inputs, outputs, math.Curves[pp.Curve])
@@ -68,7 +73,7 @@ func NewVerifier(inputs, outputs []*math.G1, pp *crypto.PublicParams) *Verifier
 	// check if this is an ownership transfer
 	// if so, skip range proof, well-formedness proof is enough
 	if len(inputs) != 1 || len(outputs) != 1 {
-		v.RangeCorrectness = rangeproof.NewVerifier(outputs, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.PedParams, pp.RangeProofParams.SignPK, pp.PedGen, pp.RangeProofParams.Q, math.Curves[pp.Curve])
+		v.RangeCorrectness = rangeproof.NewRangeVerifier(outputs, pp)
 	}
 	v.WellFormedness = NewWellFormednessVerifier(pp.PedParams, inputs, outputs, math.Curves[pp.Curve])
 
@@ -87,29 +92,21 @@ func (p *Proof) Deserialize(bytes []byte) error {
 
 // Prove produces a serialized Proof
 func (p *Prover) Prove() ([]byte, error) {
-	var wg sync.WaitGroup
-	wg.Add(1)
-
 	var wfProof, rangeProof []byte
-	var wfErr, rangeErr error
-
-	go func() {
-		defer wg.Done()
-		if p.RangeCorrectness != nil {
-			rangeProof, rangeErr = p.RangeCorrectness.Prove()
+	err := p.Workers.Run(2, func(i int) error {
+		var err error
+		if i == 0 {
+			wfProof, err = p.WellFormedness.Prove()
+			return errors.Wrapf(err, "failed to generate transfer proof")
 		}
-	}()
-
-	wfProof, wfErr = p.WellFormedness.Prove()
-
-	wg.Wait()
-
-	if wfErr != nil {
-		return nil, errors.Wrapf(wfErr, "failed to generate transfer proof")
-	}
-
-	if rangeErr != nil {
-		return nil, errors.Wrapf(rangeErr, "failed to generate range proof for transfer")
+		if p.RangeCorrectness == nil {
+			return nil
+		}
+		rangeProof, err = p.RangeCorrectness.Prove()
+		return errors.Wrapf(err, "failed to generate range proof for transfer")
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	proof := &Proof{
@@ -152,3 +149,30 @@ func (v *Verifier) Verify(proof []byte) error {
 
 	return rangeErr
 }
+
+// VerifyBatch adds the equations of the well-formedness proof and of the range proof of a TransferAction
+// to the passed batch, under the passed label. The proofs that do not reduce to equations are checked right away.
+func (v *Verifier) VerifyBatch(proof []byte, b *batch.Verifier, label string) error {
+	tp := Proof{}
+	err := tp.Deserialize(proof)
+	if err != nil {
+		return errors.Wrap(err, "invalid transfer proof")
+	}
+
+	// verify well-formedness of inputs and outputs
+	equations, err := v.WellFormedness.Equations(tp.WellFormedness)
+	if err != nil {
+		return errors.Wrap(err, "invalid transfer proof")
+	}
+	if len(equations) != 0 {
+		b.Add(label+": well-formedness proof", equations...)
+	}
+	if v.RangeCorrectness == nil {
+		return nil
+	}
+	// verify range proof
+	if bv, ok := v.RangeCorrectness.(rangeproof.BatchRangeVerifier); ok {
+		return bv.VerifyBatch(tp.RangeCorrectness, b, label+": range proof")
+	}
+	return v.RangeCorrectness.Verify(tp.RangeCorrectness)
+}
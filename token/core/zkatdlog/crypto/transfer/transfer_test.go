@@ -82,6 +82,18 @@ var _ = Describe("Transfer", func() {
 				Expect(err.Error()).To(ContainSubstring("can't compute range proof: value of token outside authorized range"))
 			})
 		})
+		Context("the range proof is a Bulletproofs range proof", func() {
+			BeforeEach(func() {
+				prover, verifier = prepareZKTransferWithBulletproofs(64)
+			})
+			It("Succeeds", func() {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proof).NotTo(BeNil())
+				err = verifier.Verify(proof)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 })
 
@@ -107,7 +119,35 @@ func prepareZKTransfer() (*transfer.Prover, *transfer.Verifier) {
 	for i := 0; i < len(outtw); i++ {
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
+	verifier := transfer.NewVerifier(in, out, pp)
+
+	return prover, verifier
+}
+
+func prepareZKTransferWithBulletproofs(bitLength int) (*transfer.Prover, *transfer.Verifier) {
+	pp, err := crypto.SetupBulletproofs(bitLength, nil, math.FP256BN_AMCL)
+	Expect(err).NotTo(HaveOccurred())
+
+	wfw, in, out := prepareInputsForZKTransfer(pp)
+
+	inBF := wfw.GetInBlindingFactors()
+	outBF := wfw.GetOutBlindingFactors()
+
+	inValues := wfw.GetInValues()
+	outValues := wfw.GetOutValues()
+
+	ttype := "ABC"
+	intw := make([]*token.TokenDataWitness, len(inValues))
+	for i := 0; i < len(intw); i++ {
+		intw[i] = &token.TokenDataWitness{BlindingFactor: inBF[i], Value: inValues[i], Type: ttype}
+	}
+
+	outtw := make([]*token.TokenDataWitness, len(outValues))
+	for i := 0; i < len(outtw); i++ {
+		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
+	}
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 
 	return prover, verifier
@@ -136,7 +176,7 @@ func prepareZKTransferWithWrongSum() (*transfer.Prover, *transfer.Verifier) {
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
 
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 
 	return prover, verifier
@@ -165,7 +205,7 @@ func prepareZKTransferWithInvalidRange() (*transfer.Prover, *transfer.Verifier)
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
 
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 	return prover, verifier
 }
@@ -286,7 +326,7 @@ func prepareOwnershipTransfer() (*transfer.Prover, *transfer.Verifier) {
 	for i := 0; i < len(outtw); i++ {
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 
 	return prover, verifier
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	crypto "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
@@ -33,6 +34,9 @@ type WellFormedness struct {
 	Sum *math.Zr
 	// challenge used in proof
 	Challenge *math.Zr
+	// Commitments are the commitments to the randomness used in the proof, for the inputs and their sum,
+	// then for the outputs and their sum. They let the proof be verified in a batch.
+	Commitments []*math.G1
 }
 
 // Serialize marshals WellFormedness
@@ -150,36 +154,77 @@ func (p *WellFormednessProver) Prove() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	wf.Commitments = crypto.GetG1Array(commitments.Inputs, []*math.G1{commitments.InputSum}, commitments.Outputs, []*math.G1{commitments.OutputSum}).Elements
 	return wf.Serialize()
 }
 
 // Verify returns an error when WellFormedness is not a valid
 func (v *WellFormednessVerifier) Verify(p []byte) error {
+	equations, err := v.Equations(p)
+	if err != nil {
+		return err
+	}
+	for _, eq := range equations {
+		if !eq.Holds(v.Curve) {
+			return errors.Errorf("invalid zero-knowledge transfer")
+		}
+	}
+	return nil
+}
+
+// Equations returns the equations that hold if and only if the passed serialized WellFormedness is valid,
+// one for each Schnorr proof it is made of. The equations can be checked at once with those of other proofs, in a batch.
+// A WellFormedness that does not carry the commitments to its randomness is verified right away,
+// and no equation is returned.
+func (v *WellFormednessVerifier) Equations(p []byte) ([]*batch.Equation, error) {
 	// deserialize WellFormedness
 	wf := &WellFormedness{}
 	err := wf.Deserialize(p)
 	if err != nil {
-		return errors.Wrapf(err, "invalid transfer proof: cannot parse proof")
+		return nil, errors.Wrapf(err, "invalid transfer proof: cannot parse proof")
 	}
-
-	// recompute commitments to proof randomness as a function of the parsed proofs and the challenge
-	// for inputs
-	zkps, err := v.parseProof(v.Inputs, wf.InputValues, wf.InputBlindingFactors, wf.Type, wf.Sum)
+	if wf.Challenge == nil {
+		return nil, errors.New("invalid transfer proof: nil challenge")
+	}
+	inZkps, err := v.parseProof(v.Inputs, wf.InputValues, wf.InputBlindingFactors, wf.Type, wf.Sum)
 	if err != nil {
-		return errors.Wrapf(err, "invalid transfer proof")
+		return nil, errors.Wrapf(err, "invalid transfer proof")
+	}
+	outZkps, err := v.parseProof(v.Outputs, wf.OutputValues, wf.OutputBlindingFactors, wf.Type, wf.Sum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid transfer proof")
 	}
 	sv := &crypto.SchnorrVerifier{Curve: v.Curve, PedParams: v.PedParams}
-	inCommitments, err := sv.RecomputeCommitments(zkps, wf.Challenge)
+	if len(wf.Commitments) == 0 {
+		return nil, v.verify(sv, inZkps, outZkps, wf.Challenge)
+	}
+
+	// the challenge is computed on the commitments sent by the prover,
+	// the equations show that they are the commitments to the randomness
+	raw, err := crypto.GetG1Array(wf.Commitments, v.Inputs, v.Outputs).Bytes()
 	if err != nil {
-		return errors.Wrapf(err, "invalid transfer proof")
+		return nil, errors.Wrapf(err, "cannot verify transfer proof")
+	}
+	if !v.Curve.HashToZr(raw).Equals(wf.Challenge) {
+		return nil, errors.Errorf("invalid zero-knowledge transfer")
+	}
+	equations, err := sv.Equations(append(inZkps, outZkps...), wf.Challenge, wf.Commitments)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid transfer proof")
 	}
+	return equations, nil
+}
 
-	// for outputs
-	zkps, err = v.parseProof(v.Outputs, wf.OutputValues, wf.OutputBlindingFactors, wf.Type, wf.Sum)
+// verify recomputes the commitments to the randomness as a function of the parsed proofs and the challenge,
+// and checks that they hash to the challenge
+func (v *WellFormednessVerifier) verify(sv *crypto.SchnorrVerifier, inZkps, outZkps []*crypto.SchnorrProof, challenge *math.Zr) error {
+	// for inputs
+	inCommitments, err := sv.RecomputeCommitments(inZkps, challenge)
 	if err != nil {
 		return errors.Wrapf(err, "invalid transfer proof")
 	}
-	outCommitments, err := sv.RecomputeCommitments(zkps, wf.Challenge)
+	// for outputs
+	outCommitments, err := sv.RecomputeCommitments(outZkps, challenge)
 	if err != nil {
 		return errors.Wrapf(err, "invalid transfer proof")
 	}
@@ -189,8 +234,9 @@ func (v *WellFormednessVerifier) Verify(p []byte) error {
 	if err != nil {
 		return errors.Wrapf(err, "cannot verify transfer proof")
 	}
+
 	// check if proof is valid
-	if !v.Curve.HashToZr(raw).Equals(wf.Challenge) {
+	if !v.Curve.HashToZr(raw).Equals(challenge) {
 		return errors.Errorf("invalid zero-knowledge transfer")
 	}
 	return nil
@@ -8,12 +8,16 @@ package validator
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 
+	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -33,6 +37,10 @@ func New(pp *crypto.PublicParams, deserializer driver.Deserializer, extraValidat
 		TransferSignatureValidate,
 		TransferZKProofValidate,
 		TransferHTLCValidate,
+		TransferEscrowValidate,
+		TransferMultisigValidate,
+		TransferTimeLockValidate,
+		TransferFractionalValidate,
 	}
 	transferValidators = append(transferValidators, extraValidators...)
 	return &Validator{
@@ -43,13 +51,83 @@ func New(pp *crypto.PublicParams, deserializer driver.Deserializer, extraValidat
 }
 
 func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
+	tr, backend, err := v.parseTokenRequest(getState, binding, raw)
+	if err != nil {
+		return nil, err
+	}
+	return v.VerifyTokenRequest(backend, backend, binding, tr)
+}
+
+// VerifyTokenRequestsFromRaw verifies the passed marshalled token requests against the passed ledger.
+// The well-formedness and range proofs of all the requests are verified at once, in a batch.
+// It returns, for each request, either its actions or the reason why it is invalid.
+func (v *Validator) VerifyTokenRequestsFromRaw(getState driver.GetStateFnc, requests []*driver.BatchedTokenRequest) ([][]interface{}, []error) {
+	actions := make([][]interface{}, len(requests))
+	errs := make([]error, len(requests))
+	b := batch.NewVerifier(math.Curves[v.pp.Curve])
+	for i, request := range requests {
+		tr, backend, err := v.parseTokenRequest(getState, request.Anchor, request.Raw)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		rb := batch.NewVerifier(math.Curves[v.pp.Curve])
+		actions[i], errs[i] = v.verifyTokenRequest(backend, backend, request.Anchor, tr, rb)
+		if errs[i] != nil {
+			continue
+		}
+		b.Append(requestLabel(i), rb)
+	}
+
+	err := b.Verify()
+	if err == nil {
+		return actions, errs
+	}
+	batchErr, ok := errors.Cause(err).(*batch.Error)
+	if !ok {
+		// fail all the requests whose proofs were in the batch
+		for i := range requests {
+			if errs[i] == nil {
+				actions[i], errs[i] = nil, errors.Wrapf(err, "failed to verify proofs [%s]", requests[i].Anchor)
+			}
+		}
+		return actions, errs
+	}
+	for i := range requests {
+		var invalid []string
+		for _, label := range batchErr.Labels {
+			if strings.HasPrefix(label, requestLabel(i)) {
+				invalid = append(invalid, strings.TrimPrefix(label, requestLabel(i)))
+			}
+		}
+		if len(invalid) != 0 {
+			actions[i], errs[i] = nil, errors.Wrapf(&batch.Error{Labels: invalid}, "failed to verify proofs [%s]", requests[i].Anchor)
+		}
+	}
+	return actions, errs
+}
+
+func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) ([]interface{}, error) {
+	b := batch.NewVerifier(math.Curves[v.pp.Curve])
+	actions, err := v.verifyTokenRequest(ledger, signatureProvider, binding, tr, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Verify(); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify proofs [%s]", binding)
+	}
+	return actions, nil
+}
+
+// parseTokenRequest unmarshals the passed token request, and returns it with the backend to verify its signatures against
+func (v *Validator) parseTokenRequest(getState driver.GetStateFnc, binding string, raw []byte) (*driver.TokenRequest, *common.Backend, error) {
 	if len(raw) == 0 {
-		return nil, errors.New("empty token request")
+		return nil, nil, errors.New("empty token request")
 	}
 	tr := &driver.TokenRequest{}
 	err := tr.FromBytes(raw)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal token request")
+		return nil, nil, errors.Wrap(err, "failed to unmarshal token request")
 	}
 
 	// Prepare message expected to be signed
@@ -59,7 +137,7 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 	req.Issues = tr.Issues
 	bytes, err := req.Bytes()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal signed token request")
+		return nil, nil, errors.Wrap(err, "failed to marshal signed token request")
 	}
 
 	logger.Debugf("cc tx-id [%s][%s]", hash.Hashable(bytes).String(), binding)
@@ -72,11 +150,11 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 		signatures = tr.Signatures
 	}
 
-	backend := common.NewBackend(getState, signed, signatures)
-	return v.VerifyTokenRequest(backend, backend, binding, tr)
+	return tr, common.NewBackend(getState, signed, signatures), nil
 }
 
-func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) ([]interface{}, error) {
+// verifyTokenRequest verifies the passed token request, but for the proofs it adds to the passed batch
+func (v *Validator) verifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest, b *batch.Verifier) ([]interface{}, error) {
 	if err := v.verifyAuditorSignature(signatureProvider); err != nil {
 		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
 	}
@@ -88,11 +166,11 @@ func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider d
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve transfer actions [%s]", binding)
 	}
-	err = v.verifyIssues(ia, signatureProvider)
+	err = v.verifyIssues(ia, signatureProvider, b)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", binding)
 	}
-	err = v.verifyTransfers(ledger, ta, signatureProvider)
+	err = v.verifyTransfers(ledger, ta, signatureProvider, b)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
 	}
@@ -107,6 +185,10 @@ func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider d
 	return actions, nil
 }
 
+func requestLabel(i int) string {
+	return fmt.Sprintf("request [%d] ", i)
+}
+
 func (v *Validator) unmarshalTransferActions(raw [][]byte) ([]driver.TransferAction, error) {
 	res := make([]driver.TransferAction, len(raw))
 	for i := 0; i < len(raw); i++ {
@@ -144,11 +226,11 @@ func (v *Validator) verifyAuditorSignature(signatureProvider driver.SignaturePro
 	return nil
 }
 
-func (v *Validator) verifyIssues(issues []driver.IssueAction, signatureProvider driver.SignatureProvider) error {
-	for _, issue := range issues {
+func (v *Validator) verifyIssues(issues []driver.IssueAction, signatureProvider driver.SignatureProvider, b *batch.Verifier) error {
+	for i, issue := range issues {
 		a := issue.(*issue2.IssueAction)
 
-		if err := v.verifyIssue(a); err != nil {
+		if err := v.verifyIssue(a, b, fmt.Sprintf("issue action [%d]", i)); err != nil {
 			return errors.Wrapf(err, "failed to verify issue action")
 		}
 
@@ -178,7 +260,7 @@ func (v *Validator) verifyIssues(issues []driver.IssueAction, signatureProvider
 	return nil
 }
 
-func (v *Validator) verifyIssue(issue driver.IssueAction) error {
+func (v *Validator) verifyIssue(issue driver.IssueAction, b *batch.Verifier, label string) error {
 	action := issue.(*issue2.IssueAction)
 	coms, err := action.GetCommitments()
 	if err != nil {
@@ -187,21 +269,21 @@ func (v *Validator) verifyIssue(issue driver.IssueAction) error {
 	return issue2.NewVerifier(
 		coms,
 		action.IsAnonymous(),
-		v.pp).Verify(action.GetProof())
+		v.pp).VerifyBatch(action.GetProof(), b, label)
 }
 
-func (v *Validator) verifyTransfers(ledger driver.Ledger, transferActions []driver.TransferAction, signatureProvider driver.SignatureProvider) error {
+func (v *Validator) verifyTransfers(ledger driver.Ledger, transferActions []driver.TransferAction, signatureProvider driver.SignatureProvider, b *batch.Verifier) error {
 	logger.Debugf("check sender start...")
 	defer logger.Debugf("check sender finished.")
-	for _, t := range transferActions {
-		if err := v.verifyTransfer(t, ledger, signatureProvider); err != nil {
+	for i, t := range transferActions {
+		if err := v.verifyTransfer(t, ledger, signatureProvider, b, i); err != nil {
 			return errors.Wrapf(err, "failed to verify transfer action")
 		}
 	}
 	return nil
 }
 
-func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledger, signatureProvider driver.SignatureProvider) error {
+func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledger, signatureProvider driver.SignatureProvider, b *batch.Verifier, index int) error {
 	action := tr.(*transfer.TransferAction)
 	context := &Context{
 		PP:                v.pp,
@@ -210,6 +292,8 @@ func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledge
 		Ledger:            ledger,
 		SignatureProvider: signatureProvider,
 		MetadataCounter:   map[string]int{},
+		Batch:             b,
+		Index:             index,
 	}
 	for _, v := range v.transferValidators {
 		if err := v(context); err != nil {
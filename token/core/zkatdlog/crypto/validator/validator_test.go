@@ -10,6 +10,7 @@ import (
 	"encoding/asn1"
 	"encoding/json"
 	"io/ioutil"
+	"strconv"
 	"time"
 
 	math "github.com/IBM/mathlib"
@@ -24,8 +25,11 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	escrow2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/escrow"
+	multisig2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/multisig"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym"
@@ -35,6 +39,8 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator/mock"
 	zkatdlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
 )
 
 var fakeldger *mock.Ledger
@@ -217,6 +223,120 @@ var _ = Describe("validator", func() {
 				Expect(len(actions)).To(Equal(1))
 			})
 		})
+		Context("validator is called with a transfer of tokens owned by a multisig identity", func() {
+			var raw []byte
+			prepare := func(signing ...int) {
+				tr, inputs := prepareMultisigTransferRequest(pp, auditor, signing...)
+				for i := 0; i < 4; i++ {
+					input, err := inputs[i%2].Serialize()
+					Expect(err).NotTo(HaveOccurred())
+					fakeldger.GetStateReturnsOnCall(i, input, nil)
+				}
+				fakeldger.GetStateReturnsOnCall(4, nil, nil)
+				fakeldger.GetStateReturnsOnCall(5, nil, nil)
+
+				var err error
+				raw, err = asn1.Marshal(*tr)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			When("all the co-owners sign", func() {
+				BeforeEach(func() {
+					prepare(0, 1)
+				})
+				It("succeeds", func() {
+					actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(len(actions)).To(Equal(1))
+				})
+			})
+			When("the co-owners that sign are less than the threshold", func() {
+				BeforeEach(func() {
+					prepare(1)
+				})
+				It("fails", func() {
+					_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("multisig token spent by [1] co-owners, at least [2] expected"))
+				})
+			})
+		})
+		Context("validator is called with the release of an escrow-token", func() {
+			var raw []byte
+			// prepare releases the escrow-token to the party at the passed index, 3 is not a party.
+			// The signature of each role is produced by the party at the passed index, -1 for no signature.
+			prepare := func(recipient int, buyer, seller, arbitrator int) {
+				tr, input := prepareEscrowReleaseRequest(pp, auditor, recipient, []int{buyer, seller, arbitrator})
+				in, err := input.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				fakeldger.GetStateReturnsOnCall(0, in, nil)
+				fakeldger.GetStateReturnsOnCall(1, in, nil)
+				fakeldger.GetStateReturnsOnCall(2, nil, nil)
+				fakeldger.GetStateReturnsOnCall(3, nil, nil)
+
+				raw, err = asn1.Marshal(*tr)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			When("the seller and the arbitrator release it to the seller", func() {
+				BeforeEach(func() {
+					prepare(1, -1, 1, 2)
+				})
+				It("succeeds", func() {
+					actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(len(actions)).To(Equal(1))
+				})
+			})
+			When("the buyer and the arbitrator refund the buyer", func() {
+				BeforeEach(func() {
+					prepare(0, 0, -1, 2)
+				})
+				It("succeeds", func() {
+					actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(len(actions)).To(Equal(1))
+				})
+			})
+			When("the seller signs for the arbitrator too", func() {
+				BeforeEach(func() {
+					prepare(1, -1, 1, 1)
+				})
+				It("fails", func() {
+					_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed verifying the signature of the arbitrator"))
+				})
+			})
+			When("a single party signs", func() {
+				BeforeEach(func() {
+					prepare(1, -1, 1, -1)
+				})
+				It("fails", func() {
+					_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("escrow-token released by [1] parties, at least [2] expected"))
+				})
+			})
+			When("it is released to the arbitrator", func() {
+				BeforeEach(func() {
+					prepare(2, -1, 1, 2)
+				})
+				It("fails", func() {
+					_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("owner of output token does not correspond to the buyer or the seller in escrow script"))
+				})
+			})
+			When("it is released to an identity that is not a party", func() {
+				BeforeEach(func() {
+					prepare(3, 0, 1, -1)
+				})
+				It("fails", func() {
+					_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("owner of output token does not correspond to the buyer or the seller in escrow script"))
+				})
+			})
+		})
 		Context("enginve is called correctly with atomic swap", func() {
 			var (
 				err error
@@ -278,6 +398,135 @@ var _ = Describe("validator", func() {
 	})
 })
 
+var _ = Describe("batch validator", func() {
+	var (
+		engine  *enginedlog.Validator
+		pp      *crypto.PublicParams
+		issuers []*nonanonym.Issuer
+		auditor *audit.Auditor
+		raws    [][]byte
+	)
+	BeforeEach(func() {
+		fakeldger = &mock.Ledger{}
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.SetupBulletproofs(64, ipk, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+
+		asigner, _ := prepareECDSASigner()
+		des, err := idemix2.NewDeserializer(pp.IdemixIssuerPK)
+		Expect(err).NotTo(HaveOccurred())
+		auditor = audit.NewAuditor(&deserializer{idemix: des}, pp.PedParams, pp.IdemixIssuerPK, asigner, math.Curves[pp.Curve])
+		araw, err := asigner.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		pp.Auditor = araw
+
+		deserializer, err := zkatdlog.NewDeserializer(pp)
+		Expect(err).NotTo(HaveOccurred())
+		engine = enginedlog.New(pp, deserializer)
+
+		raws, issuers = nil, nil
+		for i := 0; i < 3; i++ {
+			issuer, ir, _ := prepareNonAnonymousIssueRequest(pp, auditor)
+			issuers = append(issuers, issuer)
+			raw, err := asn1.Marshal(*ir)
+			Expect(err).NotTo(HaveOccurred())
+			raws = append(raws, raw)
+		}
+	})
+	requests := func() []*driver.BatchedTokenRequest {
+		var res []*driver.BatchedTokenRequest
+		for _, raw := range raws {
+			res = append(res, &driver.BatchedTokenRequest{Anchor: "1", Raw: raw})
+		}
+		return res
+	}
+	Context("when all the requests are valid", func() {
+		It("succeeds", func() {
+			actions, errs := engine.VerifyTokenRequestsFromRaw(fakeldger.GetStateStub, requests())
+			Expect(errs).To(Equal([]error{nil, nil, nil}))
+			for _, a := range actions {
+				Expect(len(a)).To(Equal(1))
+			}
+		})
+	})
+	// tamper changes the proof of the issue action of the i-th request, and signs and endorses the request again
+	tamper := func(i int, change func(proof *issue2.Proof)) {
+		ir := &driver.TokenRequest{}
+		_, err := asn1.Unmarshal(raws[i], ir)
+		Expect(err).NotTo(HaveOccurred())
+		action := &issue2.IssueAction{}
+		Expect(action.Deserialize(ir.Issues[0])).To(Succeed())
+		proof := &issue2.Proof{}
+		Expect(proof.Deserialize(action.Proof)).To(Succeed())
+		change(proof)
+		action.Proof, err = proof.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		ir.Issues[0], err = action.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+
+		signed, err := asn1.Marshal(driver.TokenRequest{Issues: ir.Issues})
+		Expect(err).NotTo(HaveOccurred())
+		sig, err := issuers[i].SignTokenActions(signed, "1")
+		Expect(err).NotTo(HaveOccurred())
+		ir.Signatures = [][]byte{sig}
+		sigma, err := auditor.Endorse(&driver.TokenRequest{Issues: ir.Issues}, "1")
+		Expect(err).NotTo(HaveOccurred())
+		ir.AuditorSignatures = [][]byte{sigma}
+		raws[i], err = asn1.Marshal(*ir)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Context("when the range proof of a request is invalid", func() {
+		BeforeEach(func() {
+			tamper(1, func(proof *issue2.Proof) {
+				rp := &bulletproof.RangeProof{}
+				Expect(json.Unmarshal(proof.RangeCorrectness, rp)).To(Succeed())
+				c := math.Curves[pp.Curve]
+				rp.THat = c.ModAdd(rp.THat, c.NewZrFromInt(1), c.GroupOrder)
+				var err error
+				proof.RangeCorrectness, err = json.Marshal(rp)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+		It("pinpoints the invalid request", func() {
+			actions, errs := engine.VerifyTokenRequestsFromRaw(fakeldger.GetStateStub, requests())
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[2]).NotTo(HaveOccurred())
+			Expect(errs[1]).To(HaveOccurred())
+			Expect(errs[1].Error()).To(Equal("failed to verify proofs [1]: invalid proofs [issue action [0]: range proof]"))
+			Expect(actions[1]).To(BeNil())
+			Expect(len(actions[0])).To(Equal(1))
+
+			_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raws[1])
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("failed to verify proofs [1]: invalid proofs [issue action [0]: range proof]"))
+		})
+	})
+	Context("when the well-formedness proof of a request is invalid", func() {
+		BeforeEach(func() {
+			tamper(2, func(proof *issue2.Proof) {
+				wf := &issue2.WellFormedness{}
+				Expect(wf.Deserialize(proof.WellFormedness)).To(Succeed())
+				c := math.Curves[pp.Curve]
+				// the challenge does not depend on the responses, only the batch detects the change
+				wf.Values[0] = c.ModAdd(wf.Values[0], c.NewZrFromInt(1), c.GroupOrder)
+				var err error
+				proof.WellFormedness, err = wf.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+		It("pinpoints the invalid request", func() {
+			actions, errs := engine.VerifyTokenRequestsFromRaw(fakeldger.GetStateStub, requests())
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[1]).NotTo(HaveOccurred())
+			Expect(errs[2]).To(HaveOccurred())
+			Expect(errs[2].Error()).To(Equal("failed to verify proofs [1]: invalid proofs [issue action [0]: well-formedness proof]"))
+			Expect(actions[2]).To(BeNil())
+			Expect(len(actions[1])).To(Equal(1))
+		})
+	})
+})
+
 func prepareECDSASigner() (*ecdsa.ECDSASigner, *ecdsa.ECDSAVerifier) {
 	signer, err := ecdsa.NewECDSASigner()
 	Expect(err).NotTo(HaveOccurred())
@@ -300,7 +549,9 @@ func prepareRedeemRequest(pp *crypto.PublicParams, auditor *audit.Auditor) (*tra
 	owners := make([][]byte, 2)
 	owners[0] = id
 
-	return prepareTransfer(pp, signer, auditor, auditInfo, id, owners)
+	raw, err := auditInfo.Bytes()
+	Expect(err).NotTo(HaveOccurred())
+	return prepareTransfer(pp, signer, auditor, raw, id, owners)
 }
 
 func prepareTransferRequest(pp *crypto.PublicParams, auditor *audit.Auditor) (*transfer.Sender, *driver.TokenRequest, *driver.TokenRequestMetadata, []*tokn.Token) {
@@ -309,7 +560,109 @@ func prepareTransferRequest(pp *crypto.PublicParams, auditor *audit.Auditor) (*t
 	owners[0] = id
 	owners[1] = id
 
-	return prepareTransfer(pp, signer, auditor, auditInfo, id, owners)
+	raw, err := auditInfo.Bytes()
+	Expect(err).NotTo(HaveOccurred())
+	return prepareTransfer(pp, signer, auditor, raw, id, owners)
+}
+
+// prepareMultisigTransferRequest prepares the transfer of tokens owned by a 2-out-of-2 multisig identity.
+// Only the co-owners at the passed indexes sign.
+func prepareMultisigTransferRequest(pp *crypto.PublicParams, auditor *audit.Auditor, signing ...int) (*driver.TokenRequest, []*tokn.Token) {
+	m := &multisig.MultiIdentity{Threshold: 2}
+	var signers []driver.Signer
+	auditInfo := &multisig2.AuditInfo{}
+	for i := 0; i < 2; i++ {
+		coOwner, coOwnerAuditInfo, signer := getIdemixInfo("./testdata/idemix")
+		m.Identities = append(m.Identities, coOwner)
+		signers = append(signers, signer)
+		raw, err := coOwnerAuditInfo.Bytes()
+		Expect(err).NotTo(HaveOccurred())
+		auditInfo.AuditInfos = append(auditInfo.AuditInfos, raw)
+	}
+	id, err := multisig.Wrap(m.Threshold, m.Identities...)
+	Expect(err).NotTo(HaveOccurred())
+	raw, err := auditInfo.Marshal()
+	Expect(err).NotTo(HaveOccurred())
+
+	signer := &multisigSigner{signers: make([]driver.Signer, len(signers))}
+	for _, i := range signing {
+		signer.signers[i] = signers[i]
+	}
+	_, tr, _, inputs := prepareTransfer(pp, signer, auditor, raw, id, [][]byte{id, id})
+	return tr, inputs
+}
+
+// multisigSigner signs for a multisig identity with the signers of the co-owners that are set
+type multisigSigner struct {
+	signers []driver.Signer
+}
+
+func (s *multisigSigner) Sign(message []byte) ([]byte, error) {
+	sig := &multisig.Signature{Signatures: make([][]byte, len(s.signers))}
+	for i, signer := range s.signers {
+		if signer == nil {
+			continue
+		}
+		sigma, err := signer.Sign(message)
+		if err != nil {
+			return nil, err
+		}
+		sig.Signatures[i] = sigma
+	}
+	return json.Marshal(sig)
+}
+
+// prepareEscrowReleaseRequest prepares the release of an escrow-token to the party at the passed index,
+// among the buyer, the seller, the arbitrator, and an identity that is not a party.
+// The signature of each role of the script is produced by the party at the passed index, if not negative.
+func prepareEscrowReleaseRequest(pp *crypto.PublicParams, auditor *audit.Auditor, recipient int, signing []int) (*driver.TokenRequest, *tokn.Token) {
+	var parties []view.Identity
+	var auditInfos [][]byte
+	var signers []driver.Signer
+	for i := 0; i < 4; i++ {
+		party, partyAuditInfo, signer := getIdemixInfo("./testdata/idemix")
+		raw, err := partyAuditInfo.Bytes()
+		Expect(err).NotTo(HaveOccurred())
+		parties = append(parties, party)
+		auditInfos = append(auditInfos, raw)
+		signers = append(signers, signer)
+	}
+	rawScript, err := json.Marshal(&escrow.Script{Buyer: parties[0], Seller: parties[1], Arbitrator: parties[2]})
+	Expect(err).NotTo(HaveOccurred())
+	id, err := identity.MarshallRawOwner(&identity.RawOwner{Type: escrow.ScriptType, Identity: rawScript})
+	Expect(err).NotTo(HaveOccurred())
+	scriptInfo := &escrow2.ScriptInfo{Buyer: auditInfos[0], Seller: auditInfos[1], Arbitrator: auditInfos[2]}
+	raw, err := scriptInfo.Marshal()
+	Expect(err).NotTo(HaveOccurred())
+
+	signer := &escrowSigner{signers: make([]driver.Signer, 3)}
+	for role, index := range signing {
+		if index >= 0 {
+			signer.signers[role] = signers[index]
+		}
+	}
+	_, tr, _, inputs := prepareTransferOf(pp, signer, auditor, raw, auditInfos[recipient], id, []int64{100}, [][]byte{parties[recipient]}, []uint64{100})
+	return tr, inputs[0]
+}
+
+// escrowSigner signs for an escrow script with the signers of the buyer, the seller, and the arbitrator that are set
+type escrowSigner struct {
+	signers []driver.Signer
+}
+
+func (s *escrowSigner) Sign(message []byte) ([]byte, error) {
+	sigmas := make([][]byte, len(s.signers))
+	for i, signer := range s.signers {
+		if signer == nil {
+			continue
+		}
+		sigma, err := signer.Sign(message)
+		if err != nil {
+			return nil, err
+		}
+		sigmas[i] = sigma
+	}
+	return json.Marshal(&escrow.Signature{Buyer: sigmas[0], Seller: sigmas[1], Arbitrator: sigmas[2]})
 }
 
 func getIssuers(N, index int, pk *math.G1, pp []*math.G1, curve *math.Curve) []*math.G1 {
@@ -479,39 +832,36 @@ func prepareIssue(auditor *audit.Auditor, issuer issue2.Issuer) (*driver.TokenRe
 	return ir, issueMetadata
 }
 
-func prepareTransfer(pp *crypto.PublicParams, signer driver.SigningIdentity, auditor *audit.Auditor, auditInfo *idemix2.AuditInfo, id []byte, owners [][]byte) (*transfer.Sender, *driver.TokenRequest, *driver.TokenRequestMetadata, []*tokn.Token) {
+func prepareTransfer(pp *crypto.PublicParams, signer driver.Signer, auditor *audit.Auditor, auditInfo []byte, id []byte, owners [][]byte) (*transfer.Sender, *driver.TokenRequest, *driver.TokenRequestMetadata, []*tokn.Token) {
+	return prepareTransferOf(pp, signer, auditor, auditInfo, auditInfo, id, []int64{70, 30}, owners, []uint64{65, 35})
+}
 
-	signers := make([]driver.Signer, 2)
-	signers[0] = signer
-	signers[1] = signer
+// prepareTransferOf prepares the transfer of tokens of the passed values, owned by the passed id, to tokens of the passed values,
+// owned by the passed owners
+func prepareTransferOf(pp *crypto.PublicParams, signer driver.Signer, auditor *audit.Auditor, senderAuditInfo, receiverAuditInfo []byte, id []byte, inValues []int64, owners [][]byte, outvalues []uint64) (*transfer.Sender, *driver.TokenRequest, *driver.TokenRequestMetadata, []*tokn.Token) {
 	c := math.Curves[pp.Curve]
-
-	invalues := make([]*math.Zr, 2)
-	invalues[0] = c.NewZrFromInt(70)
-	invalues[1] = c.NewZrFromInt(30)
-
-	inBF := make([]*math.Zr, 2)
 	rand, err := c.Rand()
 	Expect(err).NotTo(HaveOccurred())
-	for i := 0; i < 2; i++ {
+
+	n := len(inValues)
+	signers := make([]driver.Signer, n)
+	invalues := make([]*math.Zr, n)
+	inBF := make([]*math.Zr, n)
+	ids := make([]string, n)
+	for i, v := range inValues {
+		signers[i] = signer
+		invalues[i] = c.NewZrFromInt(v)
 		inBF[i] = c.NewRandomZr(rand)
+		ids[i] = strconv.Itoa(i)
 	}
-	outvalues := make([]uint64, 2)
-	outvalues[0] = 65
-	outvalues[1] = 35
-
-	ids := make([]string, 2)
-	ids[0] = "0"
-	ids[1] = "1"
 
 	inputs := prepareTokens(invalues, inBF, "ABC", pp.PedParams, c)
-	tokens := make([]*tokn.Token, 2)
-	tokens[0] = &tokn.Token{Data: inputs[0], Owner: id}
-	tokens[1] = &tokn.Token{Data: inputs[1], Owner: id}
-
-	inputInf := make([]*tokn.Metadata, 2)
-	inputInf[0] = &tokn.Metadata{Type: "ABC", Value: invalues[0], BlindingFactor: inBF[0]}
-	inputInf[1] = &tokn.Metadata{Type: "ABC", Value: invalues[1], BlindingFactor: inBF[1]}
+	tokens := make([]*tokn.Token, n)
+	inputInf := make([]*tokn.Metadata, n)
+	for i := range inputs {
+		tokens[i] = &tokn.Token{Data: inputs[i], Owner: id}
+		inputInf[i] = &tokn.Metadata{Type: "ABC", Value: invalues[i], BlindingFactor: inBF[i]}
+	}
 	sender, err := transfer.NewSender(signers, tokens, ids, inputInf, pp)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -533,8 +883,7 @@ func prepareTransfer(pp *crypto.PublicParams, signer driver.SigningIdentity, aud
 	metadata := driver.TransferMetadata{}
 	metadata.SenderAuditInfos = make([][]byte, len(transfer.Inputs))
 	for i := 0; i < len(transfer.Inputs); i++ {
-		metadata.SenderAuditInfos[i], err = auditInfo.Bytes()
-		Expect(err).NotTo(HaveOccurred())
+		metadata.SenderAuditInfos[i] = senderAuditInfo
 	}
 
 	metadata.OutputsMetadata = marshalledInfo
@@ -543,8 +892,7 @@ func prepareTransfer(pp *crypto.PublicParams, signer driver.SigningIdentity, aud
 	for i := 0; i < len(transfer.OutputTokens); i++ {
 		metadata.Outputs[i], err = json.Marshal(transfer.OutputTokens[i].Data)
 		Expect(err).NotTo(HaveOccurred())
-		metadata.ReceiverAuditInfos[i], err = auditInfo.Bytes()
-		Expect(err).NotTo(HaveOccurred())
+		metadata.ReceiverAuditInfos[i] = receiverAuditInfo
 	}
 
 	tokns := make([][]*tokn.Token, 1)
@@ -8,16 +8,23 @@ package validator
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	escrow2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/escrow"
 	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/multisig"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/timelock"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/pkg/errors"
 )
@@ -31,6 +38,10 @@ type Context struct {
 	Action            *transfer.TransferAction
 	Ledger            driver.Ledger
 	MetadataCounter   map[string]int
+	// Batch collects the proofs whose verification is deferred to a batch, if not nil
+	Batch *batch.Verifier
+	// Index is the index of the action in the token request
+	Index int
 }
 
 func (c *Context) CountMetadataKey(key string) {
@@ -87,10 +98,14 @@ func TransferZKProofValidate(ctx *Context) error {
 		in[i] = tok.GetCommitment()
 	}
 
-	if err := transfer.NewVerifier(
+	verifier := transfer.NewVerifier(
 		in,
 		ctx.Action.GetOutputCommitments(),
-		ctx.PP).Verify(ctx.Action.GetProof()); err != nil {
+		ctx.PP)
+	if ctx.Batch != nil {
+		return verifier.VerifyBatch(ctx.Action.GetProof(), ctx.Batch, fmt.Sprintf("transfer action [%d]", ctx.Index))
+	}
+	if err := verifier.Verify(ctx.Action.GetProof()); err != nil {
 		return err
 	}
 
@@ -161,3 +176,111 @@ func TransferHTLCValidate(ctx *Context) error {
 	}
 	return nil
 }
+
+// TransferEscrowValidate checks the validity of the escrow scripts, if any.
+// The signatures of the parties releasing an escrow-token are checked by TransferSignatureValidate.
+func TransferEscrowValidate(ctx *Context) error {
+	for _, in := range ctx.InputTokens {
+		owner, err := identity.UnmarshallRawOwner(in.Owner)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal owner of input token")
+		}
+		if owner.Type == escrow.ScriptType {
+			if len(ctx.InputTokens) != 1 || len(ctx.Action.GetOutputs()) != 1 {
+				return errors.Errorf("invalid transfer action: an escrow script only transfers the ownership of a token")
+			}
+
+			out := ctx.Action.GetOutputs()[0].(*token.Token)
+			if out.IsRedeem() {
+				return errors.New("invalid transfer action: the output corresponding to an escrow release should not be a redeem")
+			}
+
+			// check that owner field in output is correct
+			if _, err := escrow2.VerifyOwner(in.Owner, out.Owner); err != nil {
+				return errors.Wrap(err, "failed to verify transfer from escrow script")
+			}
+		}
+	}
+
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*token.Token)
+		if !ok {
+			return errors.Errorf("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		owner, err := identity.UnmarshallRawOwner(out.Owner)
+		if err != nil {
+			return err
+		}
+		if owner.Type == escrow.ScriptType {
+			if _, err := escrow2.VerifyScript(out.Owner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TransferMultisigValidate checks the validity of the multisig identities owning the outputs, if any.
+// The signatures of the co-owners spending a multisig token are checked by TransferSignatureValidate.
+func TransferMultisigValidate(ctx *Context) error {
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*token.Token)
+		if !ok {
+			return errors.Errorf("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if err := multisig.VerifyOwner(out.Owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferTimeLockValidate checks that the time-locked inputs, if any, are unlocked,
+// and that the time-lock scripts owning the outputs, if any, are valid.
+// The signature of the owner of a time-locked input is checked by TransferSignatureValidate.
+func TransferTimeLockValidate(ctx *Context) error {
+	now := time.Now()
+	for _, in := range ctx.InputTokens {
+		if err := timelock.VerifyOwner(in.Owner, now); err != nil {
+			return errors.Wrap(err, "failed to verify transfer from time-lock script")
+		}
+	}
+
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*token.Token)
+		if !ok {
+			return errors.Errorf("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if err := timelock.VerifyScript(out.Owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferFractionalValidate rejects the outputs owned by custody scripts.
+// The types of the tokens are hidden, therefore the supply of the shares of an NFT in custody cannot be checked.
+func TransferFractionalValidate(ctx *Context) error {
+	for _, o := range ctx.Action.GetOutputs() {
+		out, ok := o.(*token.Token)
+		if !ok {
+			return errors.Errorf("invalid output")
+		}
+		if out.IsRedeem() {
+			continue
+		}
+		if fractional.IsInCustody(out.Owner) {
+			return errors.New("invalid transfer action: fractional NFTs are not supported by this driver")
+		}
+	}
+	return nil
+}
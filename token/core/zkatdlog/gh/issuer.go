@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+// Issue returns an IssueAction as a function of the passed arguments.
+// The owner field of each issued token is a commitment to its owner, to a fresh serial number, and to the spend key of the owner.
+// Issue also returns a serialization of the metadata associated with issued tokens
+// and the identity of the issuer
+func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+	for _, owner := range owners {
+		// a recipient cannot be empty
+		if len(owner) == 0 {
+			return nil, nil, nil, errors.Errorf("all recipients should be defined")
+		}
+		if err := checkOwner(owner); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	signer, err := s.IssuerWalletByIdentity(issuerIdentity).GetSigner(issuerIdentity)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pp := s.PublicParams()
+	spendKeys, err := s.spendKeys(owners)
+	if err != nil {
+		return nil, nil, nil, errors.WithMessage(err, "failed getting the spend keys of the recipients")
+	}
+	ownerComs, sns, err := gh.CommitOwners(owners, spendKeys, pp)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	issuer := &nonanonym.Issuer{}
+	issuer.New(typ, &common.WrappedSigningIdentity{
+		Identity: issuerIdentity,
+		Signer:   signer,
+	}, pp)
+	issuer.Workers = s.Workers
+
+	issue, outputMetadata, err := issuer.GenerateZKIssue(values, ownerComs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var outputMetadataRaw [][]byte
+	for i, meta := range outputMetadata {
+		meta.Owner = owners[i]
+		raw, err := (&gh.Metadata{Metadata: *meta, SerialNumber: sns[i], SpendKey: spendKeys[i]}).Serialize()
+		if err != nil {
+			return nil, nil, nil, errors.WithMessage(err, "failed serializing token info")
+		}
+		outputMetadataRaw = append(outputMetadataRaw, raw)
+	}
+
+	fid, err := issuer.Signer.Serialize()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return issue, outputMetadataRaw, fid, err
+}
+
+// checkOwner checks that the passed owner is an identity, scripts are not supported by this driver
+func checkOwner(owner []byte) error {
+	ro, err := identity.UnmarshallRawOwner(owner)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal owner")
+	}
+	if ro.Type != identity.SerializedIdentityType {
+		return errors.Errorf("owner of type [%s] is not supported by this driver", ro.Type)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"crypto/rand"
+	"sync"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/pkg/errors"
+)
+
+const spendKeysPrefix = "token-sdk.gh.spendkeys"
+
+// KVS stores the seed of the secret keys of the owners of this node, and the spend keys of the recipients
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// SpendKeys manages the keys that make the nullifier of a token computable only by its owner.
+// The secret key of an owner identity of this node is derived from a seed, generated once and stored in the KVS.
+// Its spend key is published to the payers together with the recipient identity, as token metadata,
+// and it is stored in the KVS of the payer when the recipient identity is registered.
+type SpendKeys struct {
+	kvs   KVS
+	tmsID token.TMSID
+
+	lock sync.Mutex
+	seed []byte
+}
+
+func NewSpendKeys(kvs KVS, tmsID token.TMSID) *SpendKeys {
+	return &SpendKeys{kvs: kvs, tmsID: tmsID}
+}
+
+// SecretKey returns the secret key of the passed owner identity of this node
+func (k *SpendKeys) SecretKey(owner view.Identity, pp *crypto.PublicParams) (*math.Zr, error) {
+	seed, err := k.getSeed()
+	if err != nil {
+		return nil, err
+	}
+	return math.Curves[pp.Curve].HashToZr(append(append([]byte{}, seed...), owner...)), nil
+}
+
+// OwnSpendKey returns the spend key of the passed owner identity of this node
+func (k *SpendKeys) OwnSpendKey(owner view.Identity, pp *crypto.PublicParams) (*math.G1, error) {
+	sk, err := k.SecretKey(owner, pp)
+	if err != nil {
+		return nil, err
+	}
+	return gh.SpendKey(sk, pp), nil
+}
+
+// Register stores the passed spend key of the passed recipient identity
+func (k *SpendKeys) Register(recipient view.Identity, spendKey []byte, pp *crypto.PublicParams) error {
+	if _, err := math.Curves[pp.Curve].NewG1FromBytes(spendKey); err != nil {
+		return errors.Wrapf(err, "invalid spend key for [%s]", recipient)
+	}
+	key, err := k.key("recipient", recipient.UniqueID())
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(k.kvs.Put(key, spendKey), "failed storing spend key for [%s]", recipient)
+}
+
+// SpendKey returns the spend key registered for the passed recipient identity
+func (k *SpendKeys) SpendKey(recipient view.Identity, pp *crypto.PublicParams) (*math.G1, error) {
+	key, err := k.key("recipient", recipient.UniqueID())
+	if err != nil {
+		return nil, err
+	}
+	if !k.kvs.Exists(key) {
+		return nil, errors.Errorf("no spend key registered for recipient [%s], it must be exchanged with the recipient identity", recipient)
+	}
+	var raw []byte
+	if err := k.kvs.Get(key, &raw); err != nil {
+		return nil, errors.Wrapf(err, "failed retrieving spend key for [%s]", recipient)
+	}
+	spendKey, err := math.Curves[pp.Curve].NewG1FromBytes(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid spend key for [%s]", recipient)
+	}
+	return spendKey, nil
+}
+
+func (k *SpendKeys) getSeed() ([]byte, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.seed != nil {
+		return k.seed, nil
+	}
+	key, err := k.key("seed")
+	if err != nil {
+		return nil, err
+	}
+	var seed []byte
+	if k.kvs.Exists(key) {
+		if err := k.kvs.Get(key, &seed); err != nil {
+			return nil, errors.Wrap(err, "failed retrieving the seed of the secret keys")
+		}
+	} else {
+		seed = make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, errors.Wrap(err, "failed generating the seed of the secret keys")
+		}
+		if err := k.kvs.Put(key, seed); err != nil {
+			return nil, errors.Wrap(err, "failed storing the seed of the secret keys")
+		}
+	}
+	k.seed = seed
+	return seed, nil
+}
+
+func (k *SpendKeys) key(attrs ...string) (string, error) {
+	return kvs.CreateCompositeKey(spendKeysPrefix, append([]string{k.tmsID.Network, k.tmsID.Channel, k.tmsID.Namespace}, attrs...))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs/mock"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpendKeys(t *testing.T) {
+	pp, err := crypto.SetupWithCustomLabel(100, 2, nil, crypto.DLogGraphHidingPublicParameters, math.FP256BN_AMCL)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.GenerateGraphHidingParameters(2))
+	kvs, err := kvs2.NewWithConfig(registry2.New(), "memory", "_default", &mock.ConfigProvider{})
+	assert.NoError(t, err)
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+
+	// the secret keys are derived from a seed kept in the KVS, and differ for each identity
+	keys := NewSpendKeys(kvs, tmsID)
+	alice, err := keys.SecretKey([]byte("alice"), pp)
+	assert.NoError(t, err)
+	bob, err := keys.SecretKey([]byte("bob"), pp)
+	assert.NoError(t, err)
+	assert.False(t, alice.Equals(bob))
+	again, err := NewSpendKeys(kvs, tmsID).SecretKey([]byte("alice"), pp)
+	assert.NoError(t, err)
+	assert.True(t, alice.Equals(again))
+	other, err := NewSpendKeys(kvs, token.TMSID{Network: "n", Channel: "c", Namespace: "other"}).SecretKey([]byte("alice"), pp)
+	assert.NoError(t, err)
+	assert.False(t, alice.Equals(other))
+
+	// the spend keys of the recipients must be registered
+	_, err = keys.SpendKey([]byte("charlie"), pp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no spend key registered for recipient")
+	assert.Error(t, keys.Register([]byte("charlie"), []byte("not a point"), pp))
+	spendKey := gh.SpendKey(alice, pp)
+	assert.NoError(t, keys.Register([]byte("charlie"), spendKey.Bytes(), pp))
+	registered, err := keys.SpendKey([]byte("charlie"), pp)
+	assert.NoError(t, err)
+	assert.True(t, spendKey.Equals(registered))
+}
@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+type VaultTokenLoader struct {
+	TokenVault nogh.TokenVault
+}
+
+// LoadTokens takes an array of token identifiers (txID, index) and returns the corresponding zkatdlog tokens,
+// and their metadata
+func (s *VaultTokenLoader) LoadTokens(ids []*token3.ID) ([]*token.Token, []*gh.Metadata, error) {
+	var tokens []*token.Token
+	var inputInf []*gh.Metadata
+	if err := s.TokenVault.GetTokenInfoAndCommitments(ids, func(id *token3.ID, key string, comm, info []byte) error {
+		if len(comm) == 0 {
+			return errors.Errorf("failed getting state for id [%v], nil comm value", id)
+		}
+		if len(info) == 0 {
+			return errors.Errorf("failed getting state for id [%v], nil info value", id)
+		}
+		tok := &token.Token{}
+		if err := tok.Deserialize(comm); err != nil {
+			return errors.Wrapf(err, "failed unmarshalling token for id [%v]", id)
+		}
+		ti := &gh.Metadata{}
+		if err := ti.Deserialize(info); err != nil {
+			return errors.Wrapf(err, "failed deserializeing token info for id [%v]", id)
+		}
+		if ti.SerialNumber == nil {
+			return errors.Errorf("invalid token info for id [%v]: nil serial number", id)
+		}
+		tokens = append(tokens, tok)
+		inputInf = append(inputInf, ti)
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	return tokens, inputInf, nil
+}
+
+type LedgerTokensVault interface {
+	LedgerTokens(callback driver.QueryCallbackFunc) error
+}
+
+// VaultAnonymitySetProvider chooses the other tokens of an anonymity set uniformly at random among the tokens on the ledger,
+// spent or not, as a spend does not reveal the spent token. The vault holds every token on the ledger, because
+// the driver has the network process every transaction of the namespace.
+type VaultAnonymitySetProvider struct {
+	TokenVault LedgerTokensVault
+}
+
+// AnonymitySet returns the identifiers of the tokens of an anonymity set of the passed size,
+// that contains the passed token, and the position of the token in the set.
+// It returns an error if the ledger does not have enough distinct tokens to fill the set.
+func (p *VaultAnonymitySetProvider) AnonymitySet(id *token3.ID, size int) ([]*token3.ID, int, error) {
+	// reservoir sampling of size-1 distinct tokens, the redeemed tokens cannot be in a set
+	var candidates []*token3.ID
+	seen := 0
+	err := p.TokenVault.LedgerTokens(func(candidate *token3.ID, raw []byte) error {
+		if candidate.TxId == id.TxId && candidate.Index == id.Index {
+			return nil
+		}
+		tok := &token.Token{}
+		if err := tok.Deserialize(raw); err != nil || tok.IsRedeem() {
+			return nil
+		}
+		seen++
+		if len(candidates) < size-1 {
+			candidates = append(candidates, candidate)
+			return nil
+		}
+		j, err := randInt(seen)
+		if err != nil {
+			return err
+		}
+		if j < size-1 {
+			candidates[j] = candidate
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, errors.WithMessagef(err, "failed to list the tokens on the ledger")
+	}
+	if len(candidates) < size-1 {
+		return nil, 0, errors.Errorf("not enough tokens on the ledger to hide token [%v], [%d] are needed, [%d] are available", id, size-1, len(candidates))
+	}
+
+	set := append(candidates, id)
+	// shuffle the set, so that the position of the token is random
+	for i := len(set) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		set[i], set[j] = set[j], set[i]
+	}
+	for i, e := range set {
+		if e == id {
+			return set, i, nil
+		}
+	}
+	return nil, 0, errors.Errorf("token [%v] is not in its anonymity set", id)
+}
+
+func randInt(n int) (int, error) {
+	r, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get random number")
+	}
+	return int(r.Int64()), nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"fmt"
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+type ledgerTokens []*token3.ID
+
+func (l ledgerTokens) LedgerTokens(callback driver.QueryCallbackFunc) error {
+	c := math.Curves[math.FP256BN_AMCL]
+	for i, id := range l {
+		tok := &token.Token{Owner: c.GenG1.Bytes(), Data: c.GenG1}
+		if i == 0 {
+			// a redeemed token cannot be in an anonymity set
+			tok.Owner = nil
+		}
+		raw, err := tok.Serialize()
+		if err != nil {
+			return err
+		}
+		if err := callback(id, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAnonymitySet(t *testing.T) {
+	var ids ledgerTokens
+	for i := 0; i < 10; i++ {
+		ids = append(ids, &token3.ID{TxId: fmt.Sprintf("tx%d", i), Index: 0})
+	}
+	spent := ids[5]
+	p := &VaultAnonymitySetProvider{TokenVault: ids}
+
+	for i := 0; i < 20; i++ {
+		set, index, err := p.AnonymitySet(spent, 8)
+		assert.NoError(t, err)
+		assert.Len(t, set, 8)
+		assert.Equal(t, spent, set[index])
+		distinct := map[string]bool{}
+		for _, id := range set {
+			assert.NotEqual(t, ids[0], id, "redeemed token in the set")
+			distinct[id.String()] = true
+		}
+		assert.Len(t, distinct, 8)
+	}
+
+	// the ledger has 8 tokens that can hide the spent one, 9 are needed
+	_, _, err := p.AnonymitySet(spent, 16)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not enough tokens on the ledger")
+}
@@ -0,0 +1,215 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Transfer returns a graph hiding TransferAction as a function of the passed arguments.
+// Each spent token is hidden in an anonymity set chosen by the AnonymitySetProvider,
+// and it is signed for by a fresh identity of its owner, the sender in the returned TransferMetadata.
+// It also returns the corresponding TransferMetadata
+func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token.ID, outputTokens []*token.Token, opts *driver.TransferOptions) (driver.TransferAction, *driver.TransferMetadata, error) {
+	logger.Debugf("Prepare Transfer Action [%s,%v]", txID, ids)
+	if opts != nil {
+		// the metadata of the transfer actions is not supported
+		md := map[string][]byte{}
+		common.SetTransferActionMetadata(opts.Attributes, md)
+		if len(md) != 0 {
+			return nil, nil, errors.New("transfer action metadata is not supported by this driver")
+		}
+	}
+	// load tokens with the passed token identifiers
+	tokens, inputInf, err := s.TokenLoader.LoadTokens(ids)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to load tokens")
+	}
+	pp := s.PublicParams()
+	size := pp.GraphHidingParams.AnonymitySetSize()
+	inputs := make([]*gh.SpentToken, len(ids))
+	var signerIds []view.Identity
+	for i, id := range ids {
+		owner := inputInf[i].Owner
+		if err := checkOwner(owner); err != nil {
+			return nil, nil, errors.WithMessagef(err, "cannot spend token [%v]", id)
+		}
+		sk, err := s.SpendKeys.SecretKey(owner, pp)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "cannot spend token [%v]", id)
+		}
+		// a fresh identity of the owner signs in place of the owner, that is not revealed
+		w := wallet
+		if w == nil {
+			w = s.OwnerWalletByIdentity(owner)
+		}
+		if w == nil {
+			return nil, nil, errors.Errorf("cannot spend token [%v]: no wallet for its owner", id)
+		}
+		signer, err := w.GetRecipientIdentity()
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "cannot get a fresh identity to spend token [%v]", id)
+		}
+		signerIds = append(signerIds, signer)
+
+		set, index, err := s.AnonymitySetProvider.AnonymitySet(id, size)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting anonymity set for token [%v]", id)
+		}
+		if index < 0 || index >= len(set) || set[index].TxId != id.TxId || set[index].Index != id.Index {
+			return nil, nil, errors.Errorf("the anonymity set of token [%v] does not contain it", id)
+		}
+		setTokens, err := s.TokenCommitmentLoader.GetTokenCommitments(set)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting the anonymity set for token [%v]", id)
+		}
+		// use the token as loaded with its metadata
+		setTokens[index] = tokens[i]
+		inputs[i] = &gh.SpentToken{Metadata: inputInf[i], Set: set, SetTokens: setTokens, Index: index, SecretKey: sk, Signer: signer}
+	}
+
+	var values []uint64
+	var owners [][]byte
+	var ownerIdentities []view.Identity
+	// get values and owners of outputs
+	for i, output := range outputTokens {
+		q, err := token.ToQuantity(output.Quantity, pp.Precision())
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get value for %dth output", i)
+		}
+		values = append(values, q.ToBigInt().Uint64())
+		if output.Owner == nil {
+			return nil, nil, errors.Errorf("failed to get owner for %dth output: nil owner", i)
+		}
+		if len(output.Owner.Raw) != 0 {
+			if err := checkOwner(output.Owner.Raw); err != nil {
+				return nil, nil, errors.WithMessagef(err, "invalid owner for %dth output", i)
+			}
+		}
+		owners = append(owners, output.Owner.Raw)
+		ownerIdentities = append(ownerIdentities, output.Owner.Raw)
+	}
+
+	spendKeys, err := s.spendKeys(owners)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed getting the spend keys of the recipients")
+	}
+
+	// get sender
+	sender, err := gh.NewSender(inputs, pp)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender.Workers = s.Workers
+	// produce graph hiding transfer action
+	// return for each output its information in the clear
+	transfer, outputMetadata, err := sender.GenerateZKTransfer(txID, values, owners, spendKeys)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to generate graph hiding transfer action for txid [%s]", txID)
+	}
+
+	// prepare metadata
+	var outputMetadataRaw [][]byte
+	for _, information := range outputMetadata {
+		raw, err := information.Serialize()
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed serializing token info for graph hiding transfer action")
+		}
+		outputMetadataRaw = append(outputMetadataRaw, raw)
+	}
+	// audit info for receivers
+	var receiverAuditInfos [][]byte
+	for _, output := range outputTokens {
+		auditInfo, err := identity.GetOwnerAuditInfo(output.Owner.Raw, s)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(output.Owner.Raw).String())
+		}
+		receiverAuditInfos = append(receiverAuditInfos, auditInfo)
+	}
+
+	// audit info for senders
+	var senderAuditInfos [][]byte
+	for _, id := range signerIds {
+		auditInfo, err := identity.GetOwnerAuditInfo(id, s)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting audit info for sender identity [%s]", id.String())
+		}
+		senderAuditInfos = append(senderAuditInfos, auditInfo)
+	}
+
+	outputs, err := transfer.GetSerializedOutputs()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting serialized outputs")
+	}
+
+	receiverIsSender := make([]bool, len(ownerIdentities))
+	for i, receiver := range ownerIdentities {
+		receiverIsSender[i] = s.OwnerWalletByID(receiver) != nil
+	}
+
+	metadata := &driver.TransferMetadata{
+		Outputs:            outputs,
+		Senders:            signerIds,
+		SenderAuditInfos:   senderAuditInfos,
+		TokenIDs:           ids,
+		OutputsMetadata:    outputMetadataRaw,
+		Receivers:          ownerIdentities,
+		ReceiverAuditInfos: receiverAuditInfos,
+		ReceiverIsSender:   receiverIsSender,
+	}
+
+	return transfer, metadata, nil
+}
+
+// VerifyTransfer checks the outputs in the TransferAction against the passed metadata,
+// and the proof that the input commitments and the outputs match.
+// The spend proofs are bound to the anchor of the transfer, they are checked by the validator.
+func (s *Service) VerifyTransfer(action driver.TransferAction, outputsMetadata [][]byte) error {
+	if action == nil {
+		return errors.New("failed to verify transfer: nil transfer action")
+	}
+	tr, ok := action.(*gh.TransferAction)
+	if !ok {
+		return errors.New("failed to verify transfer: expected *gh.TransferAction")
+	}
+
+	pp := s.PublicParams()
+	for i := 0; i < len(tr.OutputTokens); i++ {
+		if len(outputsMetadata[i]) == 0 || tr.OutputTokens[i].IsRedeem() {
+			continue
+		}
+		meta := &gh.Metadata{}
+		if err := meta.Deserialize(outputsMetadata[i]); err != nil {
+			return errors.Wrap(err, "failed unmarshalling token information")
+		}
+		tok, err := gh.GetTokenInTheClear(tr.OutputTokens[i], meta, pp)
+		if err != nil {
+			return errors.Wrap(err, "failed getting token in the clear")
+		}
+		logger.Debugf("transfer output [%s,%s,%s]", tok.Type, tok.Quantity, view.Identity(tok.Owner.Raw))
+	}
+
+	return transfer.NewVerifier(tr.GetInputCommitments(), tr.GetOutputCommitments(), pp).Verify(tr.Proof)
+}
+
+// DeserializeTransferAction un-marshals a graph hiding TransferAction from the passed array of bytes.
+// DeserializeTransferAction returns an error, if the un-marshalling fails.
+func (s *Service) DeserializeTransferAction(raw []byte) (driver.TransferAction, error) {
+	transfer := &gh.TransferAction{}
+	err := transfer.Deserialize(raw)
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/gh/validator"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.driver.zkatdlog.gh")
+
+type TokenLoader interface {
+	LoadTokens(ids []*token3.ID) ([]*token.Token, []*gh.Metadata, error)
+}
+
+// AnonymitySetProvider chooses the anonymity sets of the tokens to be spent
+type AnonymitySetProvider interface {
+	// AnonymitySet returns the identifiers of the tokens of an anonymity set of the passed size,
+	// that contains the passed token, and the position of the token in the set
+	AnonymitySet(id *token3.ID, size int) ([]*token3.ID, int, error)
+}
+
+// Service is the graph hiding zkatdlog token service.
+// It differs from the zkatdlog token service in the tokens it issues, and in the way it transfers them:
+// a transfer does not reveal the spent tokens, but their nullifiers, that only the owners can compute.
+type Service struct {
+	*nogh.Service
+	TokenLoader          TokenLoader
+	AnonymitySetProvider AnonymitySetProvider
+	SpendKeys            *SpendKeys
+}
+
+func NewTokenService(service *nogh.Service, tokenLoader TokenLoader, anonymitySetProvider AnonymitySetProvider, kvs KVS) *Service {
+	return &Service{
+		Service:              service,
+		TokenLoader:          tokenLoader,
+		AnonymitySetProvider: anonymitySetProvider,
+		SpendKeys:            NewSpendKeys(kvs, service.TMSID),
+	}
+}
+
+// RegisterRecipientIdentity registers the passed recipient identity, and the spend key in the passed metadata,
+// that the outputs owned by the recipient commit to
+func (s *Service) RegisterRecipientIdentity(id view.Identity, auditInfo []byte, metadata []byte) error {
+	if err := s.Service.RegisterRecipientIdentity(id, auditInfo, metadata); err != nil {
+		return err
+	}
+	if len(metadata) == 0 {
+		return errors.Errorf("no spend key for recipient [%s]", id)
+	}
+	return s.SpendKeys.Register(id, metadata, s.PublicParams())
+}
+
+// spendKey returns the spend key of the passed owner, that is either an identity of this node
+// or a registered recipient identity
+func (s *Service) spendKey(owner view.Identity) (*math.G1, error) {
+	if s.Service.OwnerWalletByIdentity(owner) != nil {
+		return s.SpendKeys.OwnSpendKey(owner, s.PublicParams())
+	}
+	return s.SpendKeys.SpendKey(owner, s.PublicParams())
+}
+
+// spendKeys returns the spend keys of the passed owners, nil for the empty ones
+func (s *Service) spendKeys(owners [][]byte) ([]*math.G1, error) {
+	res := make([]*math.G1, len(owners))
+	for i, owner := range owners {
+		if len(owner) == 0 {
+			continue
+		}
+		var err error
+		res[i], err = s.spendKey(owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// DeserializeToken un-marshals a token and token info from raw bytes
+// It checks if the un-marshalled token matches the token info. If not, it returns
+// an error. Else it returns the token in cleartext, whose owner is the owner committed in the token,
+// and the identity of its issuer
+func (s *Service) DeserializeToken(tok []byte, infoRaw []byte) (*token3.Token, view.Identity, error) {
+	output := &token.Token{}
+	if err := output.Deserialize(tok); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to deserialize zkatdlog token")
+	}
+	meta := &gh.Metadata{}
+	if err := meta.Deserialize(infoRaw); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to deserialize token information")
+	}
+	to, err := gh.GetTokenInTheClear(output, meta, s.PublicParams())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to deserialize token")
+	}
+	// a token of this node must commit to the spend key of its owner, or it cannot be spent
+	if s.Service.OwnerWalletByIdentity(meta.Owner) != nil {
+		spendKey, err := s.SpendKeys.OwnSpendKey(meta.Owner, s.PublicParams())
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to deserialize token")
+		}
+		if !spendKey.Equals(meta.SpendKey) {
+			return nil, nil, errors.New("failed to deserialize token: the token does not commit to the spend key of its owner")
+		}
+	}
+	return to, meta.Issuer, nil
+}
+
+// Validator returns the validator associated with the service
+func (s *Service) Validator() driver.Validator {
+	d, err := s.Deserializer()
+	if err != nil {
+		panic(err)
+	}
+	return validator.New(s.PublicParams(), d)
+}
+
+// AuditorCheck verifies if the passed tokenRequest matches the tokenRequestMetadata
+func (s *Service) AuditorCheck(tokenRequest *driver.TokenRequest, tokenRequestMetadata *driver.TokenRequestMetadata, txID string) error {
+	logger.Debugf("check token request validity...")
+	des, err := s.Deserializer()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting deserializer for auditor check")
+	}
+	// the owners of the inputs are in the transfer actions, there is no input to load
+	if err := gh.NewAuditor(des, nil, s.PublicParams()).Check(
+		tokenRequest,
+		tokenRequestMetadata,
+		nil,
+		txID,
+	); err != nil {
+		return errors.WithMessagef(err, "failed to perform auditor check")
+	}
+	return nil
+}
+
+// SpentIDs returns the keys of the nullifiers of the passed tokens
+func (s *Service) SpentIDs(ids ...*token3.ID) ([]string, error) {
+	_, metas, err := s.TokenLoader.LoadTokens(ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load tokens")
+	}
+	pp := s.PublicParams()
+	sIDs := make([]string, len(metas))
+	for i, meta := range metas {
+		sk, err := s.SpendKeys.SecretKey(meta.Owner, pp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot compute spent id for [%v]", ids[i])
+		}
+		sIDs[i], err = gh.NullifierKey(gh.Nullifier(meta.SerialNumber, sk, pp))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot compute spent id for [%v]", ids[i])
+		}
+	}
+	return sIDs, nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+func (s *Service) Wallet(identity view.Identity) driver.Wallet {
+	if w := s.OwnerWalletByIdentity(identity); w != nil {
+		return w
+	}
+	return s.Service.Wallet(identity)
+}
+
+func (s *Service) OwnerWallet(walletID string) driver.OwnerWallet {
+	return s.wrapOwnerWallet(s.Service.OwnerWallet(walletID))
+}
+
+func (s *Service) OwnerWalletByIdentity(identity view.Identity) driver.OwnerWallet {
+	return s.wrapOwnerWallet(s.Service.OwnerWalletByIdentity(identity))
+}
+
+func (s *Service) OwnerWalletByID(id interface{}) driver.OwnerWallet {
+	return s.wrapOwnerWallet(s.Service.OwnerWalletByID(id))
+}
+
+func (s *Service) wrapOwnerWallet(w driver.OwnerWallet) driver.OwnerWallet {
+	if w == nil {
+		return nil
+	}
+	return &ownerWallet{OwnerWallet: w, service: s}
+}
+
+// ownerWallet publishes the spend keys of its recipient identities as their token metadata
+type ownerWallet struct {
+	driver.OwnerWallet
+	service *Service
+}
+
+// GetTokenMetadata returns the spend key of the passed recipient identity,
+// the outputs owned by the identity must commit to it
+func (w *ownerWallet) GetTokenMetadata(id view.Identity) ([]byte, error) {
+	if !w.Contains(id) {
+		return nil, errors.Errorf("identity [%s] does not belong to this wallet [%s]", id, w.ID())
+	}
+	spendKey, err := w.service.SpendKeys.OwnSpendKey(id, w.service.PublicParams())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting spend key for [%s]", id)
+	}
+	return spendKey.Bytes(), nil
+}
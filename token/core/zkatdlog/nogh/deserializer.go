@@ -8,7 +8,6 @@ package nogh
 
 import (
 	"bytes"
-	"encoding/json"
 	"sync"
 
 	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
@@ -16,7 +15,6 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
@@ -52,10 +50,11 @@ func NewDeserializer(pp *crypto.PublicParams) (*deserializer, error) {
 		return nil, errors.Wrapf(err, "failed getting idemix deserializer for passed public params")
 	}
 
+	ownerDeserializer := identity.NewRawOwnerIdentityDeserializer(idemixDes)
 	return &deserializer{
 		auditorDeserializer: &x509.MSPIdentityDeserializer{},
 		issuerDeserializer:  &x509.MSPIdentityDeserializer{},
-		ownerDeserializer:   htlc.NewDeserializer(identity.NewRawOwnerIdentityDeserializer(idemixDes)),
+		ownerDeserializer:   identity.NewScriptDeserializer(ownerDeserializer),
 		auditDeserializer:   idemixDes,
 	}, nil
 }
@@ -130,19 +129,9 @@ func (e *enrollmentService) GetEnrollmentID(auditInfo []byte) (string, error) {
 		return "", nil
 	}
 
-	// Try to unmarshal it as ScriptInfo
-	si := &htlc.ScriptInfo{}
-	err := json.Unmarshal(auditInfo, si)
-	if err == nil && (len(si.Sender) != 0 || len(si.Recipient) != 0) {
-		if len(si.Recipient) != 0 {
-			ai := &idemix2.AuditInfo{}
-			if err := ai.FromBytes(si.Recipient); err != nil {
-				return "", errors.Wrapf(err, "failed unamrshalling audit info [%s]", auditInfo)
-			}
-			return ai.EnrollmentID(), nil
-		}
-
-		return "", nil
+	// Try to unmarshal it as the audit info of a script
+	if eID, ok, err := identity.ScriptEnrollmentID(auditInfo, e.GetEnrollmentID); ok || err != nil {
+		return eID, err
 	}
 
 	ai := &idemix2.AuditInfo{}
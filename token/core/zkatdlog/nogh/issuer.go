@@ -37,6 +37,7 @@ func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 		Identity: issuerIdentity,
 		Signer:   signer,
 	}, pp)
+	issuer.Workers = s.Workers
 
 	issue, outputMetadata, err := issuer.GenerateZKIssue(values, owners)
 	if err != nil {
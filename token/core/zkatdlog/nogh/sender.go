@@ -11,7 +11,6 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -31,6 +30,16 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 	}
 	pp := s.PublicParams()
 	for _, id := range signerIds {
+		// the co-owners of a script sign when the endorsements are collected, there is no local signer
+		if _, ok, _ := identity.CoOwners(id); ok {
+			signers = append(signers, nil)
+			continue
+		}
+		// a script with a single signer, like a time-lock script, is signed for by that signer
+		id, err := identity.Signer(id)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting the signer of [%v]", id)
+		}
 		// get signers for each input token
 		si, err := s.identityProvider.GetSigner(id)
 		if err != nil {
@@ -44,6 +53,7 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 	if err != nil {
 		return nil, nil, err
 	}
+	sender.Workers = s.Workers
 	var values []uint64
 	var owners [][]byte
 	var ownerIdentities []view.Identity
@@ -62,17 +72,9 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 			ownerIdentities = append(ownerIdentities, output.Owner.Raw)
 			continue
 		}
-		owner, err := identity.UnmarshallRawOwner(output.Owner.Raw)
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "failed to unmarshal owner of the output token")
-		}
-		if owner.Type == identity.SerializedIdentityType {
-			ownerIdentities = append(ownerIdentities, output.Owner.Raw)
-			continue
-		}
-		_, recipient, err := htlc.GetScriptSenderAndRecipient(owner)
+		recipient, err := identity.Recipient(output.Owner.Raw)
 		if err != nil {
-			return nil, nil, errors.Wrap(err, "failed getting script sender and recipient")
+			return nil, nil, errors.Wrap(err, "failed getting the recipient of the output owner")
 		}
 		ownerIdentities = append(ownerIdentities, recipient)
 	}
@@ -98,7 +100,7 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 	// audit info for receivers
 	var receiverAuditInfos [][]byte
 	for _, output := range outputTokens {
-		auditInfo, err := htlc.GetOwnerAuditInfo(output.Owner.Raw, s)
+		auditInfo, err := identity.GetOwnerAuditInfo(output.Owner.Raw, s)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(output.Owner.Raw).String())
 		}
@@ -108,7 +110,7 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 	// audit info for senders
 	var senderAuditInfos [][]byte
 	for _, t := range tokens {
-		auditInfo, err := htlc.GetOwnerAuditInfo(t.Owner, s)
+		auditInfo, err := identity.GetOwnerAuditInfo(t.Owner, s)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for sender identity [%s]", view.Identity(t.Owner).String())
 		}
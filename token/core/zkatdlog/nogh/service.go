@@ -12,6 +12,7 @@ import (
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -62,6 +63,8 @@ type Service struct {
 	QE                    QueryEngine
 	DeserializerProvider  DeserializerProviderFunc
 	configManager         config.Manager
+	// Workers generate the proofs of the issue and transfer actions of the TMS
+	Workers *common.WorkerPool
 
 	identityProvider       driver.IdentityProvider
 	OwnerWalletsRegistry   *identity.WalletsRegistry
@@ -96,6 +99,11 @@ func NewTokenService(
 		OwnerWalletsRegistry:   identity.NewWalletsRegistry(tmsID, identityProvider, driver.OwnerRole, kvs),
 		IssuerWalletsRegistry:  identity.NewWalletsRegistry(tmsID, identityProvider, driver.IssuerRole, kvs),
 		AuditorWalletsRegistry: identity.NewWalletsRegistry(tmsID, identityProvider, driver.AuditorRole, kvs),
+		Workers:                common.DefaultWorkerPool,
+	}
+	// a TMS with its own number of workers does not compete with the other TMSs for them
+	if tms := configManager.TMS(); tms != nil && tms.Prover != nil && tms.Prover.Workers > 0 {
+		s.Workers = common.NewWorkerPool(tms.Prover.Workers)
 	}
 	return s, nil
 }
@@ -24,8 +24,18 @@ func (s *Service) RegisterOwnerWallet(id string, path string) error {
 	return s.identityProvider.RegisterOwnerWallet(id, path)
 }
 
+// GetAuditInfo returns the audit info of the passed identity.
+// The audit info of an owner script, if not registered, is assembled from the audit info of its parties,
+// so that tokens can be issued to the script.
 func (s *Service) GetAuditInfo(id view.Identity) ([]byte, error) {
-	return s.identityProvider.GetAuditInfo(id)
+	auditInfo, err := s.identityProvider.GetAuditInfo(id)
+	if err != nil || len(auditInfo) != 0 {
+		return auditInfo, err
+	}
+	if owner, err := identity.UnmarshallRawOwner(id); err == nil && owner.Type != identity.SerializedIdentityType {
+		return identity.GetOwnerAuditInfo(id, s.identityProvider)
+	}
+	return nil, nil
 }
 
 func (s *Service) GetEnrollmentID(auditInfo []byte) (string, error) {
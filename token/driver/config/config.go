@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 
 package config
 
+import "time"
+
 type InteractiveCertification struct {
 	IDs []string `yaml:"ids,omitempty"`
 }
@@ -33,12 +35,80 @@ type Wallets struct {
 	Auditors   []*Identity `yaml:"auditors,omitempty"`
 }
 
+// Retention defines which ttxdb records are removed from the db, and when
+type Retention struct {
+	// PurgeDeletedAfter is the age after which the records of deleted transactions are purged, 0 disables purging
+	PurgeDeletedAfter time.Duration `yaml:"purgeDeletedAfter,omitempty"`
+	// ArchiveConfirmedAfter is the age after which the records of confirmed transactions are archived, 0 disables archiving
+	ArchiveConfirmedAfter time.Duration `yaml:"archiveConfirmedAfter,omitempty"`
+	// ArchivePath is the folder where archived records are stored
+	ArchivePath string `yaml:"archivePath,omitempty"`
+	// Interval is the time between two compactions
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// Selector is the configuration of the token selector
+type Selector struct {
+	// Locker is the type of locker used to lock the selected tokens, `memory` (default), `kvs`, or `sql`
+	Locker string `yaml:"locker,omitempty"`
+	// Driver is the database/sql driver of the database the `sql` locker stores the locks in
+	Driver string `yaml:"driver,omitempty"`
+	// DataSource is the data source of the database the `sql` locker stores the locks in
+	DataSource string `yaml:"dataSource,omitempty"`
+	// Strategy is the name of the default selection strategy, if empty the tokens are selected in vault order
+	Strategy string `yaml:"strategy,omitempty"`
+	// MaxInputs is the maximum number of tokens merged by a single consolidation transfer, if 0 the default applies
+	MaxInputs int `yaml:"maxInputs,omitempty"`
+}
+
+// HTLCReclaim is the configuration of the background reclaim of the expired htlc-tokens
+type HTLCReclaim struct {
+	// Enabled starts the background reclaim
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Interval is the time between two scans for expired htlc-tokens
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// BatchSize is the maximum number of htlc-tokens reclaimed by a single transaction
+	BatchSize int `yaml:"batchSize,omitempty"`
+	// Wallets are the owner wallets whose expired htlc-tokens are reclaimed, if empty all the configured owner wallets
+	Wallets []string `yaml:"wallets,omitempty"`
+	// Auditor is the label of the auditor identity to involve in the reclaim transactions, if any
+	Auditor string `yaml:"auditor,omitempty"`
+}
+
+// HTLC is the configuration of the htlc service
+type HTLC struct {
+	Reclaim *HTLCReclaim `yaml:"reclaim,omitempty"`
+}
+
+// NFT is the configuration of the nft service
+type NFT struct {
+	// Indexes are the JSON paths of the NFT states indexed by the token processor, `owner.name` for instance
+	Indexes []string `yaml:"indexes,omitempty"`
+	// UniqueIDPath is the JSON path of the unique ID of the NFT states, recorded in the transaction records, `LinearID` if not set
+	UniqueIDPath string `yaml:"uniqueIDPath,omitempty"`
+}
+
+// Prover is the configuration of the generation of the zero-knowledge proofs, for the drivers that produce them
+type Prover struct {
+	// Workers is the maximum number of goroutines generating the proofs of the TMS, the number of CPUs if not set
+	Workers int `yaml:"workers,omitempty"`
+}
+
+type TTXDB struct {
+	Retention *Retention `yaml:"retention,omitempty"`
+}
+
 type TMS struct {
 	Network       string         `yaml:"network,omitempty"`
 	Channel       string         `yaml:"channel,omitempty"`
 	Namespace     string         `yaml:"namespace,omitempty"`
 	Certification *Certification `yaml:"certification,omitempty"`
 	Wallets       *Wallets       `yaml:"wallets,omitempty"`
+	TTXDB         *TTXDB         `yaml:"ttxdb,omitempty"`
+	Selector      *Selector      `yaml:"selector,omitempty"`
+	HTLC          *HTLC          `yaml:"htlc,omitempty"`
+	NFT           *NFT           `yaml:"nft,omitempty"`
+	Prover        *Prover        `yaml:"prover,omitempty"`
 }
 
 type Token struct {
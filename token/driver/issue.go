@@ -30,3 +30,12 @@ type IssueService interface {
 	// DeserializeIssueAction deserializes the passed bytes into an IssuerAction
 	DeserializeIssueAction(raw []byte) (IssueAction, error)
 }
+
+// BatchIssueService is implemented by the issue services whose issue actions can carry tokens of different types.
+// It is optional: whether a TokenManagerService supports it is checked with a type assertion.
+type BatchIssueService interface {
+	// BatchIssue generates a single IssuerAction whose tokens are issued by the passed identity.
+	// The tokens to be issued are passed as triples (type, value, owner), the types do not need to be all equal.
+	// The returned values are as in Issue.
+	BatchIssue(issuerIdentity view.Identity, tokenTypes []string, values []uint64, owners [][]byte, opts *IssueOptions) (IssueAction, [][]byte, view.Identity, error)
+}
@@ -29,3 +29,17 @@ type Validator interface {
 	// VerifyTokenRequestFromRaw verifies the passed marshalled token request against the passed ledger and anchor
 	VerifyTokenRequestFromRaw(getState GetStateFnc, anchor string, raw []byte) ([]interface{}, error)
 }
+
+// BatchedTokenRequest is a marshalled token request, with its anchor, to be verified in a batch with other requests
+type BatchedTokenRequest struct {
+	Anchor string
+	Raw    []byte
+}
+
+// BatchValidator models a token request validator that verifies the proofs of several requests at once
+type BatchValidator interface {
+	Validator
+	// VerifyTokenRequestsFromRaw verifies the passed marshalled token requests against the passed ledger.
+	// It returns, for each request, either its actions or the reason why it is invalid.
+	VerifyTokenRequestsFromRaw(getState GetStateFnc, requests []*BatchedTokenRequest) ([][]interface{}, []error)
+}
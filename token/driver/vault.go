@@ -36,6 +36,9 @@ type QueryEngine interface {
 	// UnspentTokensIteratorBy returns an iterator of unspent tokens owned by the passed id and whose type is the passed on.
 	// The token type can be empty. In that case, tokens of any type are returned.
 	UnspentTokensIteratorBy(id, typ string) (UnspentTokensIterator, error)
+	// IndexedTokenIDs returns the ids of the unspent NFTs owned by the passed id whose state has, at the passed indexed path,
+	// a value whose encoding is in the range [from, to)
+	IndexedTokenIDs(id, path, from, to string) ([]*token.ID, error)
 	// ListUnspentTokens returns the list of unspent tokens
 	ListUnspentTokens() (*token.UnspentTokens, error)
 	// ListAuditTokens returns the audited tokens associated to the passed ids
@@ -52,6 +55,9 @@ type QueryEngine interface {
 	GetTokenCommitments(ids []*token.ID, callback QueryCallbackFunc) error
 
 	GetTokenInfoAndCommitments(ids []*token.ID, callback QueryCallback2Func) error
+	// LedgerTokens invokes the passed callback for each token on the ledger, as stored in the vault,
+	// with the token identifier and the token commitment
+	LedgerTokens(callback QueryCallbackFunc) error
 	// GetTokens returns the list of tokens with their respective vault keys
 	GetTokens(inputs ...*token.ID) ([]string, []*token.Token, error)
 }
@@ -8,6 +8,7 @@ package token
 
 import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var (
@@ -34,6 +35,20 @@ type SelectorManager interface {
 	Unlock(id string) error
 }
 
+// StrategySelectorManager is a SelectorManager whose selectors can use a selection strategy other than the default one
+type StrategySelectorManager interface {
+	SelectorManager
+	// NewSelectorWithStrategy returns a new Selector instance bound the passed id, using the passed selection strategy.
+	NewSelectorWithStrategy(id string, strategy string) (Selector, error)
+}
+
+// TokenUnlocker is a SelectorManager that can unlock single tokens
+type TokenUnlocker interface {
+	SelectorManager
+	// UnlockIDs unlocks the passed tokens, whatever id they are bound to
+	UnlockIDs(ids ...*token2.ID) error
+}
+
 // SelectorManagerProvider provides instances of SelectorManager
 type SelectorManagerProvider interface {
 	// SelectorManager returns a SelectorManager instance for the passed inputs.
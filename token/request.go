@@ -56,6 +56,9 @@ type TransferOptions struct {
 	Attributes map[interface{}]interface{}
 	// Selector is the custom token selector to use. If nil, the default will be used.
 	Selector Selector
+	// SelectionStrategy is the name of the selection strategy the default selector should use.
+	// If empty, the one configured for the TMS will be used.
+	SelectionStrategy string
 	// TokenIDs to transfer. If empty, the tokens will be selected.
 	TokenIDs []*token.ID
 }
@@ -81,6 +84,15 @@ func WithTokenSelector(selector Selector) TransferOption {
 	}
 }
 
+// WithSelectionStrategy sets the selection strategy the default token selector should use,
+// such as largest-first, smallest-first, exact-match, or fewest-inputs
+func WithSelectionStrategy(strategy string) TransferOption {
+	return func(o *TransferOptions) error {
+		o.SelectionStrategy = strategy
+		return nil
+	}
+}
+
 // WithTransferMetadata sets transfer action metadata
 func WithTransferMetadata(key string, value []byte) TransferOption {
 	return WithTransferAttribute(TransferMetadataPrefix+key, value)
@@ -224,31 +236,120 @@ func (r *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 		return nil, err
 	}
 
-	// Append
-	raw, err := issue.Serialize()
-	if err != nil {
-		return nil, err
+	return r.appendIssue(issue, tokenInfos, issuer, []view.Identity{receiver})
+}
+
+// IssueLeg is a leg of a batch issue: the recipient receives the value of the given token type
+type IssueLeg struct {
+	// Type is the type of the token to issue
+	Type string
+	// Value is the quantity to issue
+	Value uint64
+	// Recipient is the identity receiving the token
+	Recipient view.Identity
+}
+
+// BatchIssue appends to the request a single issue action with an output for each of the passed legs.
+// The action will be prepared using the provided issuer wallet, whose issuer identity must be the same for all the types.
+// Not all drivers support outputs of different types in the same issue action, see ManagementService.BatchIssueSupported:
+// with the ones that do not, the legs must share the same type.
+// Additional options can be passed to customize the action.
+func (r *Request) BatchIssue(wallet *IssuerWallet, legs []*IssueLeg, opts ...IssueOption) (*IssueAction, error) {
+	if wallet == nil {
+		return nil, errors.Errorf("wallet is nil")
 	}
-	r.Actions.Issues = append(r.Actions.Issues, raw)
-	outputs, err := issue.GetSerializedOutputs()
+	if len(legs) == 0 {
+		return nil, errors.Errorf("no legs passed")
+	}
+
+	var id view.Identity
+	types := make([]string, len(legs))
+	values := make([]uint64, len(legs))
+	owners := make([][]byte, len(legs))
+	receivers := make([]view.Identity, len(legs))
+	for i, leg := range legs {
+		if leg.Type == "" {
+			return nil, errors.Errorf("type of leg [%d] is empty", i)
+		}
+		if leg.Value == 0 {
+			return nil, errors.Errorf("value of leg [%d] is zero", i)
+		}
+		if leg.Recipient.IsNone() {
+			return nil, errors.Errorf("all recipients should be defined")
+		}
+		legID, err := wallet.GetIssuerIdentity(leg.Type)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting issuer identity for type [%s]", leg.Type)
+		}
+		if id == nil {
+			id = legID
+		} else if !id.Equal(legID) {
+			return nil, errors.Errorf("the issuer identity for type [%s] differs from the one for type [%s]", leg.Type, legs[0].Type)
+		}
+		types[i] = leg.Type
+		values[i] = leg.Value
+		owners[i] = leg.Recipient
+		receivers[i] = leg.Recipient
+	}
+
+	opt, err := compileIssueOptions(opts...)
 	if err != nil {
-		return nil, err
+		return nil, errors.WithMessagef(err, "failed compiling options [%v]", opts)
 	}
 
-	auditInfo, err := r.TokenService.tms.GetAuditInfo(receiver)
+	// Compute Issue
+	issueOpts := &driver.IssueOptions{
+		Attributes: opt.Attributes,
+	}
+	var issue driver.IssueAction
+	var tokenInfos [][]byte
+	var issuer view.Identity
+	if bis, ok := r.TokenService.tms.(driver.BatchIssueService); ok {
+		issue, tokenInfos, issuer, err = bis.BatchIssue(id, types, values, owners, issueOpts)
+	} else {
+		for _, typ := range types[1:] {
+			if typ != types[0] {
+				return nil, errors.Errorf("the token driver does not support issue actions with different token types, got [%s] and [%s]", types[0], typ)
+			}
+		}
+		issue, tokenInfos, issuer, err = r.TokenService.tms.Issue(id, types[0], values, owners, issueOpts)
+	}
 	if err != nil {
 		return nil, err
 	}
+
+	return r.appendIssue(issue, tokenInfos, issuer, receivers)
+}
+
+// appendIssue appends the passed issue action, and its metadata, to the request
+func (r *Request) appendIssue(issue driver.IssueAction, tokenInfos [][]byte, issuer view.Identity, receivers []view.Identity) (*IssueAction, error) {
 	if r.Metadata == nil {
 		return nil, errors.New("failed to complete issue: nil Metadata in token request")
 	}
+	raw, err := issue.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := issue.GetSerializedOutputs()
+	if err != nil {
+		return nil, err
+	}
+	auditInfos := make([][]byte, len(receivers))
+	for i, receiver := range receivers {
+		auditInfos[i], err = r.TokenService.tms.GetAuditInfo(receiver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.Actions.Issues = append(r.Actions.Issues, raw)
 	r.Metadata.Issues = append(r.Metadata.Issues,
 		driver.IssueMetadata{
 			Issuer:              issuer,
 			Outputs:             outputs,
 			TokenInfo:           tokenInfos,
-			Receivers:           []view.Identity{receiver},
-			ReceiversAuditInfos: [][]byte{auditInfo},
+			Receivers:           receivers,
+			ReceiversAuditInfos: auditInfos,
 		},
 	)
 
@@ -274,6 +375,79 @@ func (r *Request) Transfer(wallet *OwnerWallet, typ string, values []uint64, own
 		return nil, errors.Wrap(err, "failed preparing transfer")
 	}
 
+	return r.appendTransfer(wallet, tokenIDs, outputTokens, opt)
+}
+
+// TransferLeg is a leg of a multi-type transfer: the recipient receives the value of the given token type
+type TransferLeg struct {
+	// Type is the type of the tokens to transfer
+	Type string
+	// Value is the quantity to transfer
+	Value uint64
+	// Recipient is the identity receiving the tokens
+	Recipient view.Identity
+}
+
+// MultiTransfer appends to the request a transfer action for each token type appearing in the passed legs.
+// The actions will be prepared using the provided owner wallet.
+// The tokens for all the legs are selected under the lock scope of the request's anchor by the same selector.
+// If the selection or the preparation of any action fails, the tokens selected by this call are unlocked,
+// if the selector manager supports it, and no action is appended.
+// The options apply to all the actions, the input tokens cannot be passed.
+func (r *Request) MultiTransfer(wallet *OwnerWallet, legs []*TransferLeg, opts ...TransferOption) ([]*TransferAction, error) {
+	if r.Metadata == nil {
+		return nil, errors.New("failed to complete transfer: nil Metadata in token request")
+	}
+	groups, err := groupTransferLegs(legs)
+	if err != nil {
+		return nil, err
+	}
+	opt, err := compileTransferOptions(opts...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed compiling options [%v]", opts)
+	}
+	if len(opt.TokenIDs) != 0 {
+		return nil, errors.Errorf("input tokens cannot be passed to a multi-type transfer")
+	}
+	// all the legs share the same selector
+	opt.Selector, err = r.selector(opt)
+	if err != nil {
+		return nil, err
+	}
+	opt.SelectionStrategy = ""
+
+	// select the tokens for all the types first
+	var selected []*token.ID
+	tokenIDs := make([][]*token.ID, len(groups))
+	outputTokens := make([][]*token.Token, len(groups))
+	for i, group := range groups {
+		tokenIDs[i], outputTokens[i], err = r.prepareTransfer(false, wallet, group.typ, group.values, group.owners, opt)
+		if err != nil {
+			r.unlockTokens(selected)
+			return nil, errors.Wrapf(err, "failed preparing transfer of type [%s]", group.typ)
+		}
+		selected = append(selected, tokenIDs[i]...)
+	}
+
+	// then generate the actions, appending them only if all of them succeed
+	numTransfers := len(r.Actions.Transfers)
+	numMetadata := len(r.Metadata.Transfers)
+	actions := make([]*TransferAction, len(groups))
+	for i, group := range groups {
+		actions[i], err = r.appendTransfer(wallet, tokenIDs[i], outputTokens[i], opt)
+		if err != nil {
+			r.Actions.Transfers = r.Actions.Transfers[:numTransfers]
+			r.Metadata.Transfers = r.Metadata.Transfers[:numMetadata]
+			r.unlockTokens(selected)
+			return nil, errors.Wrapf(err, "failed generating transfer of type [%s]", group.typ)
+		}
+	}
+	return actions, nil
+}
+
+// appendTransfer generates a transfer action, spending the passed tokens to create the passed outputs,
+// and appends it to the request
+func (r *Request) appendTransfer(wallet *OwnerWallet, tokenIDs []*token.ID, outputTokens []*token.Token, opt *TransferOptions) (*TransferAction, error) {
 	logger.Debugf("Prepare Transfer Action [id:%s,ins:%d,outs:%d]", r.Anchor, len(tokenIDs), len(outputTokens))
 
 	ts := r.TokenService.tms
@@ -1029,13 +1203,9 @@ func (r *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string,
 
 	// Select input tokens, if not passed as opt
 	if len(transferOpts.TokenIDs) == 0 {
-		selector := transferOpts.Selector
-		if selector == nil {
-			// resort to default strategy
-			selector, err = r.TokenService.SelectorManager().NewSelector(r.Anchor)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, "failed getting default selector")
-			}
+		selector, err := r.selector(transferOpts)
+		if err != nil {
+			return nil, nil, err
 		}
 		tokenIDs, inputSum, err = selector.Select(wallet, outputSum.Decimal(), typ)
 		if err != nil {
@@ -1072,6 +1242,86 @@ func (r *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string,
 	return tokenIDs, outputTokens, nil
 }
 
+// selector returns the token selector to use according to the passed options
+func (r *Request) selector(transferOpts *TransferOptions) (Selector, error) {
+	selector := transferOpts.Selector
+	switch {
+	case selector != nil && len(transferOpts.SelectionStrategy) != 0:
+		return nil, errors.Errorf("a selection strategy cannot be used with a custom selector")
+	case len(transferOpts.SelectionStrategy) != 0:
+		sm, ok := r.TokenService.SelectorManager().(StrategySelectorManager)
+		if !ok {
+			return nil, errors.Errorf("selector manager does not support selection strategies")
+		}
+		selector, err := sm.NewSelectorWithStrategy(r.Anchor, transferOpts.SelectionStrategy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed getting selector with strategy [%s]", transferOpts.SelectionStrategy)
+		}
+		return selector, nil
+	case selector == nil:
+		// resort to default strategy
+		selector, err := r.TokenService.SelectorManager().NewSelector(r.Anchor)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed getting default selector")
+		}
+		return selector, nil
+	}
+	return selector, nil
+}
+
+// unlockTokens releases the locks on the passed tokens, if the selector manager supports it.
+// Otherwise, the tokens remain locked until the locks bound to the request's anchor are released.
+func (r *Request) unlockTokens(ids []*token.ID) {
+	if len(ids) == 0 {
+		return
+	}
+	sm, ok := r.TokenService.SelectorManager().(TokenUnlocker)
+	if !ok {
+		logger.Warnf("selector manager cannot unlock single tokens, [%d] tokens remain locked by [%s]", len(ids), r.Anchor)
+		return
+	}
+	if err := sm.UnlockIDs(ids...); err != nil {
+		logger.Warnf("failed unlocking [%d] tokens locked by [%s]: [%s]", len(ids), r.Anchor, err)
+	}
+}
+
+// transferGroup collects the legs of a multi-type transfer with the same token type
+type transferGroup struct {
+	typ    string
+	values []uint64
+	owners []view.Identity
+}
+
+// groupTransferLegs groups the passed legs by token type, in order of first appearance
+func groupTransferLegs(legs []*TransferLeg) ([]*transferGroup, error) {
+	if len(legs) == 0 {
+		return nil, errors.Errorf("no legs passed")
+	}
+	var groups []*transferGroup
+	byType := map[string]*transferGroup{}
+	for i, leg := range legs {
+		switch {
+		case leg == nil:
+			return nil, errors.Errorf("leg [%d] is nil", i)
+		case len(leg.Type) == 0:
+			return nil, errors.Errorf("type of leg [%d] is empty", i)
+		case leg.Value == 0:
+			return nil, errors.Errorf("value of leg [%d] is zero", i)
+		case leg.Recipient.IsNone():
+			return nil, errors.Errorf("recipient of leg [%d] is not defined", i)
+		}
+		group, ok := byType[leg.Type]
+		if !ok {
+			group = &transferGroup{typ: leg.Type}
+			byType[leg.Type] = group
+			groups = append(groups, group)
+		}
+		group.values = append(group.values, leg.Value)
+		group.owners = append(group.owners, leg.Recipient)
+	}
+	return groups, nil
+}
+
 type requestSer struct {
 	TxID     string
 	Actions  []byte
@@ -9,9 +9,12 @@ package token
 import (
 	"testing"
 
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 func TestRequestSerialization(t *testing.T) {
@@ -43,3 +46,148 @@ func TestRequestSerialization(t *testing.T) {
 
 	assert.Equal(t, mRaw, mRaw2)
 }
+
+func TestGroupTransferLegs(t *testing.T) {
+	alice := view.Identity("alice")
+	bob := view.Identity("bob")
+	groups, err := groupTransferLegs([]*TransferLeg{
+		{Type: "USD", Value: 10, Recipient: alice},
+		{Type: "EUR", Value: 5, Recipient: bob},
+		{Type: "USD", Value: 3, Recipient: bob},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "USD", groups[0].typ)
+	assert.Equal(t, []uint64{10, 3}, groups[0].values)
+	assert.Equal(t, []view.Identity{alice, bob}, groups[0].owners)
+	assert.Equal(t, "EUR", groups[1].typ)
+	assert.Equal(t, []uint64{5}, groups[1].values)
+	assert.Equal(t, []view.Identity{bob}, groups[1].owners)
+
+	_, err = groupTransferLegs(nil)
+	assert.EqualError(t, err, "no legs passed")
+	_, err = groupTransferLegs([]*TransferLeg{{Type: "USD", Value: 0, Recipient: alice}})
+	assert.EqualError(t, err, "value of leg [0] is zero")
+	_, err = groupTransferLegs([]*TransferLeg{{Type: "", Value: 1, Recipient: alice}})
+	assert.EqualError(t, err, "type of leg [0] is empty")
+	_, err = groupTransferLegs([]*TransferLeg{{Type: "USD", Value: 1}})
+	assert.EqualError(t, err, "recipient of leg [0] is not defined")
+}
+
+type mockPublicParams struct {
+	driver.PublicParameters
+}
+
+func (m *mockPublicParams) Precision() uint64 { return 64 }
+
+func (m *mockPublicParams) GraphHiding() bool { return false }
+
+type mockPublicParamsManager struct {
+	driver.PublicParamsManager
+}
+
+func (m *mockPublicParamsManager) PublicParameters() driver.PublicParameters {
+	return &mockPublicParams{}
+}
+
+type mockTransferAction struct {
+	driver.TransferAction
+	typ string
+}
+
+func (m *mockTransferAction) Serialize() ([]byte, error) {
+	return []byte(m.typ), nil
+}
+
+// mockTMS generates the transfers of all the token types but the failing one
+type mockTMS struct {
+	driver.TokenManagerService
+	failingType string
+}
+
+func (m *mockTMS) PublicParamsManager() driver.PublicParamsManager {
+	return &mockPublicParamsManager{}
+}
+
+func (m *mockTMS) Transfer(txID string, wallet driver.OwnerWallet, ids []*token2.ID, outputs []*token2.Token, opts *driver.TransferOptions) (driver.TransferAction, *driver.TransferMetadata, error) {
+	if outputs[0].Type == m.failingType {
+		return nil, nil, errors.Errorf("cannot transfer [%s]", m.failingType)
+	}
+	return &mockTransferAction{typ: outputs[0].Type}, &driver.TransferMetadata{TokenIDs: ids}, nil
+}
+
+func (m *mockTMS) VerifyTransfer(tr driver.TransferAction, tokenInfos [][]byte) error {
+	return nil
+}
+
+// mockSelectorManager selects a token of the requested quantity and type, and records the unlocked tokens
+type mockSelectorManager struct {
+	unlocked []*token2.ID
+}
+
+func (m *mockSelectorManager) NewSelector(id string) (Selector, error) {
+	return m, nil
+}
+
+func (m *mockSelectorManager) Unlock(id string) error {
+	return nil
+}
+
+func (m *mockSelectorManager) UnlockIDs(ids ...*token2.ID) error {
+	m.unlocked = append(m.unlocked, ids...)
+	return nil
+}
+
+func (m *mockSelectorManager) Select(ownerFilter OwnerFilter, q, tokenType string) ([]*token2.ID, token2.Quantity, error) {
+	quantity, err := token2.ToQuantity(q, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []*token2.ID{{TxId: tokenType}}, quantity, nil
+}
+
+type mockSelectorManagerProvider struct {
+	sm SelectorManager
+}
+
+func (m *mockSelectorManagerProvider) SelectorManager(network string, channel string, namespace string) SelectorManager {
+	return m.sm
+}
+
+func TestMultiTransferRollback(t *testing.T) {
+	sm := &mockSelectorManager{}
+	tms := &ManagementService{
+		tms:                     &mockTMS{failingType: "EUR"},
+		selectorManagerProvider: &mockSelectorManagerProvider{sm: sm},
+	}
+	r := NewRequest(tms, "tx")
+	r.Actions.Transfers = [][]byte{[]byte("previous")}
+	r.Metadata.Transfers = []driver.TransferMetadata{{}}
+
+	// the first leg is generated, the second one fails
+	_, err := r.MultiTransfer(&OwnerWallet{}, []*TransferLeg{
+		{Type: "USD", Value: 10, Recipient: view.Identity("alice")},
+		{Type: "EUR", Value: 5, Recipient: view.Identity("bob")},
+	})
+	assert.EqualError(t, err, "failed generating transfer of type [EUR]: failed creating transfer action: cannot transfer [EUR]")
+	assert.Equal(t, [][]byte{[]byte("previous")}, r.Actions.Transfers)
+	assert.Len(t, r.Metadata.Transfers, 1)
+	assert.Equal(t, []*token2.ID{{TxId: "USD"}, {TxId: "EUR"}}, sm.unlocked)
+
+	// all the legs are generated
+	tms.tms = &mockTMS{}
+	sm.unlocked = nil
+	actions, err := r.MultiTransfer(&OwnerWallet{}, []*TransferLeg{
+		{Type: "USD", Value: 10, Recipient: view.Identity("alice")},
+		{Type: "EUR", Value: 5, Recipient: view.Identity("bob")},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, actions, 2)
+	assert.Equal(t, [][]byte{[]byte("previous"), []byte("USD"), []byte("EUR")}, r.Actions.Transfers)
+	assert.Len(t, r.Metadata.Transfers, 3)
+	assert.Empty(t, sm.unlocked)
+
+	r.Metadata = nil
+	_, err = r.MultiTransfer(&OwnerWallet{}, []*TransferLeg{{Type: "USD", Value: 10, Recipient: view.Identity("alice")}})
+	assert.EqualError(t, err, "failed to complete transfer: nil Metadata in token request")
+}
@@ -7,14 +7,29 @@ SPDX-License-Identifier: Apache-2.0
 package network
 
 import (
-	"fmt"
+	"database/sql"
+	"sync"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/orion"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector/inmemory"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector/kvs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MemoryLocker keeps the locks in memory, this is the default
+	MemoryLocker = "memory"
+	// KVSLocker persists the locks in the KVS of the node
+	KVSLocker = "kvs"
+	// SQLLocker persists the locks in a SQL database, that the replicas of a node can share
+	SQLLocker = "sql"
 )
 
 type FabricVault struct {
@@ -39,23 +54,101 @@ type LockerProvider struct {
 	sp                     view.ServiceProvider
 	sleepTimeout           time.Duration
 	validTxEvictionTimeout time.Duration
+
+	lock    sync.Mutex
+	sqlKVSs map[string]kvs.KVS
 }
 
 func NewLockerProvider(sp view.ServiceProvider, sleepTimeout time.Duration, validTxEvictionTimeout time.Duration) *LockerProvider {
-	return &LockerProvider{sp: sp, sleepTimeout: sleepTimeout, validTxEvictionTimeout: validTxEvictionTimeout}
+	return &LockerProvider{sp: sp, sleepTimeout: sleepTimeout, validTxEvictionTimeout: validTxEvictionTimeout, sqlKVSs: map[string]kvs.KVS{}}
+}
+
+func (s *LockerProvider) New(network string, channel string, namespace string) (selector.Locker, error) {
+	vault, err := s.vault(network, channel)
+	if err != nil {
+		return nil, err
+	}
+	cfg := s.selectorConfig(network, channel, namespace)
+	switch lockerType := lockerType(cfg); lockerType {
+	case KVSLocker:
+		locker, err := kvs.NewLocker(kvs.NewKVS(kvs2.GetService(s.sp)), vault, network, channel, namespace, s.sleepTimeout, s.validTxEvictionTimeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed creating kvs locker for [%s:%s:%s]", network, channel, namespace)
+		}
+		return locker, nil
+	case SQLLocker:
+		store, err := s.sqlKVS(cfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed creating sql locker for [%s:%s:%s]", network, channel, namespace)
+		}
+		locker, err := kvs.NewLocker(store, vault, network, channel, namespace, s.sleepTimeout, s.validTxEvictionTimeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed creating sql locker for [%s:%s:%s]", network, channel, namespace)
+		}
+		return locker, nil
+	case MemoryLocker:
+		return inmemory.NewLocker(vault, s.sleepTimeout, s.validTxEvictionTimeout), nil
+	default:
+		return nil, errors.Errorf("locker [%s] not supported for [%s:%s:%s]", lockerType, network, channel, namespace)
+	}
+}
+
+// selectorConfig returns the selector configuration of the passed TMS, nil if none is set
+func (s *LockerProvider) selectorConfig(network string, channel string, namespace string) *config2.Selector {
+	tms, err := config.NewTokenSDK(view.GetConfigService(s.sp)).GetTMS(network, channel, namespace)
+	if err != nil {
+		logger.Debugf("no configuration found for [%s:%s:%s], use the default locker: [%s]", network, channel, namespace, err)
+		return nil
+	}
+	return tms.TMS().Selector
+}
+
+// lockerType returns the type of locker set in the passed selector configuration, MemoryLocker if none is set
+func lockerType(cfg *config2.Selector) string {
+	if cfg == nil || len(cfg.Locker) == 0 {
+		return MemoryLocker
+	}
+	return cfg.Locker
+}
+
+// sqlKVS returns the KVS backed by the SQL database set in the passed selector configuration.
+// The database is opened once per data source, the TMSs sharing it share the connections.
+func (s *LockerProvider) sqlKVS(cfg *config2.Selector) (kvs.KVS, error) {
+	if len(cfg.Driver) == 0 || len(cfg.DataSource) == 0 {
+		return nil, errors.New("no sql driver or data source set")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := cfg.Driver + cfg.DataSource
+	if store, ok := s.sqlKVSs[key]; ok {
+		return store, nil
+	}
+	db, err := sql.Open(cfg.Driver, cfg.DataSource)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed opening sql db with driver [%s]", cfg.Driver)
+	}
+	store, err := kvs.NewSQLKVS(db, kvs.DefaultLocksTable)
+	if err != nil {
+		if err1 := db.Close(); err1 != nil {
+			logger.Errorf("failed closing sql db [%s]", err1)
+		}
+		return nil, err
+	}
+	s.sqlKVSs[key] = store
+	return store, nil
 }
 
-func (s *LockerProvider) New(network string, channel string, namespace string) selector.Locker {
+func (s *LockerProvider) vault(network string, channel string) (inmemory.Vault, error) {
 	fns := fabric.GetFabricNetworkService(s.sp, network)
 	if fns != nil {
 		ch, err := fns.Channel(channel)
 		if err == nil {
-			return inmemory.NewLocker(&FabricVault{Vault: ch.Vault()}, s.sleepTimeout, s.validTxEvictionTimeout)
+			return &FabricVault{Vault: ch.Vault()}, nil
 		}
 	}
 	ons := orion.GetOrionNetworkService(s.sp, network)
 	if ons == nil {
-		panic(fmt.Sprintf("network %s not found", network))
+		return nil, errors.Errorf("network %s not found", network)
 	}
-	return inmemory.NewLocker(&OrionVault{Vault: ons.Vault()}, s.sleepTimeout, s.validTxEvictionTimeout)
+	return &OrionVault{Vault: ons.Vault()}, nil
 }
@@ -11,9 +11,13 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/orion"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	network2 "github.com/hyperledger-labs/fabric-token-sdk/token/sdk/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/fractional"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/escrow"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/multisig"
 	fabric2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/fabric"
 	orion2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/orion"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timelock"
 	"github.com/pkg/errors"
 )
 
@@ -35,7 +39,7 @@ func (p *ProcessorManager) New(network, channel, namespace string) error {
 				ons,
 				namespace,
 				p.sp,
-				network2.NewAuthorizationMultiplexer(&network2.TMSAuthorization{}, &htlc.ScriptOwnership{}),
+				network2.NewAuthorizationMultiplexer(&network2.TMSAuthorization{}, &htlc.ScriptOwnership{}, &escrow.ScriptOwnership{}, &multisig.Ownership{}, &timelock.ScriptOwnership{}, &fractional.ScriptOwnership{}),
 				network2.NewIssuedMultiplexer(&network2.WalletIssued{}),
 			),
 		); err != nil {
@@ -50,7 +54,7 @@ func (p *ProcessorManager) New(network, channel, namespace string) error {
 			n,
 			namespace,
 			p.sp,
-			network2.NewAuthorizationMultiplexer(&network2.TMSAuthorization{}, &htlc.ScriptOwnership{}),
+			network2.NewAuthorizationMultiplexer(&network2.TMSAuthorization{}, &htlc.ScriptOwnership{}, &escrow.ScriptOwnership{}, &multisig.Ownership{}, &timelock.ScriptOwnership{}, &fractional.ScriptOwnership{}),
 			network2.NewIssuedMultiplexer(&network2.WalletIssued{}),
 		),
 	); err != nil {
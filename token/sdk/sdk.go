@@ -20,21 +20,31 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fractional"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/escrow"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/multisig"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/timelock"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh/driver"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
 	network2 "github.com/hyperledger-labs/fabric-token-sdk/token/sdk/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/sdk/vault"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/dummy"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/interactive"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/fabric"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/orion"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/query"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/badger"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/memory"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/sql"
 )
 
 var logger = flogging.MustGetLogger("token-sdk")
@@ -47,6 +57,7 @@ type Registry interface {
 
 type SDK struct {
 	registry       Registry
+	ttxdbManager   *ttxdb.Manager
 	auditorManager *auditor.Manager
 	ownerManager   *owner.Manager
 }
@@ -93,7 +104,9 @@ func (p *SDK) Install() error {
 	assert.NoError(p.registry.RegisterService(network.NewProvider(p.registry)))
 
 	// Token Transaction DB and derivatives
-	assert.NoError(p.registry.RegisterService(ttxdb.NewManager(p.registry, "")))
+	p.ttxdbManager = ttxdb.NewManager(p.registry, "")
+	p.ttxdbManager.SetUniqueIDExtractors(nfttx.UniqueIDs)
+	assert.NoError(p.registry.RegisterService(p.ttxdbManager))
 	p.auditorManager = auditor.NewManager(p.registry, kvs.GetService(p.registry))
 	assert.NoError(p.registry.RegisterService(p.auditorManager))
 	p.ownerManager = owner.NewManager(p.registry, kvs.GetService(p.registry))
@@ -125,6 +138,7 @@ func (p *SDK) Start(ctx context.Context) error {
 	if err != nil {
 		return errors.WithMessagef(err, "failed get the TMS configurations")
 	}
+	var reclaimServices []*htlc.ReclaimService
 	for _, tmsConfig := range tmsConfigs {
 		tmsID := token.TMSID{
 			Network:   tmsConfig.TMS().Network,
@@ -135,8 +149,17 @@ func (p *SDK) Start(ctx context.Context) error {
 		if tms == nil {
 			return errors.Errorf("failed to load configured TMS [%s]", tmsID)
 		}
+		if cfg := tmsConfig.TMS().HTLC; cfg != nil && cfg.Reclaim != nil && cfg.Reclaim.Enabled {
+			reclaimServices = append(reclaimServices, htlc.NewReclaimService(p.registry, tmsID, reclaimWallets(tmsConfig.TMS()), cfg.Reclaim))
+		}
 	}
 
+	// the periodic compaction of the token transaction dbs ends with the platform
+	go func() {
+		<-ctx.Done()
+		p.ttxdbManager.Stop()
+	}()
+
 	// restore owner and auditor dbs, if any
 	if err := p.ownerManager.Restore(); err != nil {
 		return errors.WithMessagef(err, "failed to restore onwer dbs")
@@ -145,6 +168,26 @@ func (p *SDK) Start(ctx context.Context) error {
 		return errors.WithMessagef(err, "failed to restore auditor dbs")
 	}
 
+	// the expired htlc-tokens are reclaimed once the owner dbs are restored
+	for _, s := range reclaimServices {
+		s.Start(ctx)
+	}
+
 	logger.Infof("Token platform enabled, starting...done")
 	return nil
 }
+
+// reclaimWallets returns the wallets whose expired htlc-tokens are reclaimed in the background,
+// the configured ones or, if none, all the owner wallets of the passed TMS
+func reclaimWallets(tms *config2.TMS) []string {
+	if len(tms.HTLC.Reclaim.Wallets) != 0 {
+		return tms.HTLC.Reclaim.Wallets
+	}
+	var wallets []string
+	if tms.Wallets != nil {
+		for _, owner := range tms.Wallets.Owners {
+			wallets = append(wallets, owner.ID)
+		}
+	}
+	return wallets
+}
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -25,6 +27,42 @@ var (
 	SelectorSufficientFundsButConcurrencyIssue = errors.New("sufficient funds but concurrency issue")
 )
 
+// SelectionError describes the outcome of a failed token selection.
+// Its cause is one of the Selector errors above, therefore errors.Cause keeps telling them apart,
+// while errors.As gives access to the details.
+type SelectionError struct {
+	// Err is the Selector error describing the failure
+	Err error
+	// TokenType is the type of the tokens requested
+	TokenType string
+	// Requested is the quantity requested
+	Requested token2.Quantity
+	// Available is the sum of the tokens that could be selected in the last attempt
+	Available token2.Quantity
+	// Locked is the sum of the tokens locked by other transactions in the last attempt
+	Locked token2.Quantity
+	// NonCertified is the sum of the tokens that could not be used because not yet certified in the last attempt
+	NonCertified token2.Quantity
+	// Retries is the number of attempts made after the first one
+	Retries int
+	// Waited is the time spent waiting between the attempts
+	Waited time.Duration
+}
+
+func (e *SelectionError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the Selector error describing the failure
+func (e *SelectionError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the Selector error describing the failure
+func (e *SelectionError) Unwrap() error {
+	return e.Err
+}
+
 // OwnerFilter tells if a passed identity is recognized
 type OwnerFilter interface {
 	// ID is the wallet identifier of the owner
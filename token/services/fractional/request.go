@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Fractionalize appends to the passed token request the issue, by the passed issuer wallet, of the passed
+// number of shares of the passed NFT to the passed recipient, and the lock of the NFT, owned by the passed wallet,
+// in a custody script naming the passed custodian.
+// Either both actions are appended or none.
+// Fractional NFTs are supported by the fabtoken driver only, the zkatdlog validator rejects the custody scripts.
+func Fractionalize(request *token.Request, wallet *token.OwnerWallet, nft *token2.UnspentToken, custodian view.Identity, issuer *token.IssuerWallet, shares uint64, recipient view.Identity) (*Script, error) {
+	if err := checkNFT(request, nft); err != nil {
+		return nil, err
+	}
+	owner, err := Wrap(custodian, nft.Type, shares)
+	if err != nil {
+		return nil, err
+	}
+	script, _, err := Unwrap(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	numIssues := len(request.Actions.Issues)
+	numMetadata := len(request.Metadata.Issues)
+	if _, err := request.Issue(issuer, recipient, script.ShareType, shares); err != nil {
+		return nil, errors.WithMessagef(err, "failed issuing shares of type [%s]", script.ShareType)
+	}
+	if _, err := request.Transfer(wallet, nft.Type, []uint64{1}, []view.Identity{owner}, token.WithTokenIDs(nft.Id)); err != nil {
+		request.Actions.Issues = request.Actions.Issues[:numIssues]
+		request.Metadata.Issues = request.Metadata.Issues[:numMetadata]
+		return nil, errors.WithMessage(err, "failed locking the NFT in custody")
+	}
+	return script, nil
+}
+
+// Reassemble appends to the passed token request the redeem of all the shares of the passed NFT,
+// owned by the passed holder wallet, and the release of the NFT, kept by the passed custodian wallet,
+// to the passed recipient.
+// All the shares must be owned by the holder wallet, the shares held by other wallets must be transferred to it first.
+// Either both actions are appended or none.
+func Reassemble(request *token.Request, holder *token.OwnerWallet, custodian *token.OwnerWallet, nft *token2.UnspentToken, recipient view.Identity) error {
+	if err := checkNFT(request, nft); err != nil {
+		return err
+	}
+	script, ok, err := Unwrap(nft.Owner.Raw)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.Errorf("token [%s] is not in custody", nft.Id)
+	}
+
+	numTransfers := len(request.Actions.Transfers)
+	numMetadata := len(request.Metadata.Transfers)
+	if err := request.Redeem(holder, script.ShareType, script.Shares); err != nil {
+		return errors.WithMessagef(err, "failed redeeming the shares of type [%s]", script.ShareType)
+	}
+	if _, err := request.Transfer(custodian, nft.Type, []uint64{1}, []view.Identity{recipient}, token.WithTokenIDs(nft.Id)); err != nil {
+		request.Actions.Transfers = request.Actions.Transfers[:numTransfers]
+		request.Metadata.Transfers = request.Metadata.Transfers[:numMetadata]
+		return errors.WithMessage(err, "failed releasing the NFT from custody")
+	}
+	return nil
+}
+
+// checkNFT checks that the passed token is a single unit
+func checkNFT(request *token.Request, nft *token2.UnspentToken) error {
+	if nft == nil {
+		return errors.New("NFT not specified")
+	}
+	precision := request.TokenService.PublicParametersManager().Precision()
+	q, err := token2.ToQuantity(nft.Quantity, precision)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert quantity [%s]", nft.Quantity)
+	}
+	if q.Cmp(token2.NewOneQuantity(precision)) != 0 {
+		return errors.Errorf("token [%s] is not an NFT, quantity is [%s]", nft.Id, nft.Quantity)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	ScriptType = "fractional" // custody script of a fractional NFT
+
+	shareTypePrefix = "share:"
+)
+
+// Script contains the details of the custody of a fractional NFT.
+// The NFT owned by the script backs Shares units of the fungible ShareType.
+// It can be spent by the custodian only in a transaction that redeems all the shares.
+type Script struct {
+	Custodian view.Identity
+	ShareType string
+	Shares    uint64
+}
+
+// Validate performs the following checks:
+// - The custodian must be set
+// - The share type must be a share type
+// - The number of shares must be positive
+func (s *Script) Validate() error {
+	if s.Custodian.IsNone() {
+		return errors.New("custodian not set")
+	}
+	if !IsShareType(s.ShareType) {
+		return errors.Errorf("invalid share type [%s]", s.ShareType)
+	}
+	if s.Shares == 0 {
+		return errors.New("number of shares not set")
+	}
+	return nil
+}
+
+// ShareType returns the fungible token type of the shares of the NFT of the passed type
+func ShareType(nftType string) string {
+	digest := sha256.Sum256([]byte(nftType))
+	return shareTypePrefix + hex.EncodeToString(digest[:])
+}
+
+// IsShareType returns true if the passed token type is the type of the shares of an NFT
+func IsShareType(typ string) bool {
+	return strings.HasPrefix(typ, shareTypePrefix)
+}
+
+// Wrap returns the owner of the NFT of the passed type, kept by the passed custodian and split into the passed number of shares
+func Wrap(custodian view.Identity, nftType string, shares uint64) (view.Identity, error) {
+	script := &Script{Custodian: custodian, ShareType: ShareType(nftType), Shares: shares}
+	if err := script.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid custody script")
+	}
+	raw, err := json.Marshal(script)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling custody script")
+	}
+	return identity.MarshallRawOwner(&identity.RawOwner{Type: ScriptType, Identity: raw})
+}
+
+// Unwrap returns the custody script contained in the passed raw owner.
+// The returned flag is false if the passed identity is not a custody script.
+func Unwrap(raw []byte) (*Script, bool, error) {
+	owner, err := identity.UnmarshallRawOwner(raw)
+	if err != nil || owner.Type != ScriptType {
+		return nil, false, nil
+	}
+	script := &Script{}
+	if err := json.Unmarshal(owner.Identity, script); err != nil {
+		return nil, true, errors.Wrap(err, "failed to unmarshal RawOwner as a custody script")
+	}
+	return script, true, nil
+}
+
+// IsInCustody returns true if the passed raw owner is a custody script
+func IsInCustody(raw []byte) bool {
+	_, ok, _ := Unwrap(raw)
+	return ok
+}
+
+// ScriptOwnership implements the Ownership interface for custody scripts
+type ScriptOwnership struct{}
+
+// AmIAnAuditor returns false for script ownership
+func (s *ScriptOwnership) AmIAnAuditor(tms *token.ManagementService) bool {
+	return false
+}
+
+// IsMine returns true if one is the custodian of a custody script.
+// The NFT belongs to the wallet of the custodian, where it is never selected.
+func (s *ScriptOwnership) IsMine(tms *token.ManagementService, tok *token3.Token) ([]string, bool) {
+	script, ok, err := Unwrap(tok.Owner.Raw)
+	if !ok || err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, not a custody script [%v]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+	if err := script.Validate(); err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, invalid content [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+	wallet := tms.WalletManager().OwnerWalletByIdentity(script.Custodian)
+	if wallet == nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, custodian not found", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity)
+		return nil, false
+	}
+	logger.Debugf("Is Mine [%s,%s,%s]? Yes, wallet [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, wallet.ID())
+	return []string{wallet.ID()}, true
+}
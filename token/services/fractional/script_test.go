@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapUnwrap(t *testing.T) {
+	raw, err := Wrap(view.Identity("alice"), "house", 100)
+	assert.NoError(t, err)
+	script, ok, err := Unwrap(raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, view.Identity("alice"), script.Custodian)
+	assert.Equal(t, ShareType("house"), script.ShareType)
+	assert.Equal(t, uint64(100), script.Shares)
+	assert.True(t, IsInCustody(raw))
+
+	// not a custody script
+	_, ok, err = Unwrap([]byte("alice"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, IsInCustody([]byte("alice")))
+
+	// invalid scripts
+	_, err = Wrap(nil, "house", 100)
+	assert.Error(t, err)
+	_, err = Wrap(view.Identity("alice"), "house", 0)
+	assert.Error(t, err)
+}
+
+func TestShareType(t *testing.T) {
+	assert.Equal(t, ShareType("house"), ShareType("house"))
+	assert.NotEqual(t, ShareType("house"), ShareType("villa"))
+	assert.True(t, IsShareType(ShareType("house")))
+	assert.False(t, IsShareType("house"))
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fractional
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+type QueryEngine interface {
+	// UnspentTokensIteratorBy returns an iterator over all unspent tokens by type and id. Type can be empty
+	UnspentTokensIteratorBy(id, typ string) (driver.UnspentTokensIterator, error)
+}
+
+// CustodyToken is an NFT in custody together with its custody script
+type CustodyToken struct {
+	*token2.UnspentToken
+	Script *Script
+}
+
+// CustodianWallet is a combination of a wallet and a query service
+type CustodianWallet struct {
+	wallet       *token.OwnerWallet
+	queryService QueryEngine
+}
+
+// ListInCustody returns the NFTs in the custody of this wallet that match the passed options
+func (w *CustodianWallet) ListInCustody(opts ...token.ListTokensOption) ([]*CustodyToken, error) {
+	compiledOpts, err := token.CompileListTokensOption(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compile options")
+	}
+	it, err := w.queryService.UnspentTokensIteratorBy(w.wallet.ID(), compiledOpts.TokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get iterator over unspent tokens")
+	}
+	defer it.Close()
+	var tokens []*CustodyToken
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get next unspent token from iterator")
+		}
+		if tok == nil {
+			break
+		}
+		script, ok, err := Unwrap(tok.Owner.Raw)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			logger.Debugf("token [%s] contains a valid custody script? No [%s]", tok.Id, err)
+			continue
+		}
+		tokens = append(tokens, &CustodyToken{UnspentToken: tok, Script: script})
+	}
+	return tokens, nil
+}
+
+// Wallet returns a CustodianWallet which contains a wallet and a query service
+func Wallet(sp view2.ServiceProvider, wallet *token.OwnerWallet, opts ...token.ServiceOption) *CustodianWallet {
+	tms := token.GetManagementService(sp, opts...)
+	nw := network.GetInstance(sp, tms.Network(), tms.Channel())
+	if nw == nil {
+		return nil
+	}
+	vault, err := nw.Vault(tms.Namespace())
+	if err != nil {
+		logger.Errorf("failed to get vault for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
+		return nil
+	}
+	return &CustodianWallet{
+		wallet:       wallet,
+		queryService: vault.TokenVault().QueryEngine(),
+	}
+}
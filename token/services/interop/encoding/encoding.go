@@ -18,6 +18,8 @@ const (
 	None Encoding = iota
 	Base64
 	Hex
+	// Hex0x is the lowercase hexadecimal encoding prefixed by 0x, as used by Ethereum tooling
+	Hex0x
 	maxEncoding
 )
 
@@ -43,6 +45,8 @@ func (e Encoding) String() string {
 		return "Base64"
 	case Hex:
 		return "Hex"
+	case Hex0x:
+		return "Hex0x"
 	default:
 		return "unknown Encoding value " + strconv.Itoa(int(e))
 	}
@@ -89,6 +93,10 @@ func init() {
 	RegisterEncoding(Hex, func() EncodingFunc {
 		return hexEncoding
 	})
+	hex0xEncoding := &hex0xEncoding{}
+	RegisterEncoding(Hex0x, func() EncodingFunc {
+		return hex0xEncoding
+	})
 }
 
 type hexEncoding struct{}
@@ -97,6 +105,12 @@ func (h hexEncoding) EncodeToString(src []byte) string {
 	return hex.EncodeToString(src)
 }
 
+type hex0xEncoding struct{}
+
+func (h hex0xEncoding) EncodeToString(src []byte) string {
+	return "0x" + hex.EncodeToString(src)
+}
+
 type noneEncoding struct{}
 
 func (n noneEncoding) EncodeToString(src []byte) string {
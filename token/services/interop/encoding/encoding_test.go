@@ -37,3 +37,12 @@ func TestEncodingHex(t *testing.T) {
 	o2 := hex.EncodeToString(msg)
 	assert.Equal(t, o1, o2)
 }
+
+func TestEncodingHex0x(t *testing.T) {
+	msg := []byte("hello world")
+	e := encoding.Hex0x.New()
+	o1 := e.EncodeToString(msg)
+	o2 := "0x" + hex.EncodeToString(msg)
+	assert.Equal(t, o1, o2)
+	assert.Equal(t, "Hex0x", encoding.Hex0x.String())
+}
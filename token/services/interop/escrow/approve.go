@@ -0,0 +1,191 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	"github.com/pkg/errors"
+)
+
+// Cosignature is the signature of a party of an escrow script on the release of an escrow-token
+type Cosignature struct {
+	// Owner is the owner of the released escrow-token
+	Owner view.Identity
+	// Party is the party of the escrow script that signed
+	Party view.Identity
+	// Signature is the signature of the party
+	Signature []byte
+}
+
+type collectApprovalView struct {
+	tx       *Transaction
+	approver view.Identity
+}
+
+// NewCollectApprovalView returns a view that collects, from the passed approver, the signatures
+// of a second party of the escrow scripts of the escrow-tokens released by the passed transaction.
+// The view does the following:
+// 1. It sends the transaction to the approver, that is expected to run the view returned by NewApproveView.
+// 2. It verifies the received signatures and adds them to the signers registered by Transaction.Release.
+// The view must run after the transaction has been assembled and before its endorsements are collected.
+func NewCollectApprovalView(tx *Transaction, approver view.Identity) *collectApprovalView {
+	return &collectApprovalView{tx: tx, approver: approver}
+}
+
+func (c *collectApprovalView) Call(context view.Context) (interface{}, error) {
+	if len(c.tx.signers) == 0 {
+		return nil, errors.New("the transaction releases no escrow-token")
+	}
+	message, err := c.tx.MessageToSign()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.tx.Bytes()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed marshalling transaction")
+	}
+
+	session, err := context.GetSession(context.Initiator(), c.approver)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting session")
+	}
+	ch := session.Receive()
+	if err := session.Send(raw); err != nil {
+		return nil, errors.Wrap(err, "failed sending transaction")
+	}
+	timeout := time.NewTimer(time.Minute)
+	defer timeout.Stop()
+	var msg *view.Message
+	select {
+	case msg = <-ch:
+	case <-timeout.C:
+		return nil, errors.Errorf("timeout from approver [%s]", c.approver)
+	}
+	if msg.Status == view.ERROR {
+		return nil, errors.New(string(msg.Payload))
+	}
+	var cosignatures []*Cosignature
+	if err := json.Unmarshal(msg.Payload, &cosignatures); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling cosignatures")
+	}
+
+	sigService := c.tx.TokenService().SigService()
+	approved := map[string]bool{}
+	for _, cosignature := range cosignatures {
+		key := cosignature.Owner.UniqueID()
+		signer, ok := c.tx.signers[key]
+		if !ok {
+			return nil, errors.Errorf("unexpected cosignature for [%s]", cosignature.Owner)
+		}
+		if signer.Party.Equal(cosignature.Party) {
+			return nil, errors.Errorf("the cosignature for [%s] comes from the local party", cosignature.Owner)
+		}
+		verifier, err := sigService.OwnerVerifier(cosignature.Party)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting verifier for [%s]", cosignature.Party)
+		}
+		if err := verifier.Verify(message, cosignature.Signature); err != nil {
+			return nil, errors.WithMessagef(err, "failed verifying cosignature of [%s]", cosignature.Party)
+		}
+		if err := signer.AddCosignature(cosignature.Party, cosignature.Signature); err != nil {
+			return nil, err
+		}
+		approved[key] = true
+	}
+	for key := range c.tx.signers {
+		if !approved[key] {
+			return nil, errors.Errorf("the release of [%s] has not been approved", key)
+		}
+	}
+	return nil, nil
+}
+
+// ReceiveReleaseRequest receives a transaction releasing escrow-tokens, sent by NewCollectApprovalView
+func ReceiveReleaseRequest(context view.Context) (*Transaction, error) {
+	tx, err := ttx.ReceiveTransaction(context)
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{Transaction: tx, signers: map[string]*Signer{}}, nil
+}
+
+type approveView struct {
+	tx *Transaction
+}
+
+// NewApproveView returns a view that signs the release of the escrow-tokens in the passed transaction,
+// received with ReceiveReleaseRequest, as a party of their escrow scripts. The caller is expected to
+// inspect the transaction before running this view.
+// For each escrow-token released by the transaction, the view signs as the first party of the escrow script
+// whose signer is available locally, and sends back the signatures.
+func NewApproveView(tx *Transaction) *approveView {
+	return &approveView{tx: tx}
+}
+
+func (a *approveView) Call(context view.Context) (interface{}, error) {
+	session := context.Session()
+	cosignatures, err := a.sign()
+	if err != nil {
+		if err2 := session.SendError([]byte(err.Error())); err2 != nil {
+			logger.Errorf("failed sending error to the releaser: [%s]", err2)
+		}
+		return nil, err
+	}
+	raw, err := json.Marshal(cosignatures)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling cosignatures")
+	}
+	if err := session.Send(raw); err != nil {
+		return nil, errors.Wrap(err, "failed sending cosignatures")
+	}
+	return nil, nil
+}
+
+func (a *approveView) sign() ([]*Cosignature, error) {
+	message, err := a.tx.MessageToSign()
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := a.tx.EscrowInputs()
+	if err != nil {
+		return nil, err
+	}
+	if inputs.Count() == 0 {
+		return nil, errors.New("the transaction releases no escrow-token")
+	}
+	sigService := a.tx.TokenService().SigService()
+	var cosignatures []*Cosignature
+	for i := 0; i < inputs.Count(); i++ {
+		owner := inputs.At(i).Owner
+		script, err := ScriptFromOwner(owner)
+		if err != nil {
+			return nil, err
+		}
+		signed := false
+		for _, party := range script.Parties() {
+			signer, err := sigService.GetSigner(party)
+			if err != nil {
+				continue
+			}
+			sigma, err := signer.Sign(message)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed signing release of [%s]", inputs.At(i).Id)
+			}
+			cosignatures = append(cosignatures, &Cosignature{Owner: owner, Party: party, Signature: sigma})
+			signed = true
+			break
+		}
+		if !signed {
+			return nil, errors.Errorf("no party of the escrow script of [%s] can sign locally", inputs.At(i).Id)
+		}
+	}
+	return cosignatures, nil
+}
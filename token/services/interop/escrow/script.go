@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	ScriptType = "escrow" // escrow script
+	// Threshold is the number of parties whose signatures release an escrow-token
+	Threshold = 2
+	// enrollmentIDPrefix prefixes the enrollment ID of an escrow script
+	enrollmentIDPrefix = "escrow:"
+)
+
+// Script contains the details of an escrow.
+// An escrow-token is released either to the buyer or to the seller when any two of the three parties sign.
+type Script struct {
+	Buyer      view.Identity
+	Seller     view.Identity
+	Arbitrator view.Identity
+}
+
+// Validate performs the following checks:
+// - The buyer, the seller, and the arbitrator must be set
+// - The three parties must be distinct
+func (s *Script) Validate() error {
+	if s.Buyer.IsNone() {
+		return errors.New("buyer not set")
+	}
+	if s.Seller.IsNone() {
+		return errors.New("seller not set")
+	}
+	if s.Arbitrator.IsNone() {
+		return errors.New("arbitrator not set")
+	}
+	if s.Buyer.Equal(s.Seller) || s.Buyer.Equal(s.Arbitrator) || s.Seller.Equal(s.Arbitrator) {
+		return errors.New("buyer, seller, and arbitrator must be distinct")
+	}
+	return nil
+}
+
+// Parties returns the buyer, the seller, and the arbitrator, in this order
+func (s *Script) Parties() []view.Identity {
+	return []view.Identity{s.Buyer, s.Seller, s.Arbitrator}
+}
+
+// IsBeneficiary returns true if the passed identity is either the buyer or the seller,
+// the only parties an escrow-token can be released to
+func (s *Script) IsBeneficiary(id view.Identity) bool {
+	return s.Buyer.Equal(id) || s.Seller.Equal(id)
+}
+
+// EnrollmentID returns the enrollment ID of an escrow script, given the enrollment IDs of its buyer, seller,
+// and arbitrator. An escrow-token belongs to none of them until it is released.
+func EnrollmentID(buyer, seller, arbitrator string) string {
+	return enrollmentIDPrefix + strings.Join([]string{buyer, seller, arbitrator}, ",")
+}
+
+// ScriptFromOwner returns the escrow script contained in the passed raw owner
+func ScriptFromOwner(raw []byte) (*Script, error) {
+	owner, err := identity.UnmarshallRawOwner(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal owner")
+	}
+	if owner.Type != ScriptType {
+		return nil, errors.Errorf("invalid owner type, expected escrow script, got [%s]", owner.Type)
+	}
+	script := &Script{}
+	if err := json.Unmarshal(owner.Identity, script); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal RawOwner as an escrow script")
+	}
+	return script, nil
+}
+
+// ScriptOwnership implements the Ownership interface for escrow scripts
+type ScriptOwnership struct{}
+
+// AmIAnAuditor returns false for script ownership
+func (s *ScriptOwnership) AmIAnAuditor(tms *token.ManagementService) bool {
+	return false
+}
+
+// IsMine returns true if one is either the buyer, the seller, or the arbitrator of an escrow script
+func (s *ScriptOwnership) IsMine(tms *token.ManagementService, tok *token3.Token) ([]string, bool) {
+	script, err := ScriptFromOwner(tok.Owner.Raw)
+	if err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, not an escrow script [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+	if err := script.Validate(); err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, invalid content [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+
+	var ids []string
+	for _, party := range script.Parties() {
+		if wallet := tms.WalletManager().OwnerWalletByIdentity(party); wallet != nil {
+			id := escrowWallet(wallet)
+			if !contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	logger.Debugf("Is Mine [%s,%s,%s]? %v", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, len(ids) != 0)
+	return ids, len(ids) != 0
+}
+
+func escrowWallet(w *token.OwnerWallet) string {
+	return "escrow" + w.ID()
+}
+
+func contains(ids []string, id string) bool {
+	for _, s := range ids {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+var roles = []string{"buyer", "seller", "arbitrator"}
+
+// Signature is the signature releasing an escrow-token, it carries the signatures of at least two parties
+type Signature struct {
+	Buyer      []byte
+	Seller     []byte
+	Arbitrator []byte
+}
+
+// signatures returns the signatures of the buyer, the seller, and the arbitrator, in this order
+func (s *Signature) signatures() [][]byte {
+	return [][]byte{s.Buyer, s.Seller, s.Arbitrator}
+}
+
+// set sets the signature of the party at the passed index of Script.Parties
+func (s *Signature) set(index int, sigma []byte) {
+	switch index {
+	case 0:
+		s.Buyer = sigma
+	case 1:
+		s.Seller = sigma
+	case 2:
+		s.Arbitrator = sigma
+	}
+}
+
+// partyIndex returns the index of the passed party in Script.Parties, -1 if not a party
+func partyIndex(script *Script, party view.Identity) int {
+	for i, p := range script.Parties() {
+		if p.Equal(party) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Signer produces the signature releasing an escrow-token. It combines the signature of a local party
+// with the co-signatures collected from the other parties on the same message.
+type Signer struct {
+	Script *Script
+	Party  view.Identity
+	Signer driver.Signer
+
+	lock         sync.Mutex
+	cosignatures map[int][]byte
+}
+
+// NewSigner returns a signer for the passed escrow script, signing as the passed party
+func NewSigner(script *Script, party view.Identity, signer driver.Signer) (*Signer, error) {
+	if partyIndex(script, party) < 0 {
+		return nil, errors.Errorf("[%s] is not a party of the escrow script", party)
+	}
+	return &Signer{Script: script, Party: party, Signer: signer, cosignatures: map[int][]byte{}}, nil
+}
+
+// AddCosignature adds the signature of another party of the escrow script
+func (s *Signer) AddCosignature(party view.Identity, sigma []byte) error {
+	index := partyIndex(s.Script, party)
+	if index < 0 {
+		return errors.Errorf("[%s] is not a party of the escrow script", party)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cosignatures[index] = sigma
+	return nil
+}
+
+// Sign signs the passed message as the local party and returns the Signature including the co-signatures
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	sigma, err := s.Signer.Sign(message)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed signing as the %s", roles[partyIndex(s.Script, s.Party)])
+	}
+	own := partyIndex(s.Script, s.Party)
+	sig := &Signature{}
+	count := 1
+	s.lock.Lock()
+	for index, cosignature := range s.cosignatures {
+		if index == own {
+			continue
+		}
+		sig.set(index, cosignature)
+		count++
+	}
+	s.lock.Unlock()
+	sig.set(own, sigma)
+	if count < Threshold {
+		return nil, errors.Errorf("not enough signatures to release the escrow-token, [%d] out of [%d]", count, Threshold)
+	}
+	return json.Marshal(sig)
+}
+
+// Verifier checks that an escrow-token is released by at least two of its parties
+type Verifier struct {
+	Buyer      driver.Verifier
+	Seller     driver.Verifier
+	Arbitrator driver.Verifier
+}
+
+// Verify checks that the passed Signature carries at least two valid signatures of distinct parties.
+// Any signature present must be valid.
+func (v *Verifier) Verify(message []byte, sigma []byte) error {
+	sig := &Signature{}
+	if err := json.Unmarshal(sigma, sig); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal escrow signature")
+	}
+	verifiers := []driver.Verifier{v.Buyer, v.Seller, v.Arbitrator}
+	valid := 0
+	for i, s := range sig.signatures() {
+		if len(s) == 0 {
+			continue
+		}
+		if err := verifiers[i].Verify(message, s); err != nil {
+			return errors.WithMessagef(err, "failed verifying the signature of the %s", roles[i])
+		}
+		valid++
+	}
+	if valid < Threshold {
+		return errors.Errorf("escrow-token released by [%d] parties, at least [%d] expected", valid, Threshold)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// party signs by prefixing the message with its name
+type party string
+
+func (p party) Sign(message []byte) ([]byte, error) {
+	return append([]byte(p), message...), nil
+}
+
+func (p party) Verify(message, sigma []byte) error {
+	if !bytes.Equal(append([]byte(p), message...), sigma) {
+		return errors.Errorf("invalid signature of [%s]", p)
+	}
+	return nil
+}
+
+func TestScriptValidate(t *testing.T) {
+	script := &Script{Buyer: view.Identity("buyer"), Seller: view.Identity("seller"), Arbitrator: view.Identity("arbitrator")}
+	assert.NoError(t, script.Validate())
+	assert.True(t, script.IsBeneficiary(view.Identity("buyer")))
+	assert.True(t, script.IsBeneficiary(view.Identity("seller")))
+	assert.False(t, script.IsBeneficiary(view.Identity("arbitrator")))
+
+	assert.Error(t, (&Script{Buyer: view.Identity("buyer"), Seller: view.Identity("seller")}).Validate())
+	assert.Error(t, (&Script{Buyer: view.Identity("buyer"), Seller: view.Identity("buyer"), Arbitrator: view.Identity("arbitrator")}).Validate())
+}
+
+func TestTwoOfThree(t *testing.T) {
+	script := &Script{Buyer: view.Identity("buyer"), Seller: view.Identity("seller"), Arbitrator: view.Identity("arbitrator")}
+	verifier := &Verifier{Buyer: party("buyer"), Seller: party("seller"), Arbitrator: party("arbitrator")}
+	message := []byte("request")
+
+	// a single party cannot release
+	signer, err := NewSigner(script, script.Arbitrator, party("arbitrator"))
+	assert.NoError(t, err)
+	_, err = signer.Sign(message)
+	assert.Error(t, err)
+	single, err := json.Marshal(&Signature{Arbitrator: []byte("arbitratorrequest")})
+	assert.NoError(t, err)
+	assert.Error(t, verifier.Verify(message, single))
+
+	// two parties can
+	assert.NoError(t, signer.AddCosignature(script.Seller, []byte("sellerrequest")))
+	sigma, err := signer.Sign(message)
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.Verify(message, sigma))
+
+	// an invalid signature is rejected, even if two valid ones are present
+	assert.NoError(t, signer.AddCosignature(script.Buyer, []byte("forged")))
+	sigma, err = signer.Sign(message)
+	assert.NoError(t, err)
+	assert.Error(t, verifier.Verify(message, sigma))
+
+	// only parties can sign
+	_, err = NewSigner(script, view.Identity("mallory"), party("mallory"))
+	assert.Error(t, err)
+	assert.Error(t, signer.AddCosignature(view.Identity("mallory"), []byte("mallory")))
+}
+
+func TestEnrollmentID(t *testing.T) {
+	assert.Equal(t, "escrow:alice,bob,charlie", EnrollmentID("alice", "bob", "charlie"))
+	assert.NotEqual(t, "bob", EnrollmentID("alice", "bob", "charlie"))
+}
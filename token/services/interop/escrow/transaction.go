@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"encoding/json"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Transaction holds a ttx transaction
+type Transaction struct {
+	*ttx.Transaction
+	// signers are the signers registered for the escrow-tokens released by this transaction, by owner
+	signers map[string]*Signer
+}
+
+// NewAnonymousTransaction returns a new anonymous token transaction customized with the passed opts
+func NewAnonymousTransaction(sp view.Context, opts ...ttx.TxOption) (*Transaction, error) {
+	tx, err := ttx.NewAnonymousTransaction(sp, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{Transaction: tx, signers: map[string]*Signer{}}, nil
+}
+
+// NewTransactionFromBytes returns a new transaction from the passed bytes
+func NewTransactionFromBytes(ctx view.Context, raw []byte) (*Transaction, error) {
+	tx, err := ttx.NewTransactionFromBytes(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{Transaction: tx, signers: map[string]*Signer{}}, nil
+}
+
+// Lock appends an action to the token request of the transaction that locks the passed value of the passed type
+// in an escrow script naming the passed buyer, seller, and arbitrator.
+// If the buyer is nil, a recipient identity of the passed wallet is used.
+func (t *Transaction) Lock(wallet *token.OwnerWallet, buyer view.Identity, typ string, value uint64, seller, arbitrator view.Identity, opts ...token.TransferOption) (*Script, error) {
+	if buyer == nil {
+		var err error
+		buyer, err = wallet.GetRecipientIdentity()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting buyer identity")
+		}
+	}
+	script := &Script{Buyer: buyer, Seller: seller, Arbitrator: arbitrator}
+	if err := script.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid escrow script")
+	}
+	rawScript, err := json.Marshal(script)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := identity.MarshallRawOwner(&identity.RawOwner{Type: ScriptType, Identity: rawScript})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.TokenRequest.Transfer(wallet, typ, []uint64{value}, []view.Identity{owner}, opts...); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// Release appends an action to the token request of the transaction that releases the passed escrow-token
+// to the passed recipient, either the buyer or the seller of the escrow script.
+// The transaction is signed by the first party of the script whose signer is available locally.
+// The signature of a second party must be collected with NewCollectApprovalView before collecting the endorsements.
+func (t *Transaction) Release(wallet *token.OwnerWallet, tok *token2.UnspentToken, recipient view.Identity) error {
+	q, err := token2.ToQuantity(tok.Quantity, t.TokenRequest.TokenService.PublicParametersManager().Precision())
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert quantity [%s]", tok.Quantity)
+	}
+	script, err := ScriptFromOwner(tok.Owner.Raw)
+	if err != nil {
+		return err
+	}
+	if !script.IsBeneficiary(recipient) {
+		return errors.New("an escrow-token can only be released to the buyer or to the seller")
+	}
+
+	// Register the signer for the release
+	sigService := t.TokenService().SigService()
+	var signer *Signer
+	for _, party := range script.Parties() {
+		partySigner, err := sigService.GetSigner(party)
+		if err != nil {
+			continue
+		}
+		signer, err = NewSigner(script, party, partySigner)
+		if err != nil {
+			return err
+		}
+		break
+	}
+	if signer == nil {
+		return errors.New("no party of the escrow script can sign locally")
+	}
+	verifier := &Verifier{}
+	if verifier.Buyer, err = sigService.OwnerVerifier(script.Buyer); err != nil {
+		return errors.WithMessage(err, "failed getting verifier of the buyer")
+	}
+	if verifier.Seller, err = sigService.OwnerVerifier(script.Seller); err != nil {
+		return errors.WithMessage(err, "failed getting verifier of the seller")
+	}
+	if verifier.Arbitrator, err = sigService.OwnerVerifier(script.Arbitrator); err != nil {
+		return errors.WithMessage(err, "failed getting verifier of the arbitrator")
+	}
+	logger.Debugf("registering signer for release...")
+	if err := sigService.RegisterSigner(tok.Owner.Raw, signer, verifier); err != nil {
+		return err
+	}
+	if err := view2.GetEndpointService(t.SP).Bind(signer.Party, tok.Owner.Raw); err != nil {
+		return err
+	}
+	t.signers[view.Identity(tok.Owner.Raw).UniqueID()] = signer
+
+	return t.Transfer(wallet, tok.Type, []uint64{q.ToBigInt().Uint64()}, []view.Identity{recipient}, token.WithTokenIDs(tok.Id))
+}
+
+// MessageToSign returns the message the parties of the escrow scripts sign to release the escrow-tokens
+func (t *Transaction) MessageToSign() ([]byte, error) {
+	request, err := t.TokenRequest.MarshalToSign()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed marshalling token request")
+	}
+	return append(request, []byte(t.ID())...), nil
+}
+
+// EscrowInputs returns the inputs of the transaction owned by an escrow script
+func (t *Transaction) EscrowInputs() (*token.InputStream, error) {
+	inputs, err := t.Inputs()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting inputs")
+	}
+	return inputs.Filter(func(i *token.Input) bool {
+		owner, err := identity.UnmarshallRawOwner(i.Owner)
+		return err == nil && owner.Type == ScriptType
+	}), nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// NewCollectEndorsementsView returns an instance of the ttx collectEndorsementsView struct
+func NewCollectEndorsementsView(tx *Transaction) view.View {
+	return ttx.NewCollectEndorsementsView(tx.Transaction)
+}
+
+// NewOrderingAndFinalityView returns an instance of the ttx orderingAndFinalityView struct
+func NewOrderingAndFinalityView(tx *Transaction) view.View {
+	return ttx.NewOrderingAndFinalityView(tx.Transaction)
+}
+
+// NewAcceptView returns an instance of the ttx acceptView struct
+func NewAcceptView(tx *Transaction) view.View {
+	return ttx.NewAcceptView(tx.Transaction)
+}
+
+// NewFinalityView returns an instance of the ttx FinalityView
+func NewFinalityView(tx *Transaction) view.View {
+	return ttx.NewFinalityView(tx.Transaction)
+}
+
+type releaseView struct {
+	wallet    *token.OwnerWallet
+	id        *token2.ID
+	recipient view.Identity
+	approver  view.Identity
+	opts      []ttx.TxOption
+}
+
+// NewReleaseView returns a view that releases the escrow-token with the passed ID, of which the passed wallet is a party,
+// to the passed recipient, either the buyer or the seller of the escrow script.
+// The view does the following:
+// 1. It assembles a transaction releasing the escrow-token, signed by the party in the wallet.
+// 2. It collects the signature of a second party from the passed approver, that runs the view returned by NewApproveView.
+// 3. It collects the endorsements, the auditor's one included if set in the passed options, and waits for finality.
+// The view returns the ID of the committed transaction.
+func NewReleaseView(wallet *token.OwnerWallet, id *token2.ID, recipient, approver view.Identity, opts ...ttx.TxOption) *releaseView {
+	return &releaseView{wallet: wallet, id: id, recipient: recipient, approver: approver, opts: opts}
+}
+
+func (r *releaseView) Call(context view.Context) (interface{}, error) {
+	if r.wallet == nil {
+		return nil, errors.Errorf("wallet not set")
+	}
+	tmsID := r.wallet.TMS().ID()
+	escrowWallet := Wallet(context, r.wallet, token.WithTMSID(tmsID))
+	if escrowWallet == nil {
+		return nil, errors.Errorf("failed getting escrow wallet [%s] for [%s]", r.wallet.ID(), tmsID)
+	}
+	tok, err := escrowWallet.GetToken(r.id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := NewAnonymousTransaction(context, append([]ttx.TxOption{ttx.WithTMSID(tmsID)}, r.opts...)...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed creating escrow transaction")
+	}
+	if err := tx.Release(r.wallet, tok, r.recipient); err != nil {
+		return nil, errors.WithMessagef(err, "failed adding release of [%s]", r.id)
+	}
+	if _, err := context.RunView(NewCollectApprovalView(tx, r.approver)); err != nil {
+		return nil, errors.WithMessage(err, "failed collecting approval")
+	}
+	if _, err := context.RunView(NewCollectEndorsementsView(tx)); err != nil {
+		return nil, errors.WithMessage(err, "failed collecting endorsements")
+	}
+	if _, err := context.RunView(NewOrderingAndFinalityView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "failed committing [%s]", tx.ID())
+	}
+	return tx.ID(), nil
+}
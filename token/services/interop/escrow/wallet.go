@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package escrow
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+type QueryEngine interface {
+	// UnspentTokensIteratorBy returns an iterator over all unspent tokens by type and id. Type can be empty
+	UnspentTokensIteratorBy(id, typ string) (driver.UnspentTokensIterator, error)
+}
+
+// SelectFunction is the prototype of a function to select pairs (token,script)
+type SelectFunction = func(*token2.UnspentToken, *Script) (bool, error)
+
+// OwnerWallet is a combination of a wallet and a query service
+type OwnerWallet struct {
+	wallet       *token.OwnerWallet
+	queryService QueryEngine
+}
+
+// ListTokens returns the escrow-tokens that match the passed options and of which this wallet is a party
+func (w *OwnerWallet) ListTokens(opts ...token.ListTokensOption) (*token2.UnspentTokens, error) {
+	return w.list(func(*token2.UnspentToken, *Script) (bool, error) { return true, nil }, opts...)
+}
+
+// ListAsBuyer returns the escrow-tokens that match the passed options and whose buyer belongs to this wallet
+func (w *OwnerWallet) ListAsBuyer(opts ...token.ListTokensOption) (*token2.UnspentTokens, error) {
+	return w.list(func(_ *token2.UnspentToken, script *Script) (bool, error) {
+		return w.wallet.Contains(script.Buyer), nil
+	}, opts...)
+}
+
+// ListAsSeller returns the escrow-tokens that match the passed options and whose seller belongs to this wallet
+func (w *OwnerWallet) ListAsSeller(opts ...token.ListTokensOption) (*token2.UnspentTokens, error) {
+	return w.list(func(_ *token2.UnspentToken, script *Script) (bool, error) {
+		return w.wallet.Contains(script.Seller), nil
+	}, opts...)
+}
+
+// ListAsArbitrator returns the escrow-tokens that match the passed options and whose arbitrator belongs to this wallet
+func (w *OwnerWallet) ListAsArbitrator(opts ...token.ListTokensOption) (*token2.UnspentTokens, error) {
+	return w.list(func(_ *token2.UnspentToken, script *Script) (bool, error) {
+		return w.wallet.Contains(script.Arbitrator), nil
+	}, opts...)
+}
+
+// GetToken returns the escrow-token with the passed ID of which this wallet is a party
+func (w *OwnerWallet) GetToken(id *token2.ID) (*token2.UnspentToken, error) {
+	tokens, err := w.list(func(tok *token2.UnspentToken, _ *Script) (bool, error) {
+		return tok.Id.TxId == id.TxId && tok.Id.Index == id.Index, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens.Tokens) == 0 {
+		return nil, errors.Errorf("escrow-token [%s] not found in wallet [%s]", id, w.wallet.ID())
+	}
+	return tokens.Tokens[0], nil
+}
+
+func (w *OwnerWallet) list(selector SelectFunction, opts ...token.ListTokensOption) (*token2.UnspentTokens, error) {
+	compiledOpts, err := token.CompileListTokensOption(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compile options")
+	}
+	it, err := w.queryService.UnspentTokensIteratorBy(escrowWallet(w.wallet), compiledOpts.TokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get iterator over unspent tokens")
+	}
+	defer it.Close()
+	var tokens []*token2.UnspentToken
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get next unspent token from iterator")
+		}
+		if tok == nil {
+			break
+		}
+		script, err := ScriptFromOwner(tok.Owner.Raw)
+		if err != nil {
+			logger.Debugf("token [%s] contains an escrow script? No [%s]", tok.Id, err)
+			continue
+		}
+		pick, err := selector(tok, script)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to select (token,script)[%v:%v] pair", tok, script)
+		}
+		if pick {
+			tokens = append(tokens, tok)
+		}
+	}
+	return &token2.UnspentTokens{Tokens: tokens}, nil
+}
+
+// GetWallet returns the wallet whose id is the passed id
+func GetWallet(sp view2.ServiceProvider, id string, opts ...token.ServiceOption) *token.OwnerWallet {
+	return ttx.GetWallet(sp, id, opts...)
+}
+
+// Wallet returns an OwnerWallet which contains a wallet and a query service
+func Wallet(sp view2.ServiceProvider, wallet *token.OwnerWallet, opts ...token.ServiceOption) *OwnerWallet {
+	tms := token.GetManagementService(sp, opts...)
+	nw := network.GetInstance(sp, tms.Network(), tms.Channel())
+	if nw == nil {
+		return nil
+	}
+	vault, err := nw.Vault(tms.Namespace())
+	if err != nil {
+		logger.Errorf("failed to get vault for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
+		return nil
+	}
+	return &OwnerWallet{
+		wallet:       wallet,
+		queryService: vault.TokenVault().QueryEngine(),
+	}
+}
@@ -25,6 +25,9 @@ type Terms struct {
 	TMSID2              token.TMSID
 	Type2               string
 	Amount2             uint64
+	// ResponderDeadline is the deadline of the responder's lock, it must be shorter than ReclamationDeadline
+	// to leave the responder the time to claim after the initiator did. If zero, half of ReclamationDeadline is used.
+	ResponderDeadline time.Duration
 }
 
 // Bytes serializes the terms
@@ -48,9 +51,20 @@ func (t *Terms) Validate() error {
 	if t.Amount1 <= 0 || t.Amount2 <= 0 {
 		return errors.New("amounts should be larger than zero")
 	}
+	if t.ResponderDeadline < 0 || t.ResponderDeadline >= t.ReclamationDeadline {
+		return errors.New("responder deadline should not be negative and should be smaller than the reclamation deadline")
+	}
 	return nil
 }
 
+// ResponderLockDeadline returns the deadline of the responder's lock
+func (t *Terms) ResponderLockDeadline() time.Duration {
+	if t.ResponderDeadline == 0 {
+		return t.ReclamationDeadline / 2
+	}
+	return t.ResponderDeadline
+}
+
 // DistributeTermsView holds the terms and the recipient identity to be used by the view
 type DistributeTermsView struct {
 	recipient view.Identity
@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"crypto"
+	"hash"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// SHA3_256 identifies the SHA3-256 hash function
+	SHA3_256 = crypto.SHA3_256
+	// Keccak256 identifies the legacy Keccak-256 hash function, as used by Ethereum and Solidity's keccak256.
+	// It differs from SHA3-256 in the padding. The standard library does not define it,
+	// therefore its value lies outside the range of the crypto.Hash constants.
+	Keccak256 crypto.Hash = 1000
+)
+
+// HashAvailable reports whether the passed hash function can be used in an htlc script
+func HashAvailable(h crypto.Hash) bool {
+	return h == Keccak256 || h.Available()
+}
+
+// NewHash returns a new hash.Hash computing the passed hash function
+func NewHash(h crypto.Hash) (hash.Hash, error) {
+	switch {
+	case h == Keccak256:
+		return sha3.NewLegacyKeccak256(), nil
+	case h.Available():
+		return h.New(), nil
+	default:
+		return nil, errors.Errorf("hash function [%d] not available", h)
+	}
+}
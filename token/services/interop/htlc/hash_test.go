@@ -0,0 +1,38 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/encoding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImage(t *testing.T) {
+	for _, tc := range []struct {
+		hashFunc crypto.Hash
+		encoding encoding.Encoding
+		image    string
+	}{
+		{Keccak256, encoding.Hex0x, "0x47173285a8d7341e5e972fc677286384f802f8ef42a5ec5f03bbfa254cb01fad"},
+		{SHA3_256, encoding.Hex0x, "0x644bcc7e564373040999aac89e7622f3ca71fba1d972fd94a31c3bfbf24e3938"},
+		{crypto.SHA256, encoding.Hex, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"},
+	} {
+		info := &HashInfo{HashFunc: tc.hashFunc, HashEncoding: tc.encoding}
+		assert.NoError(t, info.Validate())
+		image, err := info.Image([]byte("hello world"))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.image, string(image), "hash function [%d]", tc.hashFunc)
+	}
+
+	assert.False(t, HashAvailable(crypto.Hash(999)))
+	_, err := NewHash(crypto.Hash(999))
+	assert.Error(t, err)
+	assert.Error(t, (&HashInfo{HashFunc: crypto.Hash(999), HashEncoding: encoding.Hex}).Validate())
+}
@@ -0,0 +1,244 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"context"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+const (
+	// DefaultReclaimInterval is the time between two scans for expired htlc-tokens, when not configured
+	DefaultReclaimInterval = time.Minute
+	// DefaultReclaimBatchSize is the maximum number of htlc-tokens reclaimed by a single transaction, when not configured
+	DefaultReclaimBatchSize = 16
+	// ReclaimTopic is the topic of the events published for each reclaim transaction
+	ReclaimTopic = "htlc-reclaim"
+)
+
+// ReclaimResult describes the outcome of a reclaim transaction
+type ReclaimResult struct {
+	// TMSID identifies the TMS of the reclaimed tokens
+	TMSID token.TMSID
+	// Wallet is the identifier of the wallet the reclaimed tokens return to
+	Wallet string
+	// TxID is the identifier of the reclaim transaction, if it has been created
+	TxID string
+	// TokenIDs are the identifiers of the htlc-tokens to reclaim
+	TokenIDs []*token2.ID
+	// Error describes why the reclaim failed, empty if the transaction has been committed
+	Error string
+}
+
+// ReclaimEvent is published for each reclaim transaction, its message is a *ReclaimResult
+type ReclaimEvent struct {
+	result *ReclaimResult
+}
+
+func (r *ReclaimEvent) Topic() string {
+	return ReclaimTopic
+}
+
+func (r *ReclaimEvent) Message() interface{} {
+	return r.result
+}
+
+type reclaimExpiredView struct {
+	wallet    *token.OwnerWallet
+	batchSize int
+	opts      []ttx.TxOption
+}
+
+// NewReclaimExpiredView returns a view that reclaims the expired htlc-tokens whose sender belongs to the passed wallet.
+// The view does the following:
+// 1. It lists the expired htlc-tokens whose sender belongs to the wallet.
+// 2. For each batch of at most batchSize tokens, it assembles a transaction reclaiming them, collects the endorsements,
+// the auditor's one included if set in the passed options, and waits for finality.
+// 3. It publishes a ReclaimEvent for each batch, whether it succeeded or not.
+// The view returns the ReclaimResult of each batch. A failed batch does not prevent the others from being reclaimed.
+func NewReclaimExpiredView(wallet *token.OwnerWallet, batchSize int, opts ...ttx.TxOption) *reclaimExpiredView {
+	return &reclaimExpiredView{wallet: wallet, batchSize: batchSize, opts: opts}
+}
+
+func (r *reclaimExpiredView) Call(context view.Context) (interface{}, error) {
+	if r.wallet == nil {
+		return nil, errors.Errorf("wallet not set")
+	}
+	tmsID := r.wallet.TMS().ID()
+	htlcWallet := Wallet(context, r.wallet, token.WithTMSID(tmsID))
+	if htlcWallet == nil {
+		return nil, errors.Errorf("failed getting htlc wallet [%s] for [%s]", r.wallet.ID(), tmsID)
+	}
+	expired, err := htlcWallet.ListExpired()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing expired htlc-tokens of wallet [%s]", r.wallet.ID())
+	}
+	pub, err := events.GetPublisher(context)
+	if err != nil {
+		logger.Warnf("failed getting event publisher, reclaim results will not be published: [%s]", err)
+	}
+	var results []*ReclaimResult
+	for _, batch := range reclaimBatches(expired.Tokens, r.batchSize) {
+		result := &ReclaimResult{TMSID: tmsID, Wallet: r.wallet.ID()}
+		for _, tok := range batch {
+			result.TokenIDs = append(result.TokenIDs, tok.Id)
+		}
+		result.TxID, err = r.reclaim(context, tmsID, batch)
+		if err != nil {
+			logger.Errorf("failed reclaiming [%d] expired htlc-tokens of wallet [%s]: [%s]", len(batch), r.wallet.ID(), err)
+			result.Error = err.Error()
+		} else {
+			logger.Debugf("reclaimed [%d] expired htlc-tokens of wallet [%s] with transaction [%s]", len(batch), r.wallet.ID(), result.TxID)
+		}
+		results = append(results, result)
+		if pub != nil {
+			pub.Publish(&ReclaimEvent{result: result})
+		}
+	}
+	return results, nil
+}
+
+// reclaimBatches splits the passed tokens in batches of at most batchSize tokens,
+// DefaultReclaimBatchSize if batchSize is not positive
+func reclaimBatches(tokens []*token2.UnspentToken, batchSize int) [][]*token2.UnspentToken {
+	if batchSize <= 0 {
+		batchSize = DefaultReclaimBatchSize
+	}
+	var batches [][]*token2.UnspentToken
+	for start := 0; start < len(tokens); start += batchSize {
+		end := start + batchSize
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		batches = append(batches, tokens[start:end])
+	}
+	return batches
+}
+
+// reclaim assembles, endorses and commits a transaction reclaiming the passed tokens, and returns its ID
+func (r *reclaimExpiredView) reclaim(context view.Context, tmsID token.TMSID, tokens []*token2.UnspentToken) (string, error) {
+	tx, err := NewAnonymousTransaction(context, append([]ttx.TxOption{ttx.WithTMSID(tmsID)}, r.opts...)...)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed creating htlc transaction")
+	}
+	for _, tok := range tokens {
+		if err := tx.Reclaim(r.wallet, tok); err != nil {
+			return tx.ID(), errors.WithMessagef(err, "failed adding reclaim for [%s]", tok.Id)
+		}
+	}
+	if _, err := context.RunView(NewCollectEndorsementsView(tx)); err != nil {
+		return tx.ID(), errors.WithMessage(err, "failed collecting endorsements")
+	}
+	if _, err := context.RunView(NewOrderingAndFinalityView(tx)); err != nil {
+		return tx.ID(), errors.WithMessage(err, "failed committing transaction")
+	}
+	return tx.ID(), nil
+}
+
+// ReclaimService periodically reclaims the expired htlc-tokens whose sender belongs to some wallets of a TMS
+type ReclaimService struct {
+	sp        view2.ServiceProvider
+	tmsID     token.TMSID
+	wallets   []string
+	interval  time.Duration
+	batchSize int
+	auditor   string
+
+	// reclaimWallet reclaims the expired htlc-tokens of the passed wallet
+	reclaimWallet func(id string, opts []ttx.TxOption) ([]*ReclaimResult, error)
+}
+
+// NewReclaimService returns a reclaim service for the passed wallets of the passed TMS, configured as passed
+func NewReclaimService(sp view2.ServiceProvider, tmsID token.TMSID, wallets []string, cfg *config.HTLCReclaim) *ReclaimService {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultReclaimInterval
+	}
+	s := &ReclaimService{
+		sp:        sp,
+		tmsID:     tmsID,
+		wallets:   wallets,
+		interval:  interval,
+		batchSize: cfg.BatchSize,
+		auditor:   cfg.Auditor,
+	}
+	s.reclaimWallet = s.reclaimExpired
+	return s
+}
+
+// Start runs the reclaim periodically in a separate goroutine, until the passed context is done
+func (s *ReclaimService) Start(ctx context.Context) {
+	logger.Infof("starting reclaim of expired htlc-tokens of wallets %v in [%s] every [%s]", s.wallets, s.tmsID, s.interval)
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Reclaim(); err != nil {
+					logger.Errorf("failed reclaiming expired htlc-tokens in [%s]: [%s]", s.tmsID, err)
+				}
+			}
+		}
+	}()
+}
+
+// Reclaim reclaims now the expired htlc-tokens of the wallets of this service,
+// and returns the results of the reclaim transactions.
+// A wallet that cannot be reclaimed does not prevent the others from being reclaimed,
+// the returned error collects the failures of all the wallets.
+func (s *ReclaimService) Reclaim() ([]*ReclaimResult, error) {
+	var opts []ttx.TxOption
+	if len(s.auditor) != 0 {
+		auditor := view2.GetIdentityProvider(s.sp).Identity(s.auditor)
+		if auditor.IsNone() {
+			return nil, errors.Errorf("auditor identity [%s] not found", s.auditor)
+		}
+		opts = append(opts, ttx.WithAuditor(auditor))
+	}
+	var results []*ReclaimResult
+	var errs []error
+	for _, id := range s.wallets {
+		walletResults, err := s.reclaimWallet(id, opts)
+		if err != nil {
+			logger.Errorf("failed reclaiming expired htlc-tokens of wallet [%s] in [%s]: [%s]", id, s.tmsID, err)
+			errs = append(errs, errors.WithMessagef(err, "failed reclaiming expired htlc-tokens of wallet [%s]", id))
+			continue
+		}
+		results = append(results, walletResults...)
+	}
+	if len(errs) != 0 {
+		return results, errors.Errorf("failed reclaiming the expired htlc-tokens of [%d] wallets in [%s] with errs %v", len(errs), s.tmsID, errs)
+	}
+	return results, nil
+}
+
+// reclaimExpired reclaims the expired htlc-tokens of the passed wallet, with a ReclaimExpiredView
+func (s *ReclaimService) reclaimExpired(id string, opts []ttx.TxOption) ([]*ReclaimResult, error) {
+	wallet := GetWallet(s.sp, id, token.WithTMSID(s.tmsID))
+	if wallet == nil {
+		logger.Errorf("wallet [%s] not found in [%s], skipping reclaim", id, s.tmsID)
+		return nil, nil
+	}
+	boxed, err := view2.GetManager(s.sp).InitiateView(NewReclaimExpiredView(wallet, s.batchSize, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return boxed.([]*ReclaimResult), nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestReclaimBatches(t *testing.T) {
+	tokens := make([]*token2.UnspentToken, 5)
+	for i := range tokens {
+		tokens[i] = &token2.UnspentToken{Id: &token2.ID{TxId: fmt.Sprintf("tx%d", i)}}
+	}
+
+	batches := reclaimBatches(tokens, 2)
+	assert.Equal(t, [][]*token2.UnspentToken{tokens[0:2], tokens[2:4], tokens[4:5]}, batches)
+	assert.Equal(t, [][]*token2.UnspentToken{tokens}, reclaimBatches(tokens, 5))
+	assert.Equal(t, [][]*token2.UnspentToken{tokens}, reclaimBatches(tokens, 0))
+	assert.Empty(t, reclaimBatches(nil, 2))
+}
+
+func TestReclaimSkipsFailingWallets(t *testing.T) {
+	tmsID := token.TMSID{Network: "alpha"}
+	s := NewReclaimService(nil, tmsID, []string{"alice", "bob", "charlie"}, &config.HTLCReclaim{})
+	var reclaimed []string
+	s.reclaimWallet = func(id string, opts []ttx.TxOption) ([]*ReclaimResult, error) {
+		reclaimed = append(reclaimed, id)
+		if id == "bob" {
+			return nil, errors.New("endorsement failed")
+		}
+		return []*ReclaimResult{{TMSID: tmsID, Wallet: id, TxID: "tx-" + id}}, nil
+	}
+
+	results, err := s.Reclaim()
+	assert.EqualError(t, err, "failed reclaiming the expired htlc-tokens of [1] wallets in [alpha,,] with errs [failed reclaiming expired htlc-tokens of wallet [bob]: endorsement failed]")
+	assert.Equal(t, []string{"alice", "bob", "charlie"}, reclaimed)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "alice", results[0].Wallet)
+	assert.Equal(t, "charlie", results[1].Wallet)
+}
@@ -38,7 +38,7 @@ func WithStartingTransaction(txID string) token.ServiceOption {
 func ScanForPreImage(ctx view.Context, image []byte, hashFunc crypto.Hash, hashEncoding encoding.Encoding, timeout time.Duration, opts ...token.ServiceOption) ([]byte, error) {
 	logger.Debugf("scanning for preimage of [%s] with timeout [%s]", base64.StdEncoding.EncodeToString(image), timeout)
 
-	if !hashFunc.Available() {
+	if !HashAvailable(hashFunc) {
 		return nil, errors.Errorf("passed hash function is not available [%d]", hashFunc)
 	}
 	if !hashEncoding.Available() {
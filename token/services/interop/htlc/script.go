@@ -28,7 +28,7 @@ type HashInfo struct {
 
 // Validate checks that the hash and encoding functions are available
 func (i *HashInfo) Validate() error {
-	if !i.HashFunc.Available() {
+	if !HashAvailable(i.HashFunc) {
 		return errors.New("hash function not available")
 	}
 	if !i.HashEncoding.Available() {
@@ -42,7 +42,10 @@ func (i *HashInfo) Image(preImage []byte) ([]byte, error) {
 	if err := i.Validate(); err != nil {
 		return nil, errors.WithMessagef(err, "hash info not valid")
 	}
-	hash := i.HashFunc.New()
+	hash, err := NewHash(i.HashFunc)
+	if err != nil {
+		return nil, err
+	}
 	if _, err := hash.Write(preImage); err != nil {
 		return nil, errors.Wrapf(err, "failed to compute hash image")
 	}
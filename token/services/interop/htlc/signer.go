@@ -68,10 +68,13 @@ func (cv *ClaimVerifier) Verify(tokenRequestAndTxID, claimSignature []byte) erro
 		return errors.WithMessagef(err, "failed to verify recipient signature")
 	}
 
-	if !cv.HashInfo.HashFunc.Available() {
+	if !HashAvailable(cv.HashInfo.HashFunc) {
 		return errors.Errorf("script hash function not available [%d]", cv.HashInfo.HashFunc)
 	}
-	hash := cv.HashInfo.HashFunc.New()
+	hash, err := NewHash(cv.HashInfo.HashFunc)
+	if err != nil {
+		return err
+	}
 	if _, err = hash.Write(sig.Preimage); err != nil {
 		return errors.Wrapf(err, "failed to compute hash image")
 	}
@@ -0,0 +1,609 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"bytes"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// DefaultClaimMargin is the claim margin used when none is set
+const DefaultClaimMargin = time.Minute
+
+// SwapOptions configures a swap. The options are persisted with the swap, so that it can be resumed after a restart.
+type SwapOptions struct {
+	// Wallet1 is the identifier of the wallet of this party on the first TMS, the default one if empty
+	Wallet1 string
+	// Wallet2 is the identifier of the wallet of this party on the second TMS, the default one if empty
+	Wallet2 string
+	// Auditor is the identity of the auditor of the transactions of the swap, if they must be audited
+	Auditor view.Identity
+	// ClaimMargin is the minimum time this party must be left to claim the counterparty's leg
+	// once the pre-image is known, DefaultClaimMargin if zero
+	ClaimMargin time.Duration
+}
+
+// SwapOption is a function that configures a SwapOptions
+type SwapOption func(*SwapOptions) error
+
+// WithSwapWallets sets the wallets of this party on the first and second TMS
+func WithSwapWallets(wallet1, wallet2 string) SwapOption {
+	return func(o *SwapOptions) error {
+		o.Wallet1 = wallet1
+		o.Wallet2 = wallet2
+		return nil
+	}
+}
+
+// WithSwapAuditor sets the auditor of the transactions of the swap
+func WithSwapAuditor(auditor view.Identity) SwapOption {
+	return func(o *SwapOptions) error {
+		o.Auditor = auditor
+		return nil
+	}
+}
+
+// WithSwapClaimMargin sets the minimum time this party must be left to claim the counterparty's leg
+func WithSwapClaimMargin(margin time.Duration) SwapOption {
+	return func(o *SwapOptions) error {
+		if margin <= 0 {
+			return errors.Errorf("the claim margin must be positive, got [%s]", margin)
+		}
+		o.ClaimMargin = margin
+		return nil
+	}
+}
+
+// claimMargin returns the claim margin, DefaultClaimMargin if not set
+func (o *SwapOptions) claimMargin() time.Duration {
+	if o.ClaimMargin == 0 {
+		return DefaultClaimMargin
+	}
+	return o.ClaimMargin
+}
+
+// compileSwapOptions applies the passed options on top of the passed ones, if any
+func compileSwapOptions(base *SwapOptions, opts ...SwapOption) (*SwapOptions, error) {
+	options := &SwapOptions{}
+	if base != nil {
+		*options = *base
+	}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// checkInitiatorLock checks, for the responder, that the initiator's lock expires late enough for the responder
+// to claim it after the responder's own lock, starting now, expired
+func checkInitiatorLock(script *Script, terms *Terms, options *SwapOptions, now time.Time) error {
+	if earliest := now.Add(terms.ResponderLockDeadline() + options.claimMargin()); script.Deadline.Before(earliest) {
+		return errors.Errorf("the initiator's lock expires at [%s], less than [%s] after the responder's one", script.Deadline, options.claimMargin())
+	}
+	return nil
+}
+
+// checkResponderLock checks, for the initiator, that the responder's lock uses the hash of the initiator's one,
+// expires before it, and leaves the initiator the time to claim
+func checkResponderLock(script *Script, initiatorScript *Script, options *SwapOptions, now time.Time) error {
+	if !bytes.Equal(script.HashInfo.Hash, initiatorScript.HashInfo.Hash) || script.HashInfo.HashFunc != initiatorScript.HashInfo.HashFunc || script.HashInfo.HashEncoding != initiatorScript.HashInfo.HashEncoding {
+		return errors.Errorf("the responder's lock does not use the initiator's hash")
+	}
+	if !script.Deadline.Before(initiatorScript.Deadline) {
+		return errors.Errorf("the responder's lock expires at [%s], not before the initiator's one at [%s]", script.Deadline, initiatorScript.Deadline)
+	}
+	if script.Deadline.Before(now.Add(options.claimMargin())) {
+		return errors.Errorf("the responder's lock expires at [%s], in less than [%s]", script.Deadline, options.claimMargin())
+	}
+	return nil
+}
+
+// swap drives the progress of a swap, persisting it at each step
+type swap struct {
+	*Swap
+	store *SwapStore
+}
+
+// update stores the passed status of the swap
+func (s *swap) update(status SwapStatus) error {
+	s.Status = status
+	return s.store.Put(s.Swap)
+}
+
+// fail records the passed error and returns it
+func (s *swap) fail(err error) error {
+	s.Error = err.Error()
+	if err2 := s.store.Put(s.Swap); err2 != nil {
+		logger.Errorf("failed storing failure of swap [%s]: [%s]", s.ID, err2)
+	}
+	return err
+}
+
+// txOptions returns the options to create a transaction on the passed TMS
+func (s *swap) txOptions(tmsID token.TMSID) []ttx.TxOption {
+	opts := []ttx.TxOption{ttx.WithTMSID(tmsID)}
+	if !s.Options.Auditor.IsNone() {
+		opts = append(opts, ttx.WithAuditor(s.Options.Auditor))
+	}
+	return opts
+}
+
+// claim claims the htlc-tokens on the passed TMS matching the pre-image of the swap, and returns the ID of the transaction
+func (s *swap) claim(context view.Context, tmsID token.TMSID, walletID string) (string, error) {
+	wallet := GetWallet(context, walletID, token.WithTMSID(tmsID))
+	if wallet == nil {
+		return "", errors.Errorf("wallet [%s] not found in [%s]", walletID, tmsID)
+	}
+	htlcWallet := Wallet(context, wallet, token.WithTMSID(tmsID))
+	if htlcWallet == nil {
+		return "", errors.Errorf("failed getting htlc wallet [%s] for [%s]", walletID, tmsID)
+	}
+	matched, err := htlcWallet.ListByPreImage(s.PreImage)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed listing htlc-tokens matching the pre-image")
+	}
+	if matched.Count() == 0 {
+		return "", errors.Errorf("no htlc-token matching the pre-image found in [%s]", tmsID)
+	}
+	tx, err := NewAnonymousTransaction(context, s.txOptions(tmsID)...)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed creating htlc transaction")
+	}
+	for _, tok := range matched.Tokens {
+		if err := tx.Claim(wallet, tok, s.PreImage); err != nil {
+			return "", errors.WithMessagef(err, "failed adding a claim for [%s]", tok.Id)
+		}
+	}
+	return tx.ID(), commit(context, tx)
+}
+
+// reclaim reclaims the expired htlc-tokens on the passed TMS locked with the hash of the swap,
+// and returns the ID of the transaction, the empty string if there is nothing to reclaim
+func (s *swap) reclaim(context view.Context, tmsID token.TMSID, walletID string, hash []byte) (string, error) {
+	wallet := GetWallet(context, walletID, token.WithTMSID(tmsID))
+	if wallet == nil {
+		return "", errors.Errorf("wallet [%s] not found in [%s]", walletID, tmsID)
+	}
+	htlcWallet := Wallet(context, wallet, token.WithTMSID(tmsID))
+	if htlcWallet == nil {
+		return "", errors.Errorf("failed getting htlc wallet [%s] for [%s]", walletID, tmsID)
+	}
+	expired, err := htlcWallet.filter("", true, func(tok *token2.UnspentToken, script *Script) (bool, error) {
+		if !bytes.Equal(script.HashInfo.Hash, hash) {
+			return false, nil
+		}
+		return SelectExpired(tok, script)
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "failed listing expired htlc-tokens")
+	}
+	if expired.Count() == 0 {
+		return "", nil
+	}
+	tx, err := NewAnonymousTransaction(context, s.txOptions(tmsID)...)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed creating htlc transaction")
+	}
+	for _, tok := range expired.Tokens {
+		if err := tx.Reclaim(wallet, tok); err != nil {
+			return "", errors.WithMessagef(err, "failed adding a reclaim for [%s]", tok.Id)
+		}
+	}
+	return tx.ID(), commit(context, tx)
+}
+
+// commit collects the endorsements of the passed transaction, orders it and waits for its finality
+func commit(context view.Context, tx *Transaction) error {
+	if _, err := context.RunView(NewCollectEndorsementsView(tx)); err != nil {
+		return errors.WithMessagef(err, "failed collecting endorsements for [%s]", tx.ID())
+	}
+	if _, err := context.RunView(NewOrderingAndFinalityView(tx)); err != nil {
+		return errors.WithMessagef(err, "failed committing [%s]", tx.ID())
+	}
+	return nil
+}
+
+// receiveLock receives, checks and accepts a transaction locking the passed amount of the passed type for me,
+// and returns the script of the lock. The transaction is accepted only if the passed check accepts the script,
+// so that a lock that does not respect the terms of the swap is never endorsed nor committed.
+func receiveLock(context view.Context, me, sender view.Identity, typ string, amount uint64, check func(script *Script) error) (*Script, string, error) {
+	tx, err := ReceiveTransaction(context)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "failed receiving lock transaction")
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "failed getting outputs")
+	}
+	script, err := checkLock(outputs, me, sender, typ, amount, check)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := context.RunView(NewAcceptView(tx)); err != nil {
+		return nil, "", errors.WithMessage(err, "failed accepting lock transaction")
+	}
+	if _, err := context.RunView(NewFinalityView(tx)); err != nil {
+		return nil, "", errors.WithMessage(err, "lock transaction not committed")
+	}
+	return script, tx.ID(), nil
+}
+
+// checkLock returns the script of the only htlc output among the passed ones, if the output locks the passed amount
+// of the passed type from the sender for me, and the passed check accepts the script
+func checkLock(outputs *OutputStream, me, sender view.Identity, typ string, amount uint64, check func(script *Script) error) (*Script, error) {
+	outputs = outputs.ByScript()
+	if outputs.Count() != 1 {
+		return nil, errors.Errorf("expected only one htlc output, got [%d]", outputs.Count())
+	}
+	script := outputs.ScriptAt(0)
+	if script == nil {
+		return nil, errors.Errorf("expected an htlc script")
+	}
+	if !me.Equal(script.Recipient) {
+		return nil, errors.Errorf("expected me as recipient of the script")
+	}
+	if !sender.Equal(script.Sender) {
+		return nil, errors.Errorf("expected the counterparty as sender of the script")
+	}
+	output := outputs.At(0)
+	if output.Type != typ || !output.Quantity.ToBigInt().IsUint64() || output.Quantity.ToBigInt().Uint64() != amount {
+		return nil, errors.Errorf("expected [%d] of type [%s], got [%s] of type [%s]", amount, typ, output.Quantity.Decimal(), output.Type)
+	}
+	if err := check(script); err != nil {
+		return nil, errors.WithMessage(err, "invalid lock")
+	}
+	return script, nil
+}
+
+// lock locks the passed amount for the recipient, with the passed hash if any, and returns the script of the lock
+// and the pre-image, if the hash has been generated. The swap is persisted before the transaction is committed,
+// so that the pre-image is never lost.
+func (s *swap) lock(context view.Context, tmsID token.TMSID, walletID string, sender view.Identity, typ string, amount uint64, recipient view.Identity, deadline time.Duration, hashInfo *HashInfo, onLocked func(tx *Transaction, script *Script, preImage []byte) error) error {
+	tx, err := NewAnonymousTransaction(context, s.txOptions(tmsID)...)
+	if err != nil {
+		return errors.WithMessage(err, "failed creating htlc transaction")
+	}
+	wallet := GetWallet(context, walletID, token.WithTMSID(tmsID))
+	if wallet == nil {
+		return errors.Errorf("wallet [%s] not found in [%s]", walletID, tmsID)
+	}
+	var opts []token.TransferOption
+	if hashInfo != nil {
+		opts = append(opts, WithHash(hashInfo.Hash), WithHashFunc(hashInfo.HashFunc), WithHashEncoding(hashInfo.HashEncoding))
+	}
+	preImage, err := tx.Lock(wallet, sender, typ, amount, recipient, deadline, opts...)
+	if err != nil {
+		return errors.WithMessage(err, "failed adding a lock action")
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return errors.WithMessage(err, "failed getting outputs")
+	}
+	outputs = outputs.ByScript()
+	if outputs.Count() != 1 {
+		return errors.Errorf("expected only one htlc output, got [%d]", outputs.Count())
+	}
+	if err := onLocked(tx, outputs.ScriptAt(0), preImage); err != nil {
+		return err
+	}
+	return commit(context, tx)
+}
+
+type swapInitiatorView struct {
+	counterparty view.Identity
+	terms        *Terms
+	opts         []SwapOption
+}
+
+// NewSwapInitiatorView returns the view of the initiator of a swap with the passed counterparty, under the passed terms.
+// The initiator gives Amount1 of Type1 on TMSID1 and receives Amount2 of Type2 on TMSID2.
+// The view does the following:
+// 1. It exchanges the recipient identities on both TMSs and distributes the terms.
+// 2. It locks the initiator's leg on TMSID1, with a fresh pre-image, until the reclamation deadline.
+// 3. It receives the responder's lock on TMSID2, which must use the same hash, expire earlier, and leave
+//    the initiator at least the claim margin to claim it.
+// 4. It claims the responder's leg, revealing the pre-image.
+// The progress is persisted in the SwapStore under the ID of the view context, returned together with the swap.
+// If the swap stops after the initiator's leg has been locked, NewResumeSwapView completes it, claiming or reclaiming.
+// The counterparty's node must register the view returned by NewSwapResponderView as responder of this view.
+func NewSwapInitiatorView(counterparty view.Identity, terms *Terms, opts ...SwapOption) *swapInitiatorView {
+	return &swapInitiatorView{counterparty: counterparty, terms: terms, opts: opts}
+}
+
+func (v *swapInitiatorView) Call(context view.Context) (interface{}, error) {
+	if err := v.terms.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid terms")
+	}
+	options, err := compileSwapOptions(nil, v.opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed compiling swap options")
+	}
+	s := &swap{
+		Swap: &Swap{
+			ID:           context.ID(),
+			Role:         SwapInitiator,
+			Terms:        v.terms,
+			Counterparty: v.counterparty,
+			Options:      options,
+		},
+		store: GetSwapStore(context),
+	}
+	terms := v.terms
+
+	// agree on the terms
+	me1, recipient, err := ExchangeRecipientIdentities(context, options.Wallet1, v.counterparty, token.WithTMSID(terms.TMSID1))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed exchanging recipient identities on the first TMS")
+	}
+	me2, sender, err := ExchangeRecipientIdentities(context, options.Wallet2, v.counterparty, token.WithTMSID(terms.TMSID2))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed exchanging recipient identities on the second TMS")
+	}
+	if _, err := context.RunView(NewDistributeTermsView(recipient, terms)); err != nil {
+		return nil, errors.WithMessage(err, "failed distributing the terms")
+	}
+	if err := s.update(SwapAgreed); err != nil {
+		return nil, err
+	}
+
+	// lock the initiator's leg
+	err = s.lock(context, terms.TMSID1, options.Wallet1, me1, terms.Type1, terms.Amount1, recipient, terms.ReclamationDeadline, nil,
+		func(tx *Transaction, script *Script, preImage []byte) error {
+			s.Script1 = script
+			s.PreImage = preImage
+			s.LockTxID1 = tx.ID()
+			return s.store.Put(s.Swap)
+		},
+	)
+	if err != nil {
+		return s.Swap, s.fail(errors.WithMessage(err, "failed locking the initiator's leg"))
+	}
+	if err := s.update(SwapLocked1); err != nil {
+		return s.Swap, err
+	}
+
+	// receive the responder's lock
+	session, err := context.GetSession(context.Initiator(), v.counterparty)
+	if err != nil {
+		return s.Swap, s.fail(errors.WithMessage(err, "failed getting session with the counterparty"))
+	}
+	_, err = view2.AsResponder(context, session, func(context view.Context) (interface{}, error) {
+		script, txID, err := receiveLock(context, me2, sender, terms.Type2, terms.Amount2, func(script *Script) error {
+			return checkResponderLock(script, s.Script1, options, time.Now())
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.Script2 = script
+		s.LockTxID2 = txID
+		return nil, nil
+	})
+	if err != nil {
+		return s.Swap, s.fail(errors.WithMessage(err, "failed receiving the responder's lock"))
+	}
+	if err := s.update(SwapLocked2); err != nil {
+		return s.Swap, err
+	}
+
+	// claim the responder's leg
+	s.ClaimTxID, err = s.claim(context, terms.TMSID2, options.Wallet2)
+	if err != nil {
+		return s.Swap, s.fail(errors.WithMessage(err, "failed claiming the responder's leg"))
+	}
+	return s.Swap, s.update(SwapCompleted)
+}
+
+type swapResponderView struct {
+	opts []SwapOption
+}
+
+// NewSwapResponderView returns the view of the responder of a swap, to be registered as responder
+// of the view returned by NewSwapInitiatorView.
+// The responder gives Amount2 of Type2 on TMSID2 and receives Amount1 of Type1 on TMSID1.
+// The view does the following:
+// 1. It receives the recipient identities and the terms.
+// 2. It receives the initiator's lock on TMSID1, which must expire at least the claim margin after the responder's one,
+//    so that the responder can claim it after the initiator revealed the pre-image at the last moment.
+// 3. It locks the responder's leg on TMSID2, with the initiator's hash, until the responder's deadline.
+// 4. It scans TMSID2 for the pre-image revealed by the initiator's claim, and claims the initiator's leg.
+// If the pre-image is not revealed before the responder's deadline, it reclaims the responder's leg.
+// The progress is persisted in the SwapStore under the ID of the view context, returned together with the swap.
+func NewSwapResponderView(opts ...SwapOption) *swapResponderView {
+	return &swapResponderView{opts: opts}
+}
+
+func (v *swapResponderView) Call(context view.Context) (interface{}, error) {
+	options, err := compileSwapOptions(nil, v.opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed compiling swap options")
+	}
+	s := &swap{
+		Swap: &Swap{
+			ID:      context.ID(),
+			Role:    SwapResponder,
+			Options: options,
+		},
+		store: GetSwapStore(context),
+	}
+
+	// agree on the terms
+	me1, sender, err := RespondExchangeRecipientIdentities(context)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed responding to the identity request on the first TMS")
+	}
+	me2, recipient, err := RespondExchangeRecipientIdentities(context)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed responding to the identity request on the second TMS")
+	}
+	terms, err := ReceiveTerms(context)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed receiving the terms")
+	}
+	if err := terms.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid terms")
+	}
+	s.Terms = terms
+	s.Counterparty = context.Session().Info().Caller
+	if err := s.update(SwapAgreed); err != nil {
+		return nil, err
+	}
+
+	// receive the initiator's lock
+	_, err = view2.AsInitiatorCall(context, v, func(context view.Context) (interface{}, error) {
+		script, txID, err := receiveLock(context, me1, sender, terms.Type1, terms.Amount1, func(script *Script) error {
+			return checkInitiatorLock(script, terms, options, time.Now())
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.Script1 = script
+		s.LockTxID1 = txID
+		return nil, nil
+	})
+	if err != nil {
+		s.Status = SwapFailed
+		return s.Swap, s.fail(errors.WithMessage(err, "failed receiving the initiator's lock"))
+	}
+	if err := s.update(SwapLocked1); err != nil {
+		return s.Swap, err
+	}
+
+	// lock the responder's leg
+	_, err = view2.AsInitiatorCall(context, v, func(context view.Context) (interface{}, error) {
+		return nil, s.lock(context, terms.TMSID2, options.Wallet2, me2, terms.Type2, terms.Amount2, recipient, terms.ResponderLockDeadline(), &s.Script1.HashInfo,
+			func(tx *Transaction, script *Script, _ []byte) error {
+				s.Script2 = script
+				s.LockTxID2 = tx.ID()
+				return s.store.Put(s.Swap)
+			},
+		)
+	})
+	if err != nil {
+		return s.Swap, s.fail(errors.WithMessage(err, "failed locking the responder's leg"))
+	}
+	if err := s.update(SwapLocked2); err != nil {
+		return s.Swap, err
+	}
+
+	// claim the initiator's leg once the pre-image is revealed, or reclaim
+	_, err = view2.AsInitiatorCall(context, v, func(context view.Context) (interface{}, error) {
+		return nil, s.complete(context)
+	})
+	return s.Swap, err
+}
+
+// complete moves forward a swap whose legs might be locked: it claims the counterparty's leg, if the pre-image
+// is known, or it reclaims this party's own leg, once expired.
+// It returns nil, leaving the swap pending, if neither is possible yet.
+func (s *swap) complete(context view.Context) error {
+	terms := s.Terms
+	switch s.Role {
+	case SwapInitiator:
+		if len(s.LockTxID1) == 0 {
+			// nothing has been locked
+			return s.update(SwapFailed)
+		}
+		// claim the responder's leg, if locked
+		txID, err := s.claim(context, terms.TMSID2, s.Options.Wallet2)
+		if err == nil {
+			s.ClaimTxID = txID
+			return s.update(SwapCompleted)
+		}
+		logger.Debugf("cannot claim the responder's leg of swap [%s]: [%s]", s.ID, err)
+		if s.Script1 == nil || time.Now().Before(s.Script1.Deadline) {
+			return nil
+		}
+		s.ReclaimTxID, err = s.reclaim(context, terms.TMSID1, s.Options.Wallet1, s.Script1.HashInfo.Hash)
+		if err != nil {
+			return s.fail(errors.WithMessage(err, "failed reclaiming the initiator's leg"))
+		}
+		return s.update(SwapReclaimed)
+	case SwapResponder:
+		if len(s.LockTxID2) == 0 {
+			// nothing has been locked by the responder
+			return s.update(SwapFailed)
+		}
+		if len(s.PreImage) == 0 {
+			timeout := time.Until(s.Script2.Deadline)
+			if timeout <= 0 {
+				// the initiator can no longer claim, but might have claimed just before the deadline
+				timeout = time.Second
+			}
+			preImage, err := ScanForPreImage(context, s.Script2.HashInfo.Hash, s.Script2.HashInfo.HashFunc, s.Script2.HashInfo.HashEncoding, timeout, token.WithTMSID(terms.TMSID2))
+			if err != nil {
+				logger.Debugf("pre-image of swap [%s] not found: [%s]", s.ID, err)
+				if time.Now().Before(s.Script2.Deadline) {
+					return nil
+				}
+				s.ReclaimTxID, err = s.reclaim(context, terms.TMSID2, s.Options.Wallet2, s.Script2.HashInfo.Hash)
+				if err != nil {
+					return s.fail(errors.WithMessage(err, "failed reclaiming the responder's leg"))
+				}
+				return s.update(SwapReclaimed)
+			}
+			s.PreImage = preImage
+			if err := s.store.Put(s.Swap); err != nil {
+				return err
+			}
+		}
+		txID, err := s.claim(context, terms.TMSID1, s.Options.Wallet1)
+		if err != nil {
+			return s.fail(errors.WithMessage(err, "failed claiming the initiator's leg"))
+		}
+		s.ClaimTxID = txID
+		return s.update(SwapCompleted)
+	default:
+		return errors.Errorf("invalid role [%s]", s.Role)
+	}
+}
+
+type resumeSwapView struct {
+	id   string
+	opts []SwapOption
+}
+
+// NewResumeSwapView returns a view that moves forward the persisted swap with the passed ID, after a restart
+// or a failure, without involving the counterparty. The view does the following:
+// - If the party locked nothing, the swap fails.
+// - If the counterparty's leg can be claimed, because the pre-image is known or has been revealed, it claims it.
+// - Otherwise, if the party's own leg expired, it reclaims it.
+// - Otherwise, the swap remains pending and the view can be run again later.
+// The options used to start the swap are persisted with it, the passed ones override them.
+// The view returns the updated swap.
+func NewResumeSwapView(id string, opts ...SwapOption) *resumeSwapView {
+	return &resumeSwapView{id: id, opts: opts}
+}
+
+func (v *resumeSwapView) Call(context view.Context) (interface{}, error) {
+	store := GetSwapStore(context)
+	record, err := store.Get(v.id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status.IsFinal() {
+		return record, nil
+	}
+	record.Options, err = compileSwapOptions(record.Options, v.opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed compiling swap options")
+	}
+	s := &swap{Swap: record, store: store}
+	if err := s.complete(context); err != nil {
+		return s.Swap, err
+	}
+	return s.Swap, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs/mock"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/encoding"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestSwapStore(t *testing.T) {
+	kvs, err := kvs2.NewWithConfig(registry2.New(), "memory", "_default", &mock.ConfigProvider{})
+	assert.NoError(t, err)
+	store := NewSwapStore(&swapKVSAdapter{KVS: kvs})
+
+	_, err = store.Get("missing")
+	assert.Error(t, err)
+
+	terms := &Terms{
+		ReclamationDeadline: time.Hour,
+		TMSID1:              token.TMSID{Network: "alpha"},
+		Type1:               "EUR",
+		Amount1:             10,
+		TMSID2:              token.TMSID{Network: "beta"},
+		Type2:               "USD",
+		Amount2:             11,
+	}
+	options := &SwapOptions{Wallet1: "alice", Wallet2: "alice.beta", Auditor: []byte("auditor"), ClaimMargin: 5 * time.Minute}
+	assert.NoError(t, store.Put(&Swap{ID: "s1", Role: SwapInitiator, Terms: terms, Options: options, Status: SwapLocked1, PreImage: []byte("pre-image")}))
+	assert.NoError(t, store.Put(&Swap{ID: "s2", Role: SwapResponder, Terms: terms, Status: SwapCompleted}))
+	assert.NoError(t, store.Put(&Swap{ID: "s3", Role: SwapResponder, Terms: terms, Status: SwapAgreed}))
+
+	swap, err := store.Get("s1")
+	assert.NoError(t, err)
+	assert.Equal(t, SwapInitiator, swap.Role)
+	assert.Equal(t, SwapLocked1, swap.Status)
+	assert.Equal(t, []byte("pre-image"), swap.PreImage)
+	assert.Equal(t, terms, swap.Terms)
+	assert.Equal(t, options, swap.Options)
+	assert.False(t, swap.Updated.IsZero())
+
+	pending, err := store.Pending()
+	assert.NoError(t, err)
+	var ids []string
+	for _, s := range pending {
+		ids = append(ids, s.ID)
+	}
+	assert.ElementsMatch(t, []string{"s1", "s3"}, ids)
+}
+
+func TestTermsResponderDeadline(t *testing.T) {
+	terms := &Terms{ReclamationDeadline: time.Hour, Type1: "EUR", Amount1: 10, Type2: "USD", Amount2: 11}
+	assert.NoError(t, terms.Validate())
+	assert.Equal(t, 30*time.Minute, terms.ResponderLockDeadline())
+
+	terms.ResponderDeadline = 10 * time.Minute
+	assert.NoError(t, terms.Validate())
+	assert.Equal(t, 10*time.Minute, terms.ResponderLockDeadline())
+
+	terms.ResponderDeadline = time.Hour
+	assert.Error(t, terms.Validate())
+	terms.ResponderDeadline = -time.Minute
+	assert.Error(t, terms.Validate())
+}
+
+func TestSwapOptions(t *testing.T) {
+	options, err := compileSwapOptions(nil, WithSwapWallets("alice", "alice.beta"))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultClaimMargin, options.claimMargin())
+
+	// the options of a resumed swap override the persisted ones
+	resumed, err := compileSwapOptions(options, WithSwapAuditor([]byte("auditor")), WithSwapClaimMargin(5*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, &SwapOptions{Wallet1: "alice", Wallet2: "alice.beta", Auditor: view.Identity("auditor"), ClaimMargin: 5 * time.Minute}, resumed)
+	assert.Empty(t, options.Auditor)
+
+	_, err = compileSwapOptions(nil, WithSwapClaimMargin(0))
+	assert.EqualError(t, err, "the claim margin must be positive, got [0s]")
+}
+
+func TestSwapLockChecks(t *testing.T) {
+	now := time.Now()
+	terms := &Terms{ReclamationDeadline: time.Hour, Type1: "EUR", Amount1: 10, Type2: "USD", Amount2: 11}
+	options := &SwapOptions{ClaimMargin: 10 * time.Minute}
+	hashInfo := HashInfo{Hash: []byte("hash"), HashFunc: crypto.SHA256, HashEncoding: encoding.Base64}
+
+	// the responder locks for 30 minutes, and needs 10 more minutes to claim the initiator's leg
+	initiator := &Script{Deadline: now.Add(time.Hour), HashInfo: hashInfo}
+	assert.NoError(t, checkInitiatorLock(initiator, terms, options, now))
+	initiator.Deadline = now.Add(40 * time.Minute)
+	assert.NoError(t, checkInitiatorLock(initiator, terms, options, now))
+	initiator.Deadline = now.Add(35 * time.Minute)
+	assert.Error(t, checkInitiatorLock(initiator, terms, options, now))
+	initiator.Deadline = now.Add(time.Hour)
+
+	// the initiator needs 10 minutes to claim the responder's leg
+	responder := &Script{Deadline: now.Add(30 * time.Minute), HashInfo: hashInfo}
+	assert.NoError(t, checkResponderLock(responder, initiator, options, now))
+	responder.Deadline = now.Add(5 * time.Minute)
+	assert.EqualError(t, checkResponderLock(responder, initiator, options, now), fmt.Sprintf("the responder's lock expires at [%s], in less than [10m0s]", responder.Deadline))
+	responder.Deadline = now.Add(time.Hour)
+	assert.Error(t, checkResponderLock(responder, initiator, options, now))
+	responder.Deadline = now.Add(30 * time.Minute)
+	responder.HashInfo.Hash = []byte("another hash")
+	assert.EqualError(t, checkResponderLock(responder, initiator, options, now), "the responder's lock does not use the initiator's hash")
+}
+
+func TestCheckLock(t *testing.T) {
+	// the deadlines read from the scripts carry no monotonic clock reading
+	now := time.Now().Round(0)
+	options := &SwapOptions{ClaimMargin: 10 * time.Minute}
+	initiator := &Script{
+		Sender:    view.Identity("alice"),
+		Recipient: view.Identity("bob"),
+		Deadline:  now.Add(time.Hour),
+		HashInfo:  HashInfo{Hash: []byte("hash"), HashFunc: crypto.SHA256, HashEncoding: encoding.Base64},
+	}
+	lock := func(script *Script) *OutputStream {
+		rawScript, err := json.Marshal(script)
+		assert.NoError(t, err)
+		owner, err := identity.MarshallRawOwner(&identity.RawOwner{Type: ScriptType, Identity: rawScript})
+		assert.NoError(t, err)
+		return NewOutputStream(token.NewOutputStream([]*token.Output{
+			{Owner: owner, Type: "USD", Quantity: token2.NewQuantityFromUInt64(11)},
+			{Owner: view.Identity("bob"), Type: "USD", Quantity: token2.NewQuantityFromUInt64(5)},
+		}, 64))
+	}
+	check := func(script *Script) error {
+		return checkResponderLock(script, initiator, options, now)
+	}
+
+	responder := &Script{
+		Sender:    view.Identity("bob"),
+		Recipient: view.Identity("alice"),
+		Deadline:  now.Add(30 * time.Minute),
+		HashInfo:  initiator.HashInfo,
+	}
+	script, err := checkLock(lock(responder), view.Identity("alice"), view.Identity("bob"), "USD", 11, check)
+	assert.NoError(t, err)
+	assert.Equal(t, responder.HashInfo, script.HashInfo)
+
+	// receiveLock accepts a lock transaction only if checkLock accepts its lock
+	_, err = checkLock(lock(responder), view.Identity("alice"), view.Identity("bob"), "USD", 10, check)
+	assert.EqualError(t, err, "expected [10] of type [USD], got [11] of type [USD]")
+	_, err = checkLock(lock(responder), view.Identity("alice"), view.Identity("charlie"), "USD", 11, check)
+	assert.EqualError(t, err, "expected the counterparty as sender of the script")
+
+	wrongHash := *responder
+	wrongHash.HashInfo.Hash = []byte("another hash")
+	_, err = checkLock(lock(&wrongHash), view.Identity("alice"), view.Identity("bob"), "USD", 11, check)
+	assert.EqualError(t, err, "invalid lock: the responder's lock does not use the initiator's hash")
+
+	tooShort := *responder
+	tooShort.Deadline = now.Add(5 * time.Minute)
+	_, err = checkLock(lock(&tooShort), view.Identity("alice"), view.Identity("bob"), "USD", 11, check)
+	assert.EqualError(t, err, fmt.Sprintf("invalid lock: the responder's lock expires at [%s], in less than [10m0s]", tooShort.Deadline))
+}
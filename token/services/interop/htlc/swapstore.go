@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// swapPrefix is the prefix of the keys of the swap records
+const swapPrefix = "token-sdk.htlc.swap"
+
+// SwapRole is the role of a party in a swap
+type SwapRole string
+
+const (
+	// SwapInitiator is the party that chooses the pre-image and locks first, on the first TMS
+	SwapInitiator SwapRole = "initiator"
+	// SwapResponder is the party that locks second, on the second TMS, with the initiator's hash
+	SwapResponder SwapRole = "responder"
+)
+
+// SwapStatus is the progress of a swap
+type SwapStatus string
+
+const (
+	// SwapAgreed means that the parties agreed on the terms
+	SwapAgreed SwapStatus = "agreed"
+	// SwapLocked1 means that the initiator's leg is locked on the first TMS
+	SwapLocked1 SwapStatus = "locked-1"
+	// SwapLocked2 means that the responder's leg is locked on the second TMS
+	SwapLocked2 SwapStatus = "locked-2"
+	// SwapCompleted means that the party claimed the counterparty's leg
+	SwapCompleted SwapStatus = "completed"
+	// SwapReclaimed means that the party reclaimed its own leg after the deadline
+	SwapReclaimed SwapStatus = "reclaimed"
+	// SwapFailed means that the swap stopped before the party locked anything
+	SwapFailed SwapStatus = "failed"
+)
+
+// IsFinal returns true if the swap cannot progress anymore
+func (s SwapStatus) IsFinal() bool {
+	return s == SwapCompleted || s == SwapReclaimed || s == SwapFailed
+}
+
+// Swap is the persisted progress of a swap, as seen by one of the parties
+type Swap struct {
+	// ID identifies the swap locally
+	ID string
+	// Role is the role of this party
+	Role SwapRole
+	// Terms are the agreed terms
+	Terms *Terms
+	// Counterparty is the identity of the counterparty's FSC node
+	Counterparty view.Identity
+	// Options are the options this party started the swap with
+	Options *SwapOptions
+	// Script1 is the script of the initiator's leg, on the first TMS
+	Script1 *Script
+	// Script2 is the script of the responder's leg, on the second TMS
+	Script2 *Script
+	// PreImage is the pre-image of the hash locking both legs, known to the responder once the initiator claimed
+	PreImage []byte
+	// LockTxID1 is the ID of the transaction locking the initiator's leg
+	LockTxID1 string
+	// LockTxID2 is the ID of the transaction locking the responder's leg
+	LockTxID2 string
+	// ClaimTxID is the ID of the transaction claiming the counterparty's leg
+	ClaimTxID string
+	// ReclaimTxID is the ID of the transaction reclaiming this party's own leg
+	ReclaimTxID string
+	// Status is the progress of the swap
+	Status SwapStatus
+	// Error describes the last failure, if any
+	Error string
+	// Updated is the time of the last update
+	Updated time.Time
+}
+
+// SwapKVS models the key-value store the swaps are persisted in
+type SwapKVS interface {
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+	GetByPartialCompositeID(prefix string, attrs []string) (SwapIterator, error)
+}
+
+// SwapIterator iterates over the entries of a SwapKVS range
+type SwapIterator interface {
+	HasNext() bool
+	Close() error
+	Next(state interface{}) (string, error)
+}
+
+// swapKVSAdapter adapts the KVS of the node to the SwapKVS interface
+type swapKVSAdapter struct {
+	*kvs2.KVS
+}
+
+func (k *swapKVSAdapter) GetByPartialCompositeID(prefix string, attrs []string) (SwapIterator, error) {
+	it, err := k.KVS.GetByPartialCompositeID(prefix, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// SwapStore persists the progress of the swaps, so that they survive a restart
+type SwapStore struct {
+	kvs SwapKVS
+}
+
+// NewSwapStore returns a swap store backed by the passed KVS
+func NewSwapStore(kvs SwapKVS) *SwapStore {
+	return &SwapStore{kvs: kvs}
+}
+
+// GetSwapStore returns the swap store backed by the KVS of the node
+func GetSwapStore(sp view2.ServiceProvider) *SwapStore {
+	return NewSwapStore(&swapKVSAdapter{KVS: kvs2.GetService(sp)})
+}
+
+// Put stores the passed swap, updating its timestamp
+func (s *SwapStore) Put(swap *Swap) error {
+	key, err := kvs2.CreateCompositeKey(swapPrefix, []string{swap.ID})
+	if err != nil {
+		return errors.Wrapf(err, "failed creating key for swap [%s]", swap.ID)
+	}
+	swap.Updated = time.Now()
+	if err := s.kvs.Put(key, swap); err != nil {
+		return errors.Wrapf(err, "failed storing swap [%s]", swap.ID)
+	}
+	return nil
+}
+
+// Get returns the swap with the passed ID
+func (s *SwapStore) Get(id string) (*Swap, error) {
+	key, err := kvs2.CreateCompositeKey(swapPrefix, []string{id})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating key for swap [%s]", id)
+	}
+	swap := &Swap{}
+	if err := s.kvs.Get(key, swap); err != nil {
+		return nil, errors.Wrapf(err, "failed loading swap [%s]", id)
+	}
+	if len(swap.ID) == 0 {
+		return nil, errors.Errorf("swap [%s] not found", id)
+	}
+	return swap, nil
+}
+
+// Pending returns the swaps that can still progress
+func (s *SwapStore) Pending() ([]*Swap, error) {
+	it, err := s.kvs.GetByPartialCompositeID(swapPrefix, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed iterating over swaps")
+	}
+	defer it.Close()
+	var swaps []*Swap
+	for it.HasNext() {
+		swap := &Swap{}
+		if _, err := it.Next(swap); err != nil {
+			return nil, errors.Wrap(err, "failed loading swap")
+		}
+		if !swap.Status.IsFinal() {
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps, nil
+}
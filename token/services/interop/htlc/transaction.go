@@ -314,7 +314,10 @@ func (t *Transaction) recipientAsScript(sender, recipient view.Identity, deadlin
 		if err != nil {
 			return nil, nil, nil, err
 		}
-		hash := hashFunc.New()
+		hash, err := NewHash(hashFunc)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		if _, err := hash.Write(preImage); err != nil {
 			return nil, nil, nil, err
 		}
@@ -27,11 +27,14 @@ type PreImageSelector struct {
 func (f *PreImageSelector) Filter(tok *token.UnspentToken, script *Script) (bool, error) {
 	logger.Debugf("token [%s,%s,%s,%s] contains a script? Yes", tok.Id, view.Identity(tok.Owner.Raw).UniqueID(), tok.Type, tok.Quantity)
 
-	if !script.HashInfo.HashFunc.Available() {
+	if !HashAvailable(script.HashInfo.HashFunc) {
 		logger.Errorf("script hash function not available [%d]", script.HashInfo.HashFunc)
 		return false, nil
 	}
-	hash := script.HashInfo.HashFunc.New()
+	hash, err := NewHash(script.HashInfo.HashFunc)
+	if err != nil {
+		return false, err
+	}
 	if _, err := hash.Write(f.preImage); err != nil {
 		return false, err
 	}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/pkg/errors"
+)
+
+const (
+	Multisig = "multisig" // m-of-n owner
+	// enrollmentIDPrefix prefixes the enrollment ID of a multisig owner
+	enrollmentIDPrefix = "multisig:"
+)
+
+// MultiIdentity is an owner made of several co-owners.
+// A token owned by a MultiIdentity is spent when at least Threshold co-owners sign.
+type MultiIdentity struct {
+	Threshold  int
+	Identities []view.Identity
+}
+
+// Validate performs the following checks:
+// - There is at least one co-owner, and each co-owner is set
+// - The co-owners are distinct
+// - The threshold is between one and the number of co-owners
+func (m *MultiIdentity) Validate() error {
+	if len(m.Identities) == 0 {
+		return errors.New("no co-owners")
+	}
+	for i, id := range m.Identities {
+		if id.IsNone() {
+			return errors.Errorf("co-owner at index [%d] not set", i)
+		}
+		for j := 0; j < i; j++ {
+			if m.Identities[j].Equal(id) {
+				return errors.Errorf("co-owner at index [%d] is repeated", i)
+			}
+		}
+	}
+	if m.Threshold < 1 || m.Threshold > len(m.Identities) {
+		return errors.Errorf("invalid threshold [%d] for [%d] co-owners", m.Threshold, len(m.Identities))
+	}
+	return nil
+}
+
+// Index returns the index of the passed co-owner, -1 if the passed identity is not a co-owner
+func (m *MultiIdentity) Index(id view.Identity) int {
+	for i, coOwner := range m.Identities {
+		if coOwner.Equal(id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Wrap returns the owner whose tokens are spent when at least threshold of the passed co-owners sign
+func Wrap(threshold int, identities ...view.Identity) (view.Identity, error) {
+	m := &MultiIdentity{Threshold: threshold, Identities: identities}
+	if err := m.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid multisig identity")
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling multisig identity")
+	}
+	return identity.MarshallRawOwner(&identity.RawOwner{Type: Multisig, Identity: raw})
+}
+
+// Unwrap returns the MultiIdentity contained in the passed raw owner.
+// The returned flag is false if the passed identity is not a multisig owner, or it is empty, as the owner of a redeemed token.
+// It fails if the passed identity is not a raw owner.
+func Unwrap(raw []byte) (*MultiIdentity, bool, error) {
+	if len(raw) == 0 {
+		return nil, false, nil
+	}
+	owner, err := identity.UnmarshallRawOwner(raw)
+	if err != nil {
+		return nil, false, errors.WithMessage(err, "failed to unmarshal owner")
+	}
+	if owner.Type != Multisig {
+		return nil, false, nil
+	}
+	m := &MultiIdentity{}
+	if err := json.Unmarshal(owner.Identity, m); err != nil {
+		return nil, true, errors.Wrap(err, "failed to unmarshal RawOwner as a multisig identity")
+	}
+	return m, true, nil
+}
+
+// CoOwners returns the co-owners of the passed party, if it is a multisig owner, the party itself otherwise
+func CoOwners(party view.Identity) ([]view.Identity, error) {
+	m, ok, err := Unwrap(party)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []view.Identity{party}, nil
+	}
+	return m.Identities, nil
+}
+
+// EnrollmentID returns the enrollment ID of a multisig owner, given the enrollment IDs of its co-owners,
+// in the order of MultiIdentity.Identities
+func EnrollmentID(eIDs ...string) string {
+	return enrollmentIDPrefix + strings.Join(eIDs, ",")
+}
@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// coOwner signs by prefixing the message with its name
+type coOwner string
+
+func (c coOwner) Verify(message, sigma []byte) error {
+	if !bytes.Equal(append([]byte(c), message...), sigma) {
+		return errors.Errorf("invalid signature of [%s]", c)
+	}
+	return nil
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	ids := []view.Identity{view.Identity("alice"), view.Identity("bob"), view.Identity("charlie")}
+	raw, err := Wrap(2, ids...)
+	assert.NoError(t, err)
+	m, ok, err := Unwrap(raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, m.Threshold)
+	assert.Equal(t, ids, m.Identities)
+	assert.Equal(t, 1, m.Index(view.Identity("bob")))
+	assert.Equal(t, -1, m.Index(view.Identity("mallory")))
+
+	coOwners, err := CoOwners(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, ids, coOwners)
+	alice, err := identity.MarshallRawOwner(&identity.RawOwner{Type: identity.SerializedIdentityType, Identity: []byte("alice")})
+	assert.NoError(t, err)
+	coOwners, err = CoOwners(alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []view.Identity{alice}, coOwners)
+
+	_, ok, err = Unwrap(alice)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	// the owner of a redeemed token is empty
+	_, ok, err = Unwrap(nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	// malformed owners are reported
+	_, _, err = Unwrap(view.Identity("alice"))
+	assert.Error(t, err)
+	_, err = CoOwners(view.Identity("alice"))
+	assert.Error(t, err)
+	malformed, err := identity.MarshallRawOwner(&identity.RawOwner{Type: Multisig, Identity: []byte("{")})
+	assert.NoError(t, err)
+	_, ok, err = Unwrap(malformed)
+	assert.Error(t, err)
+	assert.True(t, ok)
+
+	_, err = Wrap(0, ids...)
+	assert.Error(t, err)
+	_, err = Wrap(4, ids...)
+	assert.Error(t, err)
+	_, err = Wrap(1, view.Identity("alice"), view.Identity("alice"))
+	assert.Error(t, err)
+	_, err = Wrap(1)
+	assert.Error(t, err)
+}
+
+func TestThreshold(t *testing.T) {
+	m := &MultiIdentity{Threshold: 2, Identities: []view.Identity{view.Identity("alice"), view.Identity("bob"), view.Identity("charlie")}}
+	verifier := &Verifier{Threshold: 2, Verifiers: []driver.Verifier{coOwner("alice"), coOwner("bob"), coOwner("charlie")}}
+	message := []byte("request")
+
+	// a single co-owner cannot spend
+	_, err := JoinSignatures(m, map[string][]byte{view.Identity("bob").UniqueID(): []byte("bobrequest")})
+	assert.Error(t, err)
+	single, err := JoinSignatures(&MultiIdentity{Threshold: 1, Identities: m.Identities}, map[string][]byte{view.Identity("bob").UniqueID(): []byte("bobrequest")})
+	assert.NoError(t, err)
+	assert.Error(t, verifier.Verify(message, single))
+
+	// two co-owners can
+	sigma, err := JoinSignatures(m, map[string][]byte{
+		view.Identity("alice").UniqueID():   []byte("alicerequest"),
+		view.Identity("charlie").UniqueID(): []byte("charlierequest"),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.Verify(message, sigma))
+
+	// an invalid signature is rejected, even if two valid ones are present
+	sigma, err = JoinSignatures(m, map[string][]byte{
+		view.Identity("alice").UniqueID():   []byte("alicerequest"),
+		view.Identity("bob").UniqueID():     []byte("forged"),
+		view.Identity("charlie").UniqueID(): []byte("charlierequest"),
+	})
+	assert.NoError(t, err)
+	assert.Error(t, verifier.Verify(message, sigma))
+
+	// signatures of non co-owners are ignored
+	_, err = JoinSignatures(m, map[string][]byte{
+		view.Identity("alice").UniqueID():   []byte("alicerequest"),
+		view.Identity("mallory").UniqueID(): []byte("malloryrequest"),
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+// Signature carries the signatures of the co-owners of a MultiIdentity, in the order of MultiIdentity.Identities.
+// The signature of a co-owner that did not sign is empty.
+type Signature struct {
+	Signatures [][]byte
+}
+
+// JoinSignatures returns the Signature of the passed MultiIdentity, given the signatures of some of its co-owners,
+// indexed by the unique ID of the co-owner. It fails if the co-owners that signed are less than the threshold.
+func JoinSignatures(m *MultiIdentity, sigmas map[string][]byte) ([]byte, error) {
+	sig := &Signature{Signatures: make([][]byte, len(m.Identities))}
+	count := 0
+	for i, id := range m.Identities {
+		if sigma, ok := sigmas[id.UniqueID()]; ok && len(sigma) != 0 {
+			sig.Signatures[i] = sigma
+			count++
+		}
+	}
+	if count < m.Threshold {
+		return nil, errors.Errorf("not enough signatures, [%d] out of [%d]", count, m.Threshold)
+	}
+	return json.Marshal(sig)
+}
+
+// Verifier checks that a token owned by a MultiIdentity is spent by at least Threshold of its co-owners
+type Verifier struct {
+	Threshold int
+	// Verifiers are the verifiers of the co-owners, in the order of MultiIdentity.Identities
+	Verifiers []driver.Verifier
+}
+
+// Verify checks that the passed Signature carries at least Threshold valid signatures of distinct co-owners.
+// Any signature present must be valid.
+func (v *Verifier) Verify(message []byte, sigma []byte) error {
+	sig := &Signature{}
+	if err := json.Unmarshal(sigma, sig); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal multisig signature")
+	}
+	if len(sig.Signatures) != len(v.Verifiers) {
+		return errors.Errorf("invalid multisig signature, expected [%d] entries, got [%d]", len(v.Verifiers), len(sig.Signatures))
+	}
+	valid := 0
+	for i, s := range sig.Signatures {
+		if len(s) == 0 {
+			continue
+		}
+		if err := v.Verifiers[i].Verify(message, s); err != nil {
+			return errors.WithMessagef(err, "failed verifying the signature of the co-owner at index [%d]", i)
+		}
+		valid++
+	}
+	if valid < v.Threshold {
+		return errors.Errorf("multisig token spent by [%d] co-owners, at least [%d] expected", valid, v.Threshold)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multisig
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Ownership implements the Ownership interface for multisig owners
+type Ownership struct{}
+
+// AmIAnAuditor returns false for multisig ownership
+func (o *Ownership) AmIAnAuditor(tms *token.ManagementService) bool {
+	return false
+}
+
+// IsMine returns true if one is a co-owner of a multisig owner
+func (o *Ownership) IsMine(tms *token.ManagementService, tok *token2.Token) ([]string, bool) {
+	m, ok, err := Unwrap(tok.Owner.Raw)
+	if !ok || err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, not a multisig owner [%v]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+	if err := m.Validate(); err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, invalid content [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+
+	var ids []string
+	for _, coOwner := range m.Identities {
+		if wallet := tms.WalletManager().OwnerWalletByIdentity(coOwner); wallet != nil {
+			id := multisigWallet(wallet)
+			if !contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	logger.Debugf("Is Mine [%s,%s,%s]? %v", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, len(ids) != 0)
+	return ids, len(ids) != 0
+}
+
+func multisigWallet(w *token.OwnerWallet) string {
+	return "multisig" + w.ID()
+}
+
+func contains(ids []string, id string) bool {
+	for _, s := range ids {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+type QueryEngine interface {
+	// UnspentTokensIteratorBy returns an iterator over all unspent tokens by type and id. Type can be empty
+	UnspentTokensIteratorBy(id, typ string) (driver.UnspentTokensIterator, error)
+}
+
+// OwnerWallet is a combination of a wallet and a query service
+type OwnerWallet struct {
+	wallet       *token.OwnerWallet
+	queryService QueryEngine
+}
+
+// ListTokens returns the tokens that match the passed options and whose multisig owner has a co-owner in this wallet
+func (w *OwnerWallet) ListTokens(opts ...token.ListTokensOption) (*token2.UnspentTokens, error) {
+	compiledOpts, err := token.CompileListTokensOption(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compile options")
+	}
+	it, err := w.queryService.UnspentTokensIteratorBy(multisigWallet(w.wallet), compiledOpts.TokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get iterator over unspent tokens")
+	}
+	defer it.Close()
+	var tokens []*token2.UnspentToken
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get next unspent token from iterator")
+		}
+		if tok == nil {
+			break
+		}
+		if _, ok, err := Unwrap(tok.Owner.Raw); !ok || err != nil {
+			logger.Debugf("token [%s] owned by a multisig identity? No [%v]", tok.Id, err)
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return &token2.UnspentTokens{Tokens: tokens}, nil
+}
+
+// Wallet returns an OwnerWallet which contains a wallet and a query service
+func Wallet(sp view2.ServiceProvider, wallet *token.OwnerWallet, opts ...token.ServiceOption) *OwnerWallet {
+	tms := token.GetManagementService(sp, opts...)
+	nw := network.GetInstance(sp, tms.Network(), tms.Channel())
+	if nw == nil {
+		return nil
+	}
+	vault, err := nw.Vault(tms.Namespace())
+	if err != nil {
+		logger.Errorf("failed to get vault for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
+		return nil
+	}
+	return &OwnerWallet{
+		wallet:       wallet,
+		queryService: vault.TokenVault().QueryEngine(),
+	}
+}
@@ -113,4 +113,8 @@ type Network interface {
 
 	// Ledger gives access to the remote ledger
 	Ledger() (Ledger, error)
+
+	// ProcessNamespace has the node commit every transaction of the passed namespace, even the ones it is not part of,
+	// so that its vault holds every token on the ledger
+	ProcessNamespace(namespace string) error
 }
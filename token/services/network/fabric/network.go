@@ -464,3 +464,7 @@ func (n *Network) LookupTransferMetadataKey(namespace string, startingTxID strin
 func (n *Network) Ledger() (driver.Ledger, error) {
 	return n.ledger, nil
 }
+
+func (n *Network) ProcessNamespace(namespace string) error {
+	return n.ch.Committer().ProcessNamespace(namespace)
+}
@@ -147,6 +147,12 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 
 	wrappedRWS := &rwsWrapper{RWSet: rws}
 
+	// the JSON paths the NFT states are indexed by
+	var nftIndexes []string
+	if cfg := tms.ConfigManager().NFT(); cfg != nil {
+		nftIndexes = cfg.Indexes
+	}
+
 	if tms.PublicParametersManager().GraphHiding() {
 		ids := metadata.SpentTokenID()
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
@@ -257,7 +263,7 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 			}
 
 			// Store Fabtoken-like entry
-			if err := r.tokenStore.StoreFabToken(ns, txID, index, tok, wrappedRWS, tokenInfoRaw, ids); err != nil {
+			if err := r.tokenStore.StoreFabToken(ns, txID, index, tok, wrappedRWS, tokenInfoRaw, ids, nftIndexes); err != nil {
 				return err
 			}
 		} else {
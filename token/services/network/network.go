@@ -380,6 +380,12 @@ func (n *Network) LookupTransferMetadataKey(namespace, startingTxID, key string,
 	return n.n.LookupTransferMetadataKey(namespace, startingTxID, key, timeout)
 }
 
+// ProcessNamespace has the node commit every transaction of the passed namespace, even the ones it is not part of,
+// so that its vault holds every token on the ledger
+func (n *Network) ProcessNamespace(namespace string) error {
+	return n.n.ProcessNamespace(namespace)
+}
+
 func (n *Network) Ledger(namespace string) (*Ledger, error) {
 	l, err := n.n.Ledger()
 	if err != nil {
@@ -232,6 +232,10 @@ func (n *Network) Ledger() (driver.Ledger, error) {
 	return n.ledger, nil
 }
 
+func (n *Network) ProcessNamespace(namespace string) error {
+	return errors.New("processing every transaction of a namespace is not supported by orion")
+}
+
 type nv struct {
 	v          *orion.Vault
 	tokenVault *vault.Vault
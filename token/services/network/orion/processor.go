@@ -113,6 +113,12 @@ func (r *RWSetProcessor) tokenRequest(req orion.Request, tx orion.ProcessTransac
 
 	wrappedRWS := &rwsWrapper{RWSet: rws}
 
+	// the JSON paths the NFT states are indexed by
+	var nftIndexes []string
+	if cfg := tms.ConfigManager().NFT(); cfg != nil {
+		nftIndexes = cfg.Indexes
+	}
+
 	if tms.PublicParametersManager().GraphHiding() {
 		// Delete inputs
 		ids := metadata.SpentTokenID()
@@ -229,7 +235,7 @@ func (r *RWSetProcessor) tokenRequest(req orion.Request, tx orion.ProcessTransac
 			}
 
 			// Store Fabtoken-like entry
-			if err := r.tokenStore.StoreFabToken(ns, txID, index, tok, wrappedRWS, tokenInfoRaw, ids); err != nil {
+			if err := r.tokenStore.StoreFabToken(ns, txID, index, tok, wrappedRWS, tokenInfoRaw, ids, nftIndexes); err != nil {
 				return err
 			}
 		} else {
@@ -13,6 +13,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	index2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/index"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -35,7 +36,9 @@ type TokenStore interface {
 	// DeleteFabToken adds to the passed rws the deletion of the passed token
 	// TODO: we should delete also the extra tokens for the ids
 	DeleteFabToken(ns string, txID string, index uint64, rws RWSet) error
-	StoreFabToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte, ids []string) error
+	// StoreFabToken adds to the passed rws the storage of the passed token, owned by the passed wallet ids.
+	// If the token is an NFT, its state is indexed by the passed JSON paths.
+	StoreFabToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte, ids []string, indexes []string) error
 	StoreIssuedHistoryToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte, issuer view.Identity, precision uint64) error
 	StoreAuditToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte) error
 }
@@ -101,6 +104,23 @@ func (cts *CommonTokenStore) DeleteFabToken(ns string, txID string, index uint64
 		}
 	}
 
+	indexKeysRaw, ok := meta[keys.NFTIndexes]
+	if ok && len(indexKeysRaw) > 0 {
+		// delete nft indexes as well
+		indexKeys := make([]string, 0)
+		if err := json.Unmarshal(indexKeysRaw, &indexKeys); err != nil {
+			return errors.Wrapf(err, "error unmarshalling nft indexes for key [%s]", outputID)
+		}
+		for _, indexKey := range indexKeys {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("delete nft index [%s]", indexKey)
+			}
+			if err := rws.DeleteState(ns, indexKey); err != nil {
+				return errors.Wrapf(err, "error deleting nft index [%s]", indexKey)
+			}
+		}
+	}
+
 	err = rws.DeleteState(ns, outputID)
 	if err != nil {
 		return errors.Wrapf(err, "error deleting key [%s]", outputID)
@@ -113,7 +133,7 @@ func (cts *CommonTokenStore) DeleteFabToken(ns string, txID string, index uint64
 	return nil
 }
 
-func (cts *CommonTokenStore) StoreFabToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte, ids []string) error {
+func (cts *CommonTokenStore) StoreFabToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte, ids []string, indexes []string) error {
 	outputID, err := keys.CreateFabTokenKey(txID, index)
 	if err != nil {
 		return errors.Wrapf(err, "error creating output ID: %s", err)
@@ -127,11 +147,19 @@ func (cts *CommonTokenStore) StoreFabToken(ns string, txID string, index uint64,
 		return err
 	}
 
+	indexKeys, err := cts.storeNFTIndexes(ns, txID, index, tok, rws, ids, indexes)
+	if err != nil {
+		return err
+	}
+
 	meta := map[string][]byte{}
 	meta[keys.Info] = infoRaw
 	if len(ids) > 0 {
 		meta[keys.IDs] = MarshalOrPanic(ids)
 	}
+	if len(indexKeys) > 0 {
+		meta[keys.NFTIndexes] = MarshalOrPanic(indexKeys)
+	}
 	if err := rws.SetStateMetadata(ns, outputID, meta); err != nil {
 		return err
 	}
@@ -167,6 +195,35 @@ func (cts *CommonTokenStore) StoreFabToken(ns string, txID string, index uint64,
 	return nil
 }
 
+// storeNFTIndexes adds to the passed rws, for each passed wallet id, the index entries of the passed token
+// at the passed paths, and returns their keys
+func (cts *CommonTokenStore) storeNFTIndexes(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, ids []string, paths []string) ([]string, error) {
+	entries := index2.Entries(tok.Type, paths)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	var indexKeys []string
+	for _, id := range ids {
+		if len(id) == 0 {
+			continue
+		}
+		for _, entry := range entries {
+			indexKey, err := keys.CreateNFTIndexKey(id, entry.Path, entry.Value, txID, index)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error creating nft index key for path [%s]", entry.Path)
+			}
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s], append nft index [%s]", txID, indexKey)
+			}
+			if err := rws.SetState(ns, indexKey, []byte{1}); err != nil {
+				return nil, err
+			}
+			indexKeys = append(indexKeys, indexKey)
+		}
+	}
+	return indexKeys, nil
+}
+
 func (cts *CommonTokenStore) StoreIssuedHistoryToken(ns string, txID string, index uint64, tok *token2.Token, rws RWSet, infoRaw []byte, issuer view.Identity, precision uint64) error {
 	outputID, err := keys.CreateIssuedHistoryTokenKey(txID, index)
 	if err != nil {
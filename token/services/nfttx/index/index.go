@@ -0,0 +1,242 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package index
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/pkg/errors"
+	"github.com/thedevsaddam/gojsonq"
+)
+
+// The encoding of a value starts with its kind, values of different kinds never match the same range
+const (
+	boolKind   = "b"
+	numberKind = "n"
+	stringKind = "s"
+)
+
+// Entry is the encoded value of an NFT state at an indexed path
+type Entry struct {
+	Path  string
+	Value string
+}
+
+// Entries returns the encoded values of the NFT state carried by the passed token type at the passed paths.
+// The paths whose value is missing, or is not a string, a number, or a boolean, are not indexed.
+func Entries(tokenType string, paths []string) []Entry {
+	if len(paths) == 0 {
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(tokenType)
+	if err != nil {
+		// not an NFT
+		return nil
+	}
+	var entries []Entry
+	for _, path := range paths {
+		res, ok := lookup(decoded, path)
+		if !ok {
+			continue
+		}
+		value, err := EncodeValue(res)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{Path: path, Value: value})
+	}
+	return entries
+}
+
+// UniqueID returns the string at the passed path of the NFT state carried by the passed token type,
+// the empty string if the token type is not an NFT state or there is no such string
+func UniqueID(tokenType string, path string) string {
+	decoded, err := base64.StdEncoding.DecodeString(tokenType)
+	if err != nil {
+		return ""
+	}
+	res, ok := lookup(decoded, path)
+	if !ok {
+		return ""
+	}
+	id, _ := res.(string)
+	return id
+}
+
+// numberDecoder decodes the numbers of a JSON document as json.Number, so that no digit is lost
+type numberDecoder struct{}
+
+func (numberDecoder) Decode(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// lookup returns the value at the passed path of the passed JSON document, if any.
+// Numbers are returned as json.Number.
+func lookup(doc []byte, path string) (interface{}, bool) {
+	jq := gojsonq.New(gojsonq.SetDecoder(numberDecoder{})).FromString(string(doc))
+	res := jq.Find(path)
+	if jq.Error() != nil || res == nil {
+		return nil, false
+	}
+	return res, true
+}
+
+// EncodeValue encodes the passed value so that the encodings of values of the same kind sort as the values do.
+// Numbers are encoded from their canonical decimal representation, therefore integers of any size are compared exactly.
+// Strings are compared byte-wise.
+func EncodeValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case string:
+		if err := keys.ValidateCompositeKeyAttribute(value); err != nil {
+			return "", errors.WithMessagef(err, "cannot index string [%s]", value)
+		}
+		return stringKind + value, nil
+	case bool:
+		if value {
+			return boolKind + "1", nil
+		}
+		return boolKind + "0", nil
+	case json.Number:
+		return encodeNumber(value.String())
+	case float64:
+		return encodeNumber(strconv.FormatFloat(value, 'g', -1, 64))
+	case float32:
+		return encodeNumber(strconv.FormatFloat(float64(value), 'g', -1, 32))
+	case int:
+		return encodeNumber(strconv.FormatInt(int64(value), 10))
+	case int32:
+		return encodeNumber(strconv.FormatInt(int64(value), 10))
+	case int64:
+		return encodeNumber(strconv.FormatInt(value, 10))
+	case uint:
+		return encodeNumber(strconv.FormatUint(uint64(value), 10))
+	case uint32:
+		return encodeNumber(strconv.FormatUint(uint64(value), 10))
+	case uint64:
+		return encodeNumber(strconv.FormatUint(value, 10))
+	default:
+		return "", errors.Errorf("cannot index value [%v] of type [%T]", v, v)
+	}
+}
+
+const (
+	// maxExponent bounds the decimal exponent of the numbers that can be indexed
+	maxExponent = 49999
+	// numberTerminator ends the digits of negative numbers, it sorts after all the digits
+	numberTerminator = ":"
+)
+
+// encodeNumber returns the encoding of the passed decimal number, 0.d_1d_2...d_n * 10^e with d_1 and d_n not zero.
+// The sign comes first, then the exponent, in five digits, and the digits of the number.
+// For negative numbers, the exponent and the digits are complemented and followed by a terminator,
+// so that the encodings sort as the numbers do.
+func encodeNumber(number string) (string, error) {
+	negative, digits, exp, err := parseDecimal(number)
+	if err != nil {
+		return "", errors.WithMessagef(err, "cannot index number [%s]", number)
+	}
+	switch {
+	case len(digits) == 0:
+		return numberKind + "1", nil
+	case exp > maxExponent || exp < -maxExponent:
+		return "", errors.Errorf("cannot index number [%s]: exponent out of range", number)
+	case !negative:
+		return fmt.Sprintf("%s2%05d%s", numberKind, maxExponent+1+exp, digits), nil
+	}
+	complement := []byte(digits)
+	for i, d := range complement {
+		complement[i] = '9' - d + '0'
+	}
+	return fmt.Sprintf("%s0%05d%s%s", numberKind, maxExponent+1-exp, complement, numberTerminator), nil
+}
+
+// parseDecimal parses the passed decimal number, in the JSON syntax, into its sign, its significant digits,
+// without leading and trailing zeros, and the exponent e such that the number is 0.digits * 10^e.
+// Zero has no digits.
+func parseDecimal(number string) (bool, string, int, error) {
+	negative := strings.HasPrefix(number, "-")
+	mantissa := strings.TrimPrefix(number, "-")
+	exp := 0
+	if i := strings.IndexAny(mantissa, "eE"); i >= 0 {
+		var err error
+		exp, err = strconv.Atoi(mantissa[i+1:])
+		if err != nil {
+			return false, "", 0, errors.Errorf("invalid exponent")
+		}
+		mantissa = mantissa[:i]
+	}
+	integer, fraction := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		integer, fraction = mantissa[:i], mantissa[i+1:]
+	}
+	digits := integer + fraction
+	if len(integer) == 0 || len(digits) == 0 || strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return false, "", 0, errors.Errorf("invalid number")
+	}
+	exp += len(integer)
+	trimmed := strings.TrimLeft(digits, "0")
+	exp -= len(digits) - len(trimmed)
+	return negative, strings.TrimRight(trimmed, "0"), exp, nil
+}
+
+// Equal returns the range [from, to) of the encodings of the values equal to the passed one
+func Equal(v interface{}) (string, string, error) {
+	value, err := EncodeValue(v)
+	if err != nil {
+		return "", "", err
+	}
+	return value, value + "\x01", nil
+}
+
+// Between returns the range [from, to) of the encodings of the values between the passed ones, both included.
+// A nil bound means no bound, the values are then those of the kind of the other bound.
+func Between(lower, upper interface{}) (string, string, error) {
+	if lower == nil && upper == nil {
+		return "", "", errors.New("at least one bound must be set")
+	}
+	var from, to string
+	if lower != nil {
+		value, err := EncodeValue(lower)
+		if err != nil {
+			return "", "", err
+		}
+		from = value
+	}
+	if upper != nil {
+		value, err := EncodeValue(upper)
+		if err != nil {
+			return "", "", err
+		}
+		to = value + "\x01"
+	}
+	switch {
+	case lower == nil:
+		from = to[:1]
+	case upper == nil:
+		to = from[:1] + string(keys.MaxUnicodeRuneValue)
+	case from[:1] != to[:1]:
+		return "", "", errors.Errorf("bounds [%v] and [%v] are of different kinds", lower, upper)
+	}
+	return from, to, nil
+}
+
+// Prefix returns the range [from, to) of the encodings of the strings starting with the passed prefix
+func Prefix(prefix string) (string, string, error) {
+	value, err := EncodeValue(prefix)
+	if err != nil {
+		return "", "", err
+	}
+	return value, value + string(keys.MaxUnicodeRuneValue), nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package index
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntries(t *testing.T) {
+	state := `{"LinearID":"abc","Valuation":9007199254740993,"Sold":true,"Owner":{"Name":"alice"},"Tags":["a"]}`
+	tokenType := base64.StdEncoding.EncodeToString([]byte(state))
+
+	valuation, err := EncodeValue(uint64(9007199254740993))
+	assert.NoError(t, err)
+	entries := Entries(tokenType, []string{"LinearID", "Valuation", "Sold", "Owner.Name", "Tags", "Missing"})
+	assert.Equal(t, []Entry{
+		{Path: "LinearID", Value: "sabc"},
+		{Path: "Valuation", Value: valuation},
+		{Path: "Sold", Value: "b1"},
+		{Path: "Owner.Name", Value: "salice"},
+	}, entries)
+
+	// not an NFT
+	assert.Empty(t, Entries("USD", []string{"LinearID"}))
+	// no paths
+	assert.Empty(t, Entries(tokenType, nil))
+}
+
+func TestNumberOrder(t *testing.T) {
+	numbers := []string{
+		"-9007199254740993", "-9007199254740992", "-1e10", "-100.5", "-100", "-1", "-0.25", "-0.2", "0",
+		"0.0001", "0.2", "0.25", "1", "2", "10", "100.5", "1e10", "9007199254740992", "9007199254740993",
+	}
+	var encodings []string
+	for _, n := range numbers {
+		e, err := encodeNumber(n)
+		assert.NoError(t, err)
+		encodings = append(encodings, e)
+	}
+	assert.True(t, sort.StringsAreSorted(encodings), "%v", encodings)
+
+	// the encoding is canonical
+	for _, equal := range [][]interface{}{
+		{"0", "-0", "0.000", "0e5"},
+		{"100", "1e2", "100.0", "0.001e5"},
+		{"-0.25", "-2.5e-1", "-0.250"},
+	} {
+		expected, err := encodeNumber(equal[0].(string))
+		assert.NoError(t, err)
+		for _, n := range equal[1:] {
+			e, err := encodeNumber(n.(string))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, e, "%s", n)
+		}
+	}
+	e, err := EncodeValue(json.Number("100"))
+	assert.NoError(t, err)
+	for _, n := range []interface{}{100, uint64(100), 100.0, float32(100)} {
+		e2, err := EncodeValue(n)
+		assert.NoError(t, err)
+		assert.Equal(t, e, e2)
+	}
+
+	// integers above 2^53 do not collide
+	e1, err := EncodeValue(uint64(9007199254740993))
+	assert.NoError(t, err)
+	e2, err := EncodeValue(json.Number("9007199254740992"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, e1, e2)
+
+	for _, invalid := range []string{"", "-", ".5", "1e", "abc", "NaN", "+Inf", "1e99999"} {
+		_, err := encodeNumber(invalid)
+		assert.Error(t, err, "%s", invalid)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	in := func(v interface{}, from, to string) bool {
+		e, err := EncodeValue(v)
+		assert.NoError(t, err)
+		return from <= e+"\x00" && e+"\x00" < to
+	}
+
+	from, to, err := Equal("abc")
+	assert.NoError(t, err)
+	assert.True(t, in("abc", from, to))
+	assert.False(t, in("abcd", from, to))
+	assert.False(t, in("ab", from, to))
+
+	from, to, err = Between(10, 20)
+	assert.NoError(t, err)
+	assert.True(t, in(10, from, to))
+	assert.True(t, in(15.5, from, to))
+	assert.True(t, in(20, from, to))
+	assert.False(t, in(9, from, to))
+	assert.False(t, in(21, from, to))
+	assert.False(t, in("15", from, to))
+
+	from, to, err = Between(nil, 0)
+	assert.NoError(t, err)
+	assert.True(t, in(-5, from, to))
+	assert.False(t, in(1, from, to))
+	assert.False(t, in(false, from, to))
+
+	from, to, err = Between(5, nil)
+	assert.NoError(t, err)
+	assert.True(t, in(1e10, from, to))
+	assert.False(t, in(4, from, to))
+	assert.False(t, in("zzz", from, to))
+
+	_, _, err = Between(nil, nil)
+	assert.Error(t, err)
+	_, _, err = Between(1, "a")
+	assert.Error(t, err)
+
+	from, to, err = Prefix("ab")
+	assert.NoError(t, err)
+	assert.True(t, in("ab", from, to))
+	assert.True(t, in("abc", from, to))
+	assert.False(t, in("a", from, to))
+	assert.False(t, in("b", from, to))
+
+	_, err = EncodeValue([]string{"a"})
+	assert.Error(t, err)
+}
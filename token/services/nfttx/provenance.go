@@ -0,0 +1,201 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nfttx
+
+import (
+	"sort"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/index"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+)
+
+const (
+	// Redacted replaces the enrollment ID of a party the caller has no audit visibility on
+	Redacted = "<redacted>"
+	// DefaultUniqueIDPath is the JSON path of the unique ID of the NFT states, when not configured
+	DefaultUniqueIDPath = "LinearID"
+)
+
+// UniqueIDs returns the extractor of the unique IDs of the NFTs moved by the transactions of the passed TMS.
+// The unique ID is read from the JSON path configured in `nft.uniqueIDPath`, DefaultUniqueIDPath if not set.
+func UniqueIDs(tms *token.ManagementService) ttxdb.UniqueIDExtractor {
+	path := DefaultUniqueIDPath
+	if cfg := tms.ConfigManager().NFT(); cfg != nil && len(cfg.UniqueIDPath) != 0 {
+		path = cfg.UniqueIDPath
+	}
+	return func(tokenType string) string {
+		return index.UniqueID(tokenType, path)
+	}
+}
+
+// ProvenanceEntry is a single issue, transfer, or redeem of an NFT
+type ProvenanceEntry struct {
+	// TxID is the ID of the transaction
+	TxID string
+	// ActionType is the type of action performed by the transaction
+	ActionType ttxdb.ActionType
+	// SenderEID is the enrollment ID of the previous owner, empty for issues
+	SenderEID string
+	// RecipientEID is the enrollment ID of the new owner, empty for redeems
+	RecipientEID string
+	// Status is the status of the transaction
+	Status ttxdb.TxStatus
+	// Timestamp is the time the transaction was recorded
+	Timestamp time.Time
+}
+
+// Provenance is the ordered chain of transactions that issued and moved an NFT
+type Provenance struct {
+	// UniqueID is the unique ID of the NFT
+	UniqueID string
+	// Entries are ordered from the oldest to the most recent
+	Entries []*ProvenanceEntry
+}
+
+// Owners returns the enrollment IDs of the owners of the NFT, in order.
+func (p *Provenance) Owners() []string {
+	var owners []string
+	for _, entry := range p.Entries {
+		if entry.ActionType == ttxdb.Redeem {
+			continue
+		}
+		owners = append(owners, entry.RecipientEID)
+	}
+	return owners
+}
+
+type transactionQueryExecutor interface {
+	Transactions(params ttxdb.QueryTransactionsParams) (*ttxdb.TransactionIterator, error)
+	Done()
+}
+
+// Visibility tells if the caller has audit visibility on the parties of a transaction
+type Visibility func(record *ttxdb.TransactionRecord) bool
+
+// AuditorVisibility is the visibility of an auditor: it has audited every transaction in its db,
+// and it sees all the parties
+func AuditorVisibility(*ttxdb.TransactionRecord) bool {
+	return true
+}
+
+// OwnerVisibility is the visibility of the owner with the passed enrollment ID: it exchanged audit info
+// only with the counterparties of the transactions it took part in, as sender or recipient.
+// The other transactions in the owner-side db, those of the other wallets of the node, are redacted.
+func OwnerVisibility(enrollmentID string) Visibility {
+	return func(record *ttxdb.TransactionRecord) bool {
+		return len(enrollmentID) != 0 && (record.SenderEID == enrollmentID || record.RecipientEID == enrollmentID)
+	}
+}
+
+// ProvenanceService returns the provenance of NFTs as recorded in a transaction db,
+// with the enrollment IDs the caller has audit visibility on.
+type ProvenanceService struct {
+	newQueryExecutor func() transactionQueryExecutor
+	visibility       Visibility
+}
+
+// NewProvenanceService returns the provenance service over the passed transaction db, for a caller with the passed visibility
+func NewProvenanceService(db *ttxdb.DB, visibility Visibility) *ProvenanceService {
+	return &ProvenanceService{
+		newQueryExecutor: func() transactionQueryExecutor {
+			return db.NewQueryExecutor()
+		},
+		visibility: visibility,
+	}
+}
+
+// NewOwnerProvenanceService returns the provenance service over the owner-side db of the TMS of the passed wallet,
+// for the owner of the wallet
+func NewOwnerProvenanceService(sp view2.ServiceProvider, w *token.OwnerWallet) *ProvenanceService {
+	o := owner.New(sp, w.TMS())
+	return &ProvenanceService{
+		newQueryExecutor: func() transactionQueryExecutor {
+			return o.NewQueryExecutor()
+		},
+		visibility: OwnerVisibility(w.EnrollmentID()),
+	}
+}
+
+// NewAuditorProvenanceService returns the provenance service over the db of the passed auditor wallet
+func NewAuditorProvenanceService(sp view2.ServiceProvider, w *token.AuditorWallet) *ProvenanceService {
+	a := auditor.New(sp, w)
+	return &ProvenanceService{
+		newQueryExecutor: func() transactionQueryExecutor {
+			return a.NewQueryExecutor()
+		},
+		visibility: AuditorVisibility,
+	}
+}
+
+// Provenance returns the provenance of the NFT with the passed unique ID.
+// Deleted transactions are skipped.
+func (p *ProvenanceService) Provenance(uniqueID string) (*Provenance, error) {
+	if len(uniqueID) == 0 {
+		return nil, errors.New("unique ID not specified")
+	}
+	qe := p.newQueryExecutor()
+	defer qe.Done()
+
+	it, err := qe.Transactions(ttxdb.QueryTransactionsParams{UniqueIDs: []string{uniqueID}})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying transactions for [%s]", uniqueID)
+	}
+	defer it.Close()
+
+	var records []*ttxdb.TransactionRecord
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed iterating over transactions for [%s]", uniqueID)
+		}
+		if record == nil {
+			break
+		}
+		records = append(records, record)
+	}
+	return provenance(uniqueID, records, p.visibility), nil
+}
+
+// provenance builds the provenance of an NFT from its transaction records.
+// The parties of the transactions the caller has no visibility on are redacted.
+// So are the empty senders of transfers and redeems, and the empty recipients of issues and transfers:
+// the db did not get the audit info of those parties.
+func provenance(uniqueID string, records []*ttxdb.TransactionRecord, visibility Visibility) *Provenance {
+	res := &Provenance{UniqueID: uniqueID}
+	for _, record := range records {
+		if record.Status == ttxdb.Deleted {
+			continue
+		}
+		entry := &ProvenanceEntry{
+			TxID:         record.TxID,
+			ActionType:   record.ActionType,
+			SenderEID:    record.SenderEID,
+			RecipientEID: record.RecipientEID,
+			Status:       record.Status,
+			Timestamp:    record.Timestamp,
+		}
+		visible := visibility != nil && visibility(record)
+		if record.ActionType != ttxdb.Issue && (!visible || len(entry.SenderEID) == 0) {
+			entry.SenderEID = Redacted
+		}
+		if record.ActionType != ttxdb.Redeem && (!visible || len(entry.RecipientEID) == 0) {
+			entry.RecipientEID = Redacted
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+	sort.SliceStable(res.Entries, func(i, j int) bool {
+		return res.Entries[i].Timestamp.Before(res.Entries[j].Timestamp)
+	})
+	return res
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nfttx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/memory"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvenance(t *testing.T) {
+	now := time.Now()
+	records := []*ttxdb.TransactionRecord{
+		{TxID: "tx3", ActionType: ttxdb.Transfer, SenderEID: "bob", RecipientEID: "", Status: ttxdb.Confirmed, Timestamp: now.Add(2 * time.Second)},
+		{TxID: "tx1", ActionType: ttxdb.Issue, RecipientEID: "alice", Status: ttxdb.Confirmed, Timestamp: now},
+		{TxID: "txd", ActionType: ttxdb.Transfer, SenderEID: "alice", RecipientEID: "eve", Status: ttxdb.Deleted, Timestamp: now},
+		{TxID: "tx2", ActionType: ttxdb.Transfer, SenderEID: "", RecipientEID: "bob", Status: ttxdb.Confirmed, Timestamp: now.Add(time.Second)},
+		{TxID: "tx4", ActionType: ttxdb.Redeem, SenderEID: "charlie", Status: ttxdb.Pending, Timestamp: now.Add(3 * time.Second)},
+	}
+
+	p := provenance("house1", records, AuditorVisibility)
+	assert.Equal(t, "house1", p.UniqueID)
+	assert.Len(t, p.Entries, 4)
+
+	var txIDs []string
+	for _, entry := range p.Entries {
+		txIDs = append(txIDs, entry.TxID)
+	}
+	assert.Equal(t, []string{"tx1", "tx2", "tx3", "tx4"}, txIDs)
+
+	// an issue has no sender, a redeem has no recipient
+	assert.Equal(t, "", p.Entries[0].SenderEID)
+	assert.Equal(t, "", p.Entries[3].RecipientEID)
+	// parties without audit visibility are redacted
+	assert.Equal(t, Redacted, p.Entries[1].SenderEID)
+	assert.Equal(t, Redacted, p.Entries[2].RecipientEID)
+
+	assert.Equal(t, []string{"alice", "bob", Redacted}, p.Owners())
+}
+
+func TestProvenanceService(t *testing.T) {
+	now := time.Now()
+	p := &memory.Persistence{}
+	for _, record := range []*driver.TransactionRecord{
+		{TxID: "tx1", ActionType: driver.Issue, RecipientEID: "alice", UniqueID: "house1", Timestamp: now},
+		{TxID: "tx2", ActionType: driver.Transfer, SenderEID: "alice", RecipientEID: "bob", UniqueID: "house1", Timestamp: now.Add(time.Second)},
+		{TxID: "tx3", ActionType: driver.Transfer, SenderEID: "bob", RecipientEID: "charlie", UniqueID: "house1", Timestamp: now.Add(2 * time.Second)},
+		{TxID: "tx4", ActionType: driver.Issue, RecipientEID: "alice", UniqueID: "house2", Timestamp: now},
+	} {
+		record.TokenType = "house"
+		record.Amount = big.NewInt(1)
+		record.Status = driver.Confirmed
+		assert.NoError(t, p.AddTransaction(record))
+	}
+	db := ttxdb.NewDB(p)
+
+	parties := func(p *Provenance) [][2]string {
+		var res [][2]string
+		for _, entry := range p.Entries {
+			res = append(res, [2]string{entry.SenderEID, entry.RecipientEID})
+		}
+		return res
+	}
+
+	// the auditor sees all the parties
+	prov, err := NewProvenanceService(db, AuditorVisibility).Provenance("house1")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", "alice"}, {"alice", "bob"}, {"bob", "charlie"}}, parties(prov))
+
+	// an owner sees the parties of the transactions it took part in
+	prov, err = NewProvenanceService(db, OwnerVisibility("alice")).Provenance("house1")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", "alice"}, {"alice", "bob"}, {Redacted, Redacted}}, parties(prov))
+	assert.Equal(t, []string{"alice", "bob", Redacted}, prov.Owners())
+
+	prov, err = NewProvenanceService(db, OwnerVisibility("charlie")).Provenance("house1")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", Redacted}, {Redacted, Redacted}, {"bob", "charlie"}}, parties(prov))
+
+	// an owner without enrollment ID sees nothing
+	prov, err = NewProvenanceService(db, OwnerVisibility("")).Provenance("house1")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", Redacted}, {Redacted, Redacted}, {Redacted, Redacted}}, parties(prov))
+
+	// the other NFTs are not part of the provenance
+	prov, err = NewProvenanceService(db, AuditorVisibility).Provenance("house2")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", "alice"}}, parties(prov))
+}
@@ -31,15 +31,27 @@ type selector interface {
 	Filter(filter Filter, q string) ([]*token2.ID, error)
 }
 
+type indexer interface {
+	IndexedTokenIDs(walletID, path, from, to string) ([]*token2.ID, error)
+}
+
 type QueryExecutor struct {
 	selector
 	vault
+	indexer
 	precision uint64
+	wallet    string
+	// indexes are the JSON paths the NFT states are indexed by
+	indexes []string
 }
 
 func NewQueryExecutor(sp view.ServiceProvider, wallet string, precision uint64, opts ...token.ServiceOption) (*QueryExecutor, error) {
 	tms := token.GetManagementService(sp, opts...)
 	qe := tms.Vault().NewQueryEngine()
+	var indexes []string
+	if cfg := tms.ConfigManager().NFT(); cfg != nil {
+		indexes = cfg.Indexes
+	}
 	return &QueryExecutor{
 		selector: NewFilter(
 			wallet,
@@ -48,11 +60,33 @@ func NewQueryExecutor(sp view.ServiceProvider, wallet string, precision uint64,
 			metrics.Get(sp),
 		),
 		vault:     qe,
+		indexer:   qe,
 		precision: precision,
+		wallet:    wallet,
+		indexes:   indexes,
 	}, nil
 }
 
+// QueryByKey unmarshals into the passed state the first NFT whose state has the passed value at the passed key.
+// If the key is indexed, only the index is used: the tokens stored before the key was configured are not found.
+// Otherwise, the states of all the tokens of the wallet are inspected.
 func (s *QueryExecutor) QueryByKey(state interface{}, key string, value string) error {
+	if !s.isIndexed(key) {
+		return s.scanByKey(state, key, value)
+	}
+	page, err := s.Query([]*Condition{Equal(key, value)}, WithLimit(1))
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query index [%s]", key)
+	}
+	if len(page.Tokens) == 0 {
+		return ErrNoResults
+	}
+	return page.State(0, state)
+}
+
+// scanByKey unmarshals into the passed state the first NFT whose state has the passed value at the passed key,
+// inspecting the states of all the tokens of the wallet
+func (s *QueryExecutor) scanByKey(state interface{}, key string, value string) error {
 	ids, err := s.selector.Filter(&jsonFilter{
 		q:     gojsonq.New(),
 		key:   key,
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/index"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/stretchr/testify/assert"
 	"github.com/thedevsaddam/gojsonq"
@@ -50,3 +51,141 @@ func TestJsonFilter(t *testing.T) {
 	}
 	assert.False(t, f.ContainsToken(tok))
 }
+
+// fakeIndex mimics the range scans over the index entries stored by the token processor
+// and the scans over the tokens of a wallet
+type fakeIndex struct {
+	tokens  map[string]*token2.Token
+	ids     map[string]*token2.ID
+	entries map[string][]*fakeEntry
+}
+
+type fakeEntry struct {
+	value string
+	id    *token2.ID
+}
+
+func newFakeIndex(t *testing.T, paths []string, houses ...*House) *fakeIndex {
+	f := &fakeIndex{tokens: map[string]*token2.Token{}, ids: map[string]*token2.ID{}, entries: map[string][]*fakeEntry{}}
+	for _, h := range houses {
+		f.add(t, paths, h)
+	}
+	return f
+}
+
+// add stores the passed house, indexed by the passed paths
+func (f *fakeIndex) add(t *testing.T, paths []string, h *House) {
+	raw, err := json.Marshal(h)
+	assert.NoError(t, err)
+	tok := &token2.Token{Type: base64.StdEncoding.EncodeToString(raw), Quantity: "0x1"}
+	id := &token2.ID{TxId: "tx", Index: uint64(len(f.tokens))}
+	f.tokens[id.String()] = tok
+	f.ids[tok.Type] = id
+	for _, entry := range index.Entries(tok.Type, paths) {
+		f.entries[entry.Path] = append(f.entries[entry.Path], &fakeEntry{value: entry.Value, id: id})
+	}
+}
+
+func (f *fakeIndex) IndexedTokenIDs(walletID, path, from, to string) ([]*token2.ID, error) {
+	var ids []*token2.ID
+	for _, e := range f.entries[path] {
+		// the value is followed by the separator of the composite key
+		if from <= e.value+"\x00" && e.value+"\x00" < to {
+			ids = append(ids, e.id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeIndex) GetTokens(inputs ...*token2.ID) ([]*token2.Token, error) {
+	var res []*token2.Token
+	for _, id := range inputs {
+		res = append(res, f.tokens[id.String()])
+	}
+	return res, nil
+}
+
+// Filter returns the tokens, indexed or not, accepted by the passed filter
+func (f *fakeIndex) Filter(filter Filter, q string) ([]*token2.ID, error) {
+	var ids []*token2.ID
+	for _, tok := range f.tokens {
+		id := f.ids[tok.Type]
+		if filter.ContainsToken(&token2.UnspentToken{Id: id, Type: tok.Type, Quantity: tok.Quantity}) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoResults
+	}
+	return ids, nil
+}
+
+func TestQueryByKeyNotIndexed(t *testing.T) {
+	paths := []string{"LinearID"}
+	f := newFakeIndex(t, paths,
+		&House{LinearID: "h0", Address: "5th Avenue", Valuation: 100},
+	)
+	// h1 was stored before LinearID was indexed
+	f.add(t, nil, &House{LinearID: "h1", Address: "Main Street", Valuation: 200})
+	qe := &QueryExecutor{selector: f, vault: f, indexer: f, wallet: "alice", indexes: paths, precision: 64}
+
+	house := &House{}
+	assert.NoError(t, qe.QueryByKey(house, "LinearID", "h0"))
+	assert.Equal(t, "5th Avenue", house.Address)
+	assert.Equal(t, ErrNoResults, qe.QueryByKey(house, "LinearID", "h2"))
+	// the index is authoritative, the tokens stored before the key was indexed are not found
+	assert.Equal(t, ErrNoResults, qe.QueryByKey(house, "LinearID", "h1"))
+
+	// the keys that are not indexed are always looked up by scan
+	assert.NoError(t, qe.QueryByKey(house, "Address", "5th Avenue"))
+	assert.Equal(t, "h0", house.LinearID)
+	assert.NoError(t, qe.QueryByKey(house, "Address", "Main Street"))
+	assert.Equal(t, "h1", house.LinearID)
+}
+
+func TestQuery(t *testing.T) {
+	paths := []string{"LinearID", "Address", "Valuation"}
+	f := newFakeIndex(t, paths,
+		&House{LinearID: "h0", Address: "5th Avenue", Valuation: 100},
+		&House{LinearID: "h1", Address: "5th Street", Valuation: 200},
+		&House{LinearID: "h2", Address: "Main Street", Valuation: 300},
+		&House{LinearID: "h3", Address: "5th Boulevard", Valuation: 400},
+	)
+	qe := &QueryExecutor{selector: f, vault: f, indexer: f, wallet: "alice", indexes: paths, precision: 64}
+
+	// equality
+	house := &House{}
+	assert.NoError(t, qe.QueryByKey(house, "LinearID", "h2"))
+	assert.Equal(t, "Main Street", house.Address)
+	assert.Equal(t, ErrNoResults, qe.QueryByKey(house, "LinearID", "h4"))
+
+	// prefix and range
+	page, err := qe.Query([]*Condition{Prefix("Address", "5th "), Range("Valuation", 150, nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.NoError(t, page.State(0, house))
+	assert.Equal(t, "h1", house.LinearID)
+	assert.NoError(t, page.State(1, house))
+	assert.Equal(t, "h3", house.LinearID)
+	assert.Error(t, page.State(2, house))
+
+	// pagination
+	page, err = qe.Query([]*Condition{Range("Valuation", nil, 1000)}, WithOffset(1), WithLimit(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, page.Total)
+	assert.Len(t, page.Tokens, 2)
+	assert.Equal(t, uint64(1), page.Tokens[0].Id.Index)
+	assert.Equal(t, uint64(2), page.Tokens[1].Id.Index)
+	page, err = qe.Query([]*Condition{Range("Valuation", nil, 1000)}, WithOffset(4))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, page.Total)
+	assert.Empty(t, page.Tokens)
+
+	// errors
+	_, err = qe.Query(nil)
+	assert.Error(t, err)
+	_, err = qe.Query([]*Condition{Equal("Owner", "alice")})
+	assert.EqualError(t, err, "path [Owner] is not indexed")
+	_, err = qe.Query([]*Condition{Range("Valuation", 1, "a")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nfttx
+
+import (
+	"encoding/base64"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/index"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/marshaller"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Condition selects the NFTs whose state has, at an indexed JSON path, a value in a given range
+type Condition struct {
+	// Path is the indexed JSON path
+	Path string
+	// from and to are the bounds of the range of the encoded values, from included, to excluded
+	from, to string
+	err      error
+}
+
+// Equal returns a condition that selects the NFTs whose state has, at the passed path, the passed value.
+// The value can be a string, a number, or a boolean.
+func Equal(path string, value interface{}) *Condition {
+	from, to, err := index.Equal(value)
+	return &Condition{Path: path, from: from, to: to, err: err}
+}
+
+// Range returns a condition that selects the NFTs whose state has, at the passed path, a value between the passed
+// bounds, both included. A nil bound means no bound. Numbers are compared as numbers, strings byte-wise.
+func Range(path string, lower, upper interface{}) *Condition {
+	from, to, err := index.Between(lower, upper)
+	return &Condition{Path: path, from: from, to: to, err: err}
+}
+
+// Prefix returns a condition that selects the NFTs whose state has, at the passed path, a string starting
+// with the passed prefix
+func Prefix(path string, prefix string) *Condition {
+	from, to, err := index.Prefix(prefix)
+	return &Condition{Path: path, from: from, to: to, err: err}
+}
+
+// QueryOptions configures a query
+type QueryOptions struct {
+	// Offset is the number of matching NFTs to skip
+	Offset int
+	// Limit is the maximum number of NFTs to return, zero means no limit
+	Limit int
+}
+
+// QueryOption is a function that configures a QueryOptions
+type QueryOption func(*QueryOptions) error
+
+// WithOffset sets the number of matching NFTs to skip
+func WithOffset(offset int) QueryOption {
+	return func(o *QueryOptions) error {
+		if offset < 0 {
+			return errors.Errorf("invalid offset [%d]", offset)
+		}
+		o.Offset = offset
+		return nil
+	}
+}
+
+// WithLimit sets the maximum number of NFTs to return
+func WithLimit(limit int) QueryOption {
+	return func(o *QueryOptions) error {
+		if limit < 0 {
+			return errors.Errorf("invalid limit [%d]", limit)
+		}
+		o.Limit = limit
+		return nil
+	}
+}
+
+// Page is a page of the NFTs matching a query, ordered by token ID
+type Page struct {
+	// Tokens are the NFTs of the page
+	Tokens []*token2.UnspentToken
+	// Total is the number of NFTs matching the query
+	Total int
+}
+
+// State unmarshals the state of the i-th NFT of the page into the passed state
+func (p *Page) State(i int, state interface{}) error {
+	if i < 0 || i >= len(p.Tokens) {
+		return errors.Errorf("index [%d] out of range [0,%d)", i, len(p.Tokens))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(p.Tokens[i].Type)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode type")
+	}
+	if err := marshaller.Unmarshal(decoded, state); err != nil {
+		return errors.Wrap(err, "failed to unmarshal state")
+	}
+	return nil
+}
+
+// Query returns the page, selected by the passed options, of the NFTs of the wallet matching all the passed conditions.
+// The paths of the conditions must be indexed, see the `nft.indexes` configuration of the TMS.
+func (s *QueryExecutor) Query(conditions []*Condition, opts ...QueryOption) (*Page, error) {
+	if len(conditions) == 0 {
+		return nil, errors.New("no condition passed")
+	}
+	options := &QueryOptions{}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, errors.WithMessage(err, "failed compiling query options")
+		}
+	}
+
+	var matches map[string]*token2.ID
+	for _, condition := range conditions {
+		if condition.err != nil {
+			return nil, errors.WithMessagef(condition.err, "invalid condition on path [%s]", condition.Path)
+		}
+		if !s.isIndexed(condition.Path) {
+			return nil, errors.Errorf("path [%s] is not indexed", condition.Path)
+		}
+		ids, err := s.indexer.IndexedTokenIDs(s.wallet, condition.Path, condition.from, condition.to)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed querying index [%s]", condition.Path)
+		}
+		found := make(map[string]*token2.ID, len(ids))
+		for _, id := range ids {
+			if matches == nil {
+				found[id.String()] = id
+			} else if _, ok := matches[id.String()]; ok {
+				found[id.String()] = id
+			}
+		}
+		matches = found
+		if len(matches) == 0 {
+			break
+		}
+	}
+
+	ids := make([]*token2.ID, 0, len(matches))
+	for _, id := range matches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].TxId != ids[j].TxId {
+			return ids[i].TxId < ids[j].TxId
+		}
+		return ids[i].Index < ids[j].Index
+	})
+	page := &Page{Total: len(ids)}
+	if options.Offset >= len(ids) {
+		return page, nil
+	}
+	ids = ids[options.Offset:]
+	if options.Limit > 0 && options.Limit < len(ids) {
+		ids = ids[:options.Limit]
+	}
+	tokens, err := s.vault.GetTokens(ids...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tokens")
+	}
+	for i, t := range tokens {
+		page.Tokens = append(page.Tokens, &token2.UnspentToken{
+			Id:       ids[i],
+			Owner:    t.Owner,
+			Type:     t.Type,
+			Quantity: t.Quantity,
+		})
+	}
+	return page, nil
+}
+
+// isIndexed returns true if the passed path is indexed
+func (s *QueryExecutor) isIndexed(path string) bool {
+	for _, p := range s.indexes {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,7 @@ package nfttx
 
 import (
 	"encoding/base64"
+	"reflect"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
@@ -72,12 +73,68 @@ func ReceiveTransaction(context view.Context) (*Transaction, error) {
 	return &Transaction{Transaction: cctx}, nil
 }
 
+// Issue appends to the transaction the issue of the passed state to the passed recipient.
+// The unique ID of the state, if any, is assigned to the state only if the issue is appended.
 func (t *Transaction) Issue(wallet *token.IssuerWallet, state interface{}, recipient view.Identity, opts ...token.IssueOption) error {
-	// set state id first
-	_, err := t.setStateID(state)
+	id, typ, err := t.prepareState(state)
 	if err != nil {
 		return err
 	}
+
+	// Issue
+	if err := t.Transaction.Issue(wallet, recipient, typ, 1, opts...); err != nil {
+		return err
+	}
+	assignStateID(state, id)
+	return nil
+}
+
+// BatchIssue appends to the transaction a single issue action minting the passed states, the i-th state to the i-th recipient.
+// The unique IDs of the states are computed first, and the batch is rejected if any two of them are equal.
+// The type of an NFT is its state, therefore, if the token driver does not support outputs of different types in
+// the same issue action, as zkatdlog does not, a batch of more than one state is rejected: issue them one by one.
+// The unique IDs are assigned to the states only if the action is appended.
+func (t *Transaction) BatchIssue(wallet *token.IssuerWallet, states []interface{}, recipients []view.Identity, opts ...token.IssueOption) error {
+	if len(states) == 0 {
+		return errors.New("no states to issue")
+	}
+	if len(states) != len(recipients) {
+		return errors.Errorf("expected [%d] recipients, got [%d]", len(states), len(recipients))
+	}
+
+	// compute the state ids and the token types first
+	ids := make([]string, len(states))
+	seen := map[string]int{}
+	legs := make([]*token.IssueLeg, len(states))
+	for i, state := range states {
+		id, typ, err := t.prepareState(state)
+		if err != nil {
+			return errors.WithMessagef(err, "failed preparing state [%d]", i)
+		}
+		if len(id) != 0 {
+			if j, ok := seen[id]; ok {
+				return errors.Errorf("states [%d] and [%d] have the same unique ID [%s]", j, i, id)
+			}
+			seen[id] = i
+		}
+		ids[i] = id
+		legs[i] = &token.IssueLeg{Type: typ, Value: 1, Recipient: recipients[i]}
+	}
+
+	// then issue
+	if len(states) > 1 && !t.TokenRequest.TokenService.BatchIssueSupported() {
+		return errors.Errorf("the token driver does not support issuing [%d] states in the same action, issue them one by one", len(states))
+	}
+	if err := t.Transaction.BatchIssue(wallet, legs, opts...); err != nil {
+		return errors.WithMessagef(err, "failed issuing [%d] states", len(states))
+	}
+	for i, state := range states {
+		assignStateID(state, ids[i])
+	}
+	return nil
+}
+
+func (t *Transaction) Transfer(wallet *OwnerWallet, state interface{}, recipient view.Identity, opts ...token.TransferOption) error {
 	// marshal state to json
 	stateJSON, err := marshaller.Marshal(state)
 	if err != nil {
@@ -85,11 +142,11 @@ func (t *Transaction) Issue(wallet *token.IssuerWallet, state interface{}, recip
 	}
 	stateJSONStr := base64.StdEncoding.EncodeToString(stateJSON)
 
-	// Issue
-	return t.Transaction.Issue(wallet, recipient, stateJSONStr, 1, opts...)
+	return t.Transaction.Transfer(wallet.OwnerWallet, stateJSONStr, []uint64{1}, []view.Identity{recipient}, opts...)
 }
 
-func (t *Transaction) Transfer(wallet *OwnerWallet, state interface{}, recipient view.Identity, opts ...token.TransferOption) error {
+// Burn appends to the transaction the redeem of the passed state, owned by the passed wallet.
+func (t *Transaction) Burn(wallet *OwnerWallet, state interface{}, opts ...token.TransferOption) error {
 	// marshal state to json
 	stateJSON, err := marshaller.Marshal(state)
 	if err != nil {
@@ -97,7 +154,7 @@ func (t *Transaction) Transfer(wallet *OwnerWallet, state interface{}, recipient
 	}
 	stateJSONStr := base64.StdEncoding.EncodeToString(stateJSON)
 
-	return t.Transaction.Transfer(wallet.OwnerWallet, stateJSONStr, []uint64{1}, []view.Identity{recipient}, opts...)
+	return t.Transaction.Redeem(wallet.OwnerWallet, stateJSONStr, 1, opts...)
 }
 
 func (t *Transaction) Outputs() (*OutputStream, error) {
@@ -108,6 +165,29 @@ func (t *Transaction) Outputs() (*OutputStream, error) {
 	return &OutputStream{OutputStream: os}, nil
 }
 
+// prepareState returns the unique ID of the passed state and the token type encoding it.
+// The state is not modified: a LinearState gets its ID on a copy, which is then encoded.
+func (t *Transaction) prepareState(state interface{}) (string, string, error) {
+	s := state
+	if _, ok := state.(LinearState); ok {
+		var err error
+		s, err = copyState(state)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	id, err := t.setStateID(s)
+	if err != nil {
+		return "", "", err
+	}
+	// marshal state to json
+	stateJSON, err := marshaller.Marshal(s)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to marshal state")
+	}
+	return id, base64.StdEncoding.EncodeToString(stateJSON), nil
+}
+
 func (t *Transaction) setStateID(s interface{}) (string, error) {
 	logger.Debugf("setStateID %v...", s)
 	defer logger.Debugf("setStateID...done")
@@ -130,3 +210,23 @@ func (t *Transaction) setStateID(s interface{}) (string, error) {
 	}
 	return key, nil
 }
+
+// assignStateID assigns the passed id to the passed state, if it is a LinearState
+func assignStateID(state interface{}, id string) {
+	if ls, ok := state.(LinearState); ok && len(id) != 0 {
+		ls.SetLinearID(id)
+	}
+}
+
+// copyState returns a copy of the passed state obtained by a marshalling round trip
+func copyState(state interface{}) (interface{}, error) {
+	raw, err := marshaller.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal state")
+	}
+	c := reflect.New(reflect.TypeOf(state))
+	if err := marshaller.Unmarshal(raw, c.Interface()); err != nil {
+		return nil, errors.Wrap(err, "failed to copy state")
+	}
+	return c.Elem().Interface(), nil
+}
@@ -0,0 +1,367 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nfttx
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/marshaller"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type autoHouse struct {
+	LinearID string
+	Address  string
+}
+
+func (h *autoHouse) GetLinearID() (string, error) {
+	return h.Address, nil
+}
+
+type linearHouse struct {
+	LinearID string
+	Address  string
+}
+
+func (h *linearHouse) SetLinearID(id string) string {
+	if len(h.LinearID) == 0 {
+		h.LinearID = id
+	}
+	return h.LinearID
+}
+
+func TestBatchIssueChecks(t *testing.T) {
+	tx := &Transaction{}
+
+	err := tx.BatchIssue(nil, nil, nil)
+	assert.EqualError(t, err, "no states to issue")
+
+	err = tx.BatchIssue(nil, []interface{}{&House{}, &House{}}, []view.Identity{[]byte("alice")})
+	assert.EqualError(t, err, "expected [2] recipients, got [1]")
+
+	// the unique IDs are checked across the whole batch, before issuing anything
+	err = tx.BatchIssue(
+		nil,
+		[]interface{}{&autoHouse{Address: "5th Avenue"}, &autoHouse{Address: "Main Street"}, &autoHouse{Address: "5th Avenue"}},
+		[]view.Identity{[]byte("alice"), []byte("bob"), []byte("charlie")},
+	)
+	assert.EqualError(t, err, "states [0] and [2] have the same unique ID [5th Avenue]")
+}
+
+func TestBatchIssue(t *testing.T) {
+	tms := &fakeTMS{}
+	tx, ms := newTestTransaction(tms)
+	wallet := ms.WalletManager().IssuerWallet("issuer")
+
+	states := []interface{}{&linearHouse{Address: "5th Avenue"}, &linearHouse{Address: "Main Street"}, &autoHouse{Address: "Broadway"}}
+	recipients := []view.Identity{[]byte("alice"), []byte("bob"), []byte("charlie")}
+	assert.NoError(t, tx.BatchIssue(wallet, states, recipients))
+
+	// a single issue action with an output for each state
+	assert.Len(t, tx.TokenRequest.Actions.Issues, 1)
+	assert.Len(t, tx.TokenRequest.Metadata.Issues, 1)
+	assert.Equal(t, recipients, tx.TokenRequest.Metadata.Issues[0].Receivers)
+	action := &fakeAction{}
+	assert.NoError(t, json.Unmarshal(tx.TokenRequest.Actions.Issues[0], action))
+	assert.Len(t, action.Outputs, 3)
+
+	// the states got their IDs, and each output carries its state
+	for i, state := range states {
+		assert.Equal(t, []byte(recipients[i]), action.Outputs[i].Owner.Raw)
+		assert.Equal(t, token2.NewOneQuantity(64).Hex(), action.Outputs[i].Quantity)
+		assert.Equal(t, state, decodeState(t, action.Outputs[i].Type, state))
+	}
+	assert.NotEmpty(t, states[0].(*linearHouse).LinearID)
+	assert.NotEmpty(t, states[1].(*linearHouse).LinearID)
+	assert.NotEqual(t, states[0].(*linearHouse).LinearID, states[1].(*linearHouse).LinearID)
+	assert.Empty(t, states[2].(*autoHouse).LinearID)
+}
+
+func TestBatchIssueFailure(t *testing.T) {
+	tms := &fakeTMS{issueErr: errors.New("issue failed")}
+	tx, ms := newTestTransaction(tms)
+	wallet := ms.WalletManager().IssuerWallet("issuer")
+
+	states := []interface{}{&linearHouse{Address: "5th Avenue"}, &linearHouse{Address: "Main Street"}}
+	err := tx.BatchIssue(wallet, states, []view.Identity{[]byte("alice"), []byte("bob")})
+	assert.EqualError(t, err, "failed issuing [2] states: issue failed")
+
+	// nothing is appended, and the states are left untouched
+	assert.Empty(t, tx.TokenRequest.Actions.Issues)
+	assert.Empty(t, tx.TokenRequest.Metadata.Issues)
+	assert.Empty(t, states[0].(*linearHouse).LinearID)
+	assert.Empty(t, states[1].(*linearHouse).LinearID)
+}
+
+// singleTypeTMS is a fakeTMS whose issue actions carry a single token type, as zkatdlog's do
+type singleTypeTMS struct {
+	driver.TokenManagerService
+}
+
+func TestBatchIssueSingleType(t *testing.T) {
+	tx, ms := newTestTransaction(&singleTypeTMS{TokenManagerService: &fakeTMS{}})
+	assert.False(t, ms.BatchIssueSupported())
+	wallet := ms.WalletManager().IssuerWallet("issuer")
+
+	// more than one state is rejected before issuing anything
+	states := []interface{}{&linearHouse{Address: "5th Avenue"}, &linearHouse{Address: "Main Street"}}
+	err := tx.BatchIssue(wallet, states, []view.Identity{[]byte("alice"), []byte("bob")})
+	assert.EqualError(t, err, "the token driver does not support issuing [2] states in the same action, issue them one by one")
+	assert.Empty(t, tx.TokenRequest.Actions.Issues)
+	assert.Empty(t, states[0].(*linearHouse).LinearID)
+	assert.Empty(t, states[1].(*linearHouse).LinearID)
+
+	// a single state is issued with a plain issue action
+	assert.NoError(t, tx.BatchIssue(wallet, states[:1], []view.Identity{[]byte("alice")}))
+	assert.Len(t, tx.TokenRequest.Actions.Issues, 1)
+	assert.NotEmpty(t, states[0].(*linearHouse).LinearID)
+
+	// the legs of a fungible batch must share the same type
+	_, err = tx.TokenRequest.BatchIssue(wallet, []*token.IssueLeg{
+		{Type: "USD", Value: 10, Recipient: []byte("alice")},
+		{Type: "EUR", Value: 5, Recipient: []byte("bob")},
+	})
+	assert.EqualError(t, err, "the token driver does not support issue actions with different token types, got [USD] and [EUR]")
+	_, err = tx.TokenRequest.BatchIssue(wallet, []*token.IssueLeg{
+		{Type: "USD", Value: 10, Recipient: []byte("alice")},
+		{Type: "USD", Value: 5, Recipient: []byte("bob")},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, tx.TokenRequest.Actions.Issues, 2)
+	action := &fakeAction{}
+	assert.NoError(t, json.Unmarshal(tx.TokenRequest.Actions.Issues[1], action))
+	assert.Len(t, action.Outputs, 2)
+}
+
+func TestIssue(t *testing.T) {
+	tms := &fakeTMS{issueErr: errors.New("issue failed")}
+	tx, ms := newTestTransaction(tms)
+	wallet := ms.WalletManager().IssuerWallet("issuer")
+
+	state := &linearHouse{Address: "5th Avenue"}
+	assert.EqualError(t, tx.Issue(wallet, state, []byte("alice")), "issue failed")
+	assert.Empty(t, state.LinearID)
+	assert.Empty(t, tx.TokenRequest.Actions.Issues)
+
+	tms.issueErr = nil
+	assert.NoError(t, tx.Issue(wallet, state, []byte("alice")))
+	assert.NotEmpty(t, state.LinearID)
+	assert.Len(t, tx.TokenRequest.Actions.Issues, 1)
+	action := &fakeAction{}
+	assert.NoError(t, json.Unmarshal(tx.TokenRequest.Actions.Issues[0], action))
+	assert.Len(t, action.Outputs, 1)
+	assert.Equal(t, state, decodeState(t, action.Outputs[0].Type, state))
+}
+
+func TestBurn(t *testing.T) {
+	tms := &fakeTMS{}
+	tx, ms := newTestTransaction(tms)
+	wallet := &OwnerWallet{OwnerWallet: ms.WalletManager().OwnerWallet("alice")}
+
+	state := &linearHouse{LinearID: "house", Address: "5th Avenue"}
+	stateJSON, err := marshaller.Marshal(state)
+	assert.NoError(t, err)
+	typ := base64.StdEncoding.EncodeToString(stateJSON)
+	selector := &fakeSelector{}
+	assert.NoError(t, tx.Burn(wallet, state, token.WithTokenSelector(selector)))
+
+	// the state is selected and redeemed
+	assert.Equal(t, typ, selector.tokenType)
+	assert.Equal(t, "1", selector.q)
+	assert.Len(t, tx.TokenRequest.Actions.Transfers, 1)
+	assert.Len(t, tx.TokenRequest.Metadata.Transfers, 1)
+	action := &fakeAction{}
+	assert.NoError(t, json.Unmarshal(tx.TokenRequest.Actions.Transfers[0], action))
+	assert.Equal(t, []*token2.ID{{TxId: "house_tx", Index: 0}}, action.Inputs)
+	assert.Len(t, action.Outputs, 1)
+	assert.Empty(t, action.Outputs[0].Owner.Raw)
+	assert.Equal(t, typ, action.Outputs[0].Type)
+	assert.Equal(t, token2.NewOneQuantity(64).Hex(), action.Outputs[0].Quantity)
+}
+
+// decodeState decodes the passed token type into a new state of the same type as the passed one
+func decodeState(t *testing.T, typ string, state interface{}) interface{} {
+	raw, err := base64.StdEncoding.DecodeString(typ)
+	assert.NoError(t, err)
+	decoded, err := copyState(state)
+	assert.NoError(t, err)
+	assert.NoError(t, marshaller.Unmarshal(raw, decoded))
+	return decoded
+}
+
+func newTestTransaction(tms driver.TokenManagerService) (*Transaction, *token.ManagementService) {
+	ms := token.NewManagementServiceProvider(nil, &fakeTMSProvider{tms: tms}, &fakeNormalizer{}, nil, nil, nil).GetManagementService()
+	return &Transaction{Transaction: &ttx.Transaction{Payload: &ttx.Payload{TokenRequest: token.NewRequest(ms, "anchor")}}}, ms
+}
+
+type fakeTMSProvider struct {
+	tms driver.TokenManagerService
+}
+
+func (p *fakeTMSProvider) GetTokenManagerService(network string, channel string, namespace string, publicParamsFetcher driver.PublicParamsFetcher) (driver.TokenManagerService, error) {
+	return p.tms, nil
+}
+
+type fakeNormalizer struct{}
+
+func (n *fakeNormalizer) Normalize(opt *token.ServiceOptions) *token.ServiceOptions {
+	return opt
+}
+
+type fakeTMS struct {
+	driver.TokenManagerService
+	issueErr error
+}
+
+func (f *fakeTMS) IdentityProvider() driver.IdentityProvider {
+	return nil
+}
+
+func (f *fakeTMS) PublicParamsManager() driver.PublicParamsManager {
+	return &fakePPM{}
+}
+
+func (f *fakeTMS) IssuerWallet(id string) driver.IssuerWallet {
+	return &fakeIssuerWallet{id: id}
+}
+
+func (f *fakeTMS) OwnerWallet(id string) driver.OwnerWallet {
+	return &fakeOwnerWallet{id: id}
+}
+
+func (f *fakeTMS) GetAuditInfo(id view.Identity) ([]byte, error) {
+	return []byte("audit info"), nil
+}
+
+func (f *fakeTMS) Issue(issuerIdentity view.Identity, tokenType string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+	types := make([]string, len(values))
+	for i := range types {
+		types[i] = tokenType
+	}
+	return f.BatchIssue(issuerIdentity, types, values, owners, opts)
+}
+
+func (f *fakeTMS) BatchIssue(issuerIdentity view.Identity, tokenTypes []string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+	if f.issueErr != nil {
+		return nil, nil, nil, f.issueErr
+	}
+	action := &fakeIssueAction{}
+	for i, v := range values {
+		q, err := token2.UInt64ToQuantity(v, 64)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		action.Outputs = append(action.Outputs, &token2.Token{Owner: &token2.Owner{Raw: owners[i]}, Type: tokenTypes[i], Quantity: q.Hex()})
+	}
+	return action, make([][]byte, len(values)), issuerIdentity, nil
+}
+
+func (f *fakeTMS) Transfer(txID string, wallet driver.OwnerWallet, ids []*token2.ID, outputs []*token2.Token, opts *driver.TransferOptions) (driver.TransferAction, *driver.TransferMetadata, error) {
+	return &fakeTransferAction{Inputs: ids, Outputs: outputs}, &driver.TransferMetadata{}, nil
+}
+
+func (f *fakeTMS) VerifyTransfer(tr driver.TransferAction, outputsMetadata [][]byte) error {
+	return nil
+}
+
+type fakePPM struct {
+	driver.PublicParamsManager
+}
+
+func (f *fakePPM) PublicParameters() driver.PublicParameters {
+	return &fakePP{}
+}
+
+type fakePP struct {
+	driver.PublicParameters
+}
+
+func (f *fakePP) Precision() uint64 {
+	return 64
+}
+
+func (f *fakePP) GraphHiding() bool {
+	return false
+}
+
+type fakeIssuerWallet struct {
+	driver.IssuerWallet
+	id string
+}
+
+func (f *fakeIssuerWallet) ID() string {
+	return f.id
+}
+
+func (f *fakeIssuerWallet) GetIssuerIdentity(tokenType string) (view.Identity, error) {
+	return view.Identity(f.id), nil
+}
+
+type fakeOwnerWallet struct {
+	driver.OwnerWallet
+	id string
+}
+
+func (f *fakeOwnerWallet) ID() string {
+	return f.id
+}
+
+// fakeAction is the json serialization of the fake issue and transfer actions
+type fakeAction struct {
+	Inputs  []*token2.ID
+	Outputs []*token2.Token
+}
+
+type fakeIssueAction struct {
+	driver.IssueAction `json:"-"`
+	Outputs            []*token2.Token
+}
+
+func (f *fakeIssueAction) Serialize() ([]byte, error) {
+	return json.Marshal(f)
+}
+
+func (f *fakeIssueAction) GetSerializedOutputs() ([][]byte, error) {
+	var res [][]byte
+	for _, output := range f.Outputs {
+		raw, err := json.Marshal(output)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, raw)
+	}
+	return res, nil
+}
+
+type fakeTransferAction struct {
+	driver.TransferAction `json:"-"`
+	Inputs                []*token2.ID
+	Outputs               []*token2.Token
+}
+
+func (f *fakeTransferAction) Serialize() ([]byte, error) {
+	return json.Marshal(f)
+}
+
+type fakeSelector struct {
+	q         string
+	tokenType string
+}
+
+func (f *fakeSelector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([]*token2.ID, token2.Quantity, error) {
+	f.q = q
+	f.tokenType = tokenType
+	return []*token2.ID{{TxId: "house_tx", Index: 0}}, token2.NewOneQuantity(64), nil
+}
@@ -28,6 +28,17 @@ func (o *OwnerWallet) QueryByKey(state interface{}, key string, value string) er
 	return qe.QueryByKey(state, key, value)
 }
 
+// Query returns the page, selected by the passed options, of the NFTs of this wallet matching all the passed conditions.
+// The paths of the conditions must be indexed, see the `nft.indexes` configuration of the TMS.
+// The tokens stored before a path was configured are not indexed by it, and are not returned.
+func (o *OwnerWallet) Query(conditions []*Condition, opts ...QueryOption) (*Page, error) {
+	qe, err := NewQueryExecutor(o.ServiceProvider, o.OwnerWallet.ID(), o.Precision)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create query executor")
+	}
+	return qe.Query(conditions, opts...)
+}
+
 // WithType returns a list token option that filter by the passed token type.
 // If the passed token type is the empty string, all token types are selected.
 func WithType(tokenType string) token.ListTokensOption {
@@ -126,3 +137,9 @@ func MyAuditorWallet(sp view2.ServiceProvider, opts ...token.ServiceOption) *tok
 	}
 	return w
 }
+
+// Provenance returns the provenance of the NFT with the passed unique ID, as recorded in the owner-side db,
+// with the enrollment IDs of the parties of the transactions this wallet took part in.
+func (o *OwnerWallet) Provenance(uniqueID string) (*Provenance, error) {
+	return NewOwnerProvenanceService(o.ServiceProvider, o.OwnerWallet).Provenance(uniqueID)
+}
@@ -0,0 +1,516 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+const (
+	// lockPrefix is the prefix of the keys of the lock entries, indexed by token ID
+	lockPrefix = "token-sdk.selector.lock"
+	// txPrefix is the prefix of the keys indexing the locked tokens by the ID of the locking transaction
+	txPrefix = "token-sdk.selector.tx"
+)
+
+var (
+	logger             = flogging.MustGetLogger("token-sdk.selector.kvs")
+	AlreadyLockedError = errors.New("already locked")
+)
+
+const (
+	_       int = iota
+	Valid       // Transaction is valid and committed
+	Invalid     // Transaction is invalid and has been discarded
+)
+
+type Vault interface {
+	Status(id string) (int, error)
+}
+
+// KVS models the key-value store the locks are persisted in.
+// PutIfAbsent, CompareAndSwap, and CompareAndDelete must be atomic with respect to every locker
+// using the same store, the states are compared by their JSON encoding.
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+	Delete(id string) error
+	GetByPartialCompositeID(prefix string, attrs []string) (Iterator, error)
+	// PutIfAbsent stores the passed state under the passed key, unless the key exists.
+	// It returns true if the state has been stored.
+	PutIfAbsent(id string, state interface{}) (bool, error)
+	// CompareAndSwap replaces the state stored under the passed key with next, if the stored one is old.
+	// It returns true if the state has been replaced.
+	CompareAndSwap(id string, old, next interface{}) (bool, error)
+	// CompareAndDelete deletes the state stored under the passed key, if it is old.
+	// It returns true if the state has been deleted.
+	CompareAndDelete(id string, old interface{}) (bool, error)
+}
+
+// Iterator iterates over the entries of a KVS range
+type Iterator interface {
+	HasNext() bool
+	Close() error
+	Next(state interface{}) (string, error)
+}
+
+// kvsLocks serializes the conditional writes to the same KVS of the node, indexed by KVS
+var kvsLocks sync.Map
+
+// kvsAdapter adapts the KVS of the node to the KVS interface.
+// The KVS of the node offers no conditional write, the conditional writes of the adapters of the same KVS
+// are serialized. This makes them atomic as long as the KVS backend is not shared with other processes,
+// as it happens for the backends of the node, badger included.
+type kvsAdapter struct {
+	*kvs2.KVS
+	lock *sync.Mutex
+}
+
+// NewKVS returns the passed KVS of the node as a KVS
+func NewKVS(kvs *kvs2.KVS) KVS {
+	lock, _ := kvsLocks.LoadOrStore(kvs, &sync.Mutex{})
+	return &kvsAdapter{KVS: kvs, lock: lock.(*sync.Mutex)}
+}
+
+func (k *kvsAdapter) GetByPartialCompositeID(prefix string, attrs []string) (Iterator, error) {
+	it, err := k.KVS.GetByPartialCompositeID(prefix, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+func (k *kvsAdapter) PutIfAbsent(id string, state interface{}) (bool, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.KVS.Exists(id) {
+		return false, nil
+	}
+	if err := k.KVS.Put(id, state); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (k *kvsAdapter) CompareAndSwap(id string, old, next interface{}) (bool, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if ok, err := k.matches(id, old); err != nil || !ok {
+		return false, err
+	}
+	if err := k.KVS.Put(id, next); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (k *kvsAdapter) CompareAndDelete(id string, old interface{}) (bool, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if ok, err := k.matches(id, old); err != nil || !ok {
+		return false, err
+	}
+	if err := k.KVS.Delete(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// matches returns true if the state stored under the passed key is the passed one
+func (k *kvsAdapter) matches(id string, state interface{}) (bool, error) {
+	if !k.KVS.Exists(id) {
+		return false, nil
+	}
+	current := json.RawMessage{}
+	if err := k.KVS.Get(id, &current); err != nil {
+		return false, err
+	}
+	expected, err := json.Marshal(state)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed marshalling state of [%s]", id)
+	}
+	return bytes.Equal(current, expected), nil
+}
+
+// LockEntry is the persisted lock of a token
+type LockEntry struct {
+	TokenID    token2.ID
+	TxID       string
+	Owner      string
+	Created    time.Time
+	LastAccess time.Time
+}
+
+func (l *LockEntry) String() string {
+	return fmt.Sprintf("[[%s] of [%s] since [%s], last access [%s]]", l.TxID, l.Owner, l.Created, l.LastAccess)
+}
+
+// locker is a selector.Locker that persists the locks in a KVS, so that they survive a restart
+// and are shared among the lockers using the same KVS, such as the replicas of a node sharing a SQL database.
+// Each locker is identified by a random owner ID. Locks taken by other owners, such as a previous
+// incarnation of the node, whose transaction is still unknown to the vault, are leases that expire
+// if not accessed for validTxEvictionTimeout.
+// A token is locked by a conditional write, and a lock is touched or removed only if it did not change meanwhile,
+// therefore two lockers never lock the same token at the same time.
+type locker struct {
+	vault                  Vault
+	kvs                    KVS
+	attrs                  []string
+	owner                  string
+	lock                   sync.Mutex
+	sleepTimeout           time.Duration
+	validTxEvictionTimeout time.Duration
+}
+
+// NewLocker returns a new persistent locker for the tokens of the passed TMS, backed by the passed KVS.
+func NewLocker(kvs KVS, vault Vault, network, channel, namespace string, timeout time.Duration, validTxEvictionTimeout time.Duration) (selector.Locker, error) {
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return nil, errors.Wrap(err, "failed generating locker owner")
+	}
+	r := &locker{
+		vault:                  vault,
+		kvs:                    kvs,
+		attrs:                  []string{network, channel, namespace},
+		owner:                  hex.EncodeToString(owner),
+		sleepTimeout:           timeout,
+		validTxEvictionTimeout: validTxEvictionTimeout,
+	}
+	r.Start()
+	return r, nil
+}
+
+func (d *locker) Lock(id *token2.ID, txID string, reclaim bool) (string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	key, err := d.lockKey(id)
+	if err != nil {
+		return "", err
+	}
+	txKey, err := d.txKey(txID, id)
+	if err != nil {
+		return "", err
+	}
+	// the index is written first, so that the lock can always be found by transaction
+	if err := d.kvs.Put(txKey, id); err != nil {
+		return "", errors.WithMessagef(err, "failed indexing lock of [%s] by [%s]", id, txID)
+	}
+	now := time.Now()
+	entry := &LockEntry{TokenID: *id, TxID: txID, Owner: d.owner, Created: now, LastAccess: now}
+	for {
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("locking [%s] for [%s]", id, txID)
+		}
+		locked, err := d.kvs.PutIfAbsent(key, entry)
+		if err != nil {
+			d.removeTxKey(txID, id)
+			return "", errors.WithMessagef(err, "failed locking [%s] for [%s]", id, txID)
+		}
+		if locked {
+			return "", nil
+		}
+
+		e := &LockEntry{}
+		if err := d.kvs.Get(key, e); err != nil {
+			if !d.kvs.Exists(key) {
+				// unlocked meanwhile
+				continue
+			}
+			d.removeTxKey(txID, id)
+			return "", errors.WithMessagef(err, "failed getting lock of [%s]", id)
+		}
+		if e.TxID != txID {
+			d.removeTxKey(txID, id)
+		}
+		touched := *e
+		touched.LastAccess = time.Now()
+		if _, err := d.kvs.CompareAndSwap(key, e, &touched); err != nil {
+			return e.TxID, errors.WithMessagef(err, "failed updating lock of [%s]", id)
+		}
+
+		if !reclaim {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("[%s] already locked by [%s], no reclaim", id, e)
+				return e.TxID, errors.Errorf("already locked by [%s]", e)
+			}
+			return e.TxID, AlreadyLockedError
+		}
+		// Second chance
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("[%s] already locked by [%s], try to reclaim...", id, e)
+		}
+		reclaimed, status := d.reclaim(&touched)
+		if !reclaimed {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("[%s] already locked by [%s], reclaim failed, tx status [%d]", id, e, status)
+				return e.TxID, errors.Errorf("already locked by [%s]", e)
+			}
+			return e.TxID, AlreadyLockedError
+		}
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("[%s] already locked by [%s], reclaimed successful, tx status [%d]", id, e, status)
+		}
+		// only one second chance, the token might be locked by another locker meanwhile
+		reclaim = false
+		if err := d.kvs.Put(txKey, id); err != nil {
+			return "", errors.WithMessagef(err, "failed indexing lock of [%s] by [%s]", id, txID)
+		}
+	}
+}
+
+func (d *locker) UnlockIDs(ids ...*token2.ID) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("unlocking tokens [%v]", ids)
+	}
+	for _, id := range ids {
+		key, err := d.lockKey(id)
+		if err != nil {
+			logger.Errorf("failed unlocking [%s]: [%s]", id, err)
+			continue
+		}
+		if !d.kvs.Exists(key) {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("unlocking [%s] hold by no one, skipping", id)
+			}
+			continue
+		}
+		e := &LockEntry{}
+		if err := d.kvs.Get(key, e); err != nil {
+			logger.Errorf("failed getting lock of [%s]: [%s]", id, err)
+			continue
+		}
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("unlocking [%s] hold by [%s]", id, e)
+		}
+		d.remove(e)
+	}
+}
+
+func (d *locker) UnlockByTxID(txID string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("unlocking tokens hold by [%s]", txID)
+	}
+	it, err := d.kvs.GetByPartialCompositeID(txPrefix, append(d.attrs, txID))
+	if err != nil {
+		logger.Errorf("failed getting tokens locked by [%s]: [%s]", txID, err)
+		return
+	}
+	var ids []*token2.ID
+	for it.HasNext() {
+		id := &token2.ID{}
+		if _, err := it.Next(id); err != nil {
+			logger.Errorf("failed getting token locked by [%s]: [%s]", txID, err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := it.Close(); err != nil {
+		logger.Errorf("failed closing iterator: [%s]", err)
+	}
+
+	for _, id := range ids {
+		key, err := d.lockKey(id)
+		if err != nil {
+			logger.Errorf("failed unlocking [%s]: [%s]", id, err)
+			continue
+		}
+		e := &LockEntry{}
+		if err := d.kvs.Get(key, e); err != nil || e.TxID != txID {
+			// the token has been unlocked and possibly locked again by another transaction, drop the index only
+			d.removeTxKey(txID, id)
+			continue
+		}
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("unlocking [%s] hold by [%s]", id, e)
+		}
+		d.remove(e)
+	}
+}
+
+func (d *locker) IsLocked(id *token2.ID) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	key, err := d.lockKey(id)
+	if err != nil {
+		return false
+	}
+	return d.kvs.Exists(key)
+}
+
+func (d *locker) reclaim(e *LockEntry) (bool, int) {
+	status, err := d.vault.Status(e.TxID)
+	if err != nil {
+		return false, status
+	}
+	switch status {
+	case Invalid:
+		return d.remove(e), status
+	default:
+		return false, status
+	}
+}
+
+// remove deletes the passed lock entry and its index, unless the lock changed meanwhile.
+// It returns true if the lock entry has been deleted.
+func (d *locker) remove(e *LockEntry) bool {
+	key, err := d.lockKey(&e.TokenID)
+	if err != nil {
+		logger.Errorf("failed removing lock [%s]: [%s]", e, err)
+		return false
+	}
+	removed, err := d.kvs.CompareAndDelete(key, e)
+	if err != nil {
+		logger.Errorf("failed removing lock [%s]: [%s]", e, err)
+		return false
+	}
+	if !removed {
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("lock [%s] changed meanwhile, skip removal", e)
+		}
+		return false
+	}
+	d.removeTxKey(e.TxID, &e.TokenID)
+	return true
+}
+
+func (d *locker) removeTxKey(txID string, id *token2.ID) {
+	txKey, err := d.txKey(txID, id)
+	if err != nil {
+		logger.Errorf("failed removing index of [%s] for [%s]: [%s]", id, txID, err)
+		return
+	}
+	if err := d.kvs.Delete(txKey); err != nil {
+		logger.Errorf("failed removing index of [%s] for [%s]: [%s]", id, txID, err)
+	}
+}
+
+func (d *locker) lockKey(id *token2.ID) (string, error) {
+	key, err := kvs2.CreateCompositeKey(lockPrefix, append(d.attrs, id.TxId, strconv.FormatUint(id.Index, 10)))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating lock key for [%s]", id)
+	}
+	return key, nil
+}
+
+func (d *locker) txKey(txID string, id *token2.ID) (string, error) {
+	key, err := kvs2.CreateCompositeKey(txPrefix, append(d.attrs, txID, id.TxId, strconv.FormatUint(id.Index, 10)))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating index key for [%s]", id)
+	}
+	return key, nil
+}
+
+func (d *locker) Start() {
+	go d.scan()
+}
+
+func (d *locker) scan() {
+	for {
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("token collector: sleep for some time...")
+		}
+		time.Sleep(d.sleepTimeout)
+
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("token collector: scan locked tokens")
+		}
+		d.collect()
+	}
+}
+
+// collect removes the locks that are no longer needed, it returns the number of removed locks
+func (d *locker) collect() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	it, err := d.kvs.GetByPartialCompositeID(lockPrefix, d.attrs)
+	if err != nil {
+		logger.Errorf("token collector: failed scanning locked tokens: [%s]", err)
+		return 0
+	}
+	var removeList []*LockEntry
+	for it.HasNext() {
+		entry := &LockEntry{}
+		if _, err := it.Next(entry); err != nil {
+			logger.Errorf("token collector: failed reading lock: [%s]", err)
+			continue
+		}
+		status, err := d.vault.Status(entry.TxID)
+		if err != nil {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Warnf("failed getting status for token [%s] locked by [%s], remove", entry.TokenID, entry)
+			}
+			removeList = append(removeList, entry)
+			continue
+		}
+		switch status {
+		case Valid:
+			// remove only if elapsed enough time from last access, to avoid concurrency issue
+			if time.Since(entry.LastAccess) > d.validTxEvictionTimeout {
+				removeList = append(removeList, entry)
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("token [%s] locked by [%s] in status [%d], time elapsed, remove", entry.TokenID, entry, status)
+				}
+			}
+		case Invalid:
+			removeList = append(removeList, entry)
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("token [%s] locked by [%s] in status [%d], remove", entry.TokenID, entry, status)
+			}
+		default:
+			// the lease of another owner expires if not accessed for long enough
+			if entry.Owner != d.owner && time.Since(entry.LastAccess) > d.validTxEvictionTimeout {
+				removeList = append(removeList, entry)
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("token [%s] locked by [%s] in status [%d], lease expired, remove", entry.TokenID, entry, status)
+				}
+				continue
+			}
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("token [%s] locked by [%s] in status [%d], skip", entry.TokenID, entry, status)
+			}
+		}
+	}
+	if err := it.Close(); err != nil {
+		logger.Errorf("token collector: failed closing iterator: [%s]", err)
+	}
+
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("token collector: freeing [%d] items", len(removeList))
+	}
+	for _, entry := range removeList {
+		d.remove(entry)
+	}
+	return len(removeList)
+}
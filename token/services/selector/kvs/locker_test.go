@@ -0,0 +1,206 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvs
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs/mock"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type vault struct {
+	lock     sync.Mutex
+	statuses map[string]int
+}
+
+func (v *vault) Status(id string) (int, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.statuses[id], nil
+}
+
+func (v *vault) set(id string, status int) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.statuses[id] = status
+}
+
+func newKVS(t *testing.T) KVS {
+	kvs, err := kvs2.NewWithConfig(registry2.New(), "memory", "_default", &mock.ConfigProvider{})
+	assert.NoError(t, err)
+	return NewKVS(kvs)
+}
+
+func TestLocker(t *testing.T) {
+	kvs := newKVS(t)
+	v := &vault{statuses: map[string]int{}}
+	l, err := NewLocker(kvs, v, "n", "c", "ns", time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	id1 := &token.ID{TxId: "a", Index: 0}
+	id2 := &token.ID{TxId: "a", Index: 1}
+	id3 := &token.ID{TxId: "b", Index: 0}
+
+	_, err = l.Lock(id1, "tx1", false)
+	assert.NoError(t, err)
+	_, err = l.Lock(id2, "tx1", false)
+	assert.NoError(t, err)
+	_, err = l.Lock(id3, "tx2", false)
+	assert.NoError(t, err)
+	assert.True(t, l.IsLocked(id1))
+
+	by, err := l.Lock(id1, "tx3", false)
+	assert.Error(t, err)
+	assert.Equal(t, "tx1", by)
+
+	// reclaim succeeds only if the locking transaction is invalid
+	_, err = l.Lock(id1, "tx3", true)
+	assert.Error(t, err)
+	v.set("tx1", Invalid)
+	_, err = l.Lock(id1, "tx3", true)
+	assert.NoError(t, err)
+
+	// id1 is now locked by tx3, unlocking tx1 releases id2 only
+	l.UnlockByTxID("tx1")
+	assert.True(t, l.IsLocked(id1))
+	assert.False(t, l.IsLocked(id2))
+	assert.True(t, l.IsLocked(id3))
+
+	l.UnlockIDs(id3, id2)
+	assert.False(t, l.IsLocked(id3))
+
+	// the locks survive the locker, and are scoped by TMS
+	l2, err := NewLocker(kvs, v, "n", "c", "ns", time.Hour, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, l2.IsLocked(id1))
+	l3, err := NewLocker(kvs, v, "n", "c", "ns2", time.Hour, time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, l3.IsLocked(id1))
+}
+
+func TestCollect(t *testing.T) {
+	kvs := newKVS(t)
+	v := &vault{statuses: map[string]int{}}
+	l, err := NewLocker(kvs, v, "n", "c", "ns", time.Hour, 50*time.Millisecond)
+	assert.NoError(t, err)
+
+	id1 := &token.ID{TxId: "a", Index: 0}
+	id2 := &token.ID{TxId: "a", Index: 1}
+	id3 := &token.ID{TxId: "a", Index: 2}
+	_, err = l.Lock(id1, "valid", false)
+	assert.NoError(t, err)
+	_, err = l.Lock(id2, "invalid", false)
+	assert.NoError(t, err)
+	_, err = l.Lock(id3, "unknown", false)
+	assert.NoError(t, err)
+	v.set("valid", Valid)
+	v.set("invalid", Invalid)
+
+	// invalid transactions release their tokens immediately
+	assert.Equal(t, 1, l.(*locker).collect())
+	assert.False(t, l.IsLocked(id2))
+
+	time.Sleep(100 * time.Millisecond)
+	// valid transactions release their tokens once the eviction timeout is elapsed,
+	// locks of unknown transactions are kept by their owner
+	assert.Equal(t, 1, l.(*locker).collect())
+	assert.False(t, l.IsLocked(id1))
+	assert.True(t, l.IsLocked(id3))
+
+	// another owner, as after a restart, lets the lease expire
+	l2, err := NewLocker(kvs, v, "n", "c", "ns", time.Hour, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, l2.(*locker).collect())
+	assert.False(t, l2.IsLocked(id3))
+}
+
+func TestConcurrentLockers(t *testing.T) {
+	kvs, err := kvs2.NewWithConfig(registry2.New(), "memory", "_default", &mock.ConfigProvider{})
+	assert.NoError(t, err)
+	// two lockers sharing the same KVS of the node, as two selector services of the same node
+	testConcurrentLockers(t, NewKVS(kvs), NewKVS(kvs))
+}
+
+func TestSQLLockers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks.sqlite")
+	newSQLKVS := func() KVS {
+		db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+		assert.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		kvs, err := NewSQLKVS(db, DefaultLocksTable)
+		assert.NoError(t, err)
+		return kvs
+	}
+
+	kvs := newSQLKVS()
+	v := &vault{statuses: map[string]int{}}
+	l, err := NewLocker(kvs, v, "n", "c", "ns", time.Hour, time.Hour)
+	assert.NoError(t, err)
+	id1 := &token.ID{TxId: "a", Index: 0}
+	id2 := &token.ID{TxId: "a", Index: 1}
+	_, err = l.Lock(id1, "tx1", false)
+	assert.NoError(t, err)
+	_, err = l.Lock(id2, "tx1", false)
+	assert.NoError(t, err)
+	by, err := l.Lock(id1, "tx2", false)
+	assert.Error(t, err)
+	assert.Equal(t, "tx1", by)
+	v.set("tx1", Invalid)
+	_, err = l.Lock(id1, "tx2", true)
+	assert.NoError(t, err)
+	l.UnlockByTxID("tx1")
+	assert.True(t, l.IsLocked(id1))
+	assert.False(t, l.IsLocked(id2))
+	l.UnlockByTxID("tx2")
+	assert.False(t, l.IsLocked(id1))
+
+	// two replicas sharing the same database
+	testConcurrentLockers(t, newSQLKVS(), newSQLKVS())
+}
+
+// testConcurrentLockers checks that a token is locked by one transaction only,
+// when two lockers backed by the passed KVSs try to lock it concurrently
+func testConcurrentLockers(t *testing.T, kvs1, kvs2 KVS) {
+	v := &vault{statuses: map[string]int{}}
+	l1, err := NewLocker(kvs1, v, "n", "c", "ns", time.Hour, time.Hour)
+	assert.NoError(t, err)
+	l2, err := NewLocker(kvs2, v, "n", "c", "ns", time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		id := &token.ID{TxId: "b", Index: uint64(i)}
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		var winners []string
+		for j, l := range []selector.Locker{l1, l2, l1, l2} {
+			wg.Add(1)
+			go func(txID string, l selector.Locker) {
+				defer wg.Done()
+				if _, err := l.Lock(id, txID, false); err == nil {
+					lock.Lock()
+					winners = append(winners, txID)
+					lock.Unlock()
+				}
+			}(fmt.Sprintf("tx%d", j), l)
+		}
+		wg.Wait()
+		assert.Len(t, winners, 1, "token [%s] locked by %v", id, winners)
+	}
+}
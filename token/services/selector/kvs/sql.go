@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvs
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	kvs2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+)
+
+// DefaultLocksTable is the default name of the table the locks are stored in by a SQL KVS
+const DefaultLocksTable = "token_sdk_locks"
+
+var tableNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// sqlKVS is a KVS backed by a SQL database, that can be shared by the replicas of a node.
+// PutIfAbsent relies on the primary key of the table, CompareAndSwap and CompareAndDelete on conditional statements,
+// therefore they are atomic for every process accessing the database.
+// Keys are stored hex-encoded, since composite keys contain characters not accepted in text columns by every database,
+// and hex-encoding preserves their order. States are stored JSON-encoded.
+type sqlKVS struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLKVS returns a KVS that stores the states in the passed table of the passed SQL database,
+// creating the table if it does not exist. The database/sql driver must accept the `ON CONFLICT` clause,
+// as SQLite and Postgres do.
+func NewSQLKVS(db *sql.DB, table string) (KVS, error) {
+	if !tableNameRegexp.MatchString(table) {
+		return nil, errors.Errorf("invalid table name [%s]", table)
+	}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, state TEXT NOT NULL)`, table)
+	if _, err := db.Exec(query); err != nil {
+		return nil, errors.Wrapf(err, "failed creating table [%s]", table)
+	}
+	return &sqlKVS{db: db, table: table}, nil
+}
+
+func (k *sqlKVS) Exists(id string) bool {
+	var one int
+	query := fmt.Sprintf(`SELECT 1 FROM %s WHERE id = $1`, k.table)
+	if err := k.db.QueryRow(query, encodeKey(id)).Scan(&one); err != nil {
+		if err != sql.ErrNoRows {
+			logger.Errorf("failed checking state [%s]: [%s]", id, err)
+		}
+		return false
+	}
+	return true
+}
+
+func (k *sqlKVS) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal state with id [%s]", id)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (id, state) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET state = excluded.state`, k.table)
+	if _, err := k.db.Exec(query, encodeKey(id), string(raw)); err != nil {
+		return errors.Wrapf(err, "failed storing state [%s]", id)
+	}
+	return nil
+}
+
+func (k *sqlKVS) Get(id string, state interface{}) error {
+	var raw string
+	query := fmt.Sprintf(`SELECT state FROM %s WHERE id = $1`, k.table)
+	if err := k.db.QueryRow(query, encodeKey(id)).Scan(&raw); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.Errorf("state [%s] does not exist", id)
+		}
+		return errors.Wrapf(err, "failed retrieving state [%s]", id)
+	}
+	if err := json.Unmarshal([]byte(raw), state); err != nil {
+		return errors.Wrapf(err, "failed retrieving state [%s], cannot unmarshal state", id)
+	}
+	return nil
+}
+
+func (k *sqlKVS) Delete(id string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, k.table)
+	if _, err := k.db.Exec(query, encodeKey(id)); err != nil {
+		return errors.Wrapf(err, "failed deleting state [%s]", id)
+	}
+	return nil
+}
+
+func (k *sqlKVS) GetByPartialCompositeID(prefix string, attrs []string) (Iterator, error) {
+	start, err := kvs2.CreateCompositeKey(prefix, attrs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed building composite key")
+	}
+	end := start + string(utf8.MaxRune)
+	query := fmt.Sprintf(`SELECT id, state FROM %s WHERE id >= $1 AND id < $2 ORDER BY id`, k.table)
+	rows, err := k.db.Query(query, encodeKey(start), encodeKey(end))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed scanning states with prefix [%s]", prefix)
+	}
+	defer rows.Close()
+	it := &sqlIterator{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, errors.Wrapf(err, "failed scanning states with prefix [%s]", prefix)
+		}
+		decoded, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key [%s]", key)
+		}
+		it.keys = append(it.keys, string(decoded))
+		it.values = append(it.values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed scanning states with prefix [%s]", prefix)
+	}
+	return it, nil
+}
+
+func (k *sqlKVS) PutIfAbsent(id string, state interface{}) (bool, error) {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot marshal state with id [%s]", id)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (id, state) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`, k.table)
+	return k.exec(id, query, encodeKey(id), string(raw))
+}
+
+func (k *sqlKVS) CompareAndSwap(id string, old, next interface{}) (bool, error) {
+	rawOld, err := json.Marshal(old)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot marshal state with id [%s]", id)
+	}
+	rawNext, err := json.Marshal(next)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot marshal state with id [%s]", id)
+	}
+	query := fmt.Sprintf(`UPDATE %s SET state = $1 WHERE id = $2 AND state = $3`, k.table)
+	return k.exec(id, query, string(rawNext), encodeKey(id), string(rawOld))
+}
+
+func (k *sqlKVS) CompareAndDelete(id string, old interface{}) (bool, error) {
+	raw, err := json.Marshal(old)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot marshal state with id [%s]", id)
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND state = $2`, k.table)
+	return k.exec(id, query, encodeKey(id), string(raw))
+}
+
+// exec executes the passed conditional statement and returns true if it affected a row
+func (k *sqlKVS) exec(id string, query string, args ...interface{}) (bool, error) {
+	res, err := k.db.Exec(query, args...)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed updating state [%s]", id)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed updating state [%s]", id)
+	}
+	return n == 1, nil
+}
+
+func encodeKey(id string) string {
+	return hex.EncodeToString([]byte(id))
+}
+
+// sqlIterator iterates over the states read by a range scan
+type sqlIterator struct {
+	keys   []string
+	values []string
+	next   int
+}
+
+func (i *sqlIterator) HasNext() bool {
+	return i.next < len(i.keys)
+}
+
+func (i *sqlIterator) Close() error {
+	return nil
+}
+
+func (i *sqlIterator) Next(state interface{}) (string, error) {
+	key, value := i.keys[i.next], i.values[i.next]
+	i.next++
+	return key, json.Unmarshal([]byte(value), state)
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type NewQueryEngineFunc func() QueryService
@@ -20,8 +21,11 @@ type manager struct {
 	numRetry             int
 	timeout              time.Duration
 	requestCertification bool
+	certClient           CertificationClient
 	precision            uint64
+	strategy             SelectionStrategy
 	metricsAgent         MetricsAgent
+	metrics              *Metrics
 }
 
 func NewManager(
@@ -30,8 +34,11 @@ func NewManager(
 	numRetry int,
 	timeout time.Duration,
 	requestCertification bool,
+	certClient CertificationClient,
 	precision uint64,
+	strategy SelectionStrategy,
 	metricsAgent MetricsAgent,
+	metrics *Metrics,
 ) *manager {
 	return &manager{
 		locker:               locker,
@@ -39,12 +46,29 @@ func NewManager(
 		numRetry:             numRetry,
 		timeout:              timeout,
 		requestCertification: requestCertification,
+		certClient:           certClient,
 		precision:            precision,
+		strategy:             strategy,
 		metricsAgent:         metricsAgent,
+		metrics:              metrics,
 	}
 }
 
 func (m *manager) NewSelector(id string) (token.Selector, error) {
+	return m.newSelector(id, m.strategy), nil
+}
+
+// NewSelectorWithStrategy returns a new selector bound to the passed id that uses the selection strategy
+// registered under the passed name
+func (m *manager) NewSelectorWithStrategy(id string, strategy string) (token.Selector, error) {
+	s, err := GetStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	return m.newSelector(id, s), nil
+}
+
+func (m *manager) newSelector(id string, strategy SelectionStrategy) *selector {
 	return &selector{
 		txID:                 id,
 		locker:               m.locker,
@@ -53,8 +77,17 @@ func (m *manager) NewSelector(id string) (token.Selector, error) {
 		numRetry:             m.numRetry,
 		timeout:              m.timeout,
 		requestCertification: m.requestCertification,
+		certClient:           m.certClient,
+		strategy:             strategy,
 		metricsAgent:         m.metricsAgent,
-	}, nil
+		metrics:              m.metrics,
+	}
+}
+
+// UnlockIDs unlocks the passed tokens, whatever transaction they are bound to
+func (m *manager) UnlockIDs(ids ...*token2.ID) error {
+	m.locker.UnlockIDs(ids...)
+	return nil
 }
 
 func (m *manager) Unlock(txID string) error {
@@ -0,0 +1,123 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selector
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+)
+
+const (
+	// OutcomeSuccess labels the selections that succeeded
+	OutcomeSuccess = "success"
+	// OutcomeInsufficientFunds labels the selections that failed because the funds are not enough
+	OutcomeInsufficientFunds = "insufficient_funds"
+	// OutcomeLocked labels the selections that failed because the funds are enough but partially locked by other transactions
+	OutcomeLocked = "locked"
+	// OutcomeNonCertified labels the selections that failed because the funds are enough but partially not certified
+	OutcomeNonCertified = "non_certified"
+	// OutcomeConcurrencyIssue labels the selections that failed because some of the selected tokens were spent meanwhile
+	OutcomeConcurrencyIssue = "concurrency_issue"
+)
+
+var (
+	selections = metrics.CounterOpts{
+		Namespace:    "token_sdk",
+		Subsystem:    "selector",
+		Name:         "selections",
+		Help:         "The number of token selections, by outcome.",
+		LabelNames:   []string{"network", "channel", "namespace", "outcome"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{outcome}",
+	}
+	lockedTokens = metrics.CounterOpts{
+		Namespace:    "token_sdk",
+		Subsystem:    "selector",
+		Name:         "locked_tokens",
+		Help:         "The number of tokens found locked by other transactions during the token selections.",
+		LabelNames:   []string{"network", "channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}",
+	}
+	retries = metrics.HistogramOpts{
+		Namespace:    "token_sdk",
+		Subsystem:    "selector",
+		Name:         "retries",
+		Help:         "The number of retries used by the token selections, by outcome.",
+		LabelNames:   []string{"network", "channel", "namespace", "outcome"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{outcome}",
+		Buckets:      []float64{0, 1, 2, 3, 5, 8, 13},
+	}
+	waitDuration = metrics.HistogramOpts{
+		Namespace:    "token_sdk",
+		Subsystem:    "selector",
+		Name:         "wait_duration",
+		Help:         "The time, in seconds, the token selections spent waiting before retrying, by outcome.",
+		LabelNames:   []string{"network", "channel", "namespace", "outcome"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{outcome}",
+	}
+)
+
+// Metrics are the metrics of the token selectors of a TMS
+type Metrics struct {
+	Selections   metrics.Counter
+	LockedTokens metrics.Counter
+	Retries      metrics.Histogram
+	WaitDuration metrics.Histogram
+}
+
+// MetricVectors are the metrics of the token selectors, not yet bound to a TMS.
+// They must be created once per metrics provider, since providers register their metrics.
+type MetricVectors struct {
+	Selections   metrics.Counter
+	LockedTokens metrics.Counter
+	Retries      metrics.Histogram
+	WaitDuration metrics.Histogram
+}
+
+// NewMetricVectors creates the metrics of the token selectors with the passed provider
+func NewMetricVectors(p metrics.Provider) *MetricVectors {
+	return &MetricVectors{
+		Selections:   p.NewCounter(selections),
+		LockedTokens: p.NewCounter(lockedTokens),
+		Retries:      p.NewHistogram(retries),
+		WaitDuration: p.NewHistogram(waitDuration),
+	}
+}
+
+// NewMetrics returns the metrics of the token selectors of the passed TMS
+func (v *MetricVectors) NewMetrics(network, channel, namespace string) *Metrics {
+	labels := []string{"network", network, "channel", channel, "namespace", namespace}
+	return &Metrics{
+		Selections:   v.Selections.With(labels...),
+		LockedTokens: v.LockedTokens.With(labels...),
+		Retries:      v.Retries.With(labels...),
+		WaitDuration: v.WaitDuration.With(labels...),
+	}
+}
+
+// observe records the outcome of a selection
+func (m *Metrics) observe(outcome string, numRetries int, waited time.Duration) {
+	m.Selections.With("outcome", outcome).Add(1)
+	m.Retries.With("outcome", outcome).Observe(float64(numRetries))
+	m.WaitDuration.With("outcome", outcome).Observe(waited.Seconds())
+}
+
+var metricsProviderKey = reflect.TypeOf((*metrics.Provider)(nil))
+
+// metricsProvider returns the metrics provider registered in the passed service provider.
+// Metrics are not recorded if there is none, as it happens when the operations system is not running.
+func metricsProvider(sp view.ServiceProvider) metrics.Provider {
+	s, err := sp.GetService(metricsProviderKey)
+	if err != nil {
+		logger.Debugf("no metrics provider found, selector metrics are disabled: [%s]", err)
+		return &disabled.Provider{}
+	}
+	return s.(metrics.Provider)
+}
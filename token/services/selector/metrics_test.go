@@ -0,0 +1,23 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selector
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/metrics/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricVectorsManyTMSs(t *testing.T) {
+	// the prometheus provider panics if the same metric is registered twice
+	v := NewMetricVectors(&prometheus.Provider{})
+	assert.NotPanics(t, func() {
+		v.NewMetrics("network", "channel", "ns1").observe(OutcomeSuccess, 0, 0)
+		v.NewMetrics("network", "channel", "ns2").observe(OutcomeLocked, 1, 0)
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracker/metrics"
+	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -32,7 +33,7 @@ type Repository interface {
 }
 
 type LockerProvider interface {
-	New(network, channel, namespace string) Locker
+	New(network, channel, namespace string) (Locker, error)
 }
 
 type selectorService struct {
@@ -45,6 +46,7 @@ type selectorService struct {
 	lockerProvider LockerProvider
 	lockers        map[string]Locker
 	managers       map[string]token.SelectorManager
+	metrics        *MetricVectors
 }
 
 func NewProvider(sp view.ServiceProvider, lockerProvider LockerProvider, numRetry int, timeout time.Duration) *selectorService {
@@ -80,7 +82,12 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 	locker, ok := s.lockers[key]
 	if !ok {
 		logger.Debugf("new in-memory locker for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
-		locker = s.lockerProvider.New(network, channel, namespace)
+		var err error
+		locker, err = s.lockerProvider.New(tms.Network(), tms.Channel(), tms.Namespace())
+		if err != nil {
+			logger.Errorf("invalid locker configuration for [%s:%s:%s]: [%s]", tms.Network(), tms.Channel(), tms.Namespace(), err)
+			return &invalidConfigManager{err: errors.WithMessagef(err, "invalid locker configuration for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())}
+		}
 		s.lockers[key] = locker
 	} else {
 		logger.Debugf("in-memory selector for [%s:%s:%s] exists", tms.Network(), tms.Channel(), tms.Namespace())
@@ -89,6 +96,19 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 		tms.Vault().NewQueryEngine(),
 		locker,
 	)
+	var strategy SelectionStrategy
+	if cfg := tms.ConfigManager().Selector(); cfg != nil && len(cfg.Strategy) != 0 {
+		var err error
+		strategy, err = GetStrategy(cfg.Strategy)
+		if err != nil {
+			logger.Errorf("invalid selector configuration for [%s:%s:%s]: [%s]", tms.Network(), tms.Channel(), tms.Namespace(), err)
+			return &invalidConfigManager{err: errors.WithMessagef(err, "invalid selector configuration for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())}
+		}
+	}
+	var certClient CertificationClient
+	if s.requestCertification && tms.PublicParametersManager().GraphHiding() {
+		certClient = &lazyCertificationClient{tms: tms}
+	}
 	manager = NewManager(
 		locker,
 		func() QueryService {
@@ -97,13 +117,47 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 		s.numRetry,
 		s.timeout,
 		s.requestCertification,
+		certClient,
 		tms.PublicParametersManager().Precision(),
+		strategy,
 		metrics.Get(s.sp),
+		s.metricVectors().NewMetrics(tms.Network(), tms.Channel(), tms.Namespace()),
 	)
 	s.managers[key] = manager
 	return manager
 }
 
+// lazyCertificationClient is the CertificationClient of a TMS, created at the first use
+type lazyCertificationClient struct {
+	tms    *token.ManagementService
+	once   sync.Once
+	client *token.CertificationClient
+}
+
+func (c *lazyCertificationClient) IsCertified(id *token2.ID) bool {
+	return c.get().IsCertified(id)
+}
+
+func (c *lazyCertificationClient) RequestCertification(ids ...*token2.ID) error {
+	return c.get().RequestCertification(ids...)
+}
+
+func (c *lazyCertificationClient) get() *token.CertificationClient {
+	c.once.Do(func() {
+		c.client = c.tms.CertificationClient()
+	})
+	return c.client
+}
+
+// metricVectors returns the metrics of the selectors, creating them at the first call.
+// It must be called holding the lock.
+func (s *selectorService) metricVectors() *MetricVectors {
+	if s.metrics == nil {
+		s.metrics = NewMetricVectors(metricsProvider(s.sp))
+	}
+	return s.metrics
+}
+
 func (s *selectorService) SetNumRetries(n uint) {
 	s.numRetry = int(n)
 }
@@ -116,6 +170,24 @@ func (s *selectorService) SetRequestCertification(v bool) {
 	s.requestCertification = v
 }
 
+// invalidConfigManager is the SelectorManager of a TMS whose selector configuration is invalid.
+// It fails every selection with the configuration error.
+type invalidConfigManager struct {
+	err error
+}
+
+func (m *invalidConfigManager) NewSelector(id string) (token.Selector, error) {
+	return nil, m.err
+}
+
+func (m *invalidConfigManager) NewSelectorWithStrategy(id string, strategy string) (token.Selector, error) {
+	return nil, m.err
+}
+
+func (m *invalidConfigManager) Unlock(id string) error {
+	return m.err
+}
+
 type Cache interface {
 	Get(key string) (interface{}, bool)
 	Add(key string, value interface{})
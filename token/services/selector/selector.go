@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package selector
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
@@ -32,6 +34,12 @@ type Locker interface {
 	IsLocked(id *token2.ID) bool
 }
 
+// CertificationClient tells if a token is certified, and requests the certification of the tokens
+type CertificationClient interface {
+	IsCertified(id *token2.ID) bool
+	RequestCertification(ids ...*token2.ID) error
+}
+
 type MetricsAgent interface {
 	EmitKey(val float32, event ...string)
 }
@@ -45,8 +53,14 @@ type selector struct {
 	numRetry             int
 	timeout              time.Duration
 	requestCertification bool
+	// certClient certifies the tokens, if nil all tokens are considered certified
+	certClient CertificationClient
+
+	// strategy chooses the tokens to spend, if nil the tokens are taken in vault order
+	strategy SelectionStrategy
 
 	metricsAgent MetricsAgent
+	metrics      *Metrics
 }
 
 // Select selects tokens to be spent based on ownership, quantity, and type
@@ -77,8 +91,12 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 
 	var toBeSpent []*token2.ID
 	var sum token2.Quantity
+	// available is the sum of the tokens not locked by other transactions, larger than sum when the strategy selects nothing
+	var available token2.Quantity
 	var potentialSumWithLocked token2.Quantity
 	var potentialSumWithNonCertified token2.Quantity
+	// nonCertified is the sum of the tokens not locked by other transactions that could not be certified
+	var nonCertified token2.Quantity
 	target, err := token2.ToQuantity(q, s.precision)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to convert quantity")
@@ -86,6 +104,7 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 	id := ownerFilter.ID()
 
 	i := 0
+	var waited time.Duration
 	var unspentTokens *token.UnspentTokensIterator
 	defer func() {
 		if unspentTokens != nil {
@@ -107,48 +126,92 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 		sum = token2.NewZeroQuantity(s.precision)
 		potentialSumWithLocked = token2.NewZeroQuantity(s.precision)
 		potentialSumWithNonCertified = token2.NewZeroQuantity(s.precision)
+		nonCertified = token2.NewZeroQuantity(s.precision)
 		toBeSpent = nil
 		var toBeCertified []*token2.ID
 
 		reclaim := s.numRetry == 1 || i > 0
 		numNext := 0
-		for {
-			t, err := unspentTokens.Next()
-			numNext++
+		if s.strategy != nil {
+			candidates, err := s.candidates(unspentTokens, func(t *token2.UnspentToken) bool { return true })
 			if err != nil {
-				return nil, nil, errors.Wrap(err, "token selection failed")
-			}
-			if t == nil {
-				break
+				return nil, nil, err
 			}
+			numNext = len(candidates) + 1
+			var locked token2.Quantity
+			toBeSpent, sum, available, locked, nonCertified = s.selectWithStrategy(candidates, target, reclaim)
+			potentialSumWithLocked = potentialSumWithLocked.Add(available).Add(locked)
+			potentialSumWithNonCertified = potentialSumWithNonCertified.Add(available).Add(nonCertified)
+		} else {
+			for {
+				t, err := unspentTokens.Next()
+				numNext++
+				if err != nil {
+					return nil, nil, errors.Wrap(err, "token selection failed")
+				}
+				if t == nil {
+					break
+				}
 
-			q, err := token2.ToQuantity(t.Quantity, s.precision)
-			if err != nil {
-				s.locker.UnlockIDs(toBeSpent...)
-				s.locker.UnlockIDs(toBeCertified...)
-				return nil, nil, errors.Wrap(err, "failed to convert quantity")
-			}
+				q, err := token2.ToQuantity(t.Quantity, s.precision)
+				if err != nil {
+					s.locker.UnlockIDs(toBeSpent...)
+					s.locker.UnlockIDs(toBeCertified...)
+					return nil, nil, errors.Wrap(err, "failed to convert quantity")
+				}
 
-			// lock the token
-			if _, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
-				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				// skip the token if it is time-locked or in custody
+				if isLockedByScript(t) {
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%v] is locked", q, tokenType)
+					}
+					continue
+				}
 
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("token [%s,%v] cannot be locked [%s]", q, tokenType, err)
+				// lock the token
+				if err := s.lock(t.Id, reclaim); err != nil {
+					potentialSumWithLocked = potentialSumWithLocked.Add(q)
+
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%v] cannot be locked [%s]", q, tokenType, err)
+					}
+					continue
 				}
-				continue
-			}
 
-			// Append token
-			logger.Debugf("adding quantity [%s]", q.Decimal())
-			toBeSpent = append(toBeSpent, t.Id)
-			sum = sum.Add(q)
-			potentialSumWithLocked = potentialSumWithLocked.Add(q)
-			potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+				// keep aside the token if it is not yet certified
+				if !s.isCertified(t.Id) {
+					toBeCertified = append(toBeCertified, t.Id)
+					nonCertified = nonCertified.Add(q)
+					potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%s] is not certified", q, tokenType)
+					}
+					continue
+				}
 
-			if target.Cmp(sum) <= 0 {
-				break
+				// Append token
+				logger.Debugf("adding quantity [%s]", q.Decimal())
+				toBeSpent = append(toBeSpent, t.Id)
+				sum = sum.Add(q)
+				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+
+				if target.Cmp(sum) <= 0 {
+					break
+				}
+			}
+			// the tokens kept aside are certified only if the certified ones are not enough
+			if target.Cmp(sum) > 0 && len(toBeCertified) != 0 {
+				if err := s.certClient.RequestCertification(toBeCertified...); err != nil {
+					logger.Errorf("failed requesting the certification of [%d] tokens [%s]", len(toBeCertified), err)
+				} else {
+					toBeSpent = append(toBeSpent, toBeCertified...)
+					sum = sum.Add(nonCertified)
+					toBeCertified = nil
+					nonCertified = token2.NewZeroQuantity(s.precision)
+				}
 			}
+			available = sum
 		}
 
 		s.metricsAgent.EmitKey(0, "selector", "count", "selectByIDNumNext", uuid+strconv.Itoa(i), strconv.Itoa(numNext))
@@ -157,6 +220,8 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 		if target.Cmp(sum) <= 0 {
 			err := s.concurrencyCheck(toBeSpent)
 			if err == nil {
+				s.locker.UnlockIDs(toBeCertified...)
+				s.observe(OutcomeSuccess, i, waited)
 				return toBeSpent, sum, nil
 			}
 			concurrencyIssue = true
@@ -176,59 +241,41 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 			logger.Debugf("token selection: sufficient funds but partially not certified")
 		}
 
-		i++
-		if i >= s.numRetry {
-			// it is time to fail but how?
-			if concurrencyIssue {
-				logger.Debugf("concurrency issue, some of the tokens might not exist anymore")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientFundsButConcurrencyIssue,
-					"token selection failed: sufficient funs but concurrency issue, potential [%s] tokens of type [%s] were available", potentialSumWithLocked, tokenType,
-				)
-			}
-
-			if target.Cmp(potentialSumWithLocked) <= 0 && potentialSumWithLocked.Cmp(sum) != 0 {
-				// funds are potentially enough but they are locked
-				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientButLockedFunds,
-					"token selection failed: sufficient but partially locked funds, potential [%s] tokens of type [%s] are available", potentialSumWithLocked, tokenType,
-				)
-			}
-
-			if target.Cmp(potentialSumWithNonCertified) <= 0 && potentialSumWithNonCertified.Cmp(sum) != 0 {
-				// funds are potentially enough but they are locked
-				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientButNotCertifiedFunds,
-					"token selection failed: sufficient but partially not certified, potential [%s] tokens of type [%s] are available", potentialSumWithNonCertified, tokenType,
-				)
-			}
-
-			// funds are insufficient
-			logger.Debugf("token selection: it is time to fail but how, insufficient funds")
-			return nil, nil, errors.WithMessagef(
-				token.SelectorInsufficientFunds,
-				"token selection failed: insufficient funds, only [%s] tokens of type [%s] are available", sum.Decimal(), tokenType,
-			)
+		if i+1 >= s.numRetry {
+			return nil, nil, s.failed(&token.SelectionError{
+				TokenType:    tokenType,
+				Requested:    target,
+				Available:    available,
+				Locked:       token2.NewZeroQuantity(s.precision).Add(potentialSumWithLocked).Sub(available),
+				NonCertified: nonCertified,
+				Retries:      i,
+				Waited:       waited,
+			}, concurrencyIssue, potentialSumWithLocked, potentialSumWithNonCertified)
 		}
+		i++
 
 		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
 		time.Sleep(s.timeout)
+		waited += s.timeout
 	}
 }
 
 func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenType string) ([]*token2.ID, token2.Quantity, error) {
 	var toBeSpent []*token2.ID
 	var sum token2.Quantity
+	// available is the sum of the tokens not locked by other transactions, larger than sum when the strategy selects nothing
+	var available token2.Quantity
 	var potentialSumWithLocked token2.Quantity
 	var potentialSumWithNonCertified token2.Quantity
+	// nonCertified is the sum of the tokens not locked by other transactions that could not be certified
+	var nonCertified token2.Quantity
 	target, err := token2.ToQuantity(q, s.precision)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to convert quantity")
 	}
 
 	i := 0
+	var waited time.Duration
 	for {
 		logger.Debugf("start token selection, iteration [%d/%d]", i, s.numRetry)
 		unspentTokens, err := s.queryService.UnspentTokensIterator()
@@ -242,69 +289,116 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 		sum = token2.NewZeroQuantity(s.precision)
 		potentialSumWithLocked = token2.NewZeroQuantity(s.precision)
 		potentialSumWithNonCertified = token2.NewZeroQuantity(s.precision)
+		nonCertified = token2.NewZeroQuantity(s.precision)
 		toBeSpent = nil
 		var toBeCertified []*token2.ID
 
 		reclaim := s.numRetry == 1 || i > 0
-		for {
-			t, err := unspentTokens.Next()
+		if s.strategy != nil {
+			candidates, err := s.candidates(unspentTokens, func(t *token2.UnspentToken) bool {
+				return t.Type == tokenType && ownerFilter.ContainsToken(t)
+			})
 			if err != nil {
-				return nil, nil, errors.Wrap(err, "token selection failed")
-			}
-			if t == nil {
-				break
+				return nil, nil, err
 			}
+			var locked token2.Quantity
+			toBeSpent, sum, available, locked, nonCertified = s.selectWithStrategy(candidates, target, reclaim)
+			potentialSumWithLocked = potentialSumWithLocked.Add(available).Add(locked)
+			potentialSumWithNonCertified = potentialSumWithNonCertified.Add(available).Add(nonCertified)
+		} else {
+			for {
+				t, err := unspentTokens.Next()
+				if err != nil {
+					return nil, nil, errors.Wrap(err, "token selection failed")
+				}
+				if t == nil {
+					break
+				}
 
-			q, err := token2.ToQuantity(t.Quantity, s.precision)
-			if err != nil {
-				s.locker.UnlockIDs(toBeSpent...)
-				s.locker.UnlockIDs(toBeCertified...)
-				return nil, nil, errors.Wrap(err, "failed to convert quantity")
-			}
+				q, err := token2.ToQuantity(t.Quantity, s.precision)
+				if err != nil {
+					s.locker.UnlockIDs(toBeSpent...)
+					s.locker.UnlockIDs(toBeCertified...)
+					return nil, nil, errors.Wrap(err, "failed to convert quantity")
+				}
 
-			// check type and ownership
-			if t.Type != tokenType {
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("token [%s,%s] type does not match", q, tokenType)
+				// check type and ownership
+				if t.Type != tokenType {
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%s] type does not match", q, tokenType)
+					}
+					continue
 				}
-				continue
-			}
 
-			rightOwner := ownerFilter.ContainsToken(t)
+				rightOwner := ownerFilter.ContainsToken(t)
 
-			if !rightOwner {
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("token [%s,%s,%s,%v] owner does not belong to the passed wallet", view.Identity(t.Owner.Raw), q, tokenType, rightOwner)
+				if !rightOwner {
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%s,%s,%v] owner does not belong to the passed wallet", view.Identity(t.Owner.Raw), q, tokenType, rightOwner)
+					}
+					continue
 				}
-				continue
-			}
 
-			// lock the token
-			if _, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
-				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				// skip the token if it is time-locked or in custody
+				if isLockedByScript(t) {
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%s] is locked", q, tokenType)
+					}
+					continue
+				}
 
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("token [%s,%s,%v] cannot be locked [%s]", q, tokenType, rightOwner, err)
+				// lock the token
+				if err := s.lock(t.Id, reclaim); err != nil {
+					potentialSumWithLocked = potentialSumWithLocked.Add(q)
+
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%s,%v] cannot be locked [%s]", q, tokenType, rightOwner, err)
+					}
+					continue
+				}
+
+				// keep aside the token if it is not yet certified
+				if !s.isCertified(t.Id) {
+					toBeCertified = append(toBeCertified, t.Id)
+					nonCertified = nonCertified.Add(q)
+					potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+					if logger.IsEnabledFor(zapcore.DebugLevel) {
+						logger.Debugf("token [%s,%s] is not certified", q, tokenType)
+					}
+					continue
 				}
-				continue
-			}
 
-			// Append token
-			logger.Debugf("adding quantity [%s]", q.Decimal())
-			toBeSpent = append(toBeSpent, t.Id)
-			sum = sum.Add(q)
-			potentialSumWithLocked = potentialSumWithLocked.Add(q)
-			potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+				// Append token
+				logger.Debugf("adding quantity [%s]", q.Decimal())
+				toBeSpent = append(toBeSpent, t.Id)
+				sum = sum.Add(q)
+				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
 
-			if target.Cmp(sum) <= 0 {
-				break
+				if target.Cmp(sum) <= 0 {
+					break
+				}
+			}
+			// the tokens kept aside are certified only if the certified ones are not enough
+			if target.Cmp(sum) > 0 && len(toBeCertified) != 0 {
+				if err := s.certClient.RequestCertification(toBeCertified...); err != nil {
+					logger.Errorf("failed requesting the certification of [%d] tokens [%s]", len(toBeCertified), err)
+				} else {
+					toBeSpent = append(toBeSpent, toBeCertified...)
+					sum = sum.Add(nonCertified)
+					toBeCertified = nil
+					nonCertified = token2.NewZeroQuantity(s.precision)
+				}
 			}
+			available = sum
 		}
 
 		concurrencyIssue := false
 		if target.Cmp(sum) <= 0 {
 			err := s.concurrencyCheck(toBeSpent)
 			if err == nil {
+				s.locker.UnlockIDs(toBeCertified...)
+				s.observe(OutcomeSuccess, i, waited)
 				return toBeSpent, sum, nil
 			}
 			concurrencyIssue = true
@@ -324,45 +418,199 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 			logger.Debugf("token selection: sufficient funds but partially not certified")
 		}
 
+		if i+1 >= s.numRetry {
+			return nil, nil, s.failed(&token.SelectionError{
+				TokenType:    tokenType,
+				Requested:    target,
+				Available:    available,
+				Locked:       token2.NewZeroQuantity(s.precision).Add(potentialSumWithLocked).Sub(available),
+				NonCertified: nonCertified,
+				Retries:      i,
+				Waited:       waited,
+			}, concurrencyIssue, potentialSumWithLocked, potentialSumWithNonCertified)
+		}
 		i++
-		if i >= s.numRetry {
-			// it is time to fail but how?
-			if concurrencyIssue {
-				logger.Debugf("concurrency issue, some of the tokens might not exist anymore")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientFundsButConcurrencyIssue,
-					"token selection failed: sufficient funs but concurrency issue, potential [%s] tokens of type [%s] were available", potentialSumWithLocked, tokenType,
-				)
-			}
 
-			if target.Cmp(potentialSumWithLocked) <= 0 && potentialSumWithLocked.Cmp(sum) != 0 {
-				// funds are potentially enough but they are locked
-				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientButLockedFunds,
-					"token selection failed: sufficient but partially locked funds, potential [%s] tokens of type [%s] are available", potentialSumWithLocked, tokenType,
-				)
+		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
+		time.Sleep(s.timeout)
+		waited += s.timeout
+	}
+}
+
+// candidates returns the tokens returned by the passed iterator that pass the passed filter and are not time-locked or in custody
+func (s *selector) candidates(unspentTokens *token.UnspentTokensIterator, filter func(t *token2.UnspentToken) bool) ([]*Candidate, error) {
+	var candidates []*Candidate
+	for {
+		t, err := unspentTokens.Next()
+		if err != nil {
+			return nil, errors.Wrap(err, "token selection failed")
+		}
+		if t == nil {
+			return candidates, nil
+		}
+		if !filter(t) || isLockedByScript(t) {
+			continue
+		}
+		q, err := token2.ToQuantity(t.Quantity, s.precision)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to convert quantity")
+		}
+		candidates = append(candidates, &Candidate{ID: t.Id, Quantity: q})
+	}
+}
+
+// isCertified returns true if the passed token is certified, or if the certification is not used
+func (s *selector) isCertified(id *token2.ID) bool {
+	return s.certClient == nil || s.certClient.IsCertified(id)
+}
+
+// certified returns the certified candidates among the passed ones, and the others
+func (s *selector) certified(candidates []*Candidate) ([]*Candidate, []*Candidate) {
+	if s.certClient == nil {
+		return candidates, nil
+	}
+	var certified, nonCertified []*Candidate
+	for _, c := range candidates {
+		if s.certClient.IsCertified(c.ID) {
+			certified = append(certified, c)
+			continue
+		}
+		nonCertified = append(nonCertified, c)
+	}
+	return certified, nonCertified
+}
+
+// selectWithStrategy locks the candidates chosen by the selection strategy, as lockWithStrategy does,
+// choosing among the certified candidates first. If they are not enough, the others are certified and
+// the strategy chooses among all the candidates.
+// In addition to the values returned by lockWithStrategy, it returns the sum of the candidates that could not be certified.
+func (s *selector) selectWithStrategy(candidates []*Candidate, target token2.Quantity, reclaim bool) ([]*token2.ID, token2.Quantity, token2.Quantity, token2.Quantity, token2.Quantity) {
+	certified, nonCertified := s.certified(candidates)
+	toBeSpent, sum, available, locked := s.lockWithStrategy(certified, target, reclaim)
+	if len(toBeSpent) != 0 || len(nonCertified) == 0 {
+		return toBeSpent, sum, available, locked, token2.NewZeroQuantity(s.precision)
+	}
+
+	ids := make([]*token2.ID, len(nonCertified))
+	nonCertifiedSum := token2.NewZeroQuantity(s.precision)
+	for i, c := range nonCertified {
+		ids[i] = c.ID
+		nonCertifiedSum = nonCertifiedSum.Add(c.Quantity)
+	}
+	if err := s.certClient.RequestCertification(ids...); err != nil {
+		logger.Errorf("failed requesting the certification of [%d] tokens [%s]", len(ids), err)
+		return toBeSpent, sum, available, locked, nonCertifiedSum
+	}
+	toBeSpent, sum, available, locked = s.lockWithStrategy(candidates, target, reclaim)
+	return toBeSpent, sum, available, locked, token2.NewZeroQuantity(s.precision)
+}
+
+// isLockedByScript returns true if the passed token is owned by a script that locks it,
+// like a time-lock script that is still locked, or a custody script.
+// The script types decide when their tokens are locked, see identity.RegisterScriptType.
+func isLockedByScript(t *token2.UnspentToken) bool {
+	return t.Owner != nil && identity.IsLockedByScript(t.Owner.Raw, time.Now())
+}
+
+// lockWithStrategy locks the candidates chosen by the selection strategy to cover the passed target.
+// When some of the chosen candidates cannot be locked, the others are released and the strategy is asked
+// to choose again among the candidates not known to be locked.
+// It returns the locked tokens, their sum, the sum of the free candidates, and the sum of the candidates locked by
+// other transactions. When the strategy chooses nothing, no token is locked, and the sums of the free and of the locked
+// candidates tell whether the funds are short or only locked by other transactions.
+func (s *selector) lockWithStrategy(candidates []*Candidate, target token2.Quantity, reclaim bool) ([]*token2.ID, token2.Quantity, token2.Quantity, token2.Quantity) {
+	locked := token2.NewZeroQuantity(s.precision)
+	for {
+		chosen := s.strategy.Select(candidates, target)
+		if len(chosen) == 0 {
+			free := token2.NewZeroQuantity(s.precision)
+			for _, c := range candidates {
+				if s.locker.IsLocked(c.ID) {
+					locked = locked.Add(c.Quantity)
+					continue
+				}
+				free = free.Add(c.Quantity)
 			}
+			return nil, token2.NewZeroQuantity(s.precision), free, locked
+		}
 
-			if target.Cmp(potentialSumWithNonCertified) <= 0 && potentialSumWithNonCertified.Cmp(sum) != 0 {
-				// funds are potentially enough but they are locked
-				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientButNotCertifiedFunds,
-					"token selection failed: sufficient but partially not certified, potential [%s] tokens of type [%s] are available", potentialSumWithNonCertified, tokenType,
-				)
+		var toBeSpent []*token2.ID
+		sum := token2.NewZeroQuantity(s.precision)
+		isLocked := map[*Candidate]bool{}
+		for _, c := range chosen {
+			if err := s.lock(c.ID, reclaim); err != nil {
+				isLocked[c] = true
+				locked = locked.Add(c.Quantity)
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("token [%s,%s] cannot be locked [%s]", c.ID, c.Quantity.Decimal(), err)
+				}
+				continue
 			}
+			logger.Debugf("adding quantity [%s]", c.Quantity.Decimal())
+			toBeSpent = append(toBeSpent, c.ID)
+			sum = sum.Add(c.Quantity)
+		}
+		if len(isLocked) == 0 {
+			return toBeSpent, sum, sum, locked
+		}
 
-			// funds are insufficient
-			logger.Debugf("token selection: it is time to fail but how, insufficient funds")
-			return nil, nil, errors.WithMessagef(
-				token.SelectorInsufficientFunds,
-				"token selection failed: insufficient funds, only [%s] tokens of type [%s] are available", sum, tokenType,
-			)
+		// release the chosen tokens and choose again without the locked ones
+		s.locker.UnlockIDs(toBeSpent...)
+		remaining := make([]*Candidate, 0, len(candidates))
+		for _, c := range candidates {
+			if !isLocked[c] {
+				remaining = append(remaining, c)
+			}
 		}
+		candidates = remaining
+	}
+}
 
-		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
-		time.Sleep(s.timeout)
+// lock locks the passed token for the transaction of this selector, counting the tokens locked by other transactions
+func (s *selector) lock(id *token2.ID, reclaim bool) error {
+	_, err := s.locker.Lock(id, s.txID, reclaim)
+	if err != nil && s.metrics != nil {
+		s.metrics.LockedTokens.Add(1)
+	}
+	return err
+}
+
+// failed returns the error describing why the last attempt of a selection failed, and records the outcome
+func (s *selector) failed(e *token.SelectionError, concurrencyIssue bool, potentialSumWithLocked, potentialSumWithNonCertified token2.Quantity) error {
+	var outcome, msg string
+	switch {
+	case concurrencyIssue:
+		logger.Debugf("concurrency issue, some of the tokens might not exist anymore")
+		e.Err = token.SelectorSufficientFundsButConcurrencyIssue
+		outcome = OutcomeConcurrencyIssue
+		msg = fmt.Sprintf("token selection failed: sufficient funs but concurrency issue, potential [%s] tokens of type [%s] were available", potentialSumWithLocked, e.TokenType)
+	case e.Requested.Cmp(potentialSumWithLocked) <= 0 && potentialSumWithLocked.Cmp(e.Available) != 0:
+		// funds are potentially enough but they are locked
+		logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
+		e.Err = token.SelectorSufficientButLockedFunds
+		outcome = OutcomeLocked
+		msg = fmt.Sprintf("token selection failed: sufficient but partially locked funds, potential [%s] tokens of type [%s] are available", potentialSumWithLocked, e.TokenType)
+	case e.Requested.Cmp(potentialSumWithNonCertified) <= 0 && potentialSumWithNonCertified.Cmp(e.Available) != 0:
+		// funds are potentially enough but they are not certified
+		logger.Debugf("token selection: it is time to fail but how, sufficient funds but not certified")
+		e.Err = token.SelectorSufficientButNotCertifiedFunds
+		outcome = OutcomeNonCertified
+		msg = fmt.Sprintf("token selection failed: sufficient but partially not certified, potential [%s] tokens of type [%s] are available", potentialSumWithNonCertified, e.TokenType)
+	default:
+		// funds are insufficient
+		logger.Debugf("token selection: it is time to fail but how, insufficient funds")
+		e.Err = token.SelectorInsufficientFunds
+		outcome = OutcomeInsufficientFunds
+		msg = fmt.Sprintf("token selection failed: insufficient funds, only [%s] tokens of type [%s] are available", e.Available.Decimal(), e.TokenType)
+	}
+	s.observe(outcome, e.Retries, e.Waited)
+	return errors.WithMessage(e, msg)
+}
+
+// observe records the outcome of a selection
+func (s *selector) observe(outcome string, numRetries int, waited time.Duration) {
+	if s.metrics != nil {
+		s.metrics.observe(outcome, numRetries, waited)
 	}
 }
 
@@ -0,0 +1,275 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/timelock"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timelock"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type mockIterator struct {
+	tokens []*token2.UnspentToken
+}
+
+func (m *mockIterator) Close() {}
+
+func (m *mockIterator) Next() (*token2.UnspentToken, error) {
+	if len(m.tokens) == 0 {
+		return nil, nil
+	}
+	t := m.tokens[0]
+	m.tokens = m.tokens[1:]
+	return t, nil
+}
+
+type mockQueryService struct {
+	tokens []*token2.UnspentToken
+}
+
+func (m *mockQueryService) UnspentTokensIterator() (*token.UnspentTokensIterator, error) {
+	return &token.UnspentTokensIterator{UnspentTokensIterator: &mockIterator{tokens: m.tokens}}, nil
+}
+
+func (m *mockQueryService) UnspentTokensIteratorBy(id, typ string) (*token.UnspentTokensIterator, error) {
+	return m.UnspentTokensIterator()
+}
+
+func (m *mockQueryService) GetTokens(inputs ...*token2.ID) ([]*token2.Token, error) {
+	return nil, nil
+}
+
+func newFakeMetrics() (*Metrics, *metricsfakes.Counter, *metricsfakes.Counter, *metricsfakes.Histogram) {
+	selections := &metricsfakes.Counter{}
+	selections.WithReturns(selections)
+	lockedTokens := &metricsfakes.Counter{}
+	lockedTokens.WithReturns(lockedTokens)
+	histogram := &metricsfakes.Histogram{}
+	histogram.WithReturns(histogram)
+	return &Metrics{
+		Selections:   selections,
+		LockedTokens: lockedTokens,
+		Retries:      histogram,
+		WaitDuration: histogram,
+	}, selections, lockedTokens, histogram
+}
+
+func TestSelectionError(t *testing.T) {
+	tokens := []*token2.UnspentToken{
+		{Id: &token2.ID{TxId: "a", Index: 0}, Type: "USD", Quantity: "0x5"},
+		{Id: &token2.ID{TxId: "b", Index: 0}, Type: "USD", Quantity: "0x5"},
+	}
+	locker := &mockLocker{locked: map[token2.ID]string{*tokens[1].Id: "another"}}
+	metrics, selections, lockedTokens, _ := newFakeMetrics()
+	s := &selector{
+		txID:         "tx",
+		locker:       locker,
+		queryService: &mockQueryService{tokens: tokens},
+		precision:    64,
+		numRetry:     2,
+		timeout:      time.Millisecond,
+		metricsAgent: &noopAgent{},
+		metrics:      metrics,
+	}
+
+	// funds are enough but partially locked
+	_, _, err := s.Select(nil, "8", "USD")
+	assert.Error(t, err)
+	assert.Equal(t, token.SelectorSufficientButLockedFunds, errors.Cause(err))
+	assert.Contains(t, err.Error(), "sufficient but partially locked funds")
+	selectionErr := &token.SelectionError{}
+	assert.True(t, errors.As(err, &selectionErr))
+	assert.Equal(t, "USD", selectionErr.TokenType)
+	assert.Equal(t, "8", selectionErr.Requested.Decimal())
+	assert.Equal(t, "5", selectionErr.Available.Decimal())
+	assert.Equal(t, "5", selectionErr.Locked.Decimal())
+	assert.Equal(t, 1, selectionErr.Retries)
+	assert.Equal(t, time.Millisecond, selectionErr.Waited)
+	assert.Equal(t, 1, selections.AddCallCount())
+	assert.Equal(t, []string{"outcome", OutcomeLocked}, selections.WithArgsForCall(0))
+	assert.Equal(t, 2, lockedTokens.AddCallCount())
+	assert.Len(t, locker.locked, 1)
+
+	// funds are insufficient
+	_, _, err = s.Select(nil, "20", "USD")
+	assert.Equal(t, token.SelectorInsufficientFunds, errors.Cause(err))
+	assert.True(t, errors.As(err, &selectionErr))
+	assert.Equal(t, "5", selectionErr.Available.Decimal())
+	assert.Equal(t, []string{"outcome", OutcomeInsufficientFunds}, selections.WithArgsForCall(1))
+
+	// success
+	ids, sum, err := s.Select(nil, "5", "USD")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 1)
+	assert.Equal(t, "5", sum.Decimal())
+	assert.Equal(t, []string{"outcome", OutcomeSuccess}, selections.WithArgsForCall(2))
+}
+
+func TestSelectionErrorWithStrategy(t *testing.T) {
+	tokens := []*token2.UnspentToken{
+		{Id: &token2.ID{TxId: "a", Index: 0}, Type: "USD", Quantity: "0x5"},
+		{Id: &token2.ID{TxId: "b", Index: 0}, Type: "USD", Quantity: "0x5"},
+	}
+	locker := &mockLocker{locked: map[token2.ID]string{*tokens[1].Id: "another"}}
+	metrics, selections, _, _ := newFakeMetrics()
+	s := &selector{
+		txID:         "tx",
+		locker:       locker,
+		queryService: &mockQueryService{tokens: tokens},
+		precision:    64,
+		numRetry:     1,
+		timeout:      time.Millisecond,
+		strategy:     &largestFirst{},
+		metricsAgent: &noopAgent{},
+		metrics:      metrics,
+	}
+
+	// the strategy selects nothing once the locked token is excluded, the funds are enough but partially locked
+	_, _, err := s.Select(nil, "8", "USD")
+	assert.Equal(t, token.SelectorSufficientButLockedFunds, errors.Cause(err))
+	selectionErr := &token.SelectionError{}
+	assert.True(t, errors.As(err, &selectionErr))
+	assert.Equal(t, "5", selectionErr.Available.Decimal())
+	assert.Equal(t, "5", selectionErr.Locked.Decimal())
+	assert.Equal(t, []string{"outcome", OutcomeLocked}, selections.WithArgsForCall(0))
+	assert.Len(t, locker.locked, 1)
+
+	// funds are insufficient
+	s.queryService = &mockQueryService{tokens: tokens}
+	_, _, err = s.Select(nil, "20", "USD")
+	assert.Equal(t, token.SelectorInsufficientFunds, errors.Cause(err))
+	assert.True(t, errors.As(err, &selectionErr))
+	assert.Equal(t, "5", selectionErr.Available.Decimal())
+	assert.Equal(t, "5", selectionErr.Locked.Decimal())
+}
+
+type mockCertificationClient struct {
+	certified map[token2.ID]bool
+	err       error
+}
+
+func (m *mockCertificationClient) IsCertified(id *token2.ID) bool {
+	return m.certified[*id]
+}
+
+func (m *mockCertificationClient) RequestCertification(ids ...*token2.ID) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, id := range ids {
+		m.certified[*id] = true
+	}
+	return nil
+}
+
+func TestSelectionErrorNonCertified(t *testing.T) {
+	for _, strategy := range []SelectionStrategy{nil, &largestFirst{}} {
+		tokens := []*token2.UnspentToken{
+			{Id: &token2.ID{TxId: "a", Index: 0}, Type: "USD", Quantity: "0x5"},
+			{Id: &token2.ID{TxId: "b", Index: 0}, Type: "USD", Quantity: "0x5"},
+		}
+		certClient := &mockCertificationClient{
+			certified: map[token2.ID]bool{*tokens[0].Id: true},
+			err:       errors.New("certifier unavailable"),
+		}
+		locker := &mockLocker{locked: map[token2.ID]string{}}
+		metrics, selections, _, _ := newFakeMetrics()
+		s := &selector{
+			txID:         "tx",
+			locker:       locker,
+			queryService: &mockQueryService{tokens: tokens},
+			precision:    64,
+			numRetry:     1,
+			timeout:      time.Millisecond,
+			strategy:     strategy,
+			certClient:   certClient,
+			metricsAgent: &noopAgent{},
+			metrics:      metrics,
+		}
+
+		// the funds are enough but one of the tokens cannot be certified
+		_, _, err := s.Select(nil, "8", "USD")
+		assert.Equal(t, token.SelectorSufficientButNotCertifiedFunds, errors.Cause(err))
+		selectionErr := &token.SelectionError{}
+		assert.True(t, errors.As(err, &selectionErr))
+		assert.Equal(t, "5", selectionErr.Available.Decimal())
+		assert.Equal(t, "0", selectionErr.Locked.Decimal())
+		assert.Equal(t, "5", selectionErr.NonCertified.Decimal())
+		assert.Equal(t, []string{"outcome", OutcomeNonCertified}, selections.WithArgsForCall(0))
+		assert.Empty(t, locker.locked)
+
+		// the certified tokens are enough, no certification is requested
+		s.queryService = &mockQueryService{tokens: tokens}
+		ids, sum, err := s.Select(nil, "5", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, []*token2.ID{tokens[0].Id}, ids)
+		assert.Equal(t, "5", sum.Decimal())
+		assert.Len(t, locker.locked, 1)
+		locker.UnlockIDs(ids...)
+
+		// the certification succeeds
+		certClient.err = nil
+		s.queryService = &mockQueryService{tokens: tokens}
+		ids, sum, err = s.Select(nil, "8", "USD")
+		assert.NoError(t, err)
+		assert.Len(t, ids, 2)
+		assert.Equal(t, "10", sum.Decimal())
+		assert.True(t, certClient.IsCertified(tokens[1].Id))
+	}
+}
+
+type noopAgent struct{}
+
+func (n *noopAgent) EmitKey(val float32, event ...string) {}
+
+func TestSkipTimeLocked(t *testing.T) {
+	locked, err := timelock.Wrap(view.Identity("alice"), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	unlocked, err := timelock.Wrap(view.Identity("alice"), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	tokens := []*token2.UnspentToken{
+		{Id: &token2.ID{TxId: "a", Index: 0}, Type: "USD", Quantity: "0x5", Owner: &token2.Owner{Raw: locked}},
+		{Id: &token2.ID{TxId: "b", Index: 0}, Type: "USD", Quantity: "0x3", Owner: &token2.Owner{Raw: unlocked}},
+	}
+	metrics, _, _, _ := newFakeMetrics()
+	s := &selector{
+		txID:         "tx",
+		locker:       &mockLocker{locked: map[token2.ID]string{}},
+		queryService: &mockQueryService{tokens: tokens},
+		precision:    64,
+		numRetry:     1,
+		timeout:      time.Millisecond,
+		metricsAgent: &noopAgent{},
+		metrics:      metrics,
+	}
+
+	// the time-locked token is not selected
+	_, _, err = s.Select(nil, "8", "USD")
+	assert.Equal(t, token.SelectorInsufficientFunds, errors.Cause(err))
+
+	// the unlocked one is
+	ids, sum, err := s.Select(nil, "3", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.ID{tokens[1].Id}, ids)
+	assert.Equal(t, "3", sum.Decimal())
+
+	// also when a strategy is used
+	s.strategy = &smallestFirst{}
+	s.queryService = &mockQueryService{tokens: tokens}
+	s.locker = &mockLocker{locked: map[token2.ID]string{}}
+	_, _, err = s.Select(nil, "8", "USD")
+	assert.Equal(t, token.SelectorInsufficientFunds, errors.Cause(err))
+}
@@ -0,0 +1,215 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selector
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+const (
+	// LargestFirst is the name of the strategy that spends the largest tokens first
+	LargestFirst = "largest-first"
+	// SmallestFirst is the name of the strategy that spends the smallest tokens first, consolidating dust
+	SmallestFirst = "smallest-first"
+	// ExactMatch is the name of the strategy that looks for the tokens summing up to the requested quantity,
+	// or to the closest larger quantity, so that the change is minimized
+	ExactMatch = "exact-match"
+	// FewestInputs is the name of the strategy that spends the fewest tokens possible
+	FewestInputs = "fewest-inputs"
+
+	// DefaultExactMatchMaxSteps bounds the search of the exact-match strategy
+	DefaultExactMatchMaxSteps = 100000
+)
+
+// Candidate is a token that can be selected
+type Candidate struct {
+	ID       *token2.ID
+	Quantity token2.Quantity
+}
+
+// SelectionStrategy chooses the tokens to spend among a list of candidates
+type SelectionStrategy interface {
+	// Select returns the candidates to spend to cover the passed target, nil if the candidates are not enough.
+	// The passed candidates and target must not be modified.
+	Select(candidates []*Candidate, target token2.Quantity) []*Candidate
+}
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]SelectionStrategy{
+		LargestFirst:  &largestFirst{},
+		SmallestFirst: &smallestFirst{},
+		ExactMatch:    &exactMatch{maxSteps: DefaultExactMatchMaxSteps},
+		FewestInputs:  &fewestInputs{},
+	}
+)
+
+// RegisterStrategy makes a selection strategy available by the provided name.
+// If RegisterStrategy is called twice with the same name or if strategy is nil, it panics.
+func RegisterStrategy(name string, strategy SelectionStrategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	if strategy == nil {
+		panic("RegisterStrategy strategy is nil")
+	}
+	if _, dup := strategies[name]; dup {
+		panic("RegisterStrategy called twice for strategy " + name)
+	}
+	strategies[name] = strategy
+}
+
+// GetStrategy returns the selection strategy registered under the passed name
+func GetStrategy(name string) (SelectionStrategy, error) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, errors.Errorf("selection strategy [%s] not found", name)
+	}
+	return strategy, nil
+}
+
+// sortedCandidates returns a copy of the passed candidates, sorted by quantity, in descending order if desc is true
+func sortedCandidates(candidates []*Candidate, desc bool) []*Candidate {
+	sorted := make([]*Candidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c := sorted[i].Quantity.Cmp(sorted[j].Quantity)
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return sorted
+}
+
+// takeUntil returns the shortest prefix of the passed candidates covering the target, nil if there is none
+func takeUntil(candidates []*Candidate, target *big.Int) []*Candidate {
+	sum := big.NewInt(0)
+	for i, c := range candidates {
+		sum.Add(sum, c.Quantity.ToBigInt())
+		if sum.Cmp(target) >= 0 {
+			return candidates[:i+1]
+		}
+	}
+	return nil
+}
+
+type largestFirst struct{}
+
+func (l *largestFirst) Select(candidates []*Candidate, target token2.Quantity) []*Candidate {
+	return takeUntil(sortedCandidates(candidates, true), target.ToBigInt())
+}
+
+type smallestFirst struct{}
+
+func (s *smallestFirst) Select(candidates []*Candidate, target token2.Quantity) []*Candidate {
+	return takeUntil(sortedCandidates(candidates, false), target.ToBigInt())
+}
+
+// fewestInputs takes the largest tokens, as largest-first does, but replaces the last one
+// with the smallest token that still covers the target, reducing the change.
+type fewestInputs struct{}
+
+func (f *fewestInputs) Select(candidates []*Candidate, target token2.Quantity) []*Candidate {
+	sorted := sortedCandidates(candidates, true)
+	selected := takeUntil(sorted, target.ToBigInt())
+	if len(selected) == 0 {
+		return nil
+	}
+	// the sum of all but the last selected token does not cover the target
+	missing := target.ToBigInt()
+	for _, c := range selected[:len(selected)-1] {
+		missing.Sub(missing, c.Quantity.ToBigInt())
+	}
+	// the candidates after the selected ones are not larger than the last selected one, take the smallest covering the rest
+	last := len(selected) - 1
+	for i := len(sorted) - 1; i >= last; i-- {
+		if sorted[i].Quantity.ToBigInt().Cmp(missing) >= 0 {
+			res := make([]*Candidate, 0, len(selected))
+			res = append(res, selected[:last]...)
+			return append(res, sorted[i])
+		}
+	}
+	return selected
+}
+
+// exactMatch searches for a subset of the candidates summing up to the target.
+// If there is none, or the search exceeds maxSteps, it returns the best subset found, the one with the smallest change,
+// falling back to largest-first if none has been found.
+type exactMatch struct {
+	maxSteps int
+}
+
+func (e *exactMatch) Select(candidates []*Candidate, target token2.Quantity) []*Candidate {
+	sorted := sortedCandidates(candidates, true)
+	t := target.ToBigInt()
+	amounts := make([]*big.Int, len(sorted))
+	// suffix[i] is the sum of the amounts from i on, used to prune the branches that cannot cover the target
+	suffix := make([]*big.Int, len(sorted)+1)
+	suffix[len(sorted)] = big.NewInt(0)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		amounts[i] = sorted[i].Quantity.ToBigInt()
+		suffix[i] = new(big.Int).Add(suffix[i+1], amounts[i])
+	}
+	if suffix[0].Cmp(t) < 0 {
+		return nil
+	}
+
+	s := &subsetSearch{amounts: amounts, suffix: suffix, target: t, stepsLeft: e.maxSteps}
+	s.search(0, big.NewInt(0), nil)
+	if s.best == nil {
+		return takeUntil(sorted, t)
+	}
+	res := make([]*Candidate, len(s.best))
+	for i, index := range s.best {
+		res[i] = sorted[index]
+	}
+	return res
+}
+
+type subsetSearch struct {
+	amounts   []*big.Int
+	suffix    []*big.Int
+	target    *big.Int
+	stepsLeft int
+
+	best       []int
+	bestExcess *big.Int
+}
+
+// search explores the subsets including the passed indexes and any of the amounts from i on.
+// It returns true when the search must stop, because an exact match has been found or the budget is over.
+func (s *subsetSearch) search(i int, sum *big.Int, selected []int) bool {
+	s.stepsLeft--
+	if sum.Cmp(s.target) >= 0 {
+		excess := new(big.Int).Sub(sum, s.target)
+		if s.best == nil || excess.Cmp(s.bestExcess) < 0 || (excess.Cmp(s.bestExcess) == 0 && len(selected) < len(s.best)) {
+			s.best = append([]int(nil), selected...)
+			s.bestExcess = excess
+		}
+		return excess.Sign() == 0 || s.stepsLeft <= 0
+	}
+	if s.stepsLeft <= 0 || i == len(s.amounts) {
+		return s.stepsLeft <= 0
+	}
+	if new(big.Int).Add(sum, s.suffix[i]).Cmp(s.target) < 0 {
+		// the remaining amounts cannot cover the target
+		return false
+	}
+	// include the i-th amount first, larger amounts lead to fewer inputs
+	if s.search(i+1, new(big.Int).Add(sum, s.amounts[i]), append(selected, i)) {
+		return true
+	}
+	return s.search(i+1, sum, selected)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+const precision = 64
+
+func candidates(amounts ...uint64) []*Candidate {
+	var res []*Candidate
+	for i, amount := range amounts {
+		q, _ := token2.UInt64ToQuantity(amount, precision)
+		res = append(res, &Candidate{ID: &token2.ID{TxId: fmt.Sprintf("%d", i)}, Quantity: q})
+	}
+	return res
+}
+
+func amounts(selected []*Candidate) []string {
+	var res []string
+	for _, c := range selected {
+		res = append(res, c.Quantity.Decimal())
+	}
+	return res
+}
+
+func quantity(v uint64) token2.Quantity {
+	q, _ := token2.UInt64ToQuantity(v, precision)
+	return q
+}
+
+func TestStrategies(t *testing.T) {
+	tokens := candidates(5, 1, 20, 3, 8, 2)
+
+	testCases := []struct {
+		strategy string
+		target   uint64
+		expected []string
+	}{
+		{strategy: LargestFirst, target: 22, expected: []string{"20", "8"}},
+		{strategy: SmallestFirst, target: 10, expected: []string{"1", "2", "3", "5"}},
+		{strategy: ExactMatch, target: 14, expected: []string{"8", "5", "1"}},
+		{strategy: ExactMatch, target: 39, expected: []string{"20", "8", "5", "3", "2", "1"}},
+		{strategy: FewestInputs, target: 22, expected: []string{"20", "2"}},
+		{strategy: FewestInputs, target: 7, expected: []string{"8"}},
+		{strategy: LargestFirst, target: 40, expected: nil},
+		{strategy: SmallestFirst, target: 40, expected: nil},
+		{strategy: ExactMatch, target: 40, expected: nil},
+		{strategy: FewestInputs, target: 40, expected: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s-%d", tc.strategy, tc.target), func(t *testing.T) {
+			strategy, err := GetStrategy(tc.strategy)
+			assert.NoError(t, err)
+			target := quantity(tc.target)
+			assert.Equal(t, tc.expected, amounts(strategy.Select(tokens, target)))
+			// inputs are not modified
+			assert.Equal(t, []string{"5", "1", "20", "3", "8", "2"}, amounts(tokens))
+			assert.Equal(t, fmt.Sprintf("%d", tc.target), target.Decimal())
+		})
+	}
+
+	_, err := GetStrategy("unknown")
+	assert.Error(t, err)
+}
+
+func TestExactMatchBound(t *testing.T) {
+	// no subset of even amounts sums up to an odd target, the best one found is returned
+	var values []uint64
+	for i := 0; i < 40; i++ {
+		values = append(values, 2)
+	}
+	strategy := &exactMatch{maxSteps: 1000}
+	selected := strategy.Select(candidates(values...), quantity(11))
+	assert.Len(t, selected, 6)
+}
+
+type mockLocker struct {
+	locked map[token2.ID]string
+}
+
+func (m *mockLocker) Lock(id *token2.ID, txID string, reclaim bool) (string, error) {
+	if by, ok := m.locked[*id]; ok {
+		return by, errors.New("already locked")
+	}
+	m.locked[*id] = txID
+	return "", nil
+}
+
+func (m *mockLocker) UnlockIDs(ids ...*token2.ID) {
+	for _, id := range ids {
+		delete(m.locked, *id)
+	}
+}
+
+func (m *mockLocker) UnlockByTxID(txID string) {}
+
+func (m *mockLocker) IsLocked(id *token2.ID) bool {
+	_, ok := m.locked[*id]
+	return ok
+}
+
+func TestLockWithStrategy(t *testing.T) {
+	tokens := candidates(5, 1, 20, 3, 8, 2)
+	locker := &mockLocker{locked: map[token2.ID]string{*tokens[4].ID: "another"}}
+	strategy, err := GetStrategy(ExactMatch)
+	assert.NoError(t, err)
+	s := &selector{txID: "tx", locker: locker, precision: precision, strategy: strategy}
+
+	// 8 is chosen but locked, the strategy chooses again among the other tokens
+	ids, sum, available, locked := s.lockWithStrategy(tokens, quantity(11), false)
+	assert.Equal(t, "11", sum.Decimal())
+	assert.Equal(t, "11", available.Decimal())
+	assert.Equal(t, "8", locked.Decimal())
+	assert.Len(t, ids, 4)
+	for _, id := range ids {
+		assert.Equal(t, "tx", locker.locked[*id])
+	}
+	assert.Len(t, locker.locked, 5)
+
+	// not enough unlocked tokens
+	locker.UnlockIDs(ids...)
+	ids, sum, available, locked = s.lockWithStrategy(tokens, quantity(35), false)
+	assert.Len(t, ids, 0)
+	assert.Equal(t, "0", sum.Decimal())
+	assert.Equal(t, "31", available.Decimal())
+	assert.Equal(t, "8", locked.Decimal())
+	assert.Len(t, locker.locked, 1)
+}
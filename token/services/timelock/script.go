@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	ScriptType = "timelock" // time-lock script
+)
+
+// Script contains the details of a time-lock.
+// A time-locked token can be spent by its owner only from the unlock time on.
+type Script struct {
+	Owner      view.Identity
+	UnlockTime time.Time
+}
+
+// Validate performs the following checks:
+// - The owner must be set
+// - The unlock time must be set
+func (s *Script) Validate() error {
+	if s.Owner.IsNone() {
+		return errors.New("owner not set")
+	}
+	if s.UnlockTime.IsZero() {
+		return errors.New("unlock time not set")
+	}
+	return nil
+}
+
+// IsLocked returns true if the passed time reference is before the unlock time
+func (s *Script) IsLocked(timeReference time.Time) bool {
+	return timeReference.Before(s.UnlockTime)
+}
+
+// Wrap returns the owner of the tokens that the passed owner can spend only from the passed unlock time on
+func Wrap(owner view.Identity, unlockTime time.Time) (view.Identity, error) {
+	script := &Script{Owner: owner, UnlockTime: unlockTime}
+	if err := script.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid time-lock script")
+	}
+	raw, err := json.Marshal(script)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling time-lock script")
+	}
+	return identity.MarshallRawOwner(&identity.RawOwner{Type: ScriptType, Identity: raw})
+}
+
+// Unwrap returns the time-lock script contained in the passed raw owner.
+// The returned flag is false if the passed identity is not a time-lock script.
+func Unwrap(raw []byte) (*Script, bool, error) {
+	owner, err := identity.UnmarshallRawOwner(raw)
+	if err != nil || owner.Type != ScriptType {
+		return nil, false, nil
+	}
+	script := &Script{}
+	if err := json.Unmarshal(owner.Identity, script); err != nil {
+		return nil, true, errors.Wrap(err, "failed to unmarshal RawOwner as a time-lock script")
+	}
+	return script, true, nil
+}
+
+// IsLocked returns true if the passed raw owner is a time-lock script that is still locked at the passed time reference
+func IsLocked(raw []byte, timeReference time.Time) bool {
+	script, ok, err := Unwrap(raw)
+	if !ok {
+		return false
+	}
+	// a malformed script cannot be spent
+	return err != nil || script.IsLocked(timeReference)
+}
+
+// ScriptOwnership implements the Ownership interface for time-lock scripts
+type ScriptOwnership struct{}
+
+// AmIAnAuditor returns false for script ownership
+func (s *ScriptOwnership) AmIAnAuditor(tms *token.ManagementService) bool {
+	return false
+}
+
+// IsMine returns true if one is the owner of a time-lock script.
+// The token belongs to the wallet of the owner, where it is selected only after the unlock time.
+func (s *ScriptOwnership) IsMine(tms *token.ManagementService, tok *token3.Token) ([]string, bool) {
+	script, ok, err := Unwrap(tok.Owner.Raw)
+	if !ok || err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, not a time-lock script [%v]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+	if err := script.Validate(); err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, invalid content [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, err)
+		return nil, false
+	}
+	wallet := tms.WalletManager().OwnerWalletByIdentity(script.Owner)
+	if wallet == nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, owner not found", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity)
+		return nil, false
+	}
+	logger.Debugf("Is Mine [%s,%s,%s]? Yes, wallet [%s]", view.Identity(tok.Owner.Raw), tok.Type, tok.Quantity, wallet.ID())
+	return []string{wallet.ID()}, true
+}
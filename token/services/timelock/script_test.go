@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapUnwrap(t *testing.T) {
+	unlockTime := time.Now().Add(time.Hour).Round(0)
+	raw, err := Wrap(view.Identity("alice"), unlockTime)
+	assert.NoError(t, err)
+	script, ok, err := Unwrap(raw)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, view.Identity("alice"), script.Owner)
+	assert.True(t, unlockTime.Equal(script.UnlockTime))
+
+	// not a time-lock script
+	_, ok, err = Unwrap([]byte("alice"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// invalid scripts
+	_, err = Wrap(nil, unlockTime)
+	assert.Error(t, err)
+	_, err = Wrap(view.Identity("alice"), time.Time{})
+	assert.Error(t, err)
+}
+
+func TestIsLocked(t *testing.T) {
+	now := time.Now()
+	raw, err := Wrap(view.Identity("alice"), now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, IsLocked(raw, now))
+	assert.True(t, IsLocked(raw, now.Add(time.Hour-time.Second)))
+	assert.False(t, IsLocked(raw, now.Add(time.Hour)))
+	assert.False(t, IsLocked(raw, now.Add(2*time.Hour)))
+
+	// identities that are not time-lock scripts are never locked
+	assert.False(t, IsLocked([]byte("alice"), now))
+	assert.False(t, IsLocked(nil, now))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package timelock
+
+import (
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+type QueryEngine interface {
+	// UnspentTokensIteratorBy returns an iterator over all unspent tokens by type and id. Type can be empty
+	UnspentTokensIteratorBy(id, typ string) (driver.UnspentTokensIterator, error)
+}
+
+// LockedToken is a time-locked token together with the time it unlocks at
+type LockedToken struct {
+	*token2.UnspentToken
+	UnlockTime time.Time
+}
+
+// OwnerWallet is a combination of a wallet and a query service
+type OwnerWallet struct {
+	wallet       *token.OwnerWallet
+	queryService QueryEngine
+}
+
+// ListLocked returns the tokens of this wallet that match the passed options and are still time-locked,
+// together with the time each of them unlocks at
+func (w *OwnerWallet) ListLocked(opts ...token.ListTokensOption) ([]*LockedToken, error) {
+	return w.list(func(script *Script, now time.Time) bool { return script.IsLocked(now) }, opts...)
+}
+
+// ListUnlocked returns the time-locked tokens of this wallet that match the passed options and can be spent already
+func (w *OwnerWallet) ListUnlocked(opts ...token.ListTokensOption) ([]*LockedToken, error) {
+	return w.list(func(script *Script, now time.Time) bool { return !script.IsLocked(now) }, opts...)
+}
+
+func (w *OwnerWallet) list(selector func(*Script, time.Time) bool, opts ...token.ListTokensOption) ([]*LockedToken, error) {
+	compiledOpts, err := token.CompileListTokensOption(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compile options")
+	}
+	it, err := w.queryService.UnspentTokensIteratorBy(w.wallet.ID(), compiledOpts.TokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get iterator over unspent tokens")
+	}
+	defer it.Close()
+	now := time.Now()
+	var tokens []*LockedToken
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get next unspent token from iterator")
+		}
+		if tok == nil {
+			break
+		}
+		script, ok, err := Unwrap(tok.Owner.Raw)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			logger.Debugf("token [%s] contains a valid time-lock script? No [%s]", tok.Id, err)
+			continue
+		}
+		if selector(script, now) {
+			tokens = append(tokens, &LockedToken{UnspentToken: tok, UnlockTime: script.UnlockTime})
+		}
+	}
+	return tokens, nil
+}
+
+// Wallet returns an OwnerWallet which contains a wallet and a query service
+func Wallet(sp view2.ServiceProvider, wallet *token.OwnerWallet, opts ...token.ServiceOption) *OwnerWallet {
+	tms := token.GetManagementService(sp, opts...)
+	nw := network.GetInstance(sp, tms.Network(), tms.Channel())
+	if nw == nil {
+		return nil
+	}
+	vault, err := nw.Vault(tms.Namespace())
+	if err != nil {
+		logger.Errorf("failed to get vault for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
+		return nil
+	}
+	return &OwnerWallet{
+		wallet:       wallet,
+		queryService: vault.TokenVault().QueryEngine(),
+	}
+}
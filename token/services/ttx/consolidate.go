@@ -0,0 +1,316 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+const (
+	// DefaultConsolidationMaxInputs is the default maximum number of inputs of a consolidation transfer.
+	// In zkatdlog, the size of the transfer proof and the time to generate it grow with the number of inputs.
+	DefaultConsolidationMaxInputs = 16
+	// DefaultConsolidationThreshold is the default minimum number of unspent tokens that triggers a consolidation
+	DefaultConsolidationThreshold = 2
+)
+
+// ConsolidationOptions configures the consolidation of the unspent tokens of a wallet
+type ConsolidationOptions struct {
+	// MaxInputs is the maximum number of tokens merged by a single transfer
+	MaxInputs int
+	// MaxTransactions is the maximum number of transfers assembled by a single run, zero means no limit
+	MaxTransactions int
+	// Threshold is the minimum number of unspent tokens of the given type the wallet must own to consolidate them
+	Threshold int
+	// TxOptions are the options used to create each consolidation transaction, the auditor for instance
+	TxOptions []TxOption
+}
+
+// ConsolidationOption is a function that configures a ConsolidationOptions
+type ConsolidationOption func(*ConsolidationOptions) error
+
+// WithMaxInputs sets the maximum number of tokens merged by a single transfer
+func WithMaxInputs(maxInputs int) ConsolidationOption {
+	return func(o *ConsolidationOptions) error {
+		if maxInputs < 2 {
+			return errors.Errorf("max inputs must be at least 2, got [%d]", maxInputs)
+		}
+		o.MaxInputs = maxInputs
+		return nil
+	}
+}
+
+// WithMaxTransactions sets the maximum number of transfers assembled by a single run
+func WithMaxTransactions(maxTransactions int) ConsolidationOption {
+	return func(o *ConsolidationOptions) error {
+		o.MaxTransactions = maxTransactions
+		return nil
+	}
+}
+
+// WithThreshold sets the minimum number of unspent tokens that triggers a consolidation
+func WithThreshold(threshold int) ConsolidationOption {
+	return func(o *ConsolidationOptions) error {
+		o.Threshold = threshold
+		return nil
+	}
+}
+
+// WithConsolidationTxOptions sets the options used to create each consolidation transaction
+func WithConsolidationTxOptions(opts ...TxOption) ConsolidationOption {
+	return func(o *ConsolidationOptions) error {
+		o.TxOptions = opts
+		return nil
+	}
+}
+
+// compileConsolidationOptions applies the passed options on top of the defaults.
+// The default maximum number of inputs is the one in the passed selector configuration, if set.
+func compileConsolidationOptions(cfg *config.Selector, opts ...ConsolidationOption) (*ConsolidationOptions, error) {
+	options := &ConsolidationOptions{
+		MaxInputs: DefaultConsolidationMaxInputs,
+		Threshold: DefaultConsolidationThreshold,
+	}
+	if cfg != nil && cfg.MaxInputs != 0 {
+		if err := WithMaxInputs(cfg.MaxInputs)(options); err != nil {
+			return nil, errors.WithMessage(err, "invalid selector configuration")
+		}
+	}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+type consolidateView struct {
+	wallet    *token.OwnerWallet
+	tokenType string
+	opts      []ConsolidationOption
+}
+
+// NewConsolidateView returns a view that merges the unspent tokens of the passed type owned by the passed wallet
+// into fewer tokens, by means of self-transfers.
+// The view does the following, as long as the wallet owns at least the threshold number of unspent tokens:
+// 1. It selects and locks the smallest unspent tokens, at most MaxInputs and summing up to at most the maximum token value.
+// 2. It assembles a transfer of the selected tokens to a single output owned by the wallet.
+// 3. It collects the endorsements, the auditor's one included if set, and waits for finality.
+// The view returns the IDs of the committed consolidation transactions.
+func NewConsolidateView(wallet *token.OwnerWallet, tokenType string, opts ...ConsolidationOption) *consolidateView {
+	return &consolidateView{wallet: wallet, tokenType: tokenType, opts: opts}
+}
+
+func (c *consolidateView) Call(context view.Context) (interface{}, error) {
+	if c.wallet == nil {
+		return nil, errors.Errorf("wallet not set")
+	}
+	if len(c.tokenType) == 0 {
+		return nil, errors.Errorf("token type not set")
+	}
+	options, err := compileConsolidationOptions(c.wallet.TMS().ConfigManager().Selector(), c.opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed compiling consolidation options")
+	}
+
+	var txIDs []string
+	for options.MaxTransactions <= 0 || len(txIDs) < options.MaxTransactions {
+		txID, err := c.consolidate(context, options)
+		if err != nil {
+			return txIDs, errors.WithMessagef(err, "failed consolidating tokens of type [%s] in wallet [%s]", c.tokenType, c.wallet.ID())
+		}
+		if len(txID) == 0 {
+			break
+		}
+		txIDs = append(txIDs, txID)
+	}
+	return txIDs, nil
+}
+
+// consolidate assembles and commits a single consolidation transaction, if needed.
+// It returns the ID of the transaction, the empty string if there was nothing to consolidate.
+func (c *consolidateView) consolidate(context view.Context, options *ConsolidationOptions) (string, error) {
+	unspent, err := c.wallet.ListUnspentTokens(WithType(c.tokenType))
+	if err != nil {
+		return "", errors.WithMessage(err, "failed listing unspent tokens")
+	}
+	if len(unspent.Tokens) < options.Threshold {
+		logger.Debugf("wallet [%s] owns [%d] tokens of type [%s], below threshold [%d]", c.wallet.ID(), len(unspent.Tokens), c.tokenType, options.Threshold)
+		return "", nil
+	}
+	tms := c.wallet.TMS()
+	precision := tms.PublicParametersManager().Precision()
+	target, size, err := planConsolidation(unspent.Tokens, precision, options.MaxInputs, tms.PublicParametersManager().MaxTokenValue())
+	if err != nil {
+		return "", err
+	}
+	if size < 2 {
+		return "", nil
+	}
+
+	tx, err := NewAnonymousTransaction(context, options.TxOptions...)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed creating transaction")
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Release()
+		}
+	}()
+
+	// lock the smallest tokens not locked by other transactions
+	sm, ok := tms.SelectorManager().(token.StrategySelectorManager)
+	if !ok {
+		return "", errors.Errorf("selector manager does not support selection strategies")
+	}
+	s, err := sm.NewSelectorWithStrategy(tx.ID(), selector.SmallestFirst)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed getting selector")
+	}
+	ids, sum, err := s.Select(c.wallet, target.Decimal(), c.tokenType)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed selecting [%s] of type [%s]", target.Decimal(), c.tokenType)
+	}
+	if len(ids) > options.MaxInputs || !sum.ToBigInt().IsUint64() || sum.ToBigInt().Uint64() > tms.PublicParametersManager().MaxTokenValue() {
+		// the smallest tokens are locked by other transactions, try again later
+		logger.Debugf("cannot consolidate [%d] tokens of type [%s] in wallet [%s] now", len(ids), c.tokenType, c.wallet.ID())
+		return "", nil
+	}
+	if len(ids) < 2 {
+		return "", nil
+	}
+
+	recipient, err := c.wallet.GetRecipientIdentity()
+	if err != nil {
+		return "", errors.WithMessage(err, "failed getting recipient identity")
+	}
+	if err := tx.Transfer(c.wallet, c.tokenType, []uint64{sum.ToBigInt().Uint64()}, []view.Identity{recipient}, token.WithTokenIDs(ids...)); err != nil {
+		return "", errors.WithMessage(err, "failed assembling transfer")
+	}
+	if _, err := context.RunView(NewCollectEndorsementsView(tx)); err != nil {
+		return "", errors.WithMessage(err, "failed collecting endorsements")
+	}
+	if _, err := context.RunView(NewOrderingAndFinalityView(tx)); err != nil {
+		return "", errors.WithMessagef(err, "failed committing transaction [%s]", tx.ID())
+	}
+	committed = true
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("consolidated [%d] tokens of type [%s] in wallet [%s] with transaction [%s]", len(ids), c.tokenType, c.wallet.ID(), tx.ID())
+	}
+	return tx.ID(), nil
+}
+
+// planConsolidation returns the sum and the number of the smallest passed tokens that can be merged
+// into a single one, at most maxInputs and with a sum not exceeding maxValue.
+func planConsolidation(tokens []*token2.UnspentToken, precision uint64, maxInputs int, maxValue uint64) (token2.Quantity, int, error) {
+	quantities := make([]token2.Quantity, len(tokens))
+	for i, tok := range tokens {
+		q, err := token2.ToQuantity(tok.Quantity, precision)
+		if err != nil {
+			return nil, 0, errors.WithMessagef(err, "failed parsing quantity of token [%s]", tok.Id)
+		}
+		quantities[i] = q
+	}
+	sort.SliceStable(quantities, func(i, j int) bool {
+		return quantities[i].Cmp(quantities[j]) < 0
+	})
+	limit := new(big.Int).SetUint64(maxValue)
+	sum := token2.NewZeroQuantity(precision)
+	size := 0
+	for _, q := range quantities {
+		if size == maxInputs || new(big.Int).Add(sum.ToBigInt(), q.ToBigInt()).Cmp(limit) > 0 {
+			break
+		}
+		sum = sum.Add(q)
+		size++
+	}
+	return sum, size, nil
+}
+
+// ConsolidationScheduler runs the consolidation of the unspent tokens of a wallet periodically
+type ConsolidationScheduler struct {
+	sp        view2.ServiceProvider
+	wallet    *token.OwnerWallet
+	tokenType string
+	interval  time.Duration
+	opts      []ConsolidationOption
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewConsolidationScheduler returns a scheduler that runs, every interval, the consolidation of the unspent tokens
+// of the passed type owned by the passed wallet. Use WithThreshold to consolidate only when the wallet
+// owns enough unspent tokens.
+func NewConsolidationScheduler(sp view2.ServiceProvider, wallet *token.OwnerWallet, tokenType string, interval time.Duration, opts ...ConsolidationOption) (*ConsolidationScheduler, error) {
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid consolidation interval [%s]", interval)
+	}
+	if wallet == nil {
+		return nil, errors.Errorf("wallet not set")
+	}
+	if _, err := compileConsolidationOptions(wallet.TMS().ConfigManager().Selector(), opts...); err != nil {
+		return nil, errors.WithMessage(err, "failed compiling consolidation options")
+	}
+	return &ConsolidationScheduler{
+		sp:        sp,
+		wallet:    wallet,
+		tokenType: tokenType,
+		interval:  interval,
+		opts:      opts,
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// Start runs the consolidation periodically in a separate goroutine, until Stop is called
+func (s *ConsolidationScheduler) Start() {
+	logger.Infof("starting consolidation of tokens of type [%s] in wallet [%s] every [%s]", s.tokenType, s.wallet.ID(), s.interval)
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.run()
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic consolidation, a run in progress is completed
+func (s *ConsolidationScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *ConsolidationScheduler) run() {
+	boxed, err := view2.GetManager(s.sp).InitiateView(NewConsolidateView(s.wallet, s.tokenType, s.opts...))
+	if err != nil {
+		logger.Errorf("failed consolidating tokens of type [%s] in wallet [%s]: [%s]", s.tokenType, s.wallet.ID(), err)
+		return
+	}
+	if txIDs, ok := boxed.([]string); ok && len(txIDs) != 0 {
+		logger.Infof("consolidated tokens of type [%s] in wallet [%s] with transactions %v", s.tokenType, s.wallet.ID(), txIDs)
+	}
+}
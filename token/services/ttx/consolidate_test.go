@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanConsolidation(t *testing.T) {
+	tokens := func(values ...string) []*token2.UnspentToken {
+		var res []*token2.UnspentToken
+		for i, v := range values {
+			res = append(res, &token2.UnspentToken{Id: &token2.ID{TxId: "tx", Index: uint64(i)}, Quantity: v})
+		}
+		return res
+	}
+
+	// the smallest tokens first, bounded by the max inputs
+	sum, size, err := planConsolidation(tokens("0x5", "0x1", "0x3", "0x2"), 64, 3, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, size)
+	assert.Equal(t, "6", sum.Decimal())
+
+	// bounded by the max token value
+	sum, size, err = planConsolidation(tokens("0x5", "0x1", "0x3", "0x2"), 64, 10, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, size)
+	assert.Equal(t, "6", sum.Decimal())
+
+	// nothing to merge
+	_, size, err = planConsolidation(tokens("0x5"), 64, 10, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, size)
+
+	_, _, err = planConsolidation(tokens("invalid"), 64, 10, 4)
+	assert.Error(t, err)
+
+	_, err = compileConsolidationOptions(nil, WithMaxInputs(1))
+	assert.Error(t, err)
+
+	// the maximum number of inputs comes from the selector configuration, unless overridden
+	options, err := compileConsolidationOptions(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultConsolidationMaxInputs, options.MaxInputs)
+	options, err = compileConsolidationOptions(&config.Selector{MaxInputs: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, options.MaxInputs)
+	options, err = compileConsolidationOptions(&config.Selector{MaxInputs: 8}, WithMaxInputs(4))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, options.MaxInputs)
+	_, err = compileConsolidationOptions(&config.Selector{MaxInputs: 1})
+	assert.Error(t, err)
+}
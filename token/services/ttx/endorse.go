@@ -16,6 +16,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracker/metrics"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
@@ -34,6 +35,9 @@ func (sr *signatureRequest) MessageToSign() []byte {
 type collectEndorsementsView struct {
 	tx       *Transaction
 	sessions map[string]view.Session
+	// excluded are the co-owners of multisig identities that failed to sign in time.
+	// They receive the final transaction, but their ack is not awaited.
+	excluded map[string]bool
 }
 
 // NewCollectEndorsementsView returns an instance of the collectEndorsementsView struct.
@@ -45,7 +49,7 @@ type collectEndorsementsView struct {
 // Depending on the token driver implementation, the recipient's signature might or might not be needed to make
 // the token transaction valid.
 func NewCollectEndorsementsView(tx *Transaction) *collectEndorsementsView {
-	return &collectEndorsementsView{tx: tx, sessions: map[string]view.Session{}}
+	return &collectEndorsementsView{tx: tx, sessions: map[string]view.Session{}, excluded: map[string]bool{}}
 }
 
 // Call executes the view.
@@ -234,106 +238,190 @@ func (c *collectEndorsementsView) requestSignaturesOnTransfers(context view.Cont
 		distributionList = append(distributionList, transfer.Receivers...)
 
 		// contact signer and ask for the signature unless it is me
-		var signers []view.Identity
-		signers = append(signers, transfer.Senders...)
-		signers = append(signers, transfer.ExtraSigners...)
-
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("collecting signature on [%d]-th request transfer, signers [%d]", i, len(signers))
+			logger.Debugf("collecting signature on [%d]-th request transfer, signers [%d]", i, len(transfer.Senders)+len(transfer.ExtraSigners))
 		}
 
-		for _, party := range signers {
-			signatureRequest := &signatureRequest{
-				Request: requestRaw,
-				TxID:    []byte(c.tx.ID()),
-				Signer:  party,
+		for _, party := range transfer.Senders {
+			id, err := identity.Signer(party)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed getting the signer of [%s]", party.UniqueID())
 			}
-
-			if logger.IsEnabledFor(zapcore.DebugLevel) {
-				logger.Debugf("collecting signature on request (transfer) from [%s]", party.UniqueID())
+			party = id
+			coOwners, ok, err := identity.CoOwners(party)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed getting the co-owners of [%s]", party.UniqueID())
 			}
-
-			if signer, err := c.tx.TokenService().SigService().GetSigner(party); err == nil {
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("collecting signature on request (transfer) from [%s], it is me!", party.UniqueID())
-					logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.TxID.Nonce))
-				}
-				sigma, err := signer.Sign(signatureRequest.MessageToSign())
+			if ok {
+				sigma, err := c.requestCoOwnersSignature(context, requestRaw, party, coOwners)
 				if err != nil {
-					return nil, err
-				}
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("signature verified (me) [%s,%s,%s]",
-						hash.Hashable(signatureRequest.MessageToSign()).String(),
-						hash.Hashable(sigma).String(),
-						party.UniqueID(),
-					)
+					return nil, errors.WithMessagef(err, "failed collecting signatures of the co-owners of [%s]", party.UniqueID())
 				}
-
 				c.tx.TokenRequest.AppendSignature(sigma)
 				continue
 			}
-			if logger.IsEnabledFor(zapcore.DebugLevel) {
-				logger.Debugf("collecting signature on request (transfer) from [%s], it is not me, connect to party!", party.UniqueID())
-			}
-
-			session, err := context.GetSession(context.Initiator(), party)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed getting session")
-			}
-			// Wait to receive a content back
-			ch := session.Receive()
 
-			signatureRequestRaw, err := Marshal(signatureRequest)
+			sigma, err := c.requestSignature(context, requestRaw, party, time.Now().Add(time.Minute))
 			if err != nil {
 				return nil, err
 			}
-			err = session.Send(signatureRequestRaw)
+			c.tx.TokenRequest.AppendSignature(sigma)
+		}
+		// the extra signers are not owners, they sign for themselves
+		for _, party := range transfer.ExtraSigners {
+			sigma, err := c.requestSignature(context, requestRaw, party, time.Now().Add(time.Minute))
 			if err != nil {
-				return nil, errors.Wrap(err, "failed sending transaction content")
+				return nil, err
 			}
+			c.tx.TokenRequest.AppendSignature(sigma)
+		}
+	}
 
-			timeout := time.NewTimer(time.Minute)
+	return distributionList, nil
+}
 
-			var msg *view.Message
-			select {
-			case msg = <-ch:
-				if logger.IsEnabledFor(zapcore.DebugLevel) {
-					logger.Debugf("collect signatures on transfer: reply received from [%s]", party)
-				}
-				timeout.Stop()
-			case <-timeout.C:
-				timeout.Stop()
-				return nil, errors.Errorf("Timeout from party %s", party)
-			}
-			if msg.Status == view.ERROR {
-				return nil, errors.New(string(msg.Payload))
-			}
+// requestSignature returns the signature of the passed party on the token request.
+// If the party is me, the signature is produced locally, otherwise it is requested to the party,
+// that must reply before the passed deadline.
+func (c *collectEndorsementsView) requestSignature(context view.Context, requestRaw []byte, party view.Identity, deadline time.Time) ([]byte, error) {
+	signatureRequest := &signatureRequest{
+		Request: requestRaw,
+		TxID:    []byte(c.tx.ID()),
+		Signer:  party,
+	}
 
-			sigma := msg.Payload
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("collecting signature on request (transfer) from [%s]", party.UniqueID())
+	}
 
-			verifier, err := c.tx.TokenService().SigService().OwnerVerifier(party)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
-			}
-			err = verifier.Verify(signatureRequest.MessageToSign(), sigma)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed verifying signature from [%s]", party)
-			}
+	if signer, err := c.tx.TokenService().SigService().GetSigner(party); err == nil {
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("collecting signature on request (transfer) from [%s], it is me!", party.UniqueID())
+			logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.TxID.Nonce))
+		}
+		sigma, err := signer.Sign(signatureRequest.MessageToSign())
+		if err != nil {
+			return nil, err
+		}
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("signature verified (me) [%s,%s,%s]",
+				hash.Hashable(signatureRequest.MessageToSign()).String(),
+				hash.Hashable(sigma).String(),
+				party.UniqueID(),
+			)
+		}
+		return sigma, nil
+	}
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("collecting signature on request (transfer) from [%s], it is not me, connect to party!", party.UniqueID())
+	}
 
-			if logger.IsEnabledFor(zapcore.DebugLevel) {
-				logger.Debugf("signature verified [%s,%s,%s]",
-					hash.Hashable(signatureRequest.MessageToSign()).String(),
-					hash.Hashable(sigma).String(),
-					party.UniqueID(),
-				)
-			}
+	session, err := context.GetSession(context.Initiator(), party)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting session")
+	}
+	// Wait to receive a content back
+	ch := session.Receive()
 
-			c.tx.TokenRequest.AppendSignature(sigma)
+	signatureRequestRaw, err := Marshal(signatureRequest)
+	if err != nil {
+		return nil, err
+	}
+	err = session.Send(signatureRequestRaw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed sending transaction content")
+	}
+
+	timeout := time.NewTimer(time.Until(deadline))
+
+	var msg *view.Message
+	select {
+	case msg = <-ch:
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("collect signatures on transfer: reply received from [%s]", party)
 		}
+		timeout.Stop()
+	case <-timeout.C:
+		timeout.Stop()
+		return nil, errors.Errorf("Timeout from party %s", party)
+	}
+	if msg.Status == view.ERROR {
+		return nil, errors.New(string(msg.Payload))
 	}
 
-	return distributionList, nil
+	sigma := msg.Payload
+
+	verifier, err := c.tx.TokenService().SigService().OwnerVerifier(party)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
+	}
+	err = verifier.Verify(signatureRequest.MessageToSign(), sigma)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed verifying signature from [%s]", party)
+	}
+
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("signature verified [%s,%s,%s]",
+			hash.Hashable(signatureRequest.MessageToSign()).String(),
+			hash.Hashable(sigma).String(),
+			party.UniqueID(),
+		)
+	}
+	return sigma, nil
+}
+
+// requestCoOwnersSignature requests the signature on the token request to the passed co-owners of the passed
+// owner in parallel, under a single deadline, and joins them. It is enough that the threshold is met.
+// The co-owners that fail to sign are excluded, they receive the final transaction without being waited for.
+// The co-owners reached through the same session are asked one after the other, so that their replies do not mix.
+func (c *collectEndorsementsView) requestCoOwnersSignature(context view.Context, requestRaw []byte, owner view.Identity, coOwners []view.Identity) ([]byte, error) {
+	deadline := time.Now().Add(time.Minute)
+
+	type reply struct {
+		coOwner view.Identity
+		sigma   []byte
+		err     error
+	}
+	replies := make(chan reply, len(coOwners))
+	var groups [][]view.Identity
+	sessions := map[view.Session]int{}
+	for _, coOwner := range coOwners {
+		if _, err := c.tx.TokenService().SigService().GetSigner(coOwner); err == nil {
+			groups = append(groups, []view.Identity{coOwner})
+			continue
+		}
+		session, err := context.GetSession(context.Initiator(), coOwner)
+		if err != nil {
+			replies <- reply{coOwner: coOwner, err: errors.Wrap(err, "failed getting session")}
+			continue
+		}
+		if i, ok := sessions[session]; ok {
+			groups[i] = append(groups[i], coOwner)
+			continue
+		}
+		sessions[session] = len(groups)
+		groups = append(groups, []view.Identity{coOwner})
+	}
+	for _, group := range groups {
+		go func(group []view.Identity) {
+			for _, coOwner := range group {
+				sigma, err := c.requestSignature(context, requestRaw, coOwner, deadline)
+				replies <- reply{coOwner: coOwner, sigma: sigma, err: err}
+			}
+		}(group)
+	}
+
+	sigmas := map[string][]byte{}
+	for range coOwners {
+		r := <-replies
+		if r.err != nil {
+			logger.Warnf("co-owner [%s] did not sign: [%s]", r.coOwner.UniqueID(), r.err)
+			c.excluded[r.coOwner.UniqueID()] = true
+			continue
+		}
+		sigmas[r.coOwner.UniqueID()] = r.sigma
+	}
+	return identity.JoinSignatures(owner, sigmas)
 }
 
 func (c *collectEndorsementsView) requestApproval(context view.Context) (*network.Envelope, error) {
@@ -414,6 +502,12 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 	// 	return errors.Wrap(err, "failed verifying transaction content before distributing it")
 	// }
 
+	// Replace the time-lock scripts with their owners and the multisig identities with their co-owners
+	distributionList, coOwnedEIDs, err := c.expandOwners(distributionList)
+	if err != nil {
+		return err
+	}
+
 	// Compress distributionList by removing duplicates
 	type distributionListEntry struct {
 		IsMe     bool
@@ -421,6 +515,10 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 		ID       view.Identity
 		EID      string
 		Auditor  bool
+		// CoOwnedEIDs are the enrollment IDs of the scripts the party is named in, like the multisig identities it co-owns
+		CoOwnedEIDs []string
+		// Excluded is true if the party is only a co-owner that failed to sign, its ack is not awaited
+		Excluded bool
 	}
 	var distributionListCompressed []distributionListEntry
 	for _, party := range distributionList {
@@ -455,9 +553,12 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
 			logger.Debugf("searching for long term identity [%s]", longTermIdentity)
 		}
+		excluded := c.excluded[party.UniqueID()]
 		found := false
-		for _, entry := range distributionListCompressed {
+		for i, entry := range distributionListCompressed {
 			if longTermIdentity.Equal(entry.LongTerm) {
+				distributionListCompressed[i].CoOwnedEIDs = append(entry.CoOwnedEIDs, coOwnedEIDs[party.UniqueID()]...)
+				distributionListCompressed[i].Excluded = entry.Excluded && excluded
 				found = true
 				break
 			}
@@ -474,11 +575,13 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 				}
 			}
 			distributionListCompressed = append(distributionListCompressed, distributionListEntry{
-				IsMe:     isMe,
-				LongTerm: longTermIdentity,
-				ID:       party,
-				EID:      eID,
-				Auditor:  false,
+				IsMe:        isMe,
+				LongTerm:    longTermIdentity,
+				ID:          party,
+				EID:         eID,
+				Auditor:     false,
+				CoOwnedEIDs: coOwnedEIDs[party.UniqueID()],
+				Excluded:    excluded,
 			})
 		} else {
 			if logger.IsEnabledFor(zapcore.DebugLevel) {
@@ -566,7 +669,7 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 			if logger.IsEnabledFor(zapcore.DebugLevel) {
 				logger.Debugf("This is not an auditor [%s], send the filtered metadata", entry.ID.UniqueID())
 			}
-			txRaw, err = c.tx.Bytes(entry.EID)
+			txRaw, err = c.tx.Bytes(append([]string{entry.EID}, entry.CoOwnedEIDs...)...)
 			if err != nil {
 				return errors.Wrap(err, "failed marshalling transaction content")
 			}
@@ -576,8 +679,20 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 		// Open a session to the party. and send the transaction.
 		session, err := c.getSession(context, entry.ID)
 		if err != nil {
+			if entry.Excluded {
+				logger.Warnf("failed getting session to excluded co-owner [%s]: [%s]", entry.ID.UniqueID(), err)
+				continue
+			}
 			return errors.Wrap(err, "failed getting session")
 		}
+		// A co-owner that failed to sign in time gets the final transaction, not to wait for a request that will not come.
+		// Its ack is not awaited.
+		if entry.Excluded {
+			if err := session.Send(txRaw); err != nil {
+				logger.Warnf("failed sending transaction to excluded co-owner [%s]: [%s]", entry.ID.UniqueID(), err)
+			}
+			continue
+		}
 		// Wait to receive a content back
 		ch := session.Receive()
 		// Send the content
@@ -631,6 +746,70 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 	return nil
 }
 
+// expandOwners replaces the scripts in the passed distribution list with the identities
+// that sign for them: time-lock scripts with their owners, custody scripts with their
+// custodians, and multisig identities with their co-owners.
+// It also returns the enrollment IDs of the scripts each party is named in, indexed by
+// its unique ID, so that it receives the metadata of the outputs they own.
+func (c *collectEndorsementsView) expandOwners(distributionList []view.Identity) ([]view.Identity, map[string][]string, error) {
+	var res []view.Identity
+	coOwnedEIDs := map[string][]string{}
+	for _, party := range distributionList {
+		id, err := identity.Signer(party)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting the signer of [%s]", party.UniqueID())
+		}
+		party = id
+		coOwners, ok, err := identity.CoOwners(party)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting the co-owners of [%s]", party.UniqueID())
+		}
+		if !ok {
+			res = append(res, party)
+			continue
+		}
+		eID, err := c.tx.TokenService().WalletManager().GetEnrollmentID(party)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting enrollment ID for [%s]", party.UniqueID())
+		}
+		for _, coOwner := range coOwners {
+			res = append(res, coOwner)
+			coOwnedEIDs[coOwner.UniqueID()] = append(coOwnedEIDs[coOwner.UniqueID()], eID)
+		}
+	}
+
+	// the parties named in a script owning an output, like the buyer and the seller of an escrow script,
+	// receive the metadata of the output, whose enrollment ID is the one of the script
+	outputs, err := c.tx.Outputs()
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed getting the outputs of [%s]", c.tx.ID())
+	}
+	for _, output := range outputs.Outputs() {
+		parties, _, ok, err := identity.ScriptParties(output.Owner, output.OwnerAuditInfo)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting the parties of the owner of output [%d]", output.Index)
+		}
+		if !ok {
+			continue
+		}
+		for _, party := range parties {
+			if !containsEID(coOwnedEIDs[party.UniqueID()], output.EnrollmentID) {
+				coOwnedEIDs[party.UniqueID()] = append(coOwnedEIDs[party.UniqueID()], output.EnrollmentID)
+			}
+		}
+	}
+	return res, coOwnedEIDs, nil
+}
+
+func containsEID(eIDs []string, eID string) bool {
+	for _, e := range eIDs {
+		if e == eID {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *collectEndorsementsView) requestBytes() ([]byte, error) {
 	return c.tx.TokenRequest.MarshalToSign()
 }
@@ -813,13 +992,28 @@ func (s *endorseView) requestsToBeSigned() ([]*token.Transfer, error) {
 	var res []*token.Transfer
 	transfers := s.tx.TokenRequest.Transfers()
 	for _, transfer := range transfers {
-		for _, sender := range transfer.Senders {
-			if _, err := s.tx.TokenService().SigService().GetSigner(sender); err == nil {
-				res = append(res, transfer)
+		for _, signer := range transfer.Senders {
+			id, err := identity.Signer(signer)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed getting the signer of [%s]", signer.UniqueID())
+			}
+			signer = id
+			// each co-owner of a script is asked to sign
+			parties, ok, err := identity.CoOwners(signer)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed getting the co-owners of [%s]", signer.UniqueID())
+			}
+			if !ok {
+				parties = []view.Identity{signer}
+			}
+			for _, party := range parties {
+				if _, err := s.tx.TokenService().SigService().GetSigner(party); err == nil {
+					res = append(res, transfer)
+				}
 			}
 		}
-		for _, sender := range transfer.ExtraSigners {
-			if _, err := s.tx.TokenService().SigService().GetSigner(sender); err == nil {
+		for _, signer := range transfer.ExtraSigners {
+			if _, err := s.tx.TokenService().SigService().GetSigner(signer); err == nil {
 				res = append(res, transfer)
 			}
 		}
@@ -196,12 +196,25 @@ func (t *Transaction) Issue(wallet *token.IssuerWallet, receiver view.Identity,
 	return err
 }
 
+// BatchIssue appends to the TokenRequest inside this transaction a single Issue operation with an output for each of the passed legs
+func (t *Transaction) BatchIssue(wallet *token.IssuerWallet, legs []*token.IssueLeg, opts ...token.IssueOption) error {
+	_, err := t.TokenRequest.BatchIssue(wallet, legs, opts...)
+	return err
+}
+
 // Transfer appends a new Transfer operation to the TokenRequest inside this transaction
 func (t *Transaction) Transfer(wallet *token.OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...token.TransferOption) error {
 	_, err := t.TokenRequest.Transfer(wallet, typ, values, owners, opts...)
 	return err
 }
 
+// MultiTransfer appends to the TokenRequest inside this transaction a Transfer operation for each token type
+// appearing in the passed legs. Either all the operations are appended or none.
+func (t *Transaction) MultiTransfer(wallet *token.OwnerWallet, legs []*token.TransferLeg, opts ...token.TransferOption) error {
+	_, err := t.TokenRequest.MultiTransfer(wallet, legs, opts...)
+	return err
+}
+
 func (t *Transaction) Redeem(wallet *token.OwnerWallet, typ string, value uint64, opts ...token.TransferOption) error {
 	return t.TokenRequest.Redeem(wallet, typ, value, opts...)
 }
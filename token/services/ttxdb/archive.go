@@ -0,0 +1,302 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/pkg/errors"
+)
+
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".jsonl.gz"
+)
+
+// Segment is a compressed file containing archived records.
+// Its name carries the time window spanned by the records it contains.
+type Segment struct {
+	Path string
+	From time.Time
+	To   time.Time
+}
+
+// segmentEntry is a line of a segment, only one of the records is set
+type segmentEntry struct {
+	Transaction *driver.TransactionRecord `json:"transaction,omitempty"`
+	Movement    *driver.MovementRecord    `json:"movement,omitempty"`
+}
+
+// Archive stores the records removed from a db by compaction, as segment files in a folder.
+type Archive struct {
+	path string
+}
+
+// NewArchive returns the archive stored in the passed folder
+func NewArchive(path string) *Archive {
+	return &Archive{path: path}
+}
+
+// Path returns the folder of the archive
+func (a *Archive) Path() string {
+	return a.path
+}
+
+// Segments returns the segments of the archive, sorted by the start of their time window
+func (a *Archive) Segments() ([]*Segment, error) {
+	entries, err := ioutil.ReadDir(a.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed reading archive [%s]", a.path)
+	}
+	var segments []*Segment
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		var from, to int64
+		if _, err := fmt.Sscanf(entry.Name(), segmentPrefix+"%d-%d"+segmentSuffix, &from, &to); err != nil {
+			continue
+		}
+		segments = append(segments, &Segment{
+			Path: filepath.Join(a.path, entry.Name()),
+			From: time.Unix(0, from),
+			To:   time.Unix(0, to),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].From.Before(segments[j].From)
+	})
+	return segments, nil
+}
+
+// Restore adds to the passed db the archived records stored in the passed time window, bounds included,
+// and returns the number of restored transactions.
+// The destination is meant to be a separate db, such as a memory one, to be wrapped with NewDB for audit queries.
+// Restoring into the db the records were archived from would break the ordering of its records.
+func (a *Archive) Restore(dst driver.TokenTransactionDB, from, to time.Time) (int, error) {
+	segments, err := a.Segments()
+	if err != nil {
+		return 0, err
+	}
+	if err := dst.BeginUpdate(); err != nil {
+		return 0, errors.WithMessage(err, "begin update failed")
+	}
+	restored := map[string]bool{}
+	for _, segment := range segments {
+		if segment.To.Before(from) || segment.From.After(to) {
+			continue
+		}
+		if err := restoreSegment(dst, segment, from, to, restored); err != nil {
+			if err1 := dst.Discard(); err1 != nil {
+				logger.Errorf("got error %s; discarding caused %s", err.Error(), err1.Error())
+			}
+			return 0, err
+		}
+	}
+	if err := dst.Commit(); err != nil {
+		return 0, errors.WithMessage(err, "committing restored records failed")
+	}
+	return len(restored), nil
+}
+
+// restoreSegment adds to the passed db the records of the passed segment stored in the passed time window.
+// Movements stored without a timestamp are restored together with the transactions they belong to.
+// Transactions already restored from a previous segment are skipped, this happens if compaction
+// was interrupted after writing a segment but before removing its records from the db.
+func restoreSegment(dst driver.TokenTransactionDB, segment *Segment, from, to time.Time, restored map[string]bool) error {
+	entries, err := readSegment(segment.Path)
+	if err != nil {
+		return err
+	}
+	inWindow := func(t time.Time) bool {
+		return !t.Before(from) && !t.After(to)
+	}
+	// select the transactions first, movements follow the transactions they belong to
+	selected := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Transaction != nil && !restored[entry.Transaction.TxID] && inWindow(entry.Transaction.Timestamp) {
+			selected[entry.Transaction.TxID] = true
+		}
+		if entry.Movement != nil && !restored[entry.Movement.TxID] && !entry.Movement.Timestamp.IsZero() && inWindow(entry.Movement.Timestamp) {
+			selected[entry.Movement.TxID] = true
+		}
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Transaction != nil && selected[entry.Transaction.TxID]:
+			if err := dst.AddTransaction(entry.Transaction); err != nil {
+				return errors.WithMessagef(err, "failed restoring transaction [%s]", entry.Transaction.TxID)
+			}
+		case entry.Movement != nil && selected[entry.Movement.TxID]:
+			if err := dst.AddMovement(entry.Movement); err != nil {
+				return errors.WithMessagef(err, "failed restoring movement [%s]", entry.Movement.TxID)
+			}
+		}
+	}
+	for txID := range selected {
+		restored[txID] = true
+	}
+	return nil
+}
+
+// unarchived returns the passed records but those of the transactions already archived.
+// Only the segments overlapping the time window of the passed records are read, usually none,
+// as compaction archives records in stored order.
+func (a *Archive) unarchived(transactions []*driver.TransactionRecord, movements []*driver.MovementRecord) ([]*driver.TransactionRecord, []*driver.MovementRecord, error) {
+	from, to := recordsWindow(transactions, movements)
+	segments, err := a.Segments()
+	if err != nil {
+		return nil, nil, err
+	}
+	archived := map[string]bool{}
+	for _, segment := range segments {
+		if segment.To.Before(from) || segment.From.After(to) {
+			continue
+		}
+		entries, err := readSegment(segment.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			if entry.Transaction != nil {
+				archived[entry.Transaction.TxID] = true
+			}
+			if entry.Movement != nil {
+				archived[entry.Movement.TxID] = true
+			}
+		}
+	}
+	if len(archived) == 0 {
+		return transactions, movements, nil
+	}
+	var trs []*driver.TransactionRecord
+	for _, record := range transactions {
+		if !archived[record.TxID] {
+			trs = append(trs, record)
+		}
+	}
+	var mvs []*driver.MovementRecord
+	for _, record := range movements {
+		if !archived[record.TxID] {
+			mvs = append(mvs, record)
+		}
+	}
+	return trs, mvs, nil
+}
+
+// recordsWindow returns the time window spanned by the passed records, those with no timestamp excluded
+func recordsWindow(transactions []*driver.TransactionRecord, movements []*driver.MovementRecord) (time.Time, time.Time) {
+	var from, to time.Time
+	span := func(t time.Time) {
+		if t.IsZero() {
+			return
+		}
+		if from.IsZero() || t.Before(from) {
+			from = t
+		}
+		if to.IsZero() || t.After(to) {
+			to = t
+		}
+	}
+	for _, record := range transactions {
+		span(record.Timestamp)
+	}
+	for _, record := range movements {
+		span(record.Timestamp)
+	}
+	return from, to
+}
+
+// write stores the passed records in a new segment and returns it.
+// The segment is first written to a temporary file and then renamed, so that partial segments are never visible.
+func (a *Archive) write(transactions []*driver.TransactionRecord, movements []*driver.MovementRecord) (*Segment, error) {
+	from, to := recordsWindow(transactions, movements)
+	if from.IsZero() {
+		// only movements stored by earlier versions, which have no timestamp
+		from = time.Now()
+		to = from
+	}
+
+	if err := os.MkdirAll(a.path, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed creating archive [%s]", a.path)
+	}
+	name := fmt.Sprintf("%s%d-%d%s", segmentPrefix, from.UnixNano(), to.UnixNano(), segmentSuffix)
+	segment := &Segment{Path: filepath.Join(a.path, name), From: from, To: to}
+	if _, err := os.Stat(segment.Path); err == nil {
+		return nil, errors.Errorf("segment [%s] already exists", segment.Path)
+	}
+
+	tmp, err := ioutil.TempFile(a.path, "tmp-"+segmentPrefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating segment in [%s]", a.path)
+	}
+	defer os.Remove(tmp.Name())
+	gw := gzip.NewWriter(tmp)
+	encoder := json.NewEncoder(gw)
+	for _, record := range transactions {
+		if err := encoder.Encode(&segmentEntry{Transaction: record}); err != nil {
+			tmp.Close()
+			return nil, errors.Wrapf(err, "failed writing transaction [%s]", record.TxID)
+		}
+	}
+	for _, record := range movements {
+		if err := encoder.Encode(&segmentEntry{Movement: record}); err != nil {
+			tmp.Close()
+			return nil, errors.Wrapf(err, "failed writing movement [%s]", record.TxID)
+		}
+	}
+	if err := gw.Close(); err != nil {
+		tmp.Close()
+		return nil, errors.Wrap(err, "failed compressing segment")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, errors.Wrap(err, "failed syncing segment")
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed closing segment")
+	}
+	if err := os.Rename(tmp.Name(), segment.Path); err != nil {
+		return nil, errors.Wrapf(err, "failed renaming segment to [%s]", segment.Path)
+	}
+	return segment, nil
+}
+
+func readSegment(path string) ([]*segmentEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed opening segment [%s]", path)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decompressing segment [%s]", path)
+	}
+	defer gr.Close()
+	var entries []*segmentEntry
+	decoder := json.NewDecoder(gr)
+	for decoder.More() {
+		entry := &segmentEntry{}
+		if err := decoder.Decode(entry); err != nil {
+			return nil, errors.Wrapf(err, "failed reading segment [%s]", path)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
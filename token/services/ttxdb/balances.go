@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/pkg/errors"
+)
+
+// BalanceCheckpoint is a materialized balance of an enrollment ID for a token type
+type BalanceCheckpoint = driver.BalanceCheckpoint
+
+// appendBalanceCheckpoints adds, for each pair of enrollment ID and token type touched by the passed movements,
+// a checkpoint whose balance is the latest one plus the movement amounts, or minus them if revert is true.
+// It must be called within an update.
+func (db *DB) appendBalanceCheckpoints(txID string, movements []*driver.MovementRecord, revert bool, timestamp time.Time) error {
+	type pair struct {
+		eID       string
+		tokenType string
+	}
+	var pairs []pair
+	balances := map[pair]*big.Int{}
+	for _, movement := range movements {
+		p := pair{eID: movement.EnrollmentID, tokenType: movement.TokenType}
+		balance, ok := balances[p]
+		if !ok {
+			checkpoints, err := db.db.QueryBalances(driver.QueryBalancesParams{
+				EnrollmentIDs: []string{p.eID},
+				TokenTypes:    []string{p.tokenType},
+			})
+			if err != nil {
+				return errors.WithMessagef(err, "failed getting balance of [%s:%s]", p.eID, p.tokenType)
+			}
+			balance = big.NewInt(0)
+			if len(checkpoints) != 0 {
+				balance.Set(checkpoints[0].Amount)
+			}
+			balances[p] = balance
+			pairs = append(pairs, p)
+		}
+		if revert {
+			balance.Sub(balance, movement.Amount)
+		} else {
+			balance.Add(balance, movement.Amount)
+		}
+	}
+
+	for _, p := range pairs {
+		if err := db.db.AddBalanceCheckpoint(&driver.BalanceCheckpoint{
+			EnrollmentID: p.eID,
+			TokenType:    p.tokenType,
+			Amount:       balances[p],
+			TxID:         txID,
+			Timestamp:    timestamp,
+		}); err != nil {
+			return errors.WithMessagef(err, "failed adding balance checkpoint for [%s:%s]", p.eID, p.tokenType)
+		}
+	}
+	return nil
+}
+
+// initBalanceCheckpoints creates the first checkpoints from the existing movements,
+// if the db contains movements but no checkpoints, as it happens for dbs created before checkpoints were introduced.
+func (db *DB) initBalanceCheckpoints() error {
+	checkpoints, err := db.db.QueryBalances(driver.QueryBalancesParams{})
+	if err != nil {
+		return errors.WithMessage(err, "failed getting balance checkpoints")
+	}
+	if len(checkpoints) != 0 {
+		return nil
+	}
+	movements, _, err := db.db.QueryMovements(driver.QueryMovementsParams{
+		TxStatuses:        []driver.TxStatus{driver.Pending, driver.Confirmed},
+		MovementDirection: driver.All,
+		SearchDirection:   driver.FromBeginning,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed getting movements")
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+	logger.Infof("initializing balance checkpoints from [%d] movements", len(movements))
+
+	if err := db.db.BeginUpdate(); err != nil {
+		db.rollback(err)
+		return errors.WithMessage(err, "begin update failed")
+	}
+	if err := db.appendBalanceCheckpoints("", movements, false, time.Now()); err != nil {
+		db.rollback(err)
+		return errors.WithMessage(err, "append balance checkpoints failed")
+	}
+	if err := db.db.Commit(); err != nil {
+		db.rollback(err)
+		return errors.WithMessage(err, "committing balance checkpoints failed")
+	}
+	return nil
+}
@@ -120,9 +120,19 @@ func (t *TransactionIterator) Next() (*TransactionRecord, error) {
 	return next, nil
 }
 
-// QueryTransactionsParams defines the parameters for querying movements
+// Cursor returns the continuation token to pass in QueryTransactionsParams to get the next page,
+// once Next has returned nil.
+// It is empty if the query was not paginated or if the last page has been reached.
+func (t *TransactionIterator) Cursor() (string, error) {
+	return t.it.Cursor()
+}
+
+// QueryTransactionsParams defines the parameters for querying transactions
 type QueryTransactionsParams = driver.QueryTransactionsParams
 
+// QueryMovementsParams defines the parameters for querying movements
+type QueryMovementsParams = driver.QueryMovementsParams
+
 // QueryExecutor executors queries against the DB
 type QueryExecutor struct {
 	db     *DB
@@ -152,6 +162,17 @@ func (qe *QueryExecutor) Transactions(params QueryTransactionsParams) (*Transact
 	return &TransactionIterator{it: it}, nil
 }
 
+// Movements returns the movement records matching the passed parameters, and the continuation token to pass
+// in QueryMovementsParams to get the next page.
+// The continuation token is empty if the query was not paginated or if the last page has been reached.
+func (qe *QueryExecutor) Movements(params QueryMovementsParams) ([]*MovementRecord, string, error) {
+	records, cursor, err := qe.db.db.QueryMovements(params)
+	if err != nil {
+		return nil, "", errors.Errorf("failed to query movements: %s", err)
+	}
+	return records, cursor, nil
+}
+
 // Done closes the query executor. It must be called when the query executor is no longer needed.s
 func (qe *QueryExecutor) Done() {
 	if qe.closed {
@@ -190,16 +211,44 @@ type DB struct {
 
 	// status related fields
 	pendingTXs []string
+
+	// archive is where compaction moves the records of confirmed transactions, if any
+	archive *Archive
+	// stopCompaction is closed to stop the periodic compaction
+	stopCompaction     chan struct{}
+	stopCompactionOnce sync.Once
+
+	// uniqueID extracts the unique ID recorded with the transactions of a token type, if any
+	uniqueID UniqueIDExtractor
 }
 
-func newDB(p driver.TokenTransactionDB) *DB {
+// UniqueIDExtractor returns the unique ID of the asset carried by the passed token type, such as an NFT,
+// the empty string if there is none
+type UniqueIDExtractor = func(tokenType string) string
+
+// NewDB returns a new DB backed by the passed driver instance.
+// The Manager should be used to get the DB bound to a wallet, NewDB is meant to access a db offline.
+func NewDB(p driver.TokenTransactionDB) *DB {
 	return &DB{
-		db:         p,
-		eIDsLocks:  sync.Map{},
-		pendingTXs: make([]string, 0, 10000),
+		db:             p,
+		eIDsLocks:      sync.Map{},
+		pendingTXs:     make([]string, 0, 10000),
+		stopCompaction: make(chan struct{}),
 	}
 }
 
+// SetUniqueIDExtractor sets the function that extracts the unique IDs recorded with the transactions
+func (db *DB) SetUniqueIDExtractor(f UniqueIDExtractor) {
+	db.uniqueID = f
+}
+
+func (db *DB) extractUniqueID(tokenType string) string {
+	if db.uniqueID == nil {
+		return ""
+	}
+	return db.uniqueID(tokenType)
+}
+
 // Append appends send and receive movements, and transaction records corresponding to the passed token request
 func (db *DB) Append(req *token.Request) error {
 	logger.Debugf("Appending new record... [%d]", db.counter)
@@ -216,14 +265,21 @@ func (db *DB) Append(req *token.Request) error {
 		db.rollback(err)
 		return errors.WithMessagef(err, "begin update for txid '%s' failed", record.Anchor)
 	}
-	if err := db.appendSendMovements(record); err != nil {
+	timestamp := time.Now()
+	sent, err := db.appendSendMovements(record, timestamp)
+	if err != nil {
 		db.rollback(err)
 		return errors.WithMessagef(err, "append send movements for txid '%s' failed", record.Anchor)
 	}
-	if err := db.appendReceivedMovements(record); err != nil {
+	received, err := db.appendReceivedMovements(record, timestamp)
+	if err != nil {
 		db.rollback(err)
 		return errors.WithMessagef(err, "append received movements for txid '%s' failed", record.Anchor)
 	}
+	if err := db.appendBalanceCheckpoints(record.Anchor, append(sent, received...), false, timestamp); err != nil {
+		db.rollback(err)
+		return errors.WithMessagef(err, "append balance checkpoints for txid '%s' failed", record.Anchor)
+	}
 	if err := db.appendTransactions(record); err != nil {
 		db.rollback(err)
 		return errors.WithMessagef(err, "append transactions for txid '%s' failed", record.Anchor)
@@ -290,10 +346,40 @@ func (db *DB) SetStatus(txID string, status TxStatus) error {
 	defer db.storeLock.Unlock()
 	logger.Debug("lock acquired")
 
+	// deleted transactions no longer contribute to the balances, revert their movements
+	var reverted []*driver.MovementRecord
+	if status == Deleted {
+		var err error
+		reverted, _, err = db.db.QueryMovements(driver.QueryMovementsParams{
+			TxIDs:             []string{txID},
+			TxStatuses:        []driver.TxStatus{driver.Pending, driver.Confirmed},
+			MovementDirection: driver.All,
+			SearchDirection:   driver.FromBeginning,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed getting movements of [%s]", txID)
+		}
+	}
+
+	// the status and the reverting checkpoints are stored in the same update
+	if err := db.db.BeginUpdate(); err != nil {
+		db.rollback(err)
+		return errors.WithMessagef(err, "begin update for txid '%s' failed", txID)
+	}
 	if err := db.db.SetStatus(txID, driver.TxStatus(status)); err != nil {
 		db.rollback(err)
 		return errors.Wrapf(err, "failed setting status [%s][%s]", txID, status)
 	}
+	if len(reverted) != 0 {
+		if err := db.appendBalanceCheckpoints(txID, reverted, true, time.Now()); err != nil {
+			db.rollback(err)
+			return errors.WithMessagef(err, "append balance checkpoints for txid '%s' failed", txID)
+		}
+	}
+	if err := db.db.Commit(); err != nil {
+		db.rollback(err)
+		return errors.WithMessagef(err, "committing tx for txid '%s' failed", txID)
+	}
 	logger.Debugf("Set status [%s][%s]...[%d] done without errors", txID, status, db.counter)
 	return nil
 }
@@ -322,13 +408,14 @@ func (db *DB) Unlock(eIDs ...string) {
 	}
 }
 
-func (db *DB) appendSendMovements(record *token.AuditRecord) error {
+func (db *DB) appendSendMovements(record *token.AuditRecord, timestamp time.Time) ([]*driver.MovementRecord, error) {
 	inputs := record.Inputs
 	outputs := record.Outputs
 	// we need to consider both inputs and outputs enrollment IDs because the record can refer to a redeem
 	eIDs := joinIOEIDs(record)
 	tokenTypes := outputs.TokenTypes()
 
+	var movements []*driver.MovementRecord
 	for _, eID := range eIDs {
 		for _, tokenType := range tokenTypes {
 			sent := inputs.ByEnrollmentID(eID).ByType(tokenType).Sum()
@@ -338,32 +425,36 @@ func (db *DB) appendSendMovements(record *token.AuditRecord) error {
 				continue
 			}
 
-			if err := db.db.AddMovement(&driver.MovementRecord{
+			movement := &driver.MovementRecord{
 				TxID:         record.Anchor,
 				EnrollmentID: eID,
 				Amount:       diff.Neg(diff),
 				TokenType:    tokenType,
 				Status:       driver.Pending,
-			}); err != nil {
+				Timestamp:    timestamp,
+			}
+			if err := db.db.AddMovement(movement); err != nil {
 				if err1 := db.db.Discard(); err1 != nil {
 					logger.Errorf("got error %s; discarding caused %s", err.Error(), err1.Error())
 				}
-				return err
+				return nil, err
 			}
+			movements = append(movements, movement)
 		}
 	}
 	logger.Debugf("finished to append send movements for tx [%s]", record.Anchor)
 
-	return nil
+	return movements, nil
 }
 
-func (db *DB) appendReceivedMovements(record *token.AuditRecord) error {
+func (db *DB) appendReceivedMovements(record *token.AuditRecord, timestamp time.Time) ([]*driver.MovementRecord, error) {
 	inputs := record.Inputs
 	outputs := record.Outputs
 	// we need to consider both inputs and outputs enrollment IDs because the record can refer to a redeem
 	eIDs := joinIOEIDs(record)
 	tokenTypes := outputs.TokenTypes()
 
+	var movements []*driver.MovementRecord
 	for _, eID := range eIDs {
 		for _, tokenType := range tokenTypes {
 			received := outputs.ByEnrollmentID(eID).ByType(tokenType).Sum()
@@ -374,23 +465,26 @@ func (db *DB) appendReceivedMovements(record *token.AuditRecord) error {
 				continue
 			}
 
-			if err := db.db.AddMovement(&driver.MovementRecord{
+			movement := &driver.MovementRecord{
 				TxID:         record.Anchor,
 				EnrollmentID: eID,
 				Amount:       diff,
 				TokenType:    tokenType,
 				Status:       driver.Pending,
-			}); err != nil {
+				Timestamp:    timestamp,
+			}
+			if err := db.db.AddMovement(movement); err != nil {
 				if err1 := db.db.Discard(); err1 != nil {
 					logger.Errorf("got error %s; discarding caused %s", err.Error(), err1.Error())
 				}
-				return err
+				return nil, err
 			}
+			movements = append(movements, movement)
 		}
 	}
 	logger.Debugf("finished to append received movements for tx [%s]", record.Anchor)
 
-	return nil
+	return movements, nil
 }
 
 func (db *DB) appendTransactions(record *token.AuditRecord) error {
@@ -449,6 +543,7 @@ func (db *DB) appendTransactions(record *token.AuditRecord) error {
 					SenderEID:    inEID,
 					RecipientEID: outEID,
 					TokenType:    tokenType,
+					UniqueID:     db.extractUniqueID(tokenType),
 					Amount:       received,
 					Status:       driver.Pending,
 					ActionType:   tt,
@@ -481,6 +576,9 @@ type Manager struct {
 	driver string
 	mutex  sync.Mutex
 	dbs    map[string]*DB
+
+	// uniqueIDs returns the UniqueIDExtractor of the dbs of a TMS, if set
+	uniqueIDs func(tms *token.ManagementService) UniqueIDExtractor
 }
 
 // NewManager creates a new DB manager.
@@ -503,6 +601,14 @@ func NewManager(sp view2.ServiceProvider, driver string) *Manager {
 	}
 }
 
+// SetUniqueIDExtractors sets the function that returns the UniqueIDExtractor of the dbs of a TMS.
+// It must be called before any db is opened, the nfttx service sets it for instance.
+func (cm *Manager) SetUniqueIDExtractors(f func(tms *token.ManagementService) UniqueIDExtractor) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	cm.uniqueIDs = f
+}
+
 // DB returns a DB for the given wallet
 func (cm *Manager) DB(w Wallet) (*DB, error) {
 	cm.mutex.Lock()
@@ -516,12 +622,37 @@ func (cm *Manager) DB(w Wallet) (*DB, error) {
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed instantiating ttxdb driver [%s]", cm.driver)
 		}
-		c = newDB(driver)
+		c = NewDB(driver)
+		if cm.uniqueIDs != nil {
+			c.SetUniqueIDExtractor(cm.uniqueIDs(w.TMS()))
+		}
+		if err := c.initBalanceCheckpoints(); err != nil {
+			return nil, errors.WithMessagef(err, "failed initializing balance checkpoints for [%s]", id)
+		}
+		if policy := retentionPolicy(w); policy != nil {
+			if policy.ArchiveConfirmedAfter > 0 {
+				if len(policy.ArchivePath) == 0 {
+					return nil, errors.Errorf("no archive path set for the retention policy of [%s]", id)
+				}
+				c.archive = NewArchive(archivePath(policy.ArchivePath, id))
+			}
+			c.startCompaction(id, policy)
+		}
 		cm.dbs[id] = c
 	}
 	return c, nil
 }
 
+// Stop stops the periodic compaction of the databases
+func (cm *Manager) Stop() {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	for _, db := range cm.dbs {
+		db.StopCompaction()
+	}
+}
+
 var (
 	managerType = reflect.TypeOf((*Manager)(nil))
 )
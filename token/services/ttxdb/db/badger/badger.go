@@ -30,6 +30,8 @@ const (
 	DefaultNumGoStream = 16
 	// streamLogPrefixStatus is the prefix for the status log
 	streamLogPrefixStatus = "ttxdb.SetStatus"
+	// streamLogPrefixDelete is the prefix for the delete log
+	streamLogPrefixDelete = "ttxdb.DeleteTransactions"
 )
 
 type TransactionRecordSelector interface {
@@ -46,6 +48,11 @@ type TransactionRecord struct {
 	Record *driver.TransactionRecord
 }
 
+type BalanceCheckpoint struct {
+	Id     uint64
+	Record *driver.BalanceCheckpoint
+}
+
 type Persistence struct {
 	db          *badger.DB
 	numGoStream int
@@ -182,17 +189,141 @@ func (db *Persistence) AddTransaction(record *driver.TransactionRecord) error {
 	return nil
 }
 
+func (db *Persistence) AddBalanceCheckpoint(record *driver.BalanceCheckpoint) error {
+	logger.Debugf("Adding balance checkpoint [%s:%s:%s:%s]", record.TxID, record.TokenType, record.EnrollmentID, record.Amount)
+	next, key, err := db.balanceKey(record.EnrollmentID, record.TokenType)
+	if err != nil {
+		return errors.Wrapf(err, "could not get key for balance checkpoint %s", record.TxID)
+	}
+
+	value := &BalanceCheckpoint{
+		Id:     next,
+		Record: record,
+	}
+
+	bytes, err := MarshalBalanceCheckpoint(value)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal record for key %s", key)
+	}
+
+	err = db.txn.Set([]byte(key), bytes)
+	if err != nil {
+		return errors.Wrapf(err, "could not set value for key %s", key)
+	}
+
+	return nil
+}
+
+func (db *Persistence) QueryBalances(params driver.QueryBalancesParams) ([]*driver.BalanceCheckpoint, error) {
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = true
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	selector := &BalanceSelector{
+		params: params,
+	}
+	var res []*driver.BalanceCheckpoint
+	if len(params.EnrollmentIDs) != 0 && len(params.TokenTypes) != 0 {
+		// the pairs are known, read the latest checkpoint of each of them
+		seen := map[string]bool{}
+		for _, eID := range params.EnrollmentIDs {
+			for _, typ := range params.TokenTypes {
+				pairPrefix := dbKey("bl", dbKey(eID, dbKey(typ, "")))
+				if seen[pairPrefix] {
+					continue
+				}
+				seen[pairPrefix] = true
+				record, err := latestBalance(it, []byte(pairPrefix), selector)
+				if err != nil {
+					return nil, err
+				}
+				if record != nil {
+					res = append(res, record.Record)
+				}
+			}
+		}
+	} else {
+		// walk the pairs backwards, reading the latest checkpoint of each of them and skipping the older ones
+		prefix := []byte(dbKey("bl", ""))
+		it.Seek(append(append([]byte{}, prefix...), 0xff))
+		for it.ValidForPrefix(prefix) {
+			key := it.Item().KeyCopy(nil)
+			pairPrefix := key[:bytes.LastIndex(key, []byte(keys.NamespaceSeparator))+1]
+			record, err := latestBalance(it, pairPrefix, selector)
+			if err != nil {
+				return nil, err
+			}
+			if record != nil {
+				res = append(res, record.Record)
+			}
+			// the largest key not after the pair prefix is the latest checkpoint of the previous pair
+			it.Seek(pairPrefix)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].EnrollmentID != res[j].EnrollmentID {
+			return res[i].EnrollmentID < res[j].EnrollmentID
+		}
+		return res[i].TokenType < res[j].TokenType
+	})
+	return res, nil
+}
+
+// latestBalance returns the latest balance checkpoint, with the passed key prefix, selected by the passed selector,
+// nil if there is none. The passed iterator must be a reverse one.
+func latestBalance(it *badger.Iterator, pairPrefix []byte, selector *BalanceSelector) (*BalanceCheckpoint, error) {
+	for it.Seek(append(append([]byte{}, pairPrefix...), 0xff)); it.ValidForPrefix(pairPrefix); it.Next() {
+		item := it.Item()
+		var record *BalanceCheckpoint
+		err := item.Value(func(val []byte) error {
+			var err error
+			if record, err = UnmarshalBalanceCheckpoint(val); err != nil {
+				return errors.Wrapf(err, "could not unmarshal key %s", string(item.Key()))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get balance checkpoint for key %s", string(item.Key()))
+		}
+		if !selector.Select(record) {
+			if selector.params.AsOf != nil && record.Record.Timestamp.After(*selector.params.AsOf) {
+				// an older checkpoint of the same pair might be selected
+				continue
+			}
+			return nil, nil
+		}
+		return record, nil
+	}
+	return nil, nil
+}
+
 func (db *Persistence) QueryTransactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	cursor, err := driver.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
 	txn := db.db.NewTransaction(false)
 	it := txn.NewIterator(badger.DefaultIteratorOptions)
-	it.Seek([]byte("tx"))
+	if cursor != nil {
+		// resume right after the last record returned
+		it.Seek([]byte(dbKey("tx", kThLexicographicString(IndexLength, int(cursor.ID)+1))))
+	} else {
+		it.Seek([]byte("tx"))
+	}
 
 	selector := &TransactionSelector{
 		params: params,
 	}
-	return &TransactionIterator{it: it, selector: selector}, nil
+	return &TransactionIterator{it: it, selector: selector, pageSize: params.PageSize}, nil
 }
 
+// SetStatus sets the status of the records of the passed transaction.
+// If an update is in progress, the change is part of it, otherwise it is committed right away.
 func (db *Persistence) SetStatus(txID string, status driver.TxStatus) error {
 	// search for all matching keys
 	type Entry struct {
@@ -228,8 +359,15 @@ func (db *Persistence) SetStatus(txID string, status driver.TxStatus) error {
 		return nil
 	}
 
-	// update status for all matching keys
-	txn := db.db.NewTransaction(true)
+	// update status for all matching keys, as part of the update in progress, if any
+	db.txnLock.Lock()
+	inProgress := db.txn
+	db.txnLock.Unlock()
+	txn := inProgress
+	if txn == nil {
+		txn = db.db.NewTransaction(true)
+		defer txn.Discard()
+	}
 	for _, entry := range entries {
 		var bytes []byte
 		switch {
@@ -262,14 +400,77 @@ func (db *Persistence) SetStatus(txID string, status driver.TxStatus) error {
 			return errors.Wrapf(err, "could not set value for key %s", entry.key)
 		}
 	}
+	if inProgress != nil {
+		return nil
+	}
 	if err := txn.Commit(); err != nil {
-		txn.Discard()
 		return errors.Wrapf(err, "could not commit transaction to set status for tx %s", txID)
 	}
 	return nil
 }
 
-func (db *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, error) {
+// DeleteTransactions removes the movement and transaction records of the passed transactions.
+// Balance checkpoints are indexed by enrollment ID and token type, and are not affected.
+func (db *Persistence) DeleteTransactions(txIDs ...string) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+	deleted := make(map[string]bool, len(txIDs))
+	for _, txID := range txIDs {
+		deleted[txID] = true
+	}
+
+	// search for all matching keys, they have the form prefix\x00index\x00txID
+	var keys [][]byte
+	stream := db.db.NewStream()
+	stream.NumGo = db.numGoStream
+	stream.LogPrefix = streamLogPrefixDelete
+	stream.ChooseKey = func(item *badger.Item) bool {
+		key := item.Key()
+		if !bytes.HasPrefix(key, []byte("mv\x00")) && !bytes.HasPrefix(key, []byte("tx\x00")) {
+			return false
+		}
+		parts := bytes.SplitN(key, []byte{0}, 3)
+		return len(parts) == 3 && deleted[string(parts[2])]
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		list, err := badger.BufferToKVList(buf)
+		if err != nil {
+			return err
+		}
+		for _, kv := range list.Kv {
+			keys = append(keys, kv.Key)
+		}
+		return nil
+	}
+	if err := stream.Orchestrate(context.Background()); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		logger.Debugf("no entries found for [%d] txs, skipping", len(txIDs))
+		return nil
+	}
+
+	// a write batch splits the deletion in as many transactions as needed
+	wb := db.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, key := range keys {
+		if err := wb.Delete(key); err != nil {
+			return errors.Wrapf(err, "could not delete key %s", key)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return errors.Wrapf(err, "could not delete [%d] keys of [%d] txs", len(keys), len(txIDs))
+	}
+	return nil
+}
+
+func (db *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, string, error) {
+	cursor, err := driver.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
 	// TODO: Move to stream
 	txn := db.db.NewTransaction(false)
 	it := txn.NewIterator(badger.DefaultIteratorOptions)
@@ -278,6 +479,7 @@ func (db *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*dr
 
 	selector := &MovementSelector{
 		params: params,
+		cursor: cursor,
 	}
 	for it.Rewind(); it.Valid(); it.Next() {
 		item := it.Item()
@@ -300,7 +502,7 @@ func (db *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*dr
 			continue
 		}
 		if err != nil {
-			return nil, errors.Wrapf(err, "could not get movementDirection for key %s", string(item.Key()))
+			return nil, "", errors.Wrapf(err, "could not get movementDirection for key %s", string(item.Key()))
 		}
 
 		// filter
@@ -317,16 +519,26 @@ func (db *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*dr
 		sort.Sort(sort.Reverse(records))
 	}
 
-	if params.NumRecords > 0 && len(records) > params.NumRecords {
-		records = records[:params.NumRecords]
+	limit := params.NumRecords
+	if params.PageSize > 0 && (limit <= 0 || params.PageSize < limit) {
+		limit = params.PageSize
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
 	}
 
 	var res []*driver.MovementRecord
+	var lastID uint64
 	for _, record := range records {
 		res = append(res, record.Record)
+		lastID = record.Id
 	}
 
-	return res, nil
+	next, err := driver.NextCursor(params.PageSize, len(res), lastID)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "failed computing the next cursor")
+	}
+	return res, next, nil
 }
 
 func (db *Persistence) transactionKey(txID string) (uint64, string, error) {
@@ -345,6 +557,14 @@ func (db *Persistence) movementKey(txID string) (uint64, string, error) {
 	return next, dbKey("mv", dbKey(kThLexicographicString(IndexLength, int(next)), txID)), nil
 }
 
+func (db *Persistence) balanceKey(enrollmentID, tokenType string) (uint64, string, error) {
+	next, err := db.seq.Next()
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "failed getting next index")
+	}
+	return next, dbKey("bl", dbKey(enrollmentID, dbKey(tokenType, kThLexicographicString(IndexLength, int(next))))), nil
+}
+
 func dbKey(namespace, key string) string {
 	return namespace + keys.NamespaceSeparator + key
 }
@@ -358,13 +578,23 @@ func (p RecordSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 type TransactionIterator struct {
 	it       *badger.Iterator
 	selector TransactionRecordSelector
+	pageSize int
+	count    int
+	lastID   uint64
 }
 
 func (t *TransactionIterator) Close() {
 	t.it.Close()
 }
 
+func (t *TransactionIterator) Cursor() (string, error) {
+	return driver.NextCursor(t.pageSize, t.count, t.lastID)
+}
+
 func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
+	if t.pageSize > 0 && t.count >= t.pageSize {
+		return nil, nil
+	}
 	for {
 		if !t.it.Valid() {
 			return nil, nil
@@ -401,6 +631,8 @@ func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
 			continue
 		}
 		logger.Debugf("found transaction [%s,%s]", string(item.Key()), record.Record.TxID, record.Record.SenderEID, record.Record.RecipientEID)
+		t.count++
+		t.lastID = record.Id
 		return record.Record, nil
 	}
 }
@@ -426,10 +658,24 @@ func kThLexicographicString(n, k int) string {
 // MovementSelector is used to select a set of movement records
 type MovementSelector struct {
 	params driver.QueryMovementsParams
+	cursor *driver.Cursor
 }
 
 // Select returns true is the record matches the selection criteria
 func (m *MovementSelector) Select(record *MovementRecord) bool {
+	if m.cursor != nil {
+		// skip the records already returned in the previous pages
+		switch m.params.SearchDirection {
+		case driver.FromBeginning:
+			if record.Id <= m.cursor.ID {
+				return false
+			}
+		case driver.FromLast:
+			if record.Id >= m.cursor.ID {
+				return false
+			}
+		}
+	}
 	if len(m.params.EnrollmentIDs) != 0 {
 		found := false
 		for _, id := range m.params.EnrollmentIDs {
@@ -454,6 +700,18 @@ func (m *MovementSelector) Select(record *MovementRecord) bool {
 			return false
 		}
 	}
+	if len(m.params.TxIDs) != 0 {
+		found := false
+		for _, txID := range m.params.TxIDs {
+			if record.Record.TxID == txID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
 	if len(m.params.TxStatuses) != 0 {
 		found := false
 		for _, st := range m.params.TxStatuses {
@@ -524,6 +782,18 @@ func (t *TransactionSelector) Select(record *TransactionRecord) (bool, bool) {
 			return false, false
 		}
 	}
+	if len(t.params.UniqueIDs) != 0 {
+		found := false
+		for _, uniqueID := range t.params.UniqueIDs {
+			if uniqueID == record.Record.UniqueID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, false
+		}
+	}
 	// match the wallet
 	senderMatch := true
 	if len(t.params.SenderWallet) != 0 && record.Record.SenderEID != t.params.SenderWallet {
@@ -543,3 +813,40 @@ func (t *TransactionSelector) Select(record *TransactionRecord) (bool, bool) {
 	}
 	return true, false
 }
+
+// BalanceSelector is used to select a set of balance checkpoints
+type BalanceSelector struct {
+	params driver.QueryBalancesParams
+}
+
+// Select returns true is the record matches the selection criteria
+func (b *BalanceSelector) Select(record *BalanceCheckpoint) bool {
+	if len(b.params.EnrollmentIDs) != 0 {
+		found := false
+		for _, id := range b.params.EnrollmentIDs {
+			if record.Record.EnrollmentID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(b.params.TokenTypes) != 0 {
+		found := false
+		for _, typ := range b.params.TokenTypes {
+			if record.Record.TokenType == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if b.params.AsOf != nil && record.Record.Timestamp.After(*b.params.AsOf) {
+		return false
+	}
+	return true
+}
@@ -55,21 +55,26 @@ func TestMovements(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, db.Commit())
 
-	records, err := db.QueryMovements(driver.QueryMovementsParams{
+	records, _, err := db.QueryMovements(driver.QueryMovementsParams{
 		TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 2,
 	})
 	assert.NoError(t, err)
 	assert.Len(t, records, 2)
 
-	records, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 3})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 3})
 	assert.NoError(t, err)
 	assert.Len(t, records, 3)
 
+	// a status set within a discarded update is not visible
+	assert.NoError(t, db.BeginUpdate())
+	assert.NoError(t, db.SetStatus("1", driver.Deleted))
+	assert.NoError(t, db.Discard())
+
 	assert.NoError(t, db.BeginUpdate())
 	assert.NoError(t, db.SetStatus("2", driver.Confirmed))
 	assert.NoError(t, db.Commit())
 
-	records, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 3})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 3})
 	assert.NoError(t, err)
 	assert.Len(t, records, 2)
 }
@@ -114,6 +119,209 @@ func TestTransaction(t *testing.T) {
 	it.Close()
 }
 
+func TestPagination(t *testing.T) {
+	dbpath := filepath.Join(tempDir, "DB-TestPagination")
+	db, err := OpenDB(dbpath)
+	defer db.Close()
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+
+	assert.NoError(t, db.BeginUpdate())
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Status:       driver.Pending,
+		}))
+		assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now().UTC(),
+			Status:       driver.Pending,
+		}))
+	}
+	assert.NoError(t, db.Commit())
+
+	// movements, from the last
+	var txIDs []string
+	cursor := ""
+	for {
+		records, next, err := db.QueryMovements(driver.QueryMovementsParams{SearchDirection: driver.FromLast, MovementDirection: driver.All, PageSize: 2, Cursor: cursor})
+		assert.NoError(t, err)
+		for _, record := range records {
+			txIDs = append(txIDs, record.TxID)
+		}
+		if len(next) == 0 {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, []string{"4", "3", "2", "1", "0"}, txIDs)
+
+	// transactions
+	txIDs = nil
+	cursor = ""
+	for {
+		it, err := db.QueryTransactions(driver.QueryTransactionsParams{PageSize: 2, Cursor: cursor})
+		assert.NoError(t, err)
+		for {
+			tr, err := it.Next()
+			assert.NoError(t, err)
+			if tr == nil {
+				break
+			}
+			txIDs = append(txIDs, tr.TxID)
+		}
+		cursor, err = it.Cursor()
+		assert.NoError(t, err)
+		it.Close()
+		if len(cursor) == 0 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, txIDs)
+
+	_, _, err = db.QueryMovements(driver.QueryMovementsParams{PageSize: 2, Cursor: "not a cursor"})
+	assert.Error(t, err)
+}
+
+func TestBalances(t *testing.T) {
+	dbpath := filepath.Join(tempDir, "DB-TestBalances")
+	db, err := OpenDB(dbpath)
+	defer db.Close()
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+
+	t0 := time.Now().UTC()
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+	checkpoints := []*driver.BalanceCheckpoint{
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(10), TxID: "0", Timestamp: t0},
+		{EnrollmentID: "bob", TokenType: "EUR", Amount: big.NewInt(5), TxID: "0", Timestamp: t0},
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(30), TxID: "1", Timestamp: t1},
+		{EnrollmentID: "alice", TokenType: "USD", Amount: big.NewInt(7), TxID: "2", Timestamp: t1},
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(25), TxID: "3", Timestamp: t2},
+	}
+
+	assert.NoError(t, db.BeginUpdate())
+	for _, checkpoint := range checkpoints {
+		assert.NoError(t, db.AddBalanceCheckpoint(checkpoint))
+	}
+	assert.NoError(t, db.Commit())
+
+	balances, err := db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(25)))
+	assert.Equal(t, "3", balances[0].TxID)
+
+	asOf := t1.Add(time.Minute)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, AsOf: &asOf})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(30)))
+
+	before := t0.Add(-time.Minute)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{AsOf: &before})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 0)
+
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.Equal(t, "3", balances[0].TxID)
+	assert.Equal(t, "2", balances[1].TxID)
+	assert.Equal(t, "bob", balances[2].EnrollmentID)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+
+	// the latest checkpoint of each pair not after AsOf
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{AsOf: &asOf})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.Equal(t, "1", balances[0].TxID)
+	assert.Equal(t, "USD", balances[1].TokenType)
+	assert.Equal(t, "bob", balances[2].EnrollmentID)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"bob", "alice"}, TokenTypes: []string{"EUR", "USD"}, AsOf: &asOf})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.Equal(t, "1", balances[0].TxID)
+	assert.Equal(t, "bob", balances[2].EnrollmentID)
+}
+
+func TestDeleteTransactions(t *testing.T) {
+	dbpath := filepath.Join(tempDir, "DB-TestDeleteTransactions")
+	db, err := OpenDB(dbpath)
+	defer db.Close()
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+
+	assert.NoError(t, db.BeginUpdate())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Confirmed,
+		}))
+		assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Confirmed,
+		}))
+		assert.NoError(t, db.AddBalanceCheckpoint(&driver.BalanceCheckpoint{
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			TxID:         fmt.Sprintf("%d", i),
+			Timestamp:    time.Now(),
+		}))
+	}
+	assert.NoError(t, db.Commit())
+
+	assert.NoError(t, db.DeleteTransactions("1", "unknown"))
+
+	records, _, err := db.QueryMovements(driver.QueryMovementsParams{SearchDirection: driver.FromBeginning, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "0", records[0].TxID)
+	assert.Equal(t, "2", records[1].TxID)
+
+	it, err := db.QueryTransactions(driver.QueryTransactionsParams{})
+	assert.NoError(t, err)
+	var txIDs []string
+	for {
+		tr, err := it.Next()
+		assert.NoError(t, err)
+		if tr == nil {
+			break
+		}
+		txIDs = append(txIDs, tr.TxID)
+	}
+	it.Close()
+	assert.Equal(t, []string{"0", "2"}, txIDs)
+
+	// checkpoints are untouched
+	balances, err := db.QueryBalances(driver.QueryBalancesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(3)))
+
+	assert.NoError(t, db.DeleteTransactions())
+}
+
 func TestKThLexicographicString(t *testing.T) {
 	var list []string
 	for i := 0; i < 100; i++ {
@@ -37,3 +37,18 @@ func UnmarshalMovementRecord(data []byte) (*MovementRecord, error) {
 	}
 	return &movementRecord, nil
 }
+
+// MarshalBalanceCheckpoint marshals a BalanceCheckpoint into a byte array
+func MarshalBalanceCheckpoint(balanceCheckpoint *BalanceCheckpoint) ([]byte, error) {
+	return json.Marshal(balanceCheckpoint)
+}
+
+// UnmarshalBalanceCheckpoint unmarshals a BalanceCheckpoint from a byte array
+func UnmarshalBalanceCheckpoint(data []byte) (*BalanceCheckpoint, error) {
+	var balanceCheckpoint BalanceCheckpoint
+	err := json.Unmarshal(data, &balanceCheckpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &balanceCheckpoint, nil
+}
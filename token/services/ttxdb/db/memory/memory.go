@@ -7,30 +7,50 @@ SPDX-License-Identifier: Apache-2.0
 package memory
 
 import (
+	"sort"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
 
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
 )
 
 type Persistence struct {
 	movementRecords    []*driver.MovementRecord
 	transactionRecords []*driver.TransactionRecord
+	balanceCheckpoints []*driver.BalanceCheckpoint
 }
 
-func (p *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, error) {
+func (p *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, string, error) {
 	var res []*driver.MovementRecord
 
+	// records are identified by their position in movementRecords, plus one
+	last, err := driver.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
 	var cursor int
 	switch params.SearchDirection {
 	case driver.FromBeginning:
 		cursor = -1
+		if last != nil {
+			cursor = int(last.ID) - 1
+		}
 	case driver.FromLast:
 		cursor = len(p.movementRecords)
+		if last != nil {
+			cursor = int(last.ID) - 1
+		}
 	default:
 		panic("direction not valid")
 	}
+	limit := params.NumRecords
+	if params.PageSize > 0 && (limit <= 0 || params.PageSize < limit) {
+		limit = params.PageSize
+	}
 	counter := 0
+	var lastID uint64
 	for {
 		switch params.SearchDirection {
 		case driver.FromBeginning:
@@ -67,6 +87,18 @@ func (p *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*dri
 				continue
 			}
 		}
+		if len(params.TxIDs) != 0 {
+			found := false
+			for _, txID := range params.TxIDs {
+				if record.TxID == txID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
 		if len(params.TxStatuses) != 0 {
 			found := false
 			for _, st := range params.TxStatuses {
@@ -85,7 +117,7 @@ func (p *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*dri
 			}
 		}
 
-		if params.NumRecords != 0 && counter+1 > params.NumRecords {
+		if limit != 0 && counter+1 > limit {
 			break
 		}
 
@@ -97,10 +129,15 @@ func (p *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*dri
 		}
 
 		counter++
+		lastID = uint64(cursor + 1)
 		res = append(res, record)
 	}
 
-	return res, nil
+	next, err := driver.NextCursor(params.PageSize, len(res), lastID)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "failed computing the next cursor")
+	}
+	return res, next, nil
 }
 
 func (p *Persistence) AddMovement(record *driver.MovementRecord) error {
@@ -110,9 +147,24 @@ func (p *Persistence) AddMovement(record *driver.MovementRecord) error {
 }
 
 func (p *Persistence) QueryTransactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	last, err := driver.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	// records are identified by their position in transactionRecords, plus one
+	start := 0
+	if last != nil {
+		start = int(last.ID)
+	}
+
 	// search over the transaction for those whose timestamp is between from and to
 	var subset []*driver.TransactionRecord
-	for _, record := range p.transactionRecords {
+	var lastID uint64
+	for i := start; i < len(p.transactionRecords); i++ {
+		if params.PageSize > 0 && len(subset) == params.PageSize {
+			break
+		}
+		record := p.transactionRecords[i]
 		if params.From != nil && record.Timestamp.Before(*params.From) {
 			continue
 		}
@@ -146,10 +198,27 @@ func (p *Persistence) QueryTransactions(params driver.QueryTransactionsParams) (
 				continue
 			}
 		}
+		if len(params.UniqueIDs) != 0 {
+			found := false
+			for _, uniqueID := range params.UniqueIDs {
+				if uniqueID == record.UniqueID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
 
 		subset = append(subset, record)
+		lastID = uint64(i + 1)
+	}
+	next, err := driver.NextCursor(params.PageSize, len(subset), lastID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed computing the next cursor")
 	}
-	return &TransactionIterator{txs: subset}, nil
+	return &TransactionIterator{txs: subset, cursor: next}, nil
 }
 
 func (p *Persistence) AddTransaction(record *driver.TransactionRecord) error {
@@ -158,6 +227,60 @@ func (p *Persistence) AddTransaction(record *driver.TransactionRecord) error {
 	return nil
 }
 
+func (p *Persistence) AddBalanceCheckpoint(record *driver.BalanceCheckpoint) error {
+	p.balanceCheckpoints = append(p.balanceCheckpoints, record)
+
+	return nil
+}
+
+func (p *Persistence) QueryBalances(params driver.QueryBalancesParams) ([]*driver.BalanceCheckpoint, error) {
+	// scan backwards, the first checkpoint found for a pair is the latest one
+	var res []*driver.BalanceCheckpoint
+	found := map[[2]string]bool{}
+	for i := len(p.balanceCheckpoints) - 1; i >= 0; i-- {
+		record := p.balanceCheckpoints[i]
+		if found[[2]string{record.EnrollmentID, record.TokenType}] {
+			continue
+		}
+		if len(params.EnrollmentIDs) != 0 {
+			match := false
+			for _, id := range params.EnrollmentIDs {
+				if record.EnrollmentID == id {
+					match = true
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+		if len(params.TokenTypes) != 0 {
+			match := false
+			for _, typ := range params.TokenTypes {
+				if record.TokenType == typ {
+					match = true
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+		if params.AsOf != nil && record.Timestamp.After(*params.AsOf) {
+			continue
+		}
+		found[[2]string{record.EnrollmentID, record.TokenType}] = true
+		res = append(res, record)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].EnrollmentID != res[j].EnrollmentID {
+			return res[i].EnrollmentID < res[j].EnrollmentID
+		}
+		return res[i].TokenType < res[j].TokenType
+	})
+	return res, nil
+}
+
 func (p *Persistence) SetStatus(txID string, status driver.TxStatus) error {
 	// movements
 	for _, record := range p.movementRecords {
@@ -174,6 +297,30 @@ func (p *Persistence) SetStatus(txID string, status driver.TxStatus) error {
 	return nil
 }
 
+// DeleteTransactions removes the records of the passed transactions.
+// Records are identified by their position, therefore cursors obtained before the deletion must not be reused.
+func (p *Persistence) DeleteTransactions(txIDs ...string) error {
+	deleted := make(map[string]bool, len(txIDs))
+	for _, txID := range txIDs {
+		deleted[txID] = true
+	}
+	movementRecords := p.movementRecords[:0]
+	for _, record := range p.movementRecords {
+		if !deleted[record.TxID] {
+			movementRecords = append(movementRecords, record)
+		}
+	}
+	p.movementRecords = movementRecords
+	transactionRecords := p.transactionRecords[:0]
+	for _, record := range p.transactionRecords {
+		if !deleted[record.TxID] {
+			transactionRecords = append(transactionRecords, record)
+		}
+	}
+	p.transactionRecords = transactionRecords
+	return nil
+}
+
 func (p *Persistence) Close() error {
 	return nil
 }
@@ -192,19 +339,24 @@ func (p *Persistence) Discard() error {
 
 type TransactionIterator struct {
 	txs    []*driver.TransactionRecord
-	cursor int
+	index  int
+	cursor string
 }
 
 func (t *TransactionIterator) Close() {
 }
 
+func (t *TransactionIterator) Cursor() (string, error) {
+	return t.cursor, nil
+}
+
 func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
 	// return next transaction, if any
-	if t.cursor >= len(t.txs) {
+	if t.index >= len(t.txs) {
 		return nil, nil
 	}
-	record := t.txs[t.cursor]
-	t.cursor++
+	record := t.txs[t.index]
+	t.index++
 	return record, nil
 }
 
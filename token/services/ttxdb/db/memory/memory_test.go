@@ -7,8 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package memory
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
 
@@ -42,26 +44,198 @@ func Test(t *testing.T) {
 	})
 	assert.NoError(t, err)
 
-	records, err := db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
+	records, _, err := db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
 	assert.NoError(t, err)
 	assert.Len(t, records, 1)
-	records, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromLast, MovementDirection: driver.Sent})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromLast, MovementDirection: driver.Sent})
 	assert.NoError(t, err)
 	assert.Len(t, records, 1)
-	records, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromLast, MovementDirection: driver.Received})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromLast, MovementDirection: driver.Received})
 	assert.NoError(t, err)
 	assert.Len(t, records, 2)
-	records, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 1})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 1})
 	assert.NoError(t, err)
 	assert.Len(t, records, 1)
 
-	records, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"bob"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"bob"}, TokenTypes: []string{"EUR"}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
 	assert.NoError(t, err)
 	assert.Len(t, records, 0)
-	records, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"USD"}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"USD"}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
 	assert.NoError(t, err)
 	assert.Len(t, records, 0)
-	records, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, TxStatuses: []driver.TxStatus{driver.Confirmed}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, TxStatuses: []driver.TxStatus{driver.Confirmed}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Sent})
 	assert.NoError(t, err)
 	assert.Len(t, records, 0)
 }
+
+func TestPagination(t *testing.T) {
+	db := &Persistence{}
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			Amount:       big.NewInt(int64(i + 1)),
+			TokenType:    "EUR",
+			Status:       driver.Pending,
+		}))
+		assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "EUR",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Pending,
+		}))
+	}
+
+	for _, direction := range []driver.SearchDirection{driver.FromBeginning, driver.FromLast} {
+		var txIDs []string
+		cursor := ""
+		for {
+			records, next, err := db.QueryMovements(driver.QueryMovementsParams{SearchDirection: direction, MovementDirection: driver.All, PageSize: 2, Cursor: cursor})
+			assert.NoError(t, err)
+			for _, record := range records {
+				txIDs = append(txIDs, record.TxID)
+			}
+			if len(next) == 0 {
+				break
+			}
+			cursor = next
+		}
+		if direction == driver.FromBeginning {
+			assert.Equal(t, []string{"0", "1", "2", "3", "4"}, txIDs)
+		} else {
+			assert.Equal(t, []string{"4", "3", "2", "1", "0"}, txIDs)
+		}
+	}
+
+	var txIDs []string
+	cursor := ""
+	for {
+		it, err := db.QueryTransactions(driver.QueryTransactionsParams{PageSize: 3, Cursor: cursor})
+		assert.NoError(t, err)
+		for {
+			tr, err := it.Next()
+			assert.NoError(t, err)
+			if tr == nil {
+				break
+			}
+			txIDs = append(txIDs, tr.TxID)
+		}
+		cursor, err = it.Cursor()
+		assert.NoError(t, err)
+		it.Close()
+		if len(cursor) == 0 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, txIDs)
+}
+
+func TestBalances(t *testing.T) {
+	db := &Persistence{}
+
+	t0 := time.Now().UTC()
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+	checkpoints := []*driver.BalanceCheckpoint{
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(10), TxID: "0", Timestamp: t0},
+		{EnrollmentID: "bob", TokenType: "EUR", Amount: big.NewInt(5), TxID: "0", Timestamp: t0},
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(30), TxID: "1", Timestamp: t1},
+		{EnrollmentID: "alice", TokenType: "USD", Amount: big.NewInt(7), TxID: "2", Timestamp: t1},
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(25), TxID: "3", Timestamp: t2},
+	}
+
+	for _, checkpoint := range checkpoints {
+		assert.NoError(t, db.AddBalanceCheckpoint(checkpoint))
+	}
+
+	balances, err := db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(25)))
+	assert.Equal(t, "3", balances[0].TxID)
+
+	asOf := t1.Add(time.Minute)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, AsOf: &asOf})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(30)))
+
+	before := t0.Add(-time.Minute)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{AsOf: &before})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 0)
+
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	// sorted by enrollment ID and token type, as the other backends do
+	assert.Equal(t, [2]string{"alice", "EUR"}, [2]string{balances[0].EnrollmentID, balances[0].TokenType})
+	assert.Equal(t, [2]string{"alice", "USD"}, [2]string{balances[1].EnrollmentID, balances[1].TokenType})
+	assert.Equal(t, [2]string{"bob", "EUR"}, [2]string{balances[2].EnrollmentID, balances[2].TokenType})
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+}
+
+func TestDeleteTransactions(t *testing.T) {
+	db := &Persistence{}
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Confirmed,
+		}))
+		assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Confirmed,
+		}))
+		assert.NoError(t, db.AddBalanceCheckpoint(&driver.BalanceCheckpoint{
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			TxID:         fmt.Sprintf("%d", i),
+			Timestamp:    time.Now(),
+		}))
+	}
+	assert.NoError(t, db.DeleteTransactions("1", "unknown"))
+
+	records, _, err := db.QueryMovements(driver.QueryMovementsParams{SearchDirection: driver.FromBeginning, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "0", records[0].TxID)
+	assert.Equal(t, "2", records[1].TxID)
+
+	it, err := db.QueryTransactions(driver.QueryTransactionsParams{})
+	assert.NoError(t, err)
+	var txIDs []string
+	for {
+		tr, err := it.Next()
+		assert.NoError(t, err)
+		if tr == nil {
+			break
+		}
+		txIDs = append(txIDs, tr.TxID)
+	}
+	it.Close()
+	assert.Equal(t, []string{"0", "2"}, txIDs)
+
+	// checkpoints are untouched
+	balances, err := db.QueryBalances(driver.QueryBalancesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(3)))
+
+	assert.NoError(t, db.DeleteTransactions())
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sql
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.ttxdb.sql")
+
+const (
+	// OptsKey is the key for the opts in the config
+	OptsKey = "token.ttxdb.persistence.opts"
+	// maxTablePrefixLength bounds the length of the table prefix derived from the db name,
+	// postgres does not accept identifiers longer than 63 characters
+	maxTablePrefixLength = 40
+)
+
+// Opts are the options of the sql driver
+type Opts struct {
+	// Driver is the name of the database/sql driver to use, sqlite3 and postgres are supported
+	Driver string
+	// DataSource is the driver specific data source name
+	DataSource string
+	// MaxOpenConns is the maximum number of open connections to the database. If 0, there is no limit.
+	MaxOpenConns int
+	// SkipMigrations disables the schema migrations at opening time
+	SkipMigrations bool
+}
+
+type Driver struct {
+}
+
+func (d Driver) Open(sp view2.ServiceProvider, name string) (driver.TokenTransactionDB, error) {
+	opts := &Opts{}
+	err := view2.GetConfigService(sp).UnmarshalKey(OptsKey, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting opts for ttxdb")
+	}
+	if len(opts.Driver) == 0 {
+		return nil, errors.Errorf("no sql driver specified for ttxdb, set [%s.driver]", OptsKey)
+	}
+	if len(opts.DataSource) == 0 {
+		return nil, errors.Errorf("no data source specified for ttxdb, set [%s.dataSource]", OptsKey)
+	}
+	logger.Debugf("init ttxdb with sql driver [%s] for [%s]", opts.Driver, name)
+
+	db, err := sql.Open(opts.Driver, opts.DataSource)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed opening sql db with driver [%s]", opts.Driver)
+	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+
+	persistence, err := NewPersistence(db, TablePrefix(name), opts.Driver, !opts.SkipMigrations)
+	if err != nil {
+		if err1 := db.Close(); err1 != nil {
+			logger.Errorf("failed closing sql db [%s]", err1)
+		}
+		return nil, errors.Wrapf(err, "failed opening ttxdb [%s]", name)
+	}
+	return persistence, nil
+}
+
+// TablePrefix returns the prefix of the tables used to store the records of the db with the passed name.
+// The prefix contains only lowercase alphanumeric characters and underscores.
+// Names that would produce a prefix too long are replaced by their hash.
+func TablePrefix(name string) string {
+	var sb strings.Builder
+	sb.WriteString("ttx_")
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	if sb.Len() <= maxTablePrefixLength {
+		return sb.String()
+	}
+	h := sha256.Sum256([]byte(name))
+	return "ttx_" + hex.EncodeToString(h[:16])
+}
+
+func init() {
+	ttxdb.Register("sql", &Driver{})
+}
@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dialect captures the differences between the supported sql databases
+type dialect struct {
+	// serialPrimaryKey is the column definition of an auto-incremented primary key
+	serialPrimaryKey string
+}
+
+var (
+	sqliteDialect   = dialect{serialPrimaryKey: "INTEGER PRIMARY KEY AUTOINCREMENT"}
+	postgresDialect = dialect{serialPrimaryKey: "BIGSERIAL PRIMARY KEY"}
+)
+
+// dialectFor returns the dialect of the passed database/sql driver name.
+// Only the names of the drivers imported by this package are accepted.
+func dialectFor(driverName string) (dialect, error) {
+	switch driverName {
+	case "sqlite3":
+		return sqliteDialect, nil
+	case "postgres":
+		return postgresDialect, nil
+	default:
+		return dialect{}, errors.Errorf("sql driver [%s] not supported", driverName)
+	}
+}
+
+// tables holds the names of the tables of a ttxdb
+type tables struct {
+	Schema       string
+	Movements    string
+	Transactions string
+	Balances     string
+}
+
+func newTables(prefix string) tables {
+	return tables{
+		Schema:       prefix + "_schema",
+		Movements:    prefix + "_movements",
+		Transactions: prefix + "_transactions",
+		Balances:     prefix + "_balances",
+	}
+}
+
+// migration upgrades the schema to the next version
+type migration func(t tables, d dialect) []string
+
+// migrations is the ordered list of schema migrations.
+// The i-th migration upgrades the schema from version i to version i+1.
+// Migrations must never be modified once released, append a new one instead.
+var migrations = []migration{
+	// version 1: movements and transactions with the indexes needed by the queries
+	func(t tables, d dialect) []string {
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+				id %s,
+				tx_id TEXT NOT NULL,
+				enrollment_id TEXT NOT NULL,
+				token_type TEXT NOT NULL,
+				amount TEXT NOT NULL,
+				status TEXT NOT NULL
+			)`, t.Movements, d.serialPrimaryKey),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_tx_id ON %[1]s (tx_id)`, t.Movements),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_enrollment_id ON %[1]s (enrollment_id)`, t.Movements),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_token_type ON %[1]s (token_type)`, t.Movements),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_status ON %[1]s (status)`, t.Movements),
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+				id %s,
+				tx_id TEXT NOT NULL,
+				action_type INTEGER NOT NULL,
+				sender_eid TEXT NOT NULL,
+				recipient_eid TEXT NOT NULL,
+				token_type TEXT NOT NULL,
+				amount TEXT NOT NULL,
+				stored_at TIMESTAMP NOT NULL,
+				status TEXT NOT NULL
+			)`, t.Transactions, d.serialPrimaryKey),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_tx_id ON %[1]s (tx_id)`, t.Transactions),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_sender_eid ON %[1]s (sender_eid)`, t.Transactions),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_recipient_eid ON %[1]s (recipient_eid)`, t.Transactions),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_token_type ON %[1]s (token_type)`, t.Transactions),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_status ON %[1]s (status)`, t.Transactions),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_stored_at ON %[1]s (stored_at)`, t.Transactions),
+		}
+	},
+	// version 2: movement timestamps and balance checkpoints
+	func(t tables, d dialect) []string {
+		return []string{
+			// movements stored before this version have no timestamp
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN stored_at TIMESTAMP`, t.Movements),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_stored_at ON %[1]s (stored_at)`, t.Movements),
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+				id %s,
+				enrollment_id TEXT NOT NULL,
+				token_type TEXT NOT NULL,
+				amount TEXT NOT NULL,
+				tx_id TEXT NOT NULL,
+				stored_at TIMESTAMP NOT NULL
+			)`, t.Balances, d.serialPrimaryKey),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_enrollment_id_token_type ON %[1]s (enrollment_id, token_type)`, t.Balances),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_stored_at ON %[1]s (stored_at)`, t.Balances),
+		}
+	},
+	// version 3: unique IDs of the NFTs moved by the transactions
+	func(t tables, d dialect) []string {
+		return []string{
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN unique_id TEXT NOT NULL DEFAULT ''`, t.Transactions),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_unique_id ON %[1]s (unique_id)`, t.Transactions),
+		}
+	},
+}
+
+// SchemaVersion returns the schema version reached by applying all the migrations
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrate brings the schema of the passed tables to the latest version.
+// Each migration is applied in its own transaction together with the update of the schema version.
+func migrate(db *sql.DB, t tables, d dialect) error {
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version INTEGER NOT NULL)`, t.Schema)); err != nil {
+		return errors.Wrapf(err, "failed creating schema table [%s]", t.Schema)
+	}
+	version, err := schemaVersion(db, t)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return errors.Errorf("schema version [%d] of [%s] is newer than the supported one [%d]", version, t.Schema, len(migrations))
+	}
+	for ; version < len(migrations); version++ {
+		logger.Debugf("migrating [%s] from version [%d] to [%d]", t.Schema, version, version+1)
+		tx, err := db.Begin()
+		if err != nil {
+			return errors.Wrapf(err, "failed starting migration to version [%d]", version+1)
+		}
+		for _, stmt := range migrations[version](t, d) {
+			if _, err := tx.Exec(stmt); err != nil {
+				discard(tx)
+				return errors.Wrapf(err, "failed migrating to version [%d], statement [%s]", version+1, strings.TrimSpace(stmt))
+			}
+		}
+		if version == 0 {
+			_, err = tx.Exec(fmt.Sprintf(`INSERT INTO %s (version) VALUES ($1)`, t.Schema), version+1)
+		} else {
+			_, err = tx.Exec(fmt.Sprintf(`UPDATE %s SET version = $1`, t.Schema), version+1)
+		}
+		if err != nil {
+			discard(tx)
+			return errors.Wrapf(err, "failed updating schema version to [%d]", version+1)
+		}
+		if err := tx.Commit(); err != nil {
+			return errors.Wrapf(err, "failed committing migration to version [%d]", version+1)
+		}
+	}
+	return nil
+}
+
+// schemaVersion returns the current schema version, 0 if no migration has been applied yet
+func schemaVersion(db *sql.DB, t tables) (int, error) {
+	var version int
+	err := db.QueryRow(fmt.Sprintf(`SELECT version FROM %s`, t.Schema)).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed reading schema version from [%s]", t.Schema)
+	}
+	return version, nil
+}
+
+func discard(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil {
+		logger.Errorf("failed rolling back transaction [%s]", err)
+	}
+}
@@ -0,0 +1,469 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/pkg/errors"
+)
+
+// Persistence stores the records of a ttxdb in a sql database.
+// Each ttxdb gets its own set of tables identified by a prefix.
+type Persistence struct {
+	db      *sql.DB
+	tables  tables
+	txn     *sql.Tx
+	txnLock sync.Mutex
+}
+
+// NewPersistence returns a new Persistence for the passed database whose tables are prefixed by the passed prefix.
+// If migrate is true, the schema is brought to the latest version.
+func NewPersistence(db *sql.DB, tablePrefix string, driverName string, migrateSchema bool) (*Persistence, error) {
+	d, err := dialectFor(driverName)
+	if err != nil {
+		return nil, err
+	}
+	t := newTables(tablePrefix)
+	if migrateSchema {
+		if err := migrate(db, t, d); err != nil {
+			return nil, errors.WithMessagef(err, "failed migrating schema for [%s]", tablePrefix)
+		}
+	}
+	return &Persistence{db: db, tables: t}, nil
+}
+
+func (db *Persistence) Close() error {
+	db.txnLock.Lock()
+	defer db.txnLock.Unlock()
+
+	if db.txn != nil {
+		discard(db.txn)
+		db.txn = nil
+	}
+
+	if err := db.db.Close(); err != nil {
+		return errors.Wrap(err, "could not close DB")
+	}
+	return nil
+}
+
+func (db *Persistence) BeginUpdate() error {
+	db.txnLock.Lock()
+	defer db.txnLock.Unlock()
+
+	if db.txn != nil {
+		return errors.New("previous commit in progress")
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	db.txn = tx
+
+	return nil
+}
+
+func (db *Persistence) Commit() error {
+	db.txnLock.Lock()
+	defer db.txnLock.Unlock()
+
+	if db.txn == nil {
+		return errors.New("no commit in progress")
+	}
+
+	err := db.txn.Commit()
+	db.txn = nil
+	if err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+func (db *Persistence) Discard() error {
+	db.txnLock.Lock()
+	defer db.txnLock.Unlock()
+
+	if db.txn == nil {
+		return errors.New("no commit in progress")
+	}
+
+	err := db.txn.Rollback()
+	db.txn = nil
+	if err != nil {
+		return errors.Wrap(err, "could not discard transaction")
+	}
+
+	return nil
+}
+
+func (db *Persistence) AddMovement(record *driver.MovementRecord) error {
+	logger.Debugf("Adding movement record [%s:%s:%s:%s]", record.TxID, record.TokenType, record.EnrollmentID, record.Amount)
+	if db.txn == nil {
+		return errors.New("no commit in progress")
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (tx_id, enrollment_id, token_type, amount, status, stored_at) VALUES ($1, $2, $3, $4, $5, $6)`, db.tables.Movements)
+	if _, err := db.txn.Exec(query, record.TxID, record.EnrollmentID, record.TokenType, record.Amount.String(), string(record.Status), record.Timestamp.UTC()); err != nil {
+		return errors.Wrapf(err, "could not add movement for tx %s", record.TxID)
+	}
+
+	return nil
+}
+
+func (db *Persistence) AddTransaction(record *driver.TransactionRecord) error {
+	logger.Debugf("Adding transaction record [%s:%d:%s:%s:%s:%s]", record.TxID, record.ActionType, record.TokenType, record.SenderEID, record.RecipientEID, record.Amount)
+	if db.txn == nil {
+		return errors.New("no commit in progress")
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (tx_id, action_type, sender_eid, recipient_eid, token_type, unique_id, amount, stored_at, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, db.tables.Transactions)
+	if _, err := db.txn.Exec(query, record.TxID, int(record.ActionType), record.SenderEID, record.RecipientEID, record.TokenType, record.UniqueID, record.Amount.String(), record.Timestamp.UTC(), string(record.Status)); err != nil {
+		return errors.Wrapf(err, "could not add transaction for tx %s", record.TxID)
+	}
+
+	return nil
+}
+
+func (db *Persistence) AddBalanceCheckpoint(record *driver.BalanceCheckpoint) error {
+	logger.Debugf("Adding balance checkpoint [%s:%s:%s:%s]", record.TxID, record.TokenType, record.EnrollmentID, record.Amount)
+	if db.txn == nil {
+		return errors.New("no commit in progress")
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (enrollment_id, token_type, amount, tx_id, stored_at) VALUES ($1, $2, $3, $4, $5)`, db.tables.Balances)
+	if _, err := db.txn.Exec(query, record.EnrollmentID, record.TokenType, record.Amount.String(), record.TxID, record.Timestamp.UTC()); err != nil {
+		return errors.Wrapf(err, "could not add balance checkpoint for [%s:%s]", record.EnrollmentID, record.TokenType)
+	}
+
+	return nil
+}
+
+func (db *Persistence) QueryBalances(params driver.QueryBalancesParams) ([]*driver.BalanceCheckpoint, error) {
+	where := &conditions{}
+	where.in("enrollment_id", params.EnrollmentIDs)
+	where.in("token_type", params.TokenTypes)
+	if params.AsOf != nil {
+		where.add("stored_at <= $?", params.AsOf.UTC())
+	}
+
+	// the latest checkpoint of each pair is the one with the highest id
+	query := fmt.Sprintf(`SELECT enrollment_id, token_type, amount, tx_id, stored_at FROM %[1]s WHERE id IN (SELECT MAX(id) FROM %[1]s%[2]s GROUP BY enrollment_id, token_type) ORDER BY enrollment_id, token_type`, db.tables.Balances, where.clause())
+	rows, err := db.db.Query(query, where.args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed querying balances")
+	}
+	defer rows.Close()
+
+	var res []*driver.BalanceCheckpoint
+	for rows.Next() {
+		var amount string
+		var timestamp time.Time
+		record := &driver.BalanceCheckpoint{}
+		if err := rows.Scan(&record.EnrollmentID, &record.TokenType, &amount, &record.TxID, &timestamp); err != nil {
+			return nil, errors.Wrapf(err, "failed scanning balance checkpoint")
+		}
+		if record.Amount, err = parseAmount(amount); err != nil {
+			return nil, errors.WithMessagef(err, "invalid balance checkpoint for [%s:%s]", record.EnrollmentID, record.TokenType)
+		}
+		record.Timestamp = timestamp.UTC()
+		res = append(res, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed iterating over balances")
+	}
+	return res, nil
+}
+
+// SetStatus sets the status of the records of the passed transaction.
+// If an update is in progress, the change is part of it, otherwise it is committed right away.
+func (db *Persistence) SetStatus(txID string, status driver.TxStatus) error {
+	db.txnLock.Lock()
+	inProgress := db.txn
+	db.txnLock.Unlock()
+
+	tx := inProgress
+	if tx == nil {
+		var err error
+		tx, err = db.db.Begin()
+		if err != nil {
+			return errors.Wrapf(err, "could not begin transaction to set status for tx %s", txID)
+		}
+	}
+	for _, table := range []string{db.tables.Movements, db.tables.Transactions} {
+		query := fmt.Sprintf(`UPDATE %s SET status = $1 WHERE tx_id = $2`, table)
+		if _, err := tx.Exec(query, string(status), txID); err != nil {
+			if inProgress == nil {
+				discard(tx)
+			}
+			return errors.Wrapf(err, "could not set status for tx %s in [%s]", txID, table)
+		}
+	}
+	if inProgress != nil {
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "could not commit transaction to set status for tx %s", txID)
+	}
+	return nil
+}
+
+func (db *Persistence) DeleteTransactions(txIDs ...string) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+	tx, err := db.db.Begin()
+	if err != nil {
+		return errors.Wrapf(err, "could not begin transaction to delete [%d] txs", len(txIDs))
+	}
+	for _, table := range []string{db.tables.Movements, db.tables.Transactions} {
+		c := &conditions{}
+		c.in("tx_id", txIDs)
+		query := fmt.Sprintf(`DELETE FROM %s%s`, table, c.clause())
+		if _, err := tx.Exec(query, c.args...); err != nil {
+			discard(tx)
+			return errors.Wrapf(err, "could not delete [%d] txs from [%s]", len(txIDs), table)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "could not commit transaction to delete [%d] txs", len(txIDs))
+	}
+	return nil
+}
+
+func (db *Persistence) QueryMovements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, string, error) {
+	cursor, err := driver.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	where := &conditions{}
+	where.in("enrollment_id", params.EnrollmentIDs)
+	where.in("token_type", params.TokenTypes)
+	where.in("tx_id", params.TxIDs)
+	if len(params.TxStatuses) != 0 {
+		where.in("status", statusesToStrings(params.TxStatuses))
+	} else {
+		// exclude the deleted
+		where.add("status <> $?", string(driver.Deleted))
+	}
+	// amounts are stored as decimal strings, their sign is the sign of the movement
+	switch params.MovementDirection {
+	case driver.Sent:
+		where.add("(amount LIKE '-%' OR amount = '0')")
+	case driver.Received:
+		where.add("amount NOT LIKE '-%'")
+	}
+
+	order := "ASC"
+	if params.SearchDirection == driver.FromLast {
+		order = "DESC"
+	}
+	if cursor != nil {
+		// skip the records already returned in the previous pages
+		if params.SearchDirection == driver.FromLast {
+			where.add("id < $?", cursor.ID)
+		} else {
+			where.add("id > $?", cursor.ID)
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT id, tx_id, enrollment_id, token_type, amount, status, stored_at FROM %s%s ORDER BY id %s`, db.tables.Movements, where.clause(), order)
+	limit := params.NumRecords
+	if params.PageSize > 0 && (limit <= 0 || params.PageSize < limit) {
+		limit = params.PageSize
+	}
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := db.db.Query(query, where.args...)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed querying movements")
+	}
+	defer rows.Close()
+
+	var res []*driver.MovementRecord
+	var lastID uint64
+	for rows.Next() {
+		var amount, status string
+		var timestamp sql.NullTime
+		record := &driver.MovementRecord{}
+		if err := rows.Scan(&lastID, &record.TxID, &record.EnrollmentID, &record.TokenType, &amount, &status, &timestamp); err != nil {
+			return nil, "", errors.Wrapf(err, "failed scanning movement")
+		}
+		if record.Amount, err = parseAmount(amount); err != nil {
+			return nil, "", errors.WithMessagef(err, "invalid movement for tx %s", record.TxID)
+		}
+		record.Status = driver.TxStatus(status)
+		if timestamp.Valid {
+			record.Timestamp = timestamp.Time.UTC()
+		}
+		res = append(res, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", errors.Wrapf(err, "failed iterating over movements")
+	}
+	next, err := driver.NextCursor(params.PageSize, len(res), lastID)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "failed computing the next cursor")
+	}
+	return res, next, nil
+}
+
+func (db *Persistence) QueryTransactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	cursor, err := driver.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	where := &conditions{}
+	if cursor != nil {
+		// resume right after the last record returned
+		where.add("id > $?", cursor.ID)
+	}
+	if params.From != nil {
+		where.add("stored_at >= $?", params.From.UTC())
+	}
+	if params.To != nil {
+		where.add("stored_at <= $?", params.To.UTC())
+	}
+	actionTypes := make([]string, len(params.ActionTypes))
+	for i, actionType := range params.ActionTypes {
+		actionTypes[i] = fmt.Sprintf("%d", actionType)
+	}
+	where.inInts("action_type", actionTypes)
+	where.in("status", statusesToStrings(params.Statuses))
+	where.in("unique_id", params.UniqueIDs)
+	// a record matches if either the sender or the recipient matches, an empty wallet matches any
+	if len(params.SenderWallet) != 0 && len(params.RecipientWallet) != 0 {
+		where.add("(sender_eid = $? OR recipient_eid = $?)", params.SenderWallet, params.RecipientWallet)
+	}
+
+	query := fmt.Sprintf(`SELECT id, tx_id, action_type, sender_eid, recipient_eid, token_type, unique_id, amount, stored_at, status FROM %s%s ORDER BY id ASC`, db.tables.Transactions, where.clause())
+	if params.PageSize > 0 {
+		query += fmt.Sprintf(" LIMIT %d", params.PageSize)
+	}
+	rows, err := db.db.Query(query, where.args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed querying transactions")
+	}
+	return &TransactionIterator{rows: rows, pageSize: params.PageSize}, nil
+}
+
+// TransactionIterator iterates over the rows of a transactions query
+type TransactionIterator struct {
+	rows     *sql.Rows
+	pageSize int
+	count    int
+	lastID   uint64
+}
+
+func (t *TransactionIterator) Cursor() (string, error) {
+	return driver.NextCursor(t.pageSize, t.count, t.lastID)
+}
+
+func (t *TransactionIterator) Close() {
+	if err := t.rows.Close(); err != nil {
+		logger.Errorf("failed closing rows [%s]", err)
+	}
+}
+
+func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
+	if !t.rows.Next() {
+		if err := t.rows.Err(); err != nil {
+			return nil, errors.Wrapf(err, "failed iterating over transactions")
+		}
+		return nil, nil
+	}
+
+	var actionType int
+	var amount, status string
+	var timestamp time.Time
+	record := &driver.TransactionRecord{}
+	if err := t.rows.Scan(&t.lastID, &record.TxID, &actionType, &record.SenderEID, &record.RecipientEID, &record.TokenType, &record.UniqueID, &amount, &timestamp, &status); err != nil {
+		return nil, errors.Wrapf(err, "failed scanning transaction")
+	}
+	var err error
+	if record.Amount, err = parseAmount(amount); err != nil {
+		return nil, errors.WithMessagef(err, "invalid transaction for tx %s", record.TxID)
+	}
+	record.ActionType = driver.ActionType(actionType)
+	record.Timestamp = timestamp.UTC()
+	record.Status = driver.TxStatus(status)
+	t.count++
+	return record, nil
+}
+
+// conditions collects the conditions of a where clause together with their arguments.
+// Conditions use the $? placeholder that is replaced by the positional placeholder of the argument.
+type conditions struct {
+	terms []string
+	args  []interface{}
+}
+
+func (c *conditions) add(term string, args ...interface{}) {
+	for _, arg := range args {
+		c.args = append(c.args, arg)
+		term = strings.Replace(term, "$?", fmt.Sprintf("$%d", len(c.args)), 1)
+	}
+	c.terms = append(c.terms, term)
+}
+
+// in adds a condition requiring the column to be one of the passed values, if any
+func (c *conditions) in(column string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "$?"
+		args[i] = value
+	}
+	c.add(column+" IN ("+strings.Join(placeholders, ", ")+")", args...)
+}
+
+// inInts adds a condition requiring the integer column to be one of the passed values, if any.
+// The values are inlined because they are generated by the caller.
+func (c *conditions) inInts(column string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	c.add(column + " IN (" + strings.Join(values, ", ") + ")")
+}
+
+func (c *conditions) clause() string {
+	if len(c.terms) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.terms, " AND ")
+}
+
+func statusesToStrings(statuses []driver.TxStatus) []string {
+	res := make([]string, len(statuses))
+	for i, status := range statuses {
+		res[i] = string(status)
+	}
+	return res
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, errors.Errorf("invalid amount [%s]", s)
+	}
+	return amount, nil
+}
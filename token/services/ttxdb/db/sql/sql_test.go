@@ -0,0 +1,378 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openDB(t *testing.T, name string) *Persistence {
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(tempDir, name+".sqlite"))
+	assert.NoError(t, err)
+	db, err := NewPersistence(sqlDB, TablePrefix(name), "sqlite3", true)
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+	return db
+}
+
+func TestMovements(t *testing.T) {
+	db := openDB(t, "TestMovements")
+	defer db.Close()
+
+	assert.NoError(t, db.BeginUpdate())
+	for i, amount := range []int64{10, 20, 30, -5} {
+		err := db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(amount),
+			Status:       driver.Pending,
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, db.Commit())
+
+	records, _, err := db.QueryMovements(driver.QueryMovementsParams{
+		TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 2,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "2", records[0].TxID)
+	assert.Equal(t, "1", records[1].TxID)
+
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromBeginning, MovementDirection: driver.Received, NumRecords: 3})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "0", records[0].TxID)
+	assert.Equal(t, 0, records[0].Amount.Cmp(big.NewInt(10)))
+
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"magic"}, SearchDirection: driver.FromLast, MovementDirection: driver.Sent})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, 0, records[0].Amount.Cmp(big.NewInt(-5)))
+
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{EnrollmentIDs: []string{"bob"}, SearchDirection: driver.FromLast, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, records, 0)
+
+	assert.NoError(t, db.SetStatus("2", driver.Confirmed))
+	assert.NoError(t, db.SetStatus("3", driver.Deleted))
+
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Pending}, SearchDirection: driver.FromLast, MovementDirection: driver.Received, NumRecords: 3})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	// deleted records are excluded unless explicitly requested
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{SearchDirection: driver.FromLast, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	records, _, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Deleted}, SearchDirection: driver.FromLast, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestTransaction(t *testing.T) {
+	db := openDB(t, "TestTransaction")
+	defer db.Close()
+
+	var txs []*driver.TransactionRecord
+
+	t0 := time.Now().UTC()
+	assert.NoError(t, db.BeginUpdate())
+	for i := 0; i < 20; i++ {
+		now := time.Now().UTC()
+		tr1 := &driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			SenderEID:    "",
+			RecipientEID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(10),
+			Timestamp:    now,
+			Status:       driver.Pending,
+		}
+		assert.NoError(t, db.AddTransaction(tr1))
+		txs = append(txs, tr1)
+	}
+	assert.NoError(t, db.Commit())
+	t1 := time.Now().UTC()
+
+	it, err := db.QueryTransactions(driver.QueryTransactionsParams{From: &t0, To: &t1})
+	assert.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		tr, err := it.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, txs[i].TxID, tr.TxID)
+		assert.Equal(t, txs[i].Amount, tr.Amount)
+		assert.True(t, txs[i].Timestamp.Equal(tr.Timestamp))
+	}
+	tr, err := it.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, tr)
+	it.Close()
+
+	// discarded updates are not visible
+	assert.NoError(t, db.BeginUpdate())
+	assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+		TxID:       "discarded",
+		ActionType: driver.Transfer,
+		SenderEID:  "alice",
+		TokenType:  "magic",
+		Amount:     big.NewInt(10),
+		Timestamp:  time.Now(),
+		Status:     driver.Pending,
+	}))
+	assert.NoError(t, db.Discard())
+
+	// a status set within a discarded update is not visible
+	assert.NoError(t, db.BeginUpdate())
+	assert.NoError(t, db.SetStatus("4", driver.Deleted))
+	assert.NoError(t, db.Discard())
+
+	assert.NoError(t, db.SetStatus("3", driver.Confirmed))
+	it, err = db.QueryTransactions(driver.QueryTransactionsParams{Statuses: []driver.TxStatus{driver.Confirmed}, ActionTypes: []driver.ActionType{driver.Issue}})
+	assert.NoError(t, err)
+	tr, err = it.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "3", tr.TxID)
+	assert.Equal(t, driver.Confirmed, tr.Status)
+	tr, err = it.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, tr)
+	it.Close()
+}
+
+func TestPagination(t *testing.T) {
+	db := openDB(t, "TestPagination")
+	defer db.Close()
+
+	assert.NoError(t, db.BeginUpdate())
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Status:       driver.Pending,
+		}))
+		assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Pending,
+		}))
+	}
+	assert.NoError(t, db.Commit())
+
+	var txIDs []string
+	cursor := ""
+	for {
+		records, next, err := db.QueryMovements(driver.QueryMovementsParams{SearchDirection: driver.FromLast, MovementDirection: driver.All, PageSize: 2, Cursor: cursor})
+		assert.NoError(t, err)
+		for _, record := range records {
+			txIDs = append(txIDs, record.TxID)
+		}
+		if len(next) == 0 {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, []string{"4", "3", "2", "1", "0"}, txIDs)
+
+	txIDs = nil
+	cursor = ""
+	for {
+		it, err := db.QueryTransactions(driver.QueryTransactionsParams{PageSize: 2, Cursor: cursor})
+		assert.NoError(t, err)
+		for {
+			tr, err := it.Next()
+			assert.NoError(t, err)
+			if tr == nil {
+				break
+			}
+			txIDs = append(txIDs, tr.TxID)
+		}
+		cursor, err = it.Cursor()
+		assert.NoError(t, err)
+		it.Close()
+		if len(cursor) == 0 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, txIDs)
+}
+
+func TestBalances(t *testing.T) {
+	db := openDB(t, "TestBalances")
+	defer db.Close()
+
+	t0 := time.Now().UTC()
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+	checkpoints := []*driver.BalanceCheckpoint{
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(10), TxID: "0", Timestamp: t0},
+		{EnrollmentID: "bob", TokenType: "EUR", Amount: big.NewInt(5), TxID: "0", Timestamp: t0},
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(30), TxID: "1", Timestamp: t1},
+		{EnrollmentID: "alice", TokenType: "USD", Amount: big.NewInt(7), TxID: "2", Timestamp: t1},
+		{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(25), TxID: "3", Timestamp: t2},
+	}
+
+	assert.NoError(t, db.BeginUpdate())
+	for _, checkpoint := range checkpoints {
+		assert.NoError(t, db.AddBalanceCheckpoint(checkpoint))
+	}
+	assert.NoError(t, db.Commit())
+
+	balances, err := db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(25)))
+	assert.Equal(t, "3", balances[0].TxID)
+
+	asOf := t1.Add(time.Minute)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}, TokenTypes: []string{"EUR"}, AsOf: &asOf})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(30)))
+
+	before := t0.Add(-time.Minute)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{AsOf: &before})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 0)
+
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	balances, err = db.QueryBalances(driver.QueryBalancesParams{EnrollmentIDs: []string{"alice"}})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+}
+
+func TestDeleteTransactions(t *testing.T) {
+	db := openDB(t, "TestDeleteTransactions")
+	defer db.Close()
+
+	assert.NoError(t, db.BeginUpdate())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, db.AddMovement(&driver.MovementRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Confirmed,
+		}))
+		assert.NoError(t, db.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    time.Now(),
+			Status:       driver.Confirmed,
+		}))
+		assert.NoError(t, db.AddBalanceCheckpoint(&driver.BalanceCheckpoint{
+			EnrollmentID: "alice",
+			TokenType:    "magic",
+			Amount:       big.NewInt(int64(i + 1)),
+			TxID:         fmt.Sprintf("%d", i),
+			Timestamp:    time.Now(),
+		}))
+	}
+	assert.NoError(t, db.Commit())
+
+	assert.NoError(t, db.DeleteTransactions("1", "unknown"))
+
+	records, _, err := db.QueryMovements(driver.QueryMovementsParams{SearchDirection: driver.FromBeginning, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "0", records[0].TxID)
+	assert.Equal(t, "2", records[1].TxID)
+
+	it, err := db.QueryTransactions(driver.QueryTransactionsParams{})
+	assert.NoError(t, err)
+	var txIDs []string
+	for {
+		tr, err := it.Next()
+		assert.NoError(t, err)
+		if tr == nil {
+			break
+		}
+		txIDs = append(txIDs, tr.TxID)
+	}
+	it.Close()
+	assert.Equal(t, []string{"0", "2"}, txIDs)
+
+	// checkpoints are untouched
+	balances, err := db.QueryBalances(driver.QueryBalancesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, 0, balances[0].Amount.Cmp(big.NewInt(3)))
+
+	assert.NoError(t, db.DeleteTransactions())
+}
+
+func TestMigrations(t *testing.T) {
+	db := openDB(t, "TestMigrations")
+	version, err := schemaVersion(db.db, db.tables)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion(), version)
+	assert.NoError(t, db.Close())
+
+	// migrating again is a no-op
+	db = openDB(t, "TestMigrations")
+	version, err = schemaVersion(db.db, db.tables)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion(), version)
+	assert.NoError(t, db.Close())
+}
+
+func TestTablePrefix(t *testing.T) {
+	assert.Equal(t, "ttx_n1_c1_ns1alice", TablePrefix("n1,c1,ns1alice"))
+	long := TablePrefix("network=a-very-long-network-name,channel=a-very-long-channel-name,namespace=zkat,wallet")
+	assert.Len(t, long, 36)
+}
+
+func TestDialect(t *testing.T) {
+	for _, name := range []string{"sqlite3", "postgres"} {
+		_, err := dialectFor(name)
+		assert.NoError(t, err, name)
+	}
+	// no driver is registered under these names
+	for _, name := range []string{"sqlite", "pgx", "mysql"} {
+		_, err := dialectFor(name)
+		assert.Error(t, err, name)
+	}
+}
+
+var tempDir string
+
+func TestMain(m *testing.M) {
+	var err error
+	tempDir, err = ioutil.TempDir("", "sql-fsc-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create temporary directory: %v", err)
+		os.Exit(-1)
+	}
+	defer os.RemoveAll(tempDir)
+
+	m.Run()
+}
@@ -7,12 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package driver
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
 )
 
 // ActionType is the type of transaction
@@ -77,6 +80,24 @@ type MovementRecord struct {
 	Amount *big.Int
 	// Status is the status of the transaction
 	Status TxStatus
+	// Timestamp is the time the movement was submitted to the db
+	Timestamp time.Time
+}
+
+// BalanceCheckpoint is a materialized balance of an enrollment ID for a token type.
+// A new checkpoint is created for each movement that changes the balance, and
+// when a transaction gets deleted to revert its movements.
+type BalanceCheckpoint struct {
+	// EnrollmentID is the enrollment ID of the account
+	EnrollmentID string
+	// TokenType is the type of token
+	TokenType string
+	// Amount is the sum of the amounts of the pending and confirmed movements known at Timestamp
+	Amount *big.Int
+	// TxID is the transaction ID that caused the balance change, if any
+	TxID string
+	// Timestamp is the time the checkpoint was submitted to the db
+	Timestamp time.Time
 }
 
 // TransactionRecord is a more finer-grained version of a movement record.
@@ -95,6 +116,8 @@ type TransactionRecord struct {
 	RecipientEID string
 	// TokenType is the type of token
 	TokenType string
+	// UniqueID is the unique ID of the NFT moved by this action, empty for fungible tokens
+	UniqueID string
 	// Amount is positive if tokens are received. Negative otherwise
 	Amount *big.Int
 	// Timestamp is the time the transaction was submitted to the db
@@ -116,6 +139,8 @@ func (t *TransactionRecord) String() string {
 	s.WriteString(" ")
 	s.WriteString(t.TokenType)
 	s.WriteString(" ")
+	s.WriteString(t.UniqueID)
+	s.WriteString(" ")
 	s.WriteString(t.Amount.String())
 	s.WriteString(" ")
 	s.WriteString(t.Timestamp.String())
@@ -129,6 +154,52 @@ func (t *TransactionRecord) String() string {
 type TransactionIterator interface {
 	Close()
 	Next() (*TransactionRecord, error)
+	// Cursor returns the continuation token to pass to get the next page, once Next has returned nil.
+	// It is empty if the query was not paginated or if the last page has been reached.
+	Cursor() (string, error)
+}
+
+// Cursor is the position of the last record returned by a paginated query.
+// Drivers encode it into an opaque continuation token with EncodeCursor.
+type Cursor struct {
+	// ID is the driver specific identifier of the last record returned, records are ordered by it
+	ID uint64 `json:"id"`
+}
+
+// EncodeCursor returns the continuation token corresponding to the passed cursor
+func EncodeCursor(c *Cursor) (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed encoding cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor returns the cursor encoded in the passed continuation token.
+// It returns nil, nil if the token is empty.
+func DecodeCursor(token string) (*Cursor, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid continuation token [%s]", token)
+	}
+	c := &Cursor{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, errors.Wrapf(err, "invalid continuation token [%s]", token)
+	}
+	return c, nil
+}
+
+// NextCursor returns the continuation token of a page of the passed size whose last record has the passed ID.
+// The token is empty if the query was not paginated or if the page is not full, meaning that there are no more records.
+// Notice that when the page is full, the next page might be empty.
+func NextCursor(pageSize, count int, lastID uint64) (string, error) {
+	if pageSize <= 0 || count < pageSize {
+		return "", nil
+	}
+	return EncodeCursor(&Cursor{ID: lastID})
 }
 
 // QueryMovementsParams defines the parameters for querying movements.
@@ -147,9 +218,20 @@ type QueryMovementsParams struct {
 	SearchDirection SearchDirection
 	// MovementDirection is the direction of the movement
 	MovementDirection MovementDirection
+	// TxIDs is the transaction IDs of the movements to query
+	// If empty, any transaction ID is accepted
+	TxIDs []string
 	// NumRecords is the number of records to return
 	// If 0, all records are returned
 	NumRecords int
+	// PageSize is the maximum number of records to return per query, if NumRecords is also set,
+	// the smaller of the two applies.
+	// If 0, the query is not paginated
+	PageSize int
+	// Cursor is the continuation token returned by the query of the previous page, if any.
+	// The other parameters must not change while paging.
+	// If empty, the query starts from the first record in the search direction
+	Cursor string
 }
 
 // QueryTransactionsParams defines the parameters for querying transactions.
@@ -175,6 +257,31 @@ type QueryTransactionsParams struct {
 	// Statuses is the list of transaction status to accept
 	// If empty, any status is accepted
 	Statuses []TxStatus
+	// UniqueIDs is the list of the unique IDs of the NFTs to accept
+	// If empty, any record is accepted, fungible ones included
+	UniqueIDs []string
+	// PageSize is the maximum number of records the iterator returns
+	// If 0, the query is not paginated
+	PageSize int
+	// Cursor is the continuation token returned by the iterator of the previous page, if any.
+	// The other parameters must not change while paging.
+	// If empty, the query starts from the first transaction
+	Cursor string
+}
+
+// QueryBalancesParams defines the parameters for querying balance checkpoints.
+// For each pair of enrollment ID and token type that matches the filters,
+// the latest checkpoint not after AsOf is returned.
+type QueryBalancesParams struct {
+	// EnrollmentIDs is the enrollment IDs of the accounts to query
+	// If empty, any enrollment ID is accepted
+	EnrollmentIDs []string
+	// TokenTypes is the token types to query
+	// If empty, any token type is accepted
+	TokenTypes []string
+	// AsOf is the point in time of the query
+	// If nil, the latest checkpoints are returned
+	AsOf *time.Time
 }
 
 // TokenTransactionDB defines the interface for a token transactions database
@@ -191,9 +298,14 @@ type TokenTransactionDB interface {
 	// Discard discards the current update to the database
 	Discard() error
 
-	// SetStatus sets the status of a transaction
+	// SetStatus sets the status of a transaction.
+	// If an update is in progress, the change is part of it, otherwise it is committed right away.
 	SetStatus(txID string, status TxStatus) error
 
+	// DeleteTransactions removes the movement and transaction records of the passed transactions.
+	// Balance checkpoints are left untouched.
+	DeleteTransactions(txIDs ...string) error
+
 	// AddMovement adds a movement record to the database.
 	// Each token transaction can be seen as a list of movements.
 	AddMovement(record *MovementRecord) error
@@ -204,8 +316,15 @@ type TokenTransactionDB interface {
 	// QueryTransactions returns a list of transactions that match the given criteria
 	QueryTransactions(params QueryTransactionsParams) (TransactionIterator, error)
 
-	// QueryMovements returns a list of movement records
-	QueryMovements(params QueryMovementsParams) ([]*MovementRecord, error)
+	// QueryMovements returns a list of movement records and the continuation token to get the next page, if any
+	QueryMovements(params QueryMovementsParams) ([]*MovementRecord, string, error)
+
+	// AddBalanceCheckpoint adds a balance checkpoint to the database.
+	AddBalanceCheckpoint(record *BalanceCheckpoint) error
+
+	// QueryBalances returns, for each pair of enrollment ID and token type matching the given criteria,
+	// the latest balance checkpoint, sorted by enrollment ID and token type
+	QueryBalances(params QueryBalancesParams) ([]*BalanceCheckpoint, error)
 }
 
 // Driver is the interface for a database driver
@@ -0,0 +1,263 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/pkg/errors"
+)
+
+// ExportFormat is the format used to export records
+type ExportFormat string
+
+const (
+	// CSV exports records as comma separated values, preceded by a header line
+	CSV ExportFormat = "csv"
+	// JSONL exports records as JSON Lines, one JSON object per record
+	JSONL ExportFormat = "jsonl"
+
+	// exportPageSize is the number of records read from the db at a time while exporting
+	exportPageSize = 1000
+)
+
+var (
+	// TransactionColumns are the columns of an exported transaction record, in order
+	TransactionColumns = []string{"tx_id", "action_type", "sender_eid", "recipient_eid", "token_type", "unique_id", "amount", "timestamp", "status"}
+	// MovementColumns are the columns of an exported movement record, in order
+	MovementColumns = []string{"tx_id", "enrollment_id", "token_type", "amount", "timestamp", "status"}
+)
+
+// ExportTransactions streams the transaction records matching the passed parameters to the passed writer,
+// using the passed format.
+// A transaction record matches the wallets if its sender is the sender wallet or its recipient is the recipient wallet,
+// an empty wallet matches none of them, no wallet matches any record.
+// The columns of each record are those listed in TransactionColumns.
+func (qe *QueryExecutor) ExportTransactions(w io.Writer, format ExportFormat, params QueryTransactionsParams) error {
+	ew, err := newExportWriter(w, format, TransactionColumns)
+	if err != nil {
+		return err
+	}
+
+	// the drivers do not agree on how to match a single wallet, match the wallets here
+	wallets := walletFilter{sender: params.SenderWallet, recipient: params.RecipientWallet}
+	params.SenderWallet = ""
+	params.RecipientWallet = ""
+	params.PageSize = exportPageSize
+	params.Cursor = ""
+	for {
+		it, err := qe.db.db.QueryTransactions(params)
+		if err != nil {
+			return errors.WithMessage(err, "failed to query transactions")
+		}
+		for {
+			tr, err := it.Next()
+			if err != nil {
+				it.Close()
+				return errors.WithMessage(err, "failed to get next transaction")
+			}
+			if tr == nil {
+				break
+			}
+			if !wallets.match(tr.SenderEID, tr.RecipientEID) {
+				continue
+			}
+			if err := ew.Write([]string{
+				tr.TxID,
+				ActionTypeString(tr.ActionType),
+				tr.SenderEID,
+				tr.RecipientEID,
+				tr.TokenType,
+				tr.UniqueID,
+				tr.Amount.String(),
+				formatTimestamp(tr.Timestamp),
+				string(tr.Status),
+			}); err != nil {
+				it.Close()
+				return errors.WithMessagef(err, "failed to export transaction [%s]", tr.TxID)
+			}
+		}
+		params.Cursor, err = it.Cursor()
+		it.Close()
+		if err != nil {
+			return errors.WithMessage(err, "failed to get the next cursor")
+		}
+		if len(params.Cursor) == 0 {
+			break
+		}
+	}
+	return ew.Flush()
+}
+
+// ExportMovements streams the movement records matching the passed parameters to the passed writer,
+// using the passed format.
+// A movement record matches the wallets as a transaction record does: an outgoing movement is sent by its enrollment ID,
+// an incoming one is received by it.
+// A movement record matches if it matches the wallets, its status is one of the passed statuses, if any, the deleted included otherwise, and it was stored in the passed time window, if any.
+// Action types do not apply to movement records.
+// The columns of each record are those listed in MovementColumns.
+func (qe *QueryExecutor) ExportMovements(w io.Writer, format ExportFormat, params QueryTransactionsParams) error {
+	ew, err := newExportWriter(w, format, MovementColumns)
+	if err != nil {
+		return err
+	}
+
+	statuses := params.Statuses
+	if len(statuses) == 0 {
+		// QueryMovements skips the deleted transactions unless asked for them,
+		// export them as ExportTransactions does
+		statuses = []TxStatus{Pending, Confirmed, Deleted}
+	}
+	mp := driver.QueryMovementsParams{
+		TxStatuses:        statuses,
+		SearchDirection:   driver.FromBeginning,
+		MovementDirection: driver.All,
+		PageSize:          exportPageSize,
+	}
+	wallets := walletFilter{sender: params.SenderWallet, recipient: params.RecipientWallet}
+	for _, eID := range []string{params.SenderWallet, params.RecipientWallet} {
+		if len(eID) != 0 {
+			mp.EnrollmentIDs = append(mp.EnrollmentIDs, eID)
+		}
+	}
+	for {
+		records, cursor, err := qe.db.db.QueryMovements(mp)
+		if err != nil {
+			return errors.WithMessage(err, "failed to query movements")
+		}
+		for _, record := range records {
+			if params.From != nil && record.Timestamp.Before(*params.From) {
+				continue
+			}
+			if params.To != nil && record.Timestamp.After(*params.To) {
+				continue
+			}
+			if !wallets.matchMovement(record) {
+				continue
+			}
+			if err := ew.Write([]string{
+				record.TxID,
+				record.EnrollmentID,
+				record.TokenType,
+				record.Amount.String(),
+				formatTimestamp(record.Timestamp),
+				string(record.Status),
+			}); err != nil {
+				return errors.WithMessagef(err, "failed to export movement [%s]", record.TxID)
+			}
+		}
+		if len(cursor) == 0 {
+			break
+		}
+		mp.Cursor = cursor
+	}
+	return ew.Flush()
+}
+
+// walletFilter matches records by their sender and recipient wallets
+type walletFilter struct {
+	sender    string
+	recipient string
+}
+
+// match returns true if no wallet is set, or the passed sender is the sender wallet,
+// or the passed recipient is the recipient wallet
+func (f walletFilter) match(sender, recipient string) bool {
+	if len(f.sender) == 0 && len(f.recipient) == 0 {
+		return true
+	}
+	return (len(f.sender) != 0 && sender == f.sender) || (len(f.recipient) != 0 && recipient == f.recipient)
+}
+
+// matchMovement returns true if the passed movement matches as a transaction record sent by its enrollment ID,
+// if outgoing, or received by it, if incoming
+func (f walletFilter) matchMovement(record *driver.MovementRecord) bool {
+	if record.Amount.Sign() < 0 {
+		return f.match(record.EnrollmentID, "")
+	}
+	return f.match("", record.EnrollmentID)
+}
+
+// ActionTypeString returns the name of the passed action type as used in exported records
+func ActionTypeString(actionType ActionType) string {
+	switch actionType {
+	case Issue:
+		return "Issue"
+	case Transfer:
+		return "Transfer"
+	case Redeem:
+		return "Redeem"
+	default:
+		return strconv.Itoa(int(actionType))
+	}
+}
+
+// formatTimestamp formats the passed timestamp in RFC3339 with nanoseconds, in UTC.
+// Zero timestamps, as those of the movements stored by earlier versions, are exported as empty strings.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// exportWriter writes records, given as lists of values, in a given format
+type exportWriter interface {
+	Write(values []string) error
+	Flush() error
+}
+
+func newExportWriter(w io.Writer, format ExportFormat, columns []string) (exportWriter, error) {
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, errors.Wrap(err, "failed to write csv header")
+		}
+		return &csvWriter{w: cw}, nil
+	case JSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	default:
+		return nil, errors.Errorf("export format [%s] not supported", format)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (j *jsonlWriter) Write(values []string) error {
+	// encoding a map sorts the keys, this gives a stable output
+	record := make(map[string]string, len(j.columns))
+	for i, column := range j.columns {
+		record[column] = values[i]
+	}
+	return j.encoder.Encode(record)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/memory"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	p := &memory.Persistence{}
+	timestamp := time.Date(2022, time.March, 1, 10, 30, 0, 0, time.UTC)
+	assert.NoError(t, p.AddTransaction(&driver.TransactionRecord{
+		TxID:         "tx1",
+		ActionType:   driver.Issue,
+		RecipientEID: "alice",
+		TokenType:    "EUR",
+		UniqueID:     "u1",
+		Amount:       big.NewInt(10),
+		Timestamp:    timestamp,
+		Status:       driver.Confirmed,
+	}))
+	assert.NoError(t, p.AddTransaction(&driver.TransactionRecord{
+		TxID:         "tx2",
+		ActionType:   driver.Transfer,
+		SenderEID:    "alice",
+		RecipientEID: "bob, jr.",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(3),
+		Timestamp:    timestamp.Add(time.Hour),
+		Status:       driver.Pending,
+	}))
+	assert.NoError(t, p.AddMovement(&driver.MovementRecord{
+		TxID:         "tx1",
+		EnrollmentID: "alice",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(10),
+		Status:       driver.Confirmed,
+		Timestamp:    timestamp,
+	}))
+	assert.NoError(t, p.AddMovement(&driver.MovementRecord{
+		TxID:         "tx2",
+		EnrollmentID: "alice",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(-3),
+		Status:       driver.Pending,
+		Timestamp:    timestamp.Add(time.Hour),
+	}))
+	assert.NoError(t, p.AddTransaction(&driver.TransactionRecord{
+		TxID:         "tx3",
+		ActionType:   driver.Transfer,
+		SenderEID:    "alice",
+		RecipientEID: "carol",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(1),
+		Timestamp:    timestamp.Add(2 * time.Hour),
+		Status:       driver.Deleted,
+	}))
+	assert.NoError(t, p.AddMovement(&driver.MovementRecord{
+		TxID:         "tx3",
+		EnrollmentID: "alice",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(-1),
+		Status:       driver.Deleted,
+		Timestamp:    timestamp.Add(2 * time.Hour),
+	}))
+
+	qe := ttxdb.NewDB(p).NewQueryExecutor()
+	defer qe.Done()
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, qe.ExportTransactions(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{}))
+	assert.Equal(t, "tx_id,action_type,sender_eid,recipient_eid,token_type,unique_id,amount,timestamp,status\n"+
+		"tx1,Issue,,alice,EUR,u1,10,2022-03-01T10:30:00Z,Confirmed\n"+
+		"tx2,Transfer,alice,\"bob, jr.\",EUR,,3,2022-03-01T11:30:00Z,Pending\n"+
+		"tx3,Transfer,alice,carol,EUR,,1,2022-03-01T12:30:00Z,Deleted\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, qe.ExportTransactions(buf, ttxdb.JSONL, ttxdb.QueryTransactionsParams{Statuses: []ttxdb.TxStatus{ttxdb.Confirmed}}))
+	assert.Equal(t, `{"action_type":"Issue","amount":"10","recipient_eid":"alice","sender_eid":"","status":"Confirmed","timestamp":"2022-03-01T10:30:00Z","token_type":"EUR","tx_id":"tx1","unique_id":"u1"}`+"\n", buf.String())
+
+	buf.Reset()
+	from := timestamp.Add(time.Minute)
+	assert.NoError(t, qe.ExportMovements(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{From: &from}))
+	assert.Equal(t, "tx_id,enrollment_id,token_type,amount,timestamp,status\n"+
+		"tx2,alice,EUR,-3,2022-03-01T11:30:00Z,Pending\n"+
+		"tx3,alice,EUR,-1,2022-03-01T12:30:00Z,Deleted\n", buf.String())
+
+	// both exports apply the same status filter
+	buf.Reset()
+	assert.NoError(t, qe.ExportMovements(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{Statuses: []ttxdb.TxStatus{ttxdb.Pending}}))
+	assert.Equal(t, "tx_id,enrollment_id,token_type,amount,timestamp,status\n"+
+		"tx2,alice,EUR,-3,2022-03-01T11:30:00Z,Pending\n", buf.String())
+
+	// both exports apply the same wallet filter
+	buf.Reset()
+	assert.NoError(t, qe.ExportTransactions(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{SenderWallet: "alice"}))
+	assert.Equal(t, "tx_id,action_type,sender_eid,recipient_eid,token_type,unique_id,amount,timestamp,status\n"+
+		"tx2,Transfer,alice,\"bob, jr.\",EUR,,3,2022-03-01T11:30:00Z,Pending\n"+
+		"tx3,Transfer,alice,carol,EUR,,1,2022-03-01T12:30:00Z,Deleted\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, qe.ExportMovements(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{SenderWallet: "alice"}))
+	assert.Equal(t, "tx_id,enrollment_id,token_type,amount,timestamp,status\n"+
+		"tx2,alice,EUR,-3,2022-03-01T11:30:00Z,Pending\n"+
+		"tx3,alice,EUR,-1,2022-03-01T12:30:00Z,Deleted\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, qe.ExportTransactions(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{RecipientWallet: "alice"}))
+	assert.Equal(t, "tx_id,action_type,sender_eid,recipient_eid,token_type,unique_id,amount,timestamp,status\n"+
+		"tx1,Issue,,alice,EUR,u1,10,2022-03-01T10:30:00Z,Confirmed\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, qe.ExportMovements(buf, ttxdb.CSV, ttxdb.QueryTransactionsParams{RecipientWallet: "alice"}))
+	assert.Equal(t, "tx_id,enrollment_id,token_type,amount,timestamp,status\n"+
+		"tx1,alice,EUR,10,2022-03-01T10:30:00Z,Confirmed\n", buf.String())
+
+	assert.Error(t, qe.ExportMovements(buf, "xml", ttxdb.QueryTransactionsParams{}))
+}
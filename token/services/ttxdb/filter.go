@@ -8,6 +8,7 @@ package ttxdb
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
 )
@@ -39,7 +40,7 @@ func (f *PaymentsFilter) Execute() (*PaymentsFilter, error) {
 	f.params.TxStatuses = []driver.TxStatus{driver.Pending, driver.Confirmed}
 	f.params.MovementDirection = driver.Sent
 	f.params.SearchDirection = driver.FromLast
-	records, err := f.db.db.QueryMovements(f.params)
+	records, _, err := f.db.db.QueryMovements(f.params)
 	if err != nil {
 		return nil, err
 	}
@@ -56,10 +57,12 @@ func (f *PaymentsFilter) Sum() *big.Int {
 	return sum
 }
 
+// HoldingsFilter is a filter for holdings.
+// Holdings are read from the balance checkpoints maintained by the DB.
 type HoldingsFilter struct {
-	db      *DB
-	params  driver.QueryMovementsParams
-	records []*driver.MovementRecord
+	db          *DB
+	params      driver.QueryBalancesParams
+	checkpoints []*driver.BalanceCheckpoint
 }
 
 func (f *HoldingsFilter) ByEnrollmentId(id string) *HoldingsFilter {
@@ -72,22 +75,32 @@ func (f *HoldingsFilter) ByType(tokenType string) *HoldingsFilter {
 	return f
 }
 
+// AsOf sets the point in time of the holdings.
+// The holdings are those recorded by the DB at the passed time: pending transactions are accounted for
+// from the moment they were appended, and deleted transactions until the moment they were deleted.
+func (f *HoldingsFilter) AsOf(t time.Time) *HoldingsFilter {
+	f.params.AsOf = &t
+	return f
+}
+
 func (f *HoldingsFilter) Execute() (*HoldingsFilter, error) {
-	f.params.TxStatuses = []driver.TxStatus{driver.Pending, driver.Confirmed}
-	f.params.MovementDirection = driver.All
-	f.params.SearchDirection = driver.FromBeginning
-	records, err := f.db.db.QueryMovements(f.params)
+	checkpoints, err := f.db.db.QueryBalances(f.params)
 	if err != nil {
 		return nil, err
 	}
-	f.records = records
+	f.checkpoints = checkpoints
 	return f, nil
 }
 
 func (f *HoldingsFilter) Sum() *big.Int {
 	sum := big.NewInt(0)
-	for _, record := range f.records {
-		sum = sum.Add(sum, record.Amount)
+	for _, checkpoint := range f.checkpoints {
+		sum = sum.Add(sum, checkpoint.Amount)
 	}
 	return sum
 }
+
+// Checkpoints returns the balance checkpoints matched by the filter, one per pair of enrollment ID and token type.
+func (f *HoldingsFilter) Checkpoints() []*BalanceCheckpoint {
+	return f.checkpoints
+}
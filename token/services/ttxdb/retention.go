@@ -0,0 +1,210 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/pkg/errors"
+)
+
+// DefaultCompactionInterval is the time between two compactions when the retention policy does not set one
+const DefaultCompactionInterval = time.Hour
+
+// RetentionPolicy defines which records are removed from the db by compaction.
+// The age of a record is measured from the time it was stored.
+type RetentionPolicy = config.Retention
+
+// CompactionReport describes the outcome of a compaction
+type CompactionReport struct {
+	// Purged is the number of deleted transactions whose records have been purged
+	Purged int
+	// Archived is the number of confirmed transactions whose records have been archived
+	Archived int
+	// Segment is the segment the archived records have been written to, if any
+	Segment *Segment
+}
+
+// Archive returns the archive the records of this db are moved to by compaction,
+// nil if the retention policy of the db does not archive records.
+func (db *DB) Archive() *Archive {
+	return db.archive
+}
+
+// Compact applies the passed retention policy to the records stored before the passed time minus the policy thresholds.
+// The records of deleted transactions are purged, those of confirmed transactions are written to a new segment
+// of the passed archive and then removed from the db.
+// Balance checkpoints are never removed, holdings are not affected.
+func (db *DB) Compact(policy *RetentionPolicy, archive *Archive, now time.Time) (*CompactionReport, error) {
+	logger.Debugf("Compacting...[%d]", db.counter)
+	db.storeLock.Lock()
+	defer db.storeLock.Unlock()
+	logger.Debug("lock acquired")
+
+	report := &CompactionReport{}
+	if policy.PurgeDeletedAfter > 0 {
+		transactions, movements, err := db.selectRecords(driver.Deleted, now.Add(-policy.PurgeDeletedAfter))
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed selecting deleted records")
+		}
+		txIDs := recordsTxIDs(transactions, movements)
+		if err := db.db.DeleteTransactions(txIDs...); err != nil {
+			return nil, errors.WithMessagef(err, "failed purging [%d] deleted transactions", len(txIDs))
+		}
+		report.Purged = len(txIDs)
+	}
+	if policy.ArchiveConfirmedAfter > 0 && archive != nil {
+		transactions, movements, err := db.selectRecords(driver.Confirmed, now.Add(-policy.ArchiveConfirmedAfter))
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed selecting confirmed records")
+		}
+		txIDs := recordsTxIDs(transactions, movements)
+		if len(txIDs) != 0 {
+			// the transactions archived by a compaction that failed to remove them are not archived again
+			transactions, movements, err = archive.unarchived(transactions, movements)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed checking archived transactions")
+			}
+			if len(transactions) != 0 || len(movements) != 0 {
+				segment, err := archive.write(transactions, movements)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed archiving [%d] confirmed transactions", len(txIDs))
+				}
+				report.Segment = segment
+			}
+			if err := db.db.DeleteTransactions(txIDs...); err != nil {
+				return nil, errors.WithMessagef(err, "failed removing [%d] archived transactions", len(txIDs))
+			}
+			report.Archived = len(txIDs)
+		}
+	}
+	logger.Debugf("Compacting...[%d] done, purged [%d], archived [%d]", db.counter, report.Purged, report.Archived)
+	return report, nil
+}
+
+// selectRecords returns the transaction and movement records, in the passed status, of the transactions
+// stored not after the passed time.
+// Movements stored by earlier versions have no timestamp, they are selected only with the transaction they belong to.
+func (db *DB) selectRecords(status driver.TxStatus, before time.Time) ([]*driver.TransactionRecord, []*driver.MovementRecord, error) {
+	it, err := db.db.QueryTransactions(driver.QueryTransactionsParams{
+		Statuses: []driver.TxStatus{status},
+		To:       &before,
+	})
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed querying transactions")
+	}
+	defer it.Close()
+	var transactions []*driver.TransactionRecord
+	selected := map[string]bool{}
+	for {
+		tr, err := it.Next()
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed getting next transaction")
+		}
+		if tr == nil {
+			break
+		}
+		transactions = append(transactions, tr)
+		selected[tr.TxID] = true
+	}
+
+	all, _, err := db.db.QueryMovements(driver.QueryMovementsParams{
+		TxStatuses:        []driver.TxStatus{status},
+		MovementDirection: driver.All,
+		SearchDirection:   driver.FromBeginning,
+	})
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed querying movements")
+	}
+	var movements []*driver.MovementRecord
+	for _, record := range all {
+		if selected[record.TxID] || (!record.Timestamp.IsZero() && !record.Timestamp.After(before)) {
+			movements = append(movements, record)
+		}
+	}
+	return transactions, movements, nil
+}
+
+// recordsTxIDs returns the IDs of the transactions the passed records belong to
+func recordsTxIDs(transactions []*driver.TransactionRecord, movements []*driver.MovementRecord) []string {
+	var txIDs []string
+	for _, record := range transactions {
+		txIDs = append(txIDs, record.TxID)
+	}
+	for _, record := range movements {
+		txIDs = append(txIDs, record.TxID)
+	}
+	return deduplicate(txIDs)
+}
+
+// retentionPolicy returns the retention policy configured for the TMS of the passed wallet, if any
+func retentionPolicy(w Wallet) *RetentionPolicy {
+	tms := w.TMS()
+	if tms == nil {
+		return nil
+	}
+	cfg := tms.ConfigManager().TTXDB()
+	if cfg == nil || cfg.Retention == nil {
+		return nil
+	}
+	if cfg.Retention.PurgeDeletedAfter <= 0 && cfg.Retention.ArchiveConfirmedAfter <= 0 {
+		return nil
+	}
+	return cfg.Retention
+}
+
+// startCompaction runs the compaction of the db periodically, according to the passed policy, until StopCompaction is called
+func (db *DB) startCompaction(name string, policy *RetentionPolicy) {
+	interval := policy.Interval
+	if interval <= 0 {
+		interval = DefaultCompactionInterval
+	}
+	logger.Infof("starting compaction of [%s] every [%s]", name, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-db.stopCompaction:
+				logger.Infof("compaction of [%s] stopped", name)
+				return
+			case <-ticker.C:
+			}
+			report, err := db.Compact(policy, db.archive, time.Now())
+			if err != nil {
+				logger.Errorf("failed compacting [%s]: [%s]", name, err)
+				continue
+			}
+			if report.Purged != 0 || report.Archived != 0 {
+				logger.Infof("compacted [%s]: purged [%d], archived [%d] transactions", name, report.Purged, report.Archived)
+			}
+		}
+	}()
+}
+
+// StopCompaction stops the periodic compaction of the db, if any. A compaction in progress is completed.
+func (db *DB) StopCompaction() {
+	db.stopCompactionOnce.Do(func() {
+		close(db.stopCompaction)
+	})
+}
+
+// archivePath returns the folder, under the passed one, where the records of the db with the passed name are archived
+func archivePath(root, name string) string {
+	return filepath.Join(root, strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name))
+}
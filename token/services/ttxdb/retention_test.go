@@ -0,0 +1,210 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/memory"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompact(t *testing.T) {
+	archivePath, err := ioutil.TempDir("", "ttxdb-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(archivePath)
+
+	p := &memory.Persistence{}
+	now := time.Date(2022, time.March, 10, 0, 0, 0, 0, time.UTC)
+	// one transaction per day, starting 9 days ago
+	statuses := []driver.TxStatus{driver.Confirmed, driver.Deleted, driver.Confirmed, driver.Pending, driver.Deleted, driver.Confirmed, driver.Confirmed, driver.Deleted, driver.Confirmed}
+	for i, status := range statuses {
+		timestamp := now.Add(time.Duration(i-len(statuses)) * 24 * time.Hour)
+		txID := fmt.Sprintf("tx%d", i)
+		assert.NoError(t, p.AddTransaction(&driver.TransactionRecord{
+			TxID:         txID,
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "EUR",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    timestamp,
+			Status:       status,
+		}))
+		assert.NoError(t, p.AddMovement(&driver.MovementRecord{
+			TxID:         txID,
+			EnrollmentID: "alice",
+			TokenType:    "EUR",
+			Amount:       big.NewInt(int64(i + 1)),
+			Timestamp:    timestamp,
+			Status:       status,
+		}))
+	}
+	// a movement stored by an earlier version, with no timestamp and no transaction, is never selected
+	assert.NoError(t, p.AddMovement(&driver.MovementRecord{
+		TxID:         "legacy",
+		EnrollmentID: "alice",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(1),
+		Status:       driver.Confirmed,
+	}))
+	assert.NoError(t, p.AddBalanceCheckpoint(&driver.BalanceCheckpoint{EnrollmentID: "alice", TokenType: "EUR", Amount: big.NewInt(30), Timestamp: now}))
+
+	db := ttxdb.NewDB(p)
+	archive := ttxdb.NewArchive(archivePath)
+	report, err := db.Compact(&ttxdb.RetentionPolicy{
+		PurgeDeletedAfter:     3 * 24 * time.Hour,
+		ArchiveConfirmedAfter: 5 * 24 * time.Hour,
+	}, archive, now)
+	assert.NoError(t, err)
+	// tx1 and tx4 are purged, tx7 is too recent
+	assert.Equal(t, 2, report.Purged)
+	// tx0 and tx2 are archived, tx5 and later are too recent
+	assert.Equal(t, 2, report.Archived)
+	assert.NotNil(t, report.Segment)
+
+	assert.Equal(t, []string{"tx3", "tx5", "tx6", "tx7", "tx8"}, txIDs(t, db))
+	qe := db.NewQueryExecutor()
+	movements, _, err := qe.Movements(ttxdb.QueryMovementsParams{TxIDs: []string{"legacy"}, MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, movements, 1)
+	qe.Done()
+
+	// balances are not affected
+	qe = db.NewQueryExecutor()
+	holdings, err := qe.NewHoldingsFilter().ByEnrollmentId("alice").ByType("EUR").Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, holdings.Sum().Cmp(big.NewInt(30)))
+	qe.Done()
+
+	// nothing else to do
+	report, err = db.Compact(&ttxdb.RetentionPolicy{
+		PurgeDeletedAfter:     3 * 24 * time.Hour,
+		ArchiveConfirmedAfter: 5 * 24 * time.Hour,
+	}, archive, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Purged)
+	assert.Equal(t, 0, report.Archived)
+	assert.Nil(t, report.Segment)
+
+	segments, err := archive.Segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+	assert.True(t, segments[0].From.Equal(now.Add(-9*24*time.Hour)))
+	assert.True(t, segments[0].To.Equal(now.Add(-7*24*time.Hour)))
+
+	// restore the whole archive
+	dst := &memory.Persistence{}
+	n, err := archive.Restore(dst, now.Add(-30*24*time.Hour), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	restored := ttxdb.NewDB(dst)
+	assert.Equal(t, []string{"tx0", "tx2"}, txIDs(t, restored))
+	qe = restored.NewQueryExecutor()
+	movements, _, err = qe.Movements(ttxdb.QueryMovementsParams{MovementDirection: driver.All, TxStatuses: []driver.TxStatus{driver.Confirmed}})
+	assert.NoError(t, err)
+	assert.Len(t, movements, 2)
+	assert.Equal(t, 0, movements[0].Amount.Cmp(big.NewInt(3)))
+	qe.Done()
+
+	// restore a range
+	dst = &memory.Persistence{}
+	n, err = archive.Restore(dst, now.Add(-8*24*time.Hour), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"tx2"}, txIDs(t, ttxdb.NewDB(dst)))
+}
+
+// failingDeletePersistence fails the first removal of records
+type failingDeletePersistence struct {
+	*memory.Persistence
+	failed bool
+}
+
+func (p *failingDeletePersistence) DeleteTransactions(txIDs ...string) error {
+	if !p.failed {
+		p.failed = true
+		return errors.New("delete failed")
+	}
+	return p.Persistence.DeleteTransactions(txIDs...)
+}
+
+func TestCompactIdempotent(t *testing.T) {
+	archivePath, err := ioutil.TempDir("", "ttxdb-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(archivePath)
+
+	p := &failingDeletePersistence{Persistence: &memory.Persistence{}}
+	now := time.Date(2022, time.March, 10, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, p.AddTransaction(&driver.TransactionRecord{
+			TxID:         fmt.Sprintf("tx%d", i),
+			ActionType:   driver.Issue,
+			RecipientEID: "alice",
+			TokenType:    "EUR",
+			Amount:       big.NewInt(1),
+			Timestamp:    now.Add(time.Duration(i-10) * 24 * time.Hour),
+			Status:       driver.Confirmed,
+		}))
+	}
+
+	db := ttxdb.NewDB(p)
+	archive := ttxdb.NewArchive(archivePath)
+	policy := &ttxdb.RetentionPolicy{ArchiveConfirmedAfter: 5 * 24 * time.Hour}
+	// the segment is written but the records are not removed
+	_, err = db.Compact(policy, archive, now)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"tx0", "tx1", "tx2"}, txIDs(t, db))
+
+	// a record stored meanwhile is archived in a new segment, the others are removed only
+	assert.NoError(t, p.AddTransaction(&driver.TransactionRecord{
+		TxID:         "tx3",
+		ActionType:   driver.Issue,
+		RecipientEID: "alice",
+		TokenType:    "EUR",
+		Amount:       big.NewInt(1),
+		Timestamp:    now.Add(-6 * 24 * time.Hour),
+		Status:       driver.Confirmed,
+	}))
+	report, err := db.Compact(policy, archive, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Archived)
+	assert.Len(t, txIDs(t, db), 0)
+
+	segments, err := archive.Segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2)
+	dst := &memory.Persistence{}
+	n, err := archive.Restore(dst, now.Add(-30*24*time.Hour), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []string{"tx0", "tx1", "tx2", "tx3"}, txIDs(t, ttxdb.NewDB(dst)))
+}
+
+func txIDs(t *testing.T, db *ttxdb.DB) []string {
+	qe := db.NewQueryExecutor()
+	defer qe.Done()
+	it, err := qe.Transactions(ttxdb.QueryTransactionsParams{Statuses: []driver.TxStatus{driver.Pending, driver.Confirmed, driver.Deleted}})
+	assert.NoError(t, err)
+	defer it.Close()
+	var res []string
+	for {
+		tr, err := it.Next()
+		assert.NoError(t, err)
+		if tr == nil {
+			return res
+		}
+		res = append(res, tr.TxID)
+	}
+}
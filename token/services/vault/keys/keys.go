@@ -26,11 +26,14 @@ const (
 	TokenMineKeyPrefix          = "mine"
 	TokenSetupKeyPrefix         = "setup"
 	IssuedHistoryTokenKeyPrefix = "issued"
+	NFTIndexKeyPrefix           = "nftidx"
 	TokenNamespace              = "tns"
 	numComponentsInKey          = 2 // 2 components: txid, index, excluding TokenKeyPrefix
 	numComponentsInExtendedKey  = 4 // 2 components: id, type, txid, index, excluding TokenKeyPrefix
+	numComponentsInNFTIndexKey  = 5 // 5 components: id, path, value, txid, index, excluding NFTIndexKeyPrefix
 	Info                        = "info"
 	IDs                         = "ids"
+	NFTIndexes                  = "nftidxs"
 	TokenRequestKeyPrefix       = "token_request"
 	SerialNumber                = "sn"
 	IssueActionMetadata         = "iam"
@@ -78,6 +81,26 @@ func GetTokenIdFromExtendedKey(key string) (*token.ID, error) {
 	return &token.ID{TxId: txID, Index: index}, nil
 }
 
+func GetTokenIdFromNFTIndexKey(key string) (*token.ID, error) {
+	_, components, err := SplitCompositeKey(key)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error splitting input composite key: '%s'", err))
+	}
+
+	// 5 components in key: id, path, value, txid, index
+	if len(components) != numComponentsInNFTIndexKey {
+		return nil, errors.New(fmt.Sprintf("not enough components in nft index composite key; expected 5, received '%s'", components))
+	}
+
+	// txid and index are the last 2 components
+	txID := components[numComponentsInNFTIndexKey-2]
+	index, err := strconv.ParseUint(components[numComponentsInNFTIndexKey-1], 10, 64)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error parsing output index '%s': '%s'", components[numComponentsInNFTIndexKey-1], err))
+	}
+	return &token.ID{TxId: txID, Index: index}, nil
+}
+
 func SplitCompositeKey(compositeKey string) (string, []string, error) {
 	componentIndex := 1
 	components := []string{}
@@ -113,6 +136,12 @@ func CreateExtendedFabTokenKey(id string, typ string, txID string, index uint64)
 	return CreateCompositeKey(FabTokenExtendedKeyPrefix, []string{id, typ, txID, strconv.FormatUint(index, 10)})
 }
 
+// CreateNFTIndexKey returns the key indexing, for the passed wallet id, the token with the passed transaction id and index
+// by the passed encoded value at the passed path of its state
+func CreateNFTIndexKey(id string, path string, value string, txID string, index uint64) (string, error) {
+	return CreateCompositeKey(NFTIndexKeyPrefix, []string{id, path, value, txID, strconv.FormatUint(index, 10)})
+}
+
 func CreateAuditTokenKey(txID string, index uint64) (string, error) {
 	return CreateCompositeKey(AuditTokenKeyPrefix, []string{txID, strconv.FormatUint(index, 10)})
 }
@@ -96,6 +96,52 @@ func (e *Engine) UnspentTokensIteratorBy(id, typ string) (driver2.UnspentTokensI
 	return &UnspentTokensIterator{it: iterator, e: e, extended: true}, nil
 }
 
+// IndexedTokenIDs returns the ids of the unspent NFTs owned by the passed id whose state has, at the passed indexed path,
+// a value whose encoding is in the range [from, to)
+func (e *Engine) IndexedTokenIDs(id, path, from, to string) ([]*token.ID, error) {
+	if len(id) == 0 {
+		return nil, errors.New("wallet id must be specified")
+	}
+	prefix, err := keys.CreateCompositeKey(keys.NFTIndexKeyPrefix, []string{id, path})
+	if err != nil {
+		return nil, err
+	}
+	startKey := prefix + from
+	endKey := prefix + to
+
+	qe, err := e.Vault.NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+
+	logger.Debugf("Get range query scan iterator... [%s,%s]", startKey, endKey)
+	iterator, err := qe.GetStateRangeScanIterator(e.namespace, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	var ids []*token.ID
+	for {
+		next, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return ids, nil
+		}
+		if len(next.V()) == 0 {
+			continue
+		}
+		id, err := keys.GetTokenIdFromNFTIndexKey(next.K())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to extract token id from key [%s]", next.K())
+		}
+		ids = append(ids, id)
+	}
+}
+
 func (e *Engine) UnspentTokensIterator() (driver2.UnspentTokensIterator, error) {
 	logger.Debugf("List token iterator...")
 	startKey, err := keys.CreateCompositeKey(keys.FabTokenKeyPrefix, nil)
@@ -328,6 +374,48 @@ func (e *Engine) GetTokenCommitments(ids []*token.ID, callback driver2.QueryCall
 	return nil
 }
 
+// LedgerTokens invokes the passed callback for each token on the ledger, as stored in the vault,
+// with the token identifier and the token commitment
+func (e *Engine) LedgerTokens(callback driver2.QueryCallbackFunc) error {
+	startKey, err := keys.CreateCompositeKey(keys.TokenKeyPrefix, nil)
+	if err != nil {
+		return err
+	}
+	endKey := startKey + string(keys.MaxUnicodeRuneValue)
+
+	qe, err := e.Vault.NewQueryExecutor()
+	if err != nil {
+		return err
+	}
+	defer qe.Done()
+
+	iterator, err := qe.GetStateRangeScanIterator(e.namespace, startKey, endKey)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	for {
+		next, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			return nil
+		}
+		if len(next.V()) == 0 {
+			continue
+		}
+		// the same prefix is used by serial numbers, setup, and other entries, that are not tokens
+		id, err := keys.GetTokenIdFromKey(next.K())
+		if err != nil {
+			continue
+		}
+		if err := callback(id, next.V()); err != nil {
+			return err
+		}
+	}
+}
+
 func (e *Engine) GetTokenInfoAndCommitments(ids []*token.ID, callback driver2.QueryCallback2Func) error {
 	qe, err := e.Vault.NewQueryExecutor()
 	if err != nil {
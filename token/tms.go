@@ -234,6 +234,12 @@ func (t *ManagementService) CertificationClient() *CertificationClient {
 	return &CertificationClient{cc: certificationClient}
 }
 
+// BatchIssueSupported returns true if the issue actions of this TMS can carry tokens of different types
+func (t *ManagementService) BatchIssueSupported() bool {
+	_, ok := t.tms.(driver.BatchIssueService)
+	return ok
+}
+
 // PublicParametersManager returns a manager that gives access to the public parameters
 // governing this TMS.
 func (t *ManagementService) PublicParametersManager() *PublicParametersManager {
@@ -8,6 +8,7 @@ package token
 
 import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
 )
 
 // Ledger models a read-only ledger
@@ -33,3 +34,33 @@ func (c *Validator) UnmarshallAndVerify(ledger Ledger, anchor string, raw []byte
 	copy(res, actions)
 	return res, nil
 }
+
+// UnmarshallAndVerifyAll unmarshalls the passed token requests and verifies them against the passed ledger and anchors.
+// If the driver supports it, the proofs of all the requests are verified at once, in a batch.
+// It returns, for each request, either its actions or the reason why it is invalid.
+func (c *Validator) UnmarshallAndVerifyAll(ledger Ledger, anchors []string, raws [][]byte) ([][]interface{}, []error) {
+	if len(anchors) != len(raws) {
+		errs := make([]error, len(raws))
+		for i := range errs {
+			errs[i] = errors.Errorf("expected [%d] anchors, got [%d]", len(raws), len(anchors))
+		}
+		return make([][]interface{}, len(raws)), errs
+	}
+	getState := func(key string) ([]byte, error) {
+		return ledger.GetState(key)
+	}
+	bv, ok := c.backend.(driver.BatchValidator)
+	if !ok {
+		actions := make([][]interface{}, len(raws))
+		errs := make([]error, len(raws))
+		for i := range raws {
+			actions[i], errs[i] = c.backend.VerifyTokenRequestFromRaw(getState, anchors[i], raws[i])
+		}
+		return actions, errs
+	}
+	requests := make([]*driver.BatchedTokenRequest, len(raws))
+	for i := range raws {
+		requests[i] = &driver.BatchedTokenRequest{Anchor: anchors[i], Raw: raws[i]}
+	}
+	return bv.VerifyTokenRequestsFromRaw(getState, requests)
+}
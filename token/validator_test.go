@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+)
+
+type ledger struct{}
+
+func (l *ledger) GetState(key string) ([]byte, error) {
+	return []byte(key), nil
+}
+
+// validator accepts the requests whose raw is "valid"
+type validator struct {
+	calls int
+}
+
+func (v *validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, anchor string, raw []byte) ([]interface{}, error) {
+	v.calls++
+	if string(raw) != "valid" {
+		return nil, errors.Errorf("invalid request [%s]", anchor)
+	}
+	state, err := getState(anchor)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{string(state)}, nil
+}
+
+type batchValidator struct {
+	validator
+	batches [][]*driver.BatchedTokenRequest
+}
+
+func (v *batchValidator) VerifyTokenRequestsFromRaw(getState driver.GetStateFnc, requests []*driver.BatchedTokenRequest) ([][]interface{}, []error) {
+	v.batches = append(v.batches, requests)
+	actions := make([][]interface{}, len(requests))
+	errs := make([]error, len(requests))
+	for i, r := range requests {
+		actions[i], errs[i] = v.validator.VerifyTokenRequestFromRaw(getState, r.Anchor, r.Raw)
+	}
+	return actions, errs
+}
+
+func TestUnmarshallAndVerifyAll(t *testing.T) {
+	anchors := []string{"a", "b", "c"}
+	raws := [][]byte{[]byte("valid"), []byte("invalid"), []byte("valid")}
+
+	// the requests are verified one by one, when the driver does not support batches
+	v := &validator{}
+	actions, errs := (&Validator{backend: v}).UnmarshallAndVerifyAll(&ledger{}, anchors, raws)
+	assert.Equal(t, 3, v.calls)
+	assert.Equal(t, [][]interface{}{{"a"}, nil, {"c"}}, actions)
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "invalid request [b]")
+	assert.NoError(t, errs[2])
+
+	// the requests are verified at once, otherwise
+	bv := &batchValidator{}
+	actions, errs = (&Validator{backend: bv}).UnmarshallAndVerifyAll(&ledger{}, anchors, raws)
+	assert.Len(t, bv.batches, 1)
+	assert.Len(t, bv.batches[0], 3)
+	assert.Equal(t, "b", bv.batches[0][1].Anchor)
+	assert.Equal(t, [][]interface{}{{"a"}, nil, {"c"}}, actions)
+	assert.EqualError(t, errs[1], "invalid request [b]")
+
+	// anchors and requests must match
+	_, errs = (&Validator{backend: bv}).UnmarshallAndVerifyAll(&ledger{}, anchors[:2], raws)
+	assert.Len(t, errs, 3)
+	assert.EqualError(t, errs[0], "expected [3] anchors, got [2]")
+}
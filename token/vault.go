@@ -39,6 +39,12 @@ func (q *QueryEngine) UnspentTokensIteratorBy(walletID, tokenType string) (*Unsp
 	return &UnspentTokensIterator{UnspentTokensIterator: it}, nil
 }
 
+// IndexedTokenIDs returns the ids of the unspent NFTs owned by passed wallet id whose state has, at the passed indexed path,
+// a value whose encoding is in the range [from, to)
+func (q *QueryEngine) IndexedTokenIDs(walletID, path, from, to string) ([]*token2.ID, error) {
+	return q.qe.IndexedTokenIDs(walletID, path, from, to)
+}
+
 // ListUnspentTokens returns a list of all unspent tokens stored in the vault
 func (q *QueryEngine) ListUnspentTokens() (*token2.UnspentTokens, error) {
 	return q.qe.ListUnspentTokens()